@@ -0,0 +1,7 @@
+package acorn
+
+type Image interface {
+	Read() error
+	DisplayGeometry()
+	Warnings() []string
+}
@@ -0,0 +1,243 @@
+package uef
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// blockSync is the synchronisation byte at the start of each tape block.
+const blockSync = 0x2A
+
+// maxFilenameLength is the longest filename saved by the cassette filing system.
+const maxFilenameLength = 10
+
+// blockHeaderLength is the length of the block header following the filename:
+// the load and execution addresses, the block number and length, the flags,
+// and the spare bytes.
+const blockHeaderLength = 17
+
+// Block flags.
+const (
+	blockLocked = 0x01 // Bit 0: the file is locked
+	blockEmpty  = 0x40 // Bit 6: the block has no data
+	blockLast   = 0x80 // Bit 7: the last block of the file
+)
+
+// Block is a block of a file saved by the Acorn cassette filing system, as
+// used by the BBC Micro and Electron, held in an implicit data chunk. Files
+// are saved as 256 byte blocks, each with a header and CRCs:
+//
+//   BYTE       Synchronisation byte, &2A
+//   CHAR[1-10] Filename, zero terminated
+//   DWORD      Load address
+//   DWORD      Execution address
+//   WORD       Block number, from 0
+//   WORD       Data length
+//   BYTE       Block flags
+//   DWORD      Spare, the address of the next file
+//   WORD       Header CRC, from the filename to the spare bytes, high byte first
+//   BYTE[N]    Data
+//   WORD       Data CRC, high byte first, only when the length is not zero
+type Block struct {
+	Filename    string
+	LoadAddress uint32
+	ExecAddress uint32
+	Number      uint16
+	Length      uint16
+	Flags       uint8
+	Spare       uint32
+	HeaderCRC   uint16
+	Data        []byte
+	DataCRC     uint16
+
+	headerCRC uint16 // CRC calculated from the header
+	dataCRC   uint16 // CRC calculated from the data
+}
+
+// DecodeBlock decodes a cassette filing system block from the tape data.
+func DecodeBlock(data []byte) (*Block, error) {
+	if len(data) == 0 || data[0] != blockSync {
+		return nil, errors.New("tape block has no sync byte")
+	}
+
+	end := strings.IndexByte(string(data[1:]), 0)
+	if end < 0 || end > maxFilenameLength {
+		return nil, errors.New("tape block filename is not terminated")
+	}
+	header := 1 + end + 1
+	if len(data) < header+blockHeaderLength+2 {
+		return nil, errors.Errorf("tape block header is incomplete, %d bytes", len(data))
+	}
+
+	b := &Block{Filename: storage.DecodeLatin1(data[1 : 1+end])}
+	fields := data[header:]
+	b.LoadAddress = binary.LittleEndian.Uint32(fields[0:])
+	b.ExecAddress = binary.LittleEndian.Uint32(fields[4:])
+	b.Number = binary.LittleEndian.Uint16(fields[8:])
+	b.Length = binary.LittleEndian.Uint16(fields[10:])
+	b.Flags = fields[12]
+	b.Spare = binary.LittleEndian.Uint32(fields[13:])
+	b.HeaderCRC = binary.BigEndian.Uint16(fields[blockHeaderLength:])
+	b.headerCRC = crc(data[1 : header+blockHeaderLength])
+
+	if b.Length == 0 {
+		return b, nil
+	}
+	start := header + blockHeaderLength + 2
+	if len(data) < start+int(b.Length)+2 {
+		return b, errors.Errorf("tape block data is incomplete, expected %d bytes, got %d", b.Length, len(data)-start)
+	}
+	b.Data = data[start : start+int(b.Length)]
+	b.DataCRC = binary.BigEndian.Uint16(data[start+int(b.Length):])
+	b.dataCRC = crc(b.Data)
+
+	return b, nil
+}
+
+// Last returns true when this is the last block of the file.
+func (b Block) Last() bool {
+	return b.Flags&blockLast != 0
+}
+
+// Locked returns true when the file is locked, so can only be run.
+func (b Block) Locked() bool {
+	return b.Flags&blockLocked != 0
+}
+
+// HeaderOK returns true when the header CRC is correct.
+func (b Block) HeaderOK() bool {
+	return b.HeaderCRC == b.headerCRC
+}
+
+// DataOK returns true when the data CRC is correct, or there is no data.
+func (b Block) DataOK() bool {
+	return b.Length == 0 || b.DataCRC == b.dataCRC
+}
+
+// String returns the filename, block number and addresses of the block.
+func (b Block) String() string {
+	str := fmt.Sprintf("%q block %d, load &%08X, exec &%08X", storage.DisplayText(b.Filename), b.Number, b.LoadAddress, b.ExecAddress)
+	if b.Last() {
+		str += ", last"
+	}
+	return str
+}
+
+// crc returns the CRC-16 of the data, as calculated by the cassette filing
+// system: the CCITT polynomial &1021, starting from zero.
+func crc(data []byte) uint16 {
+	var c uint16
+	for _, b := range data {
+		c ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x1021
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// File is a file saved on the tape by the cassette filing system, joined from
+// its blocks.
+type File struct {
+	Name        string
+	LoadAddress uint32
+	ExecAddress uint32
+	Locked      bool
+	Data        []byte
+	Blocks      int  // Number of blocks read
+	Missing     bool // Blocks are missing, or the last block was not found
+	BadCRC      bool // Blocks have a CRC error, their data is still included
+}
+
+// notes returns the problems with the file, for the file listing.
+func (f File) notes() string {
+	var notes []string
+	if f.Locked {
+		notes = append(notes, "locked")
+	}
+	if f.Missing {
+		notes = append(notes, "incomplete")
+	}
+	if f.BadCRC {
+		notes = append(notes, "CRC error")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, ", ") + ")"
+}
+
+// Files returns the files saved on the tape by the cassette filing system, in
+// tape order. Each file is started by block 0, or a block with a different
+// filename, and ends with the block flagged as the last. Blocks repeated after
+// a loading error, having a block number already read, are skipped.
+func (u UEF) Files() []File {
+	var files []File
+	var file *File
+	var next uint16
+
+	for _, c := range u.Chunks {
+		block, err := c.TapeBlock()
+		if block == nil || (err != nil && len(block.Data) == 0) {
+			continue
+		}
+
+		if file == nil || block.Number == 0 || block.Filename != file.Name {
+			if file != nil {
+				file.Missing = true
+				files = append(files, *file)
+			}
+			file = &File{
+				Name:        block.Filename,
+				LoadAddress: block.LoadAddress,
+				ExecAddress: block.ExecAddress,
+				Locked:      block.Locked(),
+				Missing:     block.Number != 0,
+			}
+			next = block.Number
+		}
+		if block.Number < next {
+			continue // repeated block
+		}
+		if block.Number > next {
+			file.Missing = true
+		}
+
+		file.Data = append(file.Data, block.Data...)
+		file.Blocks++
+		file.BadCRC = file.BadCRC || !block.HeaderOK() || !block.DataOK() || err != nil
+		next = block.Number + 1
+
+		if block.Last() {
+			files = append(files, *file)
+			file = nil
+		}
+	}
+
+	if file != nil {
+		file.Missing = true
+		files = append(files, *file)
+	}
+
+	return files
+}
+
+// File returns the first file on the tape with the name, ignoring case, as
+// the filing system does.
+func (u UEF) File(name string) (File, error) {
+	for _, f := range u.Files() {
+		if strings.EqualFold(f.Name, name) {
+			return f, nil
+		}
+	}
+	return File{}, errors.Errorf("file '%s' not found on the tape", name)
+}
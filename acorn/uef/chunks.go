@@ -0,0 +1,217 @@
+package uef
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"retroio/storage"
+)
+
+// Chunk IDs of the common UEF chunks.
+const (
+	OriginInfo           = 0x0000 // Text: the program that created the file
+	Instructions         = 0x0001 // Text: game instructions or manual
+	InlayScan            = 0x0003 // Image of the cassette inlay
+	TargetMachine        = 0x0005 // Byte: the machine the tape is for
+	BitMultiplexing      = 0x0006 // Byte: bit multiplexing information
+	ExtraPalette         = 0x0007 // RGB palette entries
+	ROMHint              = 0x0008 // ROMs needed by the tape
+	ShortTitle           = 0x0009 // Text: the title of the tape
+	VisibleArea          = 0x000A // Screen area shown by the program
+	ImplicitData         = 0x0100 // Bytes with 1 start bit and 1 stop bit
+	MultiplexedData      = 0x0101 // Multiplexed bytes, with implicit start and stop bits
+	ExplicitData         = 0x0102 // Bit stream, including the start and stop bits
+	DefinedFormatData    = 0x0104 // Bytes with the bits, parity and stop bits given
+	CarrierTone          = 0x0110 // WORD: cycles of the carrier tone
+	CarrierToneDummyByte = 0x0111 // WORD, WORD: cycles of carrier tone, either side of a dummy &AA byte
+	IntegerGap           = 0x0112 // WORD: gap, in 1/(2*base frequency) seconds
+	BaseFrequency        = 0x0113 // FLOAT: base frequency of the tape, in Hz
+	SecurityCycles       = 0x0114 // Cycles of non-standard length, for copy protection
+	PhaseChange          = 0x0115 // WORD: phase of the waves, in degrees
+	FloatGap             = 0x0116 // FLOAT: gap, in seconds
+	DataEncoding         = 0x0117 // WORD: baud rate of the data, 300 or 1200
+	PositionMarker       = 0x0120 // Text: a position on the tape, e.g. "Side B"
+	TapeSetInfo          = 0x0130 // The tape, side and channel of the following chunks
+	StartOfTapeSide      = 0x0131 // The tape side, and its description
+)
+
+// defaultBaseFrequency of the tape, in Hz, unless changed by a base frequency
+// chunk.
+const defaultBaseFrequency = 1200
+
+// dummyByte is saved between the two carrier tones of a carrier tone with
+// dummy byte chunk.
+const dummyByte = 0xAA
+
+// chunkNames of the chunks decoded, as given in the UEF specification.
+var chunkNames = map[uint16]string{
+	OriginInfo:           "Origin Information",
+	Instructions:         "Instructions",
+	InlayScan:            "Inlay Scan",
+	TargetMachine:        "Target Machine",
+	BitMultiplexing:      "Bit Multiplexing",
+	ExtraPalette:         "Extra Palette",
+	ROMHint:              "ROM Hint",
+	ShortTitle:           "Short Title",
+	VisibleArea:          "Visible Area",
+	ImplicitData:         "Implicit Data",
+	MultiplexedData:      "Multiplexed Data",
+	ExplicitData:         "Explicit Data",
+	DefinedFormatData:    "Defined Format Data",
+	CarrierTone:          "Carrier Tone",
+	CarrierToneDummyByte: "Carrier Tone with Dummy Byte",
+	IntegerGap:           "Gap",
+	BaseFrequency:        "Base Frequency",
+	SecurityCycles:       "Security Cycles",
+	PhaseChange:          "Phase Change",
+	FloatGap:             "Gap",
+	DataEncoding:         "Data Encoding",
+	PositionMarker:       "Position Marker",
+	TapeSetInfo:          "Tape Set Info",
+	StartOfTapeSide:      "Start of Tape Side",
+}
+
+// targetMachines given by the low nibble of the target machine chunk.
+var targetMachines = []string{"BBC Model A", "Electron", "BBC Model B", "BBC Master", "Atom"}
+
+// Chunk is a single chunk of the UEF file, holding the raw chunk data, which
+// is decoded by the methods for each chunk type.
+type Chunk struct {
+	ID     uint16 // Chunk ID
+	Length uint32 // Length of the chunk data
+	Data   []byte // Chunk data
+	Offset int64  // Offset of the chunk ID in the file
+}
+
+// Known returns true for the chunks listed in the UEF specification which are
+// decoded, others being skipped.
+func (c Chunk) Known() bool {
+	_, ok := chunkNames[c.ID]
+	return ok
+}
+
+// Name of the chunk, as given in the UEF specification.
+func (c Chunk) Name() string {
+	if name, ok := chunkNames[c.ID]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Text returns the text of the origin, instructions, title and position marker
+// chunks, which are zero terminated.
+func (c Chunk) Text() string {
+	text := c.Data
+	if i := strings.IndexByte(string(text), 0); i >= 0 {
+		text = text[:i]
+	}
+	return storage.DecodeLatin1(text)
+}
+
+// TapeData returns the bytes of an implicit or defined format data chunk, or
+// nil for other chunks.
+func (c Chunk) TapeData() []byte {
+	switch c.ID {
+	case ImplicitData:
+		return c.Data
+	case DefinedFormatData:
+		if len(c.Data) >= 3 {
+			return c.Data[3:]
+		}
+	}
+	return nil
+}
+
+// TapeBlock decodes the Acorn cassette filing system block held in an implicit
+// data chunk, or returns nil when the chunk does not hold a block, such as the
+// data of custom loaders.
+func (c Chunk) TapeBlock() (*Block, error) {
+	data := c.TapeData()
+	if c.ID != ImplicitData || len(data) == 0 || data[0] != blockSync {
+		return nil, nil
+	}
+	return DecodeBlock(data)
+}
+
+// word returns the WORD at the offset of the chunk data, or 0 when the chunk
+// is too short.
+func (c Chunk) word(offset int) uint16 {
+	if offset+2 > len(c.Data) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(c.Data[offset:])
+}
+
+// float returns the FLOAT, an IEEE 754 single precision number, at the start
+// of the chunk data, or 0 when the chunk is too short.
+func (c Chunk) float() float64 {
+	if len(c.Data) < 4 {
+		return 0
+	}
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(c.Data)))
+}
+
+// Details returns the decoded chunk data, or an empty string for chunks with
+// nothing to show.
+func (c Chunk) Details() string {
+	var details string
+
+	switch c.ID {
+	case OriginInfo, Instructions, ShortTitle, PositionMarker:
+		details = storage.DisplayText(c.Text())
+	case TargetMachine:
+		if len(c.Data) > 0 && int(c.Data[0]&0x0F) < len(targetMachines) {
+			details = targetMachines[c.Data[0]&0x0F]
+		}
+	case ImplicitData:
+		details = fmt.Sprintf("%d bytes", len(c.Data))
+		if block, err := c.TapeBlock(); err != nil {
+			details += fmt.Sprintf(", %s", err)
+		} else if block != nil {
+			details += fmt.Sprintf(", %s", block)
+		}
+	case ExplicitData:
+		bits := 0
+		if len(c.Data) > 0 {
+			bits = (len(c.Data)-1)*8 - int(c.Data[0])
+		}
+		details = fmt.Sprintf("%d bits", bits)
+	case DefinedFormatData:
+		if len(c.Data) >= 3 {
+			details = fmt.Sprintf("%d bytes, %d%c%d", len(c.Data)-3, c.Data[0], c.Data[1], int8(c.Data[2]))
+		}
+	case CarrierTone:
+		details = fmt.Sprintf("%d cycles", c.word(0))
+	case CarrierToneDummyByte:
+		details = fmt.Sprintf("%d cycles, &%02X, %d cycles", c.word(0), dummyByte, c.word(2))
+	case IntegerGap:
+		details = fmt.Sprintf("%.2f seconds (at %d Hz)", float64(c.word(0))/(2*defaultBaseFrequency), defaultBaseFrequency)
+	case FloatGap:
+		details = fmt.Sprintf("%.2f seconds", c.float())
+	case BaseFrequency:
+		details = fmt.Sprintf("%.0f Hz", c.float())
+	case SecurityCycles:
+		if len(c.Data) >= 3 {
+			details = fmt.Sprintf("%d cycles", uint32(c.Data[0])|uint32(c.Data[1])<<8|uint32(c.Data[2])<<16)
+		}
+	case PhaseChange:
+		details = fmt.Sprintf("%d degrees", c.word(0))
+	case DataEncoding:
+		details = fmt.Sprintf("%d baud", c.word(0))
+	default:
+		details = fmt.Sprintf("%d bytes", c.Length)
+	}
+
+	return details
+}
+
+// String returns the chunk name, followed by the decoded chunk data.
+func (c Chunk) String() string {
+	details := c.Details()
+	if details == "" {
+		return c.Name()
+	}
+	return fmt.Sprintf("%-19s : %s", c.Name(), details)
+}
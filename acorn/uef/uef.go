@@ -0,0 +1,166 @@
+// Package uef implements reading of Acorn UEF (Unified Emulator Format) tape
+// files, as used by BBC Micro and Electron emulators, as specified at:
+// http://electrem.emuunlim.com/UEFSpecs.html
+//
+// A UEF file starts with a 12 byte header: the signature "UEF File!", with a
+// zero terminator, followed by the minor and major version numbers. The rest
+// of the file is a stream of chunks, each with a 2 byte chunk ID and a 4 byte
+// length, followed by the chunk data. Chunks with an unknown ID can therefore
+// be skipped by their length.
+//
+// UEF files are usually gzip compressed, which is handled when the file is
+// opened, so only the uncompressed stream is read here.
+//
+// Note: all WORD and DWORD values are stored in little endian byte order.
+package uef
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// signature identifies the file as a UEF file.
+const signature = "UEF File!\x00"
+
+// Latest UEF version supported.
+const (
+	supportedMajorVersion = 0
+	supportedMinorVersion = 10
+)
+
+// UEF File structure
+type UEF struct {
+	reader *storage.Reader
+
+	Signature    [10]byte // File signature "UEF File!", zero terminated
+	MinorVersion uint8
+	MajorVersion uint8
+	Chunks       []Chunk
+}
+
+func New(reader *storage.Reader) *UEF {
+	return &UEF{reader: reader}
+}
+
+// Read processes the header, and then each chunk of the file.
+func (u *UEF) Read() error {
+	if _, err := u.reader.Read(u.Signature[:]); err != nil {
+		return storage.TruncatedError{Blocks: 0, Offset: u.reader.Offset()}
+	}
+	u.MinorVersion = u.reader.ReadUint8()
+	u.MajorVersion = u.reader.ReadUint8()
+	if err := u.reader.Err(); err != nil {
+		return storage.TruncatedError{Blocks: 0, Offset: u.reader.Offset()}
+	}
+
+	if string(u.Signature[:]) != signature {
+		err := errors.Errorf("UEF header: incorrect signature, got '%s'", storage.DisplayText(string(u.Signature[:])))
+		if err := u.reader.Tolerate(err); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if _, err := u.reader.PeekByte(); storage.IsEOF(err) {
+			break // no problems, we're done!
+		} else if err != nil {
+			return err
+		}
+
+		offset := u.reader.Offset()
+		c := Chunk{Offset: offset}
+		c.ID = u.reader.ReadShort()
+		c.Length = u.reader.ReadLong()
+		if err := u.reader.Err(); err != nil {
+			return storage.TruncatedError{Blocks: len(u.Chunks), Offset: offset}
+		}
+
+		// the data is read up to the length, so a corrupt length is not
+		// allocated in full before finding the file is truncated
+		data, err := ioutil.ReadAll(io.LimitReader(u.reader, int64(c.Length)))
+		if err != nil && !storage.IsEOF(err) {
+			return errors.Wrap(err, "error reading UEF chunk")
+		} else if len(data) < int(c.Length) {
+			return storage.TruncatedError{Blocks: len(u.Chunks), Offset: offset}
+		}
+		c.Data = data
+
+		u.Chunks = append(u.Chunks, c)
+	}
+
+	return nil
+}
+
+// DisplayGeometry prints the header, each chunk, with the offset of its chunk
+// ID in the file, and the files saved on the tape, to the terminal.
+func (u UEF) DisplayGeometry() {
+	fmt.Println("HEADER INFORMATION:")
+	fmt.Println(u)
+
+	fmt.Println("CHUNKS:")
+	for i, c := range u.Chunks {
+		fmt.Printf("#%02d @0x%04X &%04X %s\n", i+1, c.Offset, c.ID, c)
+	}
+
+	files := u.Files()
+	if len(files) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("FILES:")
+	fmt.Println("  NAME        LOAD      EXEC      SIZE    BLOCKS")
+	for _, f := range files {
+		fmt.Printf("  %-11s &%08X &%08X %-7d %d%s\n",
+			fmt.Sprintf("%q", f.Name), f.LoadAddress, f.ExecAddress, len(f.Data), f.Blocks, f.notes())
+	}
+}
+
+func (u UEF) String() string {
+	str := ""
+	str += fmt.Sprintf("Signature: %s\n", strings.TrimRight(string(u.Signature[:]), "\x00"))
+	str += fmt.Sprintf("Version:   %d.%02d\n", u.MajorVersion, u.MinorVersion)
+	str += fmt.Sprintf("Chunks:    %d\n", len(u.Chunks))
+	return str
+}
+
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read, such as a newer version than supported, unknown chunks,
+// and tape blocks with a CRC error.
+func (u UEF) Warnings() []string {
+	var warnings []string
+
+	if u.MajorVersion > supportedMajorVersion ||
+		(u.MajorVersion == supportedMajorVersion && u.MinorVersion > supportedMinorVersion) {
+		warnings = append(warnings, fmt.Sprintf(
+			"UEF version %d.%02d is newer than the supported %d.%02d, some chunks may not be decoded",
+			u.MajorVersion, u.MinorVersion, supportedMajorVersion, supportedMinorVersion,
+		))
+	}
+
+	for i, c := range u.Chunks {
+		if !c.Known() {
+			warnings = append(warnings, fmt.Sprintf("chunk #%02d: unknown chunk &%04X, %d bytes skipped", i+1, c.ID, c.Length))
+			continue
+		}
+		block, err := c.TapeBlock()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("chunk #%02d: %s", i+1, err))
+			continue
+		} else if block == nil {
+			continue
+		}
+		if !block.HeaderOK() {
+			warnings = append(warnings, fmt.Sprintf("chunk #%02d: block %d of %q has a header CRC error", i+1, block.Number, block.Filename))
+		} else if !block.DataOK() {
+			warnings = append(warnings, fmt.Sprintf("chunk #%02d: block %d of %q has a data CRC error", i+1, block.Number, block.Filename))
+		}
+	}
+
+	return warnings
+}
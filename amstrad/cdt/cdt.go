@@ -3,16 +3,30 @@
 // https://www.worldofspectrum.org/TZXformat.html
 //
 // The `.CDT` tape image file format is identical to the `.TZX` file format designed by Tomaz Kac.
-// Therefore this package is a simple wrapper around the `spectrum/tzx` package.
+// Therefore this package is a simple wrapper around the `spectrum/tzx` package,
+// with only the machine specific details, such as the hardware, being handled here.
 package cdt
 
 import (
 	"fmt"
+	"strings"
 
+	"retroio/spectrum/hardware"
 	"retroio/spectrum/tzx"
+	"retroio/spectrum/tzx/blocks"
 	"retroio/storage"
 )
 
+// Hardware IDs of the Amstrad CPC computers, as used in the TZX hardware type
+// block.
+var cpcHardwareIDs = map[uint8]string{
+	0x15: "Amstrad CPC 464",
+	0x16: "Amstrad CPC 664",
+	0x17: "Amstrad CPC 6128",
+	0x18: "Amstrad CPC 464+",
+	0x19: "Amstrad CPC 6128+",
+}
+
 type CDT struct {
 	*tzx.TZX
 }
@@ -21,6 +35,37 @@ func New(reader *storage.Reader) *CDT {
 	return &CDT{tzx.New(reader)}
 }
 
-func (d CDT) CommandDir() {
+func (d CDT) CommandDir(user int) {
 	fmt.Println("directory listing unsupported for tapes")
 }
+
+// Machine returns the Amstrad CPC models the tape runs on, as declared in
+// the hardware type block, or "Amstrad CPC" when no models are given.
+func (d CDT) Machine() string {
+	var machines []string
+
+	for _, block := range d.Blocks() {
+		hw, ok := block.(*blocks.HardwareType)
+		if !ok {
+			continue
+		}
+		for _, m := range hw.Machines {
+			// Information 0x03: the tape DOESN'T RUN on this machine
+			if name, ok := cpcHardwareIDs[m.Id]; ok && m.Type == hardware.Computers && m.Information != 0x03 {
+				machines = append(machines, name)
+			}
+		}
+	}
+
+	if len(machines) == 0 {
+		return "Amstrad CPC"
+	}
+	return strings.Join(machines, ", ")
+}
+
+// DisplayGeometry prints the machine, followed by the TZX geometry.
+func (d CDT) DisplayGeometry() {
+	fmt.Printf("Machine: %s\n", d.Machine())
+	fmt.Println()
+	d.TZX.DisplayGeometry()
+}
@@ -4,7 +4,10 @@ package dsk
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -14,71 +17,531 @@ import (
 type AmsDos struct {
 	DPB         amsdos.DiskParameterBlock
 	Directories []amsdos.Directory
+
+	// DiscSpec is the PCW/Spectrum +3 boot sector disc specification,
+	// or nil when the disc uses one of the Amstrad CPC formats.
+	DiscSpec *amsdos.PcwSpectrumDPB
+
+	// FormatChange is the change of format found at the start of the data
+	// area, or nil when the whole disc uses the format of the first track.
+	FormatChange *FormatChange
+
+	// SectorTranslation is the CP/M sector translation table (XLT), giving the
+	// physical sector of each logical sector of a track, or nil when the disc
+	// has no software skew, as with the Amstrad formats. See SetSkew.
+	SectorTranslation []uint8
+
+	// directoryError is the error reading the directory sectors, when only
+	// some of the directory was read, such as from a damaged track.
+	directoryError error
+}
+
+// FormatChange describes a disc whose data area uses a different sector
+// layout to the reserved tracks, such as a data area following a system area
+// on some commercial +3 and PCW discs.
+type FormatChange struct {
+	Track int // index of the first track of the data area
+
+	FromSectorID, ToSectorID     uint8 // lowest sector IDs
+	FromSectors, ToSectors       uint8 // sectors per track
+	FromSectorSize, ToSectorSize uint16
+}
+
+func (f FormatChange) String() string {
+	return fmt.Sprintf(
+		"format changes at track %d, from %d sectors of %d bytes (first ID %02X) to %d sectors of %d bytes (first ID %02X)",
+		f.Track, f.FromSectors, f.FromSectorSize, f.FromSectorID, f.ToSectors, f.ToSectorSize, f.ToSectorID,
+	)
 }
 
 // Read the contents of an AMSDOS formatted disk
 func (a *AmsDos) Read(disk *DSK) error {
 	if len(disk.Tracks) == 0 {
-		return errors.New("no available tracks")
+		return badGeometry("no available tracks")
+	}
+	track, err := disk.loadedTrack(0)
+	if err != nil {
+		return err
 	}
-	track := disk.Tracks[0]
 
 	if len(track.Sectors) == 0 {
-		return errors.New("no sectors found")
+		return badGeometry("no sectors found")
 	}
 
 	sectorSize, ok := sectorSizeMap[track.SectorSize]
 	if !ok {
-		return errors.Errorf("invalid sector size: 0x%02X", track.SectorSize)
+		return badGeometry("invalid sector size: 0x%02X", track.SectorSize)
 	}
 
-	a.readDirectories(sectorSize, &track)
+	firstID := firstSectorID(track)
 
-	// must be executed after reading the directories
-	a.generateDPB(disk.Info.TrackSize, sectorSize, track.Sectors[0].ID, disk.Info.mediaType())
+	// Only the CPC System and Data formats are identified by their sector IDs,
+	// other discs may carry a PCW/Spectrum +3 disc specification.
+	a.DiscSpec = nil
+	if firstID != 0x41 && firstID != 0xC1 {
+		if sector := track.sectorData(firstID); sector != nil {
+			if spec, err := amsdos.ReadPcwSpectrumDPB(sector); err == nil {
+				a.DiscSpec = &spec
+			}
+		}
+	}
+
+	if a.DiscSpec != nil {
+		a.generateDPBFromSpec(*a.DiscSpec, firstID)
+	} else {
+		a.generateDPB(disk.Info, *track, sectorSize, firstID)
+	}
+
+	if err := a.detectFormatChange(disk); err != nil {
+		return err
+	}
+
+	// must be executed after generating the DPB
+	if err := a.readDirectories(disk); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (a *AmsDos) readDirectories(sectorSize uint16, track *TrackInformation) {
-	// 64 files * 32-bytes each = 2048 bytes
-	maxDirSectors := (amsdos.DRM * 32) / sectorSize
+// detectFormatChange checks the sector IDs of the first track of the data
+// area, following any reserved tracks, as the format may change partway
+// through the disc. When the sector layout differs from that of the first
+// track, the geometry of the DPB is taken from the data track instead, so the
+// directory and files are read from the sectors actually present.
+func (a *AmsDos) detectFormatChange(disk *DSK) error {
+	a.FormatChange = nil
 
-	// merge the sector data into one slice
-	var dirBytes []byte
-	for _, s := range track.SectorData[0 : maxDirSectors-1] {
-		for _, b := range s {
-			dirBytes = append(dirBytes, b)
-		}
+	index := int(a.DPB.ReservedTracksOffset)
+	if index == 0 || index >= len(disk.Tracks) || len(disk.Tracks[index].Sectors) == 0 {
+		return nil
+	}
+	track, err := disk.loadedTrack(index)
+	if err != nil {
+		return err
+	}
+
+	sectorSize, ok := sectorSizeMap[track.SectorSize]
+	if !ok {
+		return badGeometry("invalid sector size on track %d: 0x%02X", index, track.SectorSize)
+	}
+	firstID := firstSectorID(track)
+
+	if firstID == a.DPB.FirstSectorNumber && track.SectorsCount == a.DPB.SectorCountPerTrack && sectorSize == a.DPB.SectorSize {
+		return nil
+	}
+
+	a.FormatChange = &FormatChange{
+		Track:          index,
+		FromSectorID:   a.DPB.FirstSectorNumber,
+		ToSectorID:     firstID,
+		FromSectors:    a.DPB.SectorCountPerTrack,
+		ToSectors:      track.SectorsCount,
+		FromSectorSize: a.DPB.SectorSize,
+		ToSectorSize:   sectorSize,
+	}
+
+	sides := 1
+	if a.DPB.MediaType&0x03 > 0 {
+		sides = 2
+	}
+	dataTracks := int(a.DPB.TrackCountPerSide)*sides - index
+	blocks := dataTracks * int(track.SectorsCount) * int(sectorSize) / a.DPB.BlockSize()
+	if blocks == 0 {
+		blocks = 1
+	}
+
+	a.DPB.FirstSectorNumber = firstID
+	a.DPB.SectorCountPerTrack = track.SectorsCount
+	a.DPB.SectorSize = sectorSize
+	a.DPB.RecordsPerTrack = uint16(track.SectorsCount) * (sectorSize / amsdos.CpmRecordSize)
+	a.DPB.BlockCount = uint16(blocks - 1)
+	if physicalRecord, ok := amsdos.PhysicalShiftMaskTable[sectorSize]; ok {
+		a.DPB.PhysicalShift = physicalRecord.PSH
+		a.DPB.PhysicalMask = physicalRecord.PHM
+	}
+
+	return nil
+}
+
+// SetSkew sets the software sector skew of the disc, for CP/M discs from other
+// systems, translating the logical sectors of each track through the skew
+// table, then reads the directory again, as it is also read through the skew.
+// A skew of 0 or 1 removes the translation.
+func (a *AmsDos) SetSkew(disk *DSK, skew int) error {
+	a.SectorTranslation = amsdos.SkewTable(int(a.DPB.SectorCountPerTrack), skew)
+	return a.readDirectories(disk)
+}
+
+// readDirectories reads the directory entries from the directory blocks, which
+// may span several sectors and tracks, as given by the DRM of the XDPB: 64
+// entries on CPC and +3 discs, and 256 entries on the 720K PCW discs.
+//
+// When a directory sector is missing, the entries read up to that sector are
+// kept, and the error is given as a warning.
+func (a *AmsDos) readDirectories(disk *DSK) error {
+	a.directoryError = nil
+	dirBytes, err := a.readBlocks(disk, a.DPB.DirectoryBlocks())
+	var badGeometry ErrBadGeometry
+	if errors.As(err, &badGeometry) && len(dirBytes) > 0 {
+		a.directoryError = err
+	} else if err != nil {
+		return errors.Wrap(err, "error reading directory")
 	}
 
 	// Unmarshal the directory entries
+	a.Directories = nil
 	reader := bytes.NewReader(dirBytes)
-	for {
+	for len(a.Directories) <= int(a.DPB.DirectoryCount) {
 		dir := amsdos.Directory{}
 		err := binary.Read(reader, binary.LittleEndian, &dir)
 		if err != nil && err == io.EOF {
 			break
 		} else if err != nil {
-			panic("sector read error: " + err.Error())
+			return errors.Wrap(err, "error reading directory entry")
 		}
 		a.Directories = append(a.Directories, dir)
 	}
+
+	return nil
+}
+
+// AddFile allocates free blocks for the file data, writes the data to them and
+// creates the directory entries for the file.
+//
+// Files larger than one directory entry are split across multiple entries,
+// each holding up to 16 8-bit, or 8 16-bit, block numbers. An entry may hold
+// several logical 16K extents, as given by the extent mask (EXM), so its extent
+// counter is that of the last logical extent used, with the records of that
+// extent in RC.
+func (a *AmsDos) AddFile(disk *DSK, name string, data []byte, header bool) error {
+	filename, fileType, err := cpmFilename(name)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range a.Directories {
+		if !dir.Deleted() && dir.Filename == filename && clearAttributes(dir.FileType) == fileType {
+			return errors.Errorf("file already exists: %s", name)
+		}
+	}
+
+	if header {
+		if len(data) > 0xFFFF {
+			return errors.Errorf("file too large for an AMSDOS header: %d bytes", len(data))
+		}
+		h := amsdos.NewRecordHeader(0, filename, fileType, uint16(len(data)))
+		data = append(h.Bytes(), data...)
+	}
+
+	blockSize := a.DPB.BlockSize()
+	blocksNeeded := (len(data) + blockSize - 1) / blockSize
+
+	freeBlocks := a.freeBlocks()
+	if len(freeBlocks) < blocksNeeded {
+		return errors.Errorf("not enough free space: %d blocks required, %d available", blocksNeeded, len(freeBlocks))
+	}
+
+	// the records of a directory entry, limited by both the block numbers and
+	// the logical extents it may hold
+	extentsPerEntry := int(a.DPB.ExtentMask) + 1
+	recordsPerEntry := a.DPB.BlockPointers() * blockSize / amsdos.CpmRecordSize
+	if max := extentsPerEntry * amsdos.RecordsPerExtent; recordsPerEntry > max {
+		recordsPerEntry = max
+	}
+
+	records := (len(data) + amsdos.CpmRecordSize - 1) / amsdos.CpmRecordSize
+	entriesNeeded := (records + recordsPerEntry - 1) / recordsPerEntry
+	if entriesNeeded == 0 {
+		entriesNeeded = 1 // empty files still require a directory entry
+	}
+
+	var freeEntries []int
+	for i, dir := range a.Directories {
+		if dir.Deleted() {
+			freeEntries = append(freeEntries, i)
+		}
+	}
+	if len(freeEntries) < entriesNeeded {
+		return errors.Errorf("not enough free directory entries: %d required, %d available", entriesNeeded, len(freeEntries))
+	}
+
+	for entry := 0; entry < entriesNeeded; entry++ {
+		entryRecords := records - entry*recordsPerEntry
+		if entryRecords > recordsPerEntry {
+			entryRecords = recordsPerEntry
+		}
+
+		// the last logical extent of the entry, and its records
+		lastExtent, recordCount := 0, 0
+		if entryRecords > 0 {
+			lastExtent = (entryRecords - 1) / amsdos.RecordsPerExtent
+			recordCount = entryRecords - lastExtent*amsdos.RecordsPerExtent
+		}
+		extent := entry*extentsPerEntry + lastExtent
+
+		dir := amsdos.Directory{
+			UserNumber:  0,
+			Filename:    filename,
+			FileType:    fileType,
+			ExtentLow:   uint8(extent % 32),
+			ExtentHigh:  uint8(extent / 32),
+			RecordCount: uint8(recordCount),
+		}
+
+		var blocks []int
+		for len(data) > 0 && len(blocks)*blockSize < entryRecords*amsdos.CpmRecordSize {
+			block := freeBlocks[0]
+			freeBlocks = freeBlocks[1:]
+
+			chunk := data
+			if len(chunk) > blockSize {
+				chunk = chunk[:blockSize]
+			}
+			if err := a.writeBlocks(disk, block, chunk); err != nil {
+				return errors.Wrapf(err, "error writing block %d", block)
+			}
+			data = data[len(chunk):]
+
+			blocks = append(blocks, block)
+		}
+		a.DPB.SetAllocatedBlocks(&dir, blocks)
+
+		a.Directories[freeEntries[entry]] = dir
+	}
+
+	return a.writeDirectories(disk)
+}
+
+// ReadFile reads the data of the named file, by joining the blocks of all the
+// file extents, in order. As CP/M only stores the file length in 128 byte
+// records, the data is padded to the end of the last record.
+func (a AmsDos) ReadFile(disk *DSK, name string) ([]byte, error) {
+	filename, fileType, err := cpmFilename(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var extents []amsdos.Directory
+	for _, dir := range a.Directories {
+		if dir.UserNumber < 16 && dir.Filename == filename && clearAttributes(dir.FileType) == fileType {
+			extents = append(extents, dir)
+		}
+	}
+	if len(extents) == 0 {
+		return nil, errors.Errorf("file not found: %s", name)
+	}
+
+	sort.Slice(extents, func(i, j int) bool {
+		return extents[i].ExtentNumber() < extents[j].ExtentNumber()
+	})
+
+	var data []byte
+	for _, dir := range extents {
+		var extentData []byte
+		for _, block := range a.DPB.AllocatedBlocks(dir) {
+			for _, sector := range a.blockSectors(block) {
+				s, err := a.logicalSector(disk, sector)
+				if err != nil {
+					return nil, errors.Wrapf(err, "error reading block %d", block)
+				}
+				extentData = append(extentData, s...)
+			}
+		}
+
+		if size := dir.Records(a.DPB.ExtentMask) * amsdos.CpmRecordSize; size < len(extentData) {
+			extentData = extentData[:size]
+		}
+		data = append(data, extentData...)
+	}
+
+	return data, nil
+}
+
+// freeBlocks returns the block numbers not reserved for the directory and not
+// allocated to any file.
+func (a AmsDos) freeBlocks() []int {
+	used := make(map[int]bool)
+	for i := 0; i < a.DPB.DirectoryBlocks(); i++ {
+		used[i] = true
+	}
+	for _, dir := range a.Directories {
+		if dir.Deleted() {
+			continue
+		}
+		for _, block := range a.DPB.AllocatedBlocks(dir) {
+			used[block] = true
+		}
+	}
+
+	var free []int
+	for block := 0; block <= int(a.DPB.BlockCount); block++ {
+		if !used[block] {
+			free = append(free, block)
+		}
+	}
+	return free
 }
 
-// Constructs an AMSDOS Extended Disk Parameter Block
-func (a *AmsDos) generateDPB(trackSize, sectorSize uint16, firstSectorID, mediaType uint8) {
+// cpmFilename converts a "NAME.EXT" string into the padded CP/M filename and type.
+func cpmFilename(name string) (filename [8]uint8, fileType [3]uint8, err error) {
+	parts := strings.SplitN(strings.ToUpper(name), ".", 2)
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+
+	if len(parts[0]) == 0 || len(parts[0]) > len(filename) || len(parts[1]) > len(fileType) {
+		return filename, fileType, errors.Errorf("invalid CP/M filename: %q", name)
+	}
+	if strings.ContainsAny(name, "<>,;:=?*[] ") {
+		return filename, fileType, errors.Errorf("invalid characters in CP/M filename: %q", name)
+	}
+
+	copy(filename[:], fmt.Sprintf("%-8s", parts[0]))
+	copy(fileType[:], fmt.Sprintf("%-3s", parts[1]))
+
+	return filename, fileType, nil
+}
+
+// clearAttributes removes the attribute bits from a file type.
+func clearAttributes(fileType [3]uint8) [3]uint8 {
+	for i := range fileType {
+		fileType[i] &= 0x7F
+	}
+	return fileType
+}
+
+// writeDirectories marshals the directory entries back to the directory blocks.
+func (a *AmsDos) writeDirectories(disk *DSK) error {
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, a.Directories); err != nil {
+		return err
+	}
+
+	return a.writeBlocks(disk, 0, buf.Bytes())
+}
+
+// readBlocks reads the given number of data blocks, starting at block 0. On an
+// error, the data of the sectors read before it is also returned.
+func (a AmsDos) readBlocks(disk *DSK, count int) ([]byte, error) {
+	var data []byte
+	for block := 0; block < count; block++ {
+		for _, sector := range a.blockSectors(block) {
+			s, err := a.logicalSector(disk, sector)
+			if err != nil {
+				return data, err
+			}
+			data = append(data, s...)
+		}
+	}
+	return data, nil
+}
+
+// writeBlocks writes the data into consecutive data blocks, starting at the
+// given block number. The final block is padded with zeros.
+func (a AmsDos) writeBlocks(disk *DSK, block int, data []byte) error {
+	for ; len(data) > 0; block++ {
+		for _, sector := range a.blockSectors(block) {
+			s, err := a.logicalSector(disk, sector)
+			if err != nil {
+				return err
+			}
+			n := copy(s, data)
+			for i := n; i < len(s); i++ {
+				s[i] = 0
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// blockSectors returns the logical sector numbers making up a data block.
+func (a AmsDos) blockSectors(block int) []int {
+	perBlock := a.DPB.BlockSize() / int(a.DPB.SectorSize)
+
+	sectors := make([]int, perBlock)
+	for i := range sectors {
+		sectors[i] = block*perBlock + i
+	}
+	return sectors
+}
+
+// logicalSector returns the sector data for a logical sector number, counted
+// from the start of the data area, which follows any reserved tracks. The
+// sector is translated through any software skew, then found by its sector
+// ID, so the physical interleave of the sectors on the track does not matter.
+// The returned slice references the track data, so it may be written to.
+func (a AmsDos) logicalSector(disk *DSK, sector int) ([]byte, error) {
+	trackNumber := int(a.DPB.ReservedTracksOffset) + sector/int(a.DPB.SectorCountPerTrack)
+	if trackNumber >= len(disk.Tracks) {
+		return nil, badGeometry("logical sector %d is beyond the last track", sector)
+	}
+	track, err := disk.loadedTrack(trackNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	index := sector % int(a.DPB.SectorCountPerTrack)
+	if index < len(a.SectorTranslation) {
+		index = int(a.SectorTranslation[index])
+	}
+
+	id := a.DPB.FirstSectorNumber + uint8(index)
+	if data := track.sectorData(id); data != nil {
+		return data, nil
+	}
+
+	return nil, badGeometry("sector ID 0x%02X not found on track %d", id, trackNumber)
+}
+
+// firstSectorID returns the lowest sector ID on the track, regardless of the
+// sector interleave used when formatting the disc. The sectors with no data
+// stored in a damaged track are ignored.
+func firstSectorID(track *TrackInformation) uint8 {
+	sectors := track.Sectors
+	if track.storedCount > 0 {
+		sectors = sectors[:track.storedCount]
+	}
+	first := sectors[0].ID
+	for _, s := range sectors {
+		if s.ID < first {
+			first = s.ID
+		}
+	}
+	return first
+}
+
+// Constructs an AMSDOS Extended Disk Parameter Block for the CPC formats, which
+// have no disc specification, so the disc capacity is derived from the track
+// geometry, less the reserved tracks of the format.
+func (a *AmsDos) generateDPB(info DiskInformation, track TrackInformation, sectorSize uint16, firstSectorID uint8) {
+	reserved := reservedTracks(firstSectorID)
+
+	sides := int(info.Sides)
+	if sides == 0 {
+		sides = 1
+	}
+	dataTracks := int(info.Tracks)*sides - int(reserved)
+	if dataTracks < 0 {
+		dataTracks = 0
+	}
+	blocks := dataTracks * int(track.SectorsCount) * int(sectorSize) / int(amsdos.BLS)
+	if blocks == 0 {
+		blocks = 1
+	}
+
 	dpb := amsdos.DiskParameterBlock{
 		ExtentMask:           amsdos.ExtentMask,
-		BlockCount:           amsdos.DSM - 1,
+		BlockCount:           uint16(blocks - 1),
 		DirectoryCount:       amsdos.DRM - 1,
 		Checksum:             0, // CKS = 0 (Fixed Media)
-		ReservedTracksOffset: 0, // TODO:michael
+		ReservedTracksOffset: reserved,
 
 		// AMSDOS extended parameters
-		MediaType:           mediaType,
-		TrackCountPerSide:   amsdos.TrackCount,
-		SectorCountPerTrack: amsdos.SectorsPerTrack,
+		MediaType:           info.mediaType(),
+		TrackCountPerSide:   info.Tracks,
+		SectorCountPerTrack: track.SectorsCount,
 		FirstSectorNumber:   firstSectorID,
 		SectorSize:          sectorSize,
 		ReadWriteGap:        amsdos.ReadWriteGap,
@@ -87,15 +550,15 @@ func (a *AmsDos) generateDPB(trackSize, sectorSize uint16, firstSectorID, mediaT
 		FreezeFlag:          1, // Non-zero value: use current format
 	}
 
-	dpb.RecordsPerTrack = (trackSize - sectorDataStartAddress) / amsdos.CpmRecordSize
+	dpb.RecordsPerTrack = uint16(track.SectorsCount) * (sectorSize / amsdos.CpmRecordSize)
 
 	// BLS, BSH, BLM for the Amstrad CPC standard
 	blsTable := amsdos.BlsTable[amsdos.BLS]
 	dpb.BlockShift = blsTable.BSH
 	dpb.BlockMask = blsTable.BLM
 
-	dirsPerBlock := amsdos.BlsTable[amsdos.BLS].Dirs
-	reservedBlocks := len(a.Directories)/int(dirsPerBlock) + 1
+	dirsPerBlock := blsTable.Dirs
+	reservedBlocks := int((amsdos.DRM + dirsPerBlock - 1) / dirsPerBlock)
 	dpb.SetAllocationBitmap(reservedBlocks)
 
 	if physicalRecord, ok := amsdos.PhysicalShiftMaskTable[sectorSize]; ok {
@@ -105,3 +568,85 @@ func (a *AmsDos) generateDPB(trackSize, sectorSize uint16, firstSectorID, mediaT
 
 	a.DPB = dpb
 }
+
+// Constructs the Extended Disk Parameter Block from a PCW/Spectrum +3 disc specification.
+func (a *AmsDos) generateDPBFromSpec(spec amsdos.PcwSpectrumDPB, firstSectorID uint8) {
+	sectorSize := uint16(amsdos.CpmRecordSize) << spec.PhysicalShift
+	blockSize := uint16(amsdos.CpmRecordSize) << spec.BlockShift
+
+	sides := 1
+	if spec.MediaType&0x03 > 0 {
+		sides = 2
+	}
+
+	dataTracks := int(spec.TrackCountPerSide)*sides - int(spec.ReservedTracks)
+	blocks := dataTracks * int(spec.SectorCountPerTrack) * int(sectorSize) / int(blockSize)
+
+	dpb := amsdos.DiskParameterBlock{
+		RecordsPerTrack:      uint16(spec.SectorCountPerTrack) * (sectorSize / amsdos.CpmRecordSize),
+		BlockShift:           spec.BlockShift,
+		BlockMask:            uint8(blockSize/amsdos.CpmRecordSize) - 1,
+		BlockCount:           uint16(blocks - 1),
+		DirectoryCount:       uint16(spec.DirectoryBlockCount)*(blockSize/32) - 1,
+		Checksum:             0, // CKS = 0 (Fixed Media)
+		ReservedTracksOffset: uint16(spec.ReservedTracks),
+
+		// AMSDOS extended parameters
+		MediaType:           spec.MediaType,
+		TrackCountPerSide:   spec.TrackCountPerSide,
+		SectorCountPerTrack: spec.SectorCountPerTrack,
+		FirstSectorNumber:   firstSectorID,
+		SectorSize:          sectorSize,
+		ReadWriteGap:        spec.ReadWriteGap,
+		FormatGap:           spec.FormatGap,
+		MultiTrackFlags:     0, // Non multi-track disk
+		FreezeFlag:          0, // Zero value: format determined from the disc
+	}
+
+	// EXM depends on the block size and whether DSM is greater than 255
+	if blocks-1 < 256 {
+		dpb.ExtentMask = uint8(blockSize/1024) - 1
+	} else {
+		dpb.ExtentMask = uint8(blockSize/2048) - 1
+	}
+
+	dpb.SetAllocationBitmap(int(spec.DirectoryBlockCount))
+
+	if physicalRecord, ok := amsdos.PhysicalShiftMaskTable[sectorSize]; ok {
+		dpb.PhysicalShift = physicalRecord.PSH
+		dpb.PhysicalMask = physicalRecord.PHM
+	}
+
+	a.DPB = dpb
+}
+
+// FormatName returns a description of the disc format.
+func (a AmsDos) FormatName() string {
+	if a.DiscSpec != nil {
+		return fmt.Sprintf("PCW/Spectrum +3 (%dK)", (int(a.DPB.BlockCount)+1-a.DPB.DirectoryBlocks())*a.DPB.BlockSize()/1024)
+	}
+
+	switch a.DPB.FirstSectorNumber {
+	case 0x41:
+		return "Amstrad CPC System"
+	case 0xC1:
+		return "Amstrad CPC Data"
+	case 0x01:
+		return "Amstrad CPC IBM"
+	default:
+		return "Unknown"
+	}
+}
+
+// reservedTracks returns the number of reserved tracks for the disc format,
+// which is identified by the first sector ID on the track.
+func reservedTracks(firstSectorID uint8) uint16 {
+	switch firstSectorID {
+	case 0x41: // SYSTEM format
+		return 2
+	case 0x01: // IBM format
+		return 1
+	default: // DATA format
+		return 0
+	}
+}
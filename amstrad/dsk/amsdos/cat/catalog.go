@@ -8,56 +8,115 @@ import (
 	"retroio/amstrad/dsk/amsdos"
 )
 
+const (
+	AllUsers     = -1                 // Lists the files of every user number, instead of a single user
+	DeletedFiles = amsdos.DeletedUser // Lists the deleted files, for recovery
+)
+
 // COMMAND: CAT
 // Catalogs the disc. Generates a list, in alpha-numeric order, the full names
 // of all files found, together with each file's length (to the nearest higher Kbyte).
 // The free space left on the disc is also displayed, together with Drive and
 // User identification.
-func CommandCat(diskMaxBlocks uint16, directories []amsdos.Directory) (*catalog, error) {
+//
+// Files larger than one extent have several directory entries, which are not
+// always adjacent in the directory, so all the entries of a file are grouped by
+// the user, filename and file type, and their records are totalled.
+//
+// Only the files of the given user number (0-15) are listed, as with the CPC
+// |USER command, or the files of every user when AllUsers is given. The free
+// space always takes into account the files of all users.
+//
+// Deleted files are never listed, unless DeletedFiles is given, in which case
+// only the deleted files are listed, flagging those that can be recovered, as
+// none of their blocks have been reused by another file.
+func CommandCat(dpb amsdos.DiskParameterBlock, directories []amsdos.Directory, user int) (*catalog, error) {
 	if len(directories) == 0 {
 		return nil, errors.New("no directories found")
 	}
 
 	cat := &catalog{
-		Drive:     'A',
-		User:      directories[0].UserNumber,
-		FreeSpace: diskMaxBlocks,
-		Records:   make([]directoryRecord, 0),
+		Drive:   'A',
+		User:    user,
+		Records: make([]DirectoryRecord, 0),
 	}
 
-	// TODO: research a proper solution for this free space adjustment
-	wasExtent := false
+	type fileKey struct {
+		user     uint8
+		filename [8]byte
+		fileType [3]byte
+	}
+	files := make(map[fileKey][]amsdos.Directory)
+	var keys []fileKey
+
+	used := make(map[int]bool)
+	for i := 0; i < dpb.DirectoryBlocks(); i++ {
+		used[i] = true
+	}
 
-	var lastFilename [8]byte
-	var lastFileType [3]byte
+	var deleted []amsdos.Directory
 
 	for _, d := range directories {
+		if d.Deleted() {
+			if !d.Unused() {
+				deleted = append(deleted, d)
+			}
+			continue
+		}
 		if !cat.validDirRecord(&d) {
 			continue
 		}
 
-		record := newDirectoryRecord(d.Filename, d.FileType, cat.blockCount(d.Allocation))
+		for _, block := range dpb.AllocatedBlocks(d) {
+			used[block] = true
+		}
+
+		if user == DeletedFiles || (user != AllUsers && int(d.UserNumber) != user) {
+			continue
+		}
 
-		cat.FreeSpace -= record.RecordCount
+		key := fileKey{user: d.UserNumber, filename: d.Filename, fileType: clearAttributes(d.FileType)}
+		if _, ok := files[key]; !ok {
+			keys = append(keys, key)
+		}
+		files[key] = append(files[key], d)
+	}
 
-		if lastFilename == d.Filename && lastFileType == d.FileType {
-			// add this record count to the the last record
-			cat.Records[len(cat.Records)-1].RecordCount += record.RecordCount
-			wasExtent = true
-		} else {
-			if record.Hidden {
-				cat.HiddenFiles += 1
+	if user == DeletedFiles {
+		for _, d := range deleted {
+			key := fileKey{user: d.UserNumber, filename: d.Filename, fileType: clearAttributes(d.FileType)}
+			if _, ok := files[key]; !ok {
+				keys = append(keys, key)
 			}
-			cat.Records = append(cat.Records, record)
+			files[key] = append(files[key], d)
+		}
+	}
+
+	for _, key := range keys {
+		extents := files[key]
+		sort.Slice(extents, func(i, j int) bool {
+			return extents[i].ExtentNumber() < extents[j].ExtentNumber()
+		})
+
+		records := 0
+		for _, d := range extents {
+			records += d.Records(dpb.ExtentMask)
 		}
 
-		lastFilename = d.Filename
-		lastFileType = d.FileType
+		// the attributes are taken from the first extent
+		record := newDirectoryRecord(key.user, extents[0].Filename, extents[0].FileType, uint16(records))
+		if user == DeletedFiles {
+			record.Recoverable = recoverable(dpb, extents, used)
+		}
+		if record.Hidden {
+			cat.HiddenFiles += 1
+		}
+		cat.Records = append(cat.Records, record)
 	}
 
-	// without this adjustment the free space is off by 1.
-	if wasExtent {
-		cat.FreeSpace -= 1
+	freeBlocks := int(dpb.BlockCount) + 1 - len(used)
+	if freeBlocks > 0 {
+		cat.FreeSpace = uint16(freeBlocks * dpb.BlockSize() / 1024)
 	}
 
 	cat.alphabetize()
@@ -67,14 +126,15 @@ func CommandCat(diskMaxBlocks uint16, directories []amsdos.Directory) (*catalog,
 
 type catalog struct {
 	Drive       byte
-	User        uint8
-	FreeSpace   uint16
+	User        int    // User number of the listed files, or AllUsers
+	FreeSpace   uint16 // Free space in Kbytes
 	HiddenFiles int
-	Records     []directoryRecord
+	Records     []DirectoryRecord
 }
 
 // TODO: is there a better way of checking for valid directory entries?
-// Check if a directory entry is actually a directory entry
+// Check if a directory entry is actually a directory entry.
+// Deleted files, with a user number of 0xE5, are excluded.
 func (c catalog) validDirRecord(dir *amsdos.Directory) bool {
 	// Nemesis (1986) is an example game where the first three checks are required.
 	// Roland in the Caves (1984) is an example game where all four checks are required.
@@ -84,20 +144,13 @@ func (c catalog) validDirRecord(dir *amsdos.Directory) bool {
 	return false
 }
 
-// Count the allocation blocks
-func (c catalog) blockCount(allocation [16]uint8) uint16 {
-	var blocks uint16
-	for _, b := range allocation {
-		if b > 0 {
-			blocks += 1
-		}
-	}
-	return blocks
-}
-
-// Sorts the catalog records in ascending order using the filename and file extension
+// Sorts the catalog records in ascending order using the user number, filename
+// and file extension
 func (c *catalog) alphabetize() {
 	sort.Slice(c.Records, func(i, j int) bool {
+		if c.Records[i].User != c.Records[j].User {
+			return c.Records[i].User < c.Records[j].User
+		}
 		if c.Records[i].Filename == c.Records[j].Filename {
 			return c.Records[i].FileType < c.Records[j].FileType
 		}
@@ -105,23 +158,27 @@ func (c *catalog) alphabetize() {
 	})
 }
 
-// directoryRecord is the displayable data for a directory record.
+// DirectoryRecord is the displayable data for a directory record.
 // This is similar to the CP/M Directory, except each entry merges all record extents.
-type directoryRecord struct {
+type DirectoryRecord struct {
+	User        uint8
 	Filename    string
 	FileType    string
-	RecordCount uint16 // Total record count for all extents of a record
+	RecordCount uint16 // Total record count for all extents of a record, in 128 byte records
 
 	ReadOnly bool
 	Hidden   bool
 	Archived bool
+
+	Recoverable bool // Deleted files only, when none of the blocks have been reused
 }
 
 // Returns a displayable directory record from the given disk entry
-func newDirectoryRecord(filename [8]byte, fileType [3]byte, blockCount uint16) directoryRecord {
-	record := directoryRecord{
+func newDirectoryRecord(user uint8, filename [8]byte, fileType [3]byte, recordCount uint16) DirectoryRecord {
+	record := DirectoryRecord{
+		User:        user,
 		Filename:    string(filename[:]),
-		RecordCount: blockCount,
+		RecordCount: recordCount,
 	}
 
 	// Check file type attributes and clear them
@@ -145,21 +202,57 @@ func newDirectoryRecord(filename [8]byte, fileType [3]byte, blockCount uint16) d
 
 // String formatted as an Amstrad CAT listing
 // Adds a custom "hidden" marker, although not present on the original Amstrad CAT.
-func (d directoryRecord) String() string {
+func (d DirectoryRecord) String() string {
 	marker := " "
 	if d.Hidden {
 		marker = "*"
 	}
 
-	return fmt.Sprintf("%s.%s %3dK%s", d.Filename, d.FileType, d.RecordCount, marker)
+	return fmt.Sprintf("%s.%s %3dK%s", d.Filename, d.FileType, d.Size(), marker)
+}
+
+// UserString is the listing with the user number of the file, as used when
+// the files of all users are listed.
+func (d DirectoryRecord) UserString() string {
+	return fmt.Sprintf("%2d:%s", d.User, d.String())
 }
 
-func (d directoryRecord) bitSet(n uint8, pos uint8) bool {
+// Size returns the file length in Kbytes, rounded up to the nearest Kbyte.
+func (d DirectoryRecord) Size() int {
+	return (int(d.RecordCount)*amsdos.CpmRecordSize + 1023) / 1024
+}
+
+func (d DirectoryRecord) bitSet(n uint8, pos uint8) bool {
 	val := n & (1 << pos)
 	return val > 0
 }
 
-func (d directoryRecord) clearBit(n uint8, pos uint8) uint8 {
+func (d DirectoryRecord) clearBit(n uint8, pos uint8) uint8 {
 	var mask uint8 = ^(1 << pos)
 	return n & mask
 }
+
+// recoverable returns true when none of the blocks of the deleted file are
+// used by the directory or the files on the disc, and each block is only
+// allocated once by the file extents.
+func recoverable(dpb amsdos.DiskParameterBlock, extents []amsdos.Directory, used map[int]bool) bool {
+	seen := make(map[int]bool)
+	for _, d := range extents {
+		for _, block := range dpb.AllocatedBlocks(d) {
+			if used[block] || seen[block] || block > int(dpb.BlockCount) {
+				return false
+			}
+			seen[block] = true
+		}
+	}
+	return true
+}
+
+// clearAttributes removes the attribute bits from a file type, so the extents
+// of a file are grouped even when only the first has the attributes set.
+func clearAttributes(fileType [3]byte) [3]byte {
+	for i := range fileType {
+		fileType[i] &= 0x7F
+	}
+	return fileType
+}
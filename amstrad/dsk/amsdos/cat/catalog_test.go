@@ -0,0 +1,119 @@
+package cat
+
+import (
+	"testing"
+
+	"retroio/amstrad/dsk/amsdos"
+)
+
+// dataFormat is the DPB of an AMSDOS data format disc, with 1K blocks.
+var dataFormat = amsdos.DiskParameterBlock{BlockShift: 3, BlockMask: 7, BlockCount: 179, DirectoryCount: 63, AllocationBitmap0: 0xC0}
+
+// extent returns a directory entry for the extent of a file.
+func extent(user uint8, name string, extent int, records uint8, blocks ...uint8) amsdos.Directory {
+	d := amsdos.Directory{UserNumber: user, ExtentLow: uint8(extent % 32), ExtentHigh: uint8(extent / 32), RecordCount: records}
+	copy(d.Filename[:], "        ")
+	copy(d.FileType[:], "BAS")
+	copy(d.Filename[:], name)
+	copy(d.Allocation[:], blocks)
+	return d
+}
+
+func TestCommandCatExtents(t *testing.T) {
+	type file struct {
+		name    string
+		records uint16
+	}
+
+	largeExtents := dataFormat
+	largeExtents.BlockShift, largeExtents.BlockMask, largeExtents.ExtentMask = 4, 15, 1
+
+	tests := []struct {
+		name        string
+		dpb         amsdos.DiskParameterBlock
+		directories []amsdos.Directory
+		want        []file
+	}{
+		{
+			name: "single extent",
+			dpb:  dataFormat,
+			directories: []amsdos.Directory{
+				extent(0, "ONE", 0, 0x10, 2, 3),
+			},
+			want: []file{{"ONE     ", 0x10}},
+		},
+		{
+			name: "extents in order",
+			dpb:  dataFormat,
+			directories: []amsdos.Directory{
+				extent(0, "BIG", 0, 0x80, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17),
+				extent(0, "BIG", 1, 0x10, 18, 19),
+			},
+			want: []file{{"BIG     ", 0x90}},
+		},
+		{
+			name: "extents out of order",
+			dpb:  dataFormat,
+			directories: []amsdos.Directory{
+				extent(0, "BIG", 2, 0x05, 34),
+				extent(0, "ONE", 0, 0x10, 35, 36),
+				extent(0, "BIG", 0, 0x80, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17),
+				extent(0, "BIG", 1, 0x80, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33),
+			},
+			want: []file{{"BIG     ", 0x105}, {"ONE     ", 0x10}},
+		},
+		{
+			name: "same name for two users",
+			dpb:  dataFormat,
+			directories: []amsdos.Directory{
+				extent(1, "ONE", 0, 0x20, 4, 5, 6, 7),
+				extent(0, "ONE", 0, 0x10, 2, 3),
+			},
+			want: []file{{"ONE     ", 0x10}, {"ONE     ", 0x20}},
+		},
+		{
+			name: "two logical extents per entry",
+			dpb:  largeExtents,
+			directories: []amsdos.Directory{
+				extent(0, "BIG", 2, 0x05, 18),
+				extent(0, "BIG", 1, 0x80, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17),
+			},
+			want: []file{{"BIG     ", 0x105}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat, err := CommandCat(tt.dpb, tt.directories, AllUsers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cat.Records) != len(tt.want) {
+				t.Fatalf("got %d files, want %d", len(cat.Records), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := cat.Records[i]
+				if got.Filename != want.name || got.RecordCount != want.records {
+					t.Errorf("file %d: got %q with %d records, want %q with %d", i, got.Filename, got.RecordCount, want.name, want.records)
+				}
+			}
+		})
+	}
+}
+
+func TestCommandCatFreeSpace(t *testing.T) {
+	directories := []amsdos.Directory{
+		extent(0, "BIG", 1, 0x10, 18, 19),
+		extent(0, "BIG", 0, 0x80, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17),
+	}
+
+	cat, err := CommandCat(dataFormat, directories, AllUsers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 180 blocks, less the 2 directory blocks and the 18 blocks of the file
+	if cat.FreeSpace != 160 {
+		t.Errorf("got %dK free, want 160K", cat.FreeSpace)
+	}
+}
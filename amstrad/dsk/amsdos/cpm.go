@@ -6,6 +6,10 @@ package amsdos
 
 const CpmRecordSize = 128 // CP/M records are 128 bytes in length
 
+const RecordsPerExtent = 128 // Records of a logical 16K extent, the most given by RC
+
+const DeletedUser = 0xE5 // User number of deleted, and unused, directory entries
+
 // DiskParameterBlock - a based on the CP/M v3 disc format, with extensions for
 // the Amstrad, and referenced as the XDPB - Extended Disc Parameter Block.
 //
@@ -217,6 +221,76 @@ func (d *DiskParameterBlock) SetAllocationBitmap(reservedBlocks int) {
 	d.AllocationBitmap1 = uint8(allocation & 0x00FF)
 }
 
+// BlockSize returns the size of a data block in bytes: BLS = 128 << BSH.
+func (d DiskParameterBlock) BlockSize() int {
+	return CpmRecordSize << d.BlockShift
+}
+
+// DirectoryBlocks returns the number of blocks reserved for the directory,
+// as given by the bits set in the AL0/AL1 allocation bitmap.
+func (d DiskParameterBlock) DirectoryBlocks() int {
+	allocation := uint16(d.AllocationBitmap0)<<8 | uint16(d.AllocationBitmap1)
+
+	count := 0
+	for allocation&0x8000 > 0 {
+		count++
+		allocation <<= 1
+	}
+	return count
+}
+
+// AllocatedBlocks returns the block numbers allocated to a directory entry.
+// Block numbers are 8-bit when the disc has fewer than 256 blocks, otherwise
+// they are 16-bit, stored low byte first.
+func (d DiskParameterBlock) AllocatedBlocks(dir Directory) []int {
+	var blocks []int
+
+	if d.BlockCount < 256 {
+		for _, block := range dir.Allocation {
+			if block > 0 {
+				blocks = append(blocks, int(block))
+			}
+		}
+		return blocks
+	}
+
+	for i := 0; i < len(dir.Allocation); i += 2 {
+		block := int(dir.Allocation[i]) | int(dir.Allocation[i+1])<<8
+		if block > 0 {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// BlockPointers returns the number of block numbers held by a directory
+// entry: 16 8-bit numbers when the disc has fewer than 256 blocks, otherwise
+// 8 16-bit numbers.
+func (d DiskParameterBlock) BlockPointers() int {
+	if d.BlockCount < 256 {
+		return len(Directory{}.Allocation)
+	}
+	return len(Directory{}.Allocation) / 2
+}
+
+// SetAllocatedBlocks stores the block numbers in the allocation of a directory
+// entry, as 8 or 16-bit numbers, as read by AllocatedBlocks. Any blocks beyond
+// the BlockPointers of the entry are ignored.
+func (d DiskParameterBlock) SetAllocatedBlocks(dir *Directory, blocks []int) {
+	dir.Allocation = [16]uint8{}
+	for i, block := range blocks {
+		if i >= d.BlockPointers() {
+			break
+		}
+		if d.BlockCount < 256 {
+			dir.Allocation[i] = uint8(block)
+		} else {
+			dir.Allocation[2*i] = uint8(block)
+			dir.Allocation[2*i+1] = uint8(block >> 8)
+		}
+	}
+}
+
 // BLS Table
 //
 // The values of BSH and BLM determine (implicitly) the data allocation
@@ -322,3 +396,30 @@ type Directory struct {
 	// disc) or 16-bit (stored low byte first).
 	Allocation [16]uint8
 }
+
+// Deleted returns true when the entry is a deleted file, or an unused entry.
+func (d Directory) Deleted() bool {
+	return d.UserNumber == DeletedUser
+}
+
+// Unused returns true when the entry has not been used since the disc was
+// formatted, as the whole directory is filled with 0xE5.
+func (d Directory) Unused() bool {
+	for _, b := range d.Filename {
+		if b != 0xE5 {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtentNumber returns the extent counter of the directory entry: (32 * S2) + EX.
+func (d Directory) ExtentNumber() int {
+	return 32*int(d.ExtentHigh) + int(d.ExtentLow)
+}
+
+// Records returns the number of records used by this extent: (EX & exm) * 128 + RC,
+// where exm is the extent mask from the Disc Parameter Block.
+func (d Directory) Records(extentMask uint8) int {
+	return int(d.ExtentLow&extentMask)*128 + int(d.RecordCount)
+}
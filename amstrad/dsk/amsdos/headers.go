@@ -1,5 +1,12 @@
 package amsdos
 
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
 // Default DPB values for the Amstrad CPC SSSD disk format.
 const (
 	ExtentMask      uint8  = 0
@@ -48,7 +55,87 @@ type RecordHeader struct {
 
 	FileLength [3]uint8  // 24-bit value. Length of the file in bytes, excluding the header record. Least significant byte in lowest address.
 	Checksum   uint16    // Sixteen bit checksum, sum of bytes 0..66
-	Undefined  [58]uint8 // 69... 127 Undefined
+	Undefined  [59]uint8 // 69... 127 Undefined
+}
+
+// AMSDOS file types, as stored in the header FileType field.
+const (
+	FileTypeBasic  uint8 = 0x00
+	FileTypeBinary uint8 = 0x02
+	FileTypeASCII  uint8 = 0x16 // Unprotected ASCII version 1
+)
+
+// NewRecordHeader builds a binary file header for the given filename and data
+// length. The checksum is calculated from the completed header record.
+func NewRecordHeader(user uint8, name [8]uint8, fileType [3]uint8, length uint16) RecordHeader {
+	h := RecordHeader{
+		User:          user,
+		Name:          name,
+		Type:          fileType,
+		FileType:      FileTypeBinary,
+		DataLength:    length,
+		FirstBlock:    0xFF,
+		LogicalLength: length,
+	}
+	h.FileLength = [3]uint8{uint8(length), uint8(length >> 8), 0}
+	h.Checksum = h.CalculateChecksum()
+
+	return h
+}
+
+// CalculateChecksum returns the sixteen bit sum of the header bytes 0..66.
+func (h RecordHeader) CalculateChecksum() uint16 {
+	var sum uint16
+	for _, b := range h.Bytes()[0:67] {
+		sum += uint16(b)
+	}
+	return sum
+}
+
+// ReadRecordHeader reads the header record from the start of the file data.
+// An error is returned when the data has no valid header, as the checksum does
+// not match, e.g. an unprotected ASCII or CP/M file.
+func ReadRecordHeader(data []byte) (RecordHeader, error) {
+	h := RecordHeader{}
+
+	if len(data) < CpmRecordSize {
+		return h, errors.New("no AMSDOS header found")
+	}
+	if err := binary.Read(bytes.NewReader(data[:CpmRecordSize]), binary.LittleEndian, &h); err != nil {
+		return h, errors.Wrap(err, "error reading AMSDOS header")
+	}
+	// an empty record would also give a matching checksum
+	if h.Checksum == 0 || h.Checksum != h.CalculateChecksum() {
+		return h, errors.New("no AMSDOS header found")
+	}
+
+	return h, nil
+}
+
+// Length returns the length of the file data, excluding the header record.
+func (h RecordHeader) Length() int {
+	return int(h.FileLength[0]) | int(h.FileLength[1])<<8 | int(h.FileLength[2])<<16
+}
+
+// FileTypeName returns the AMSDOS file type, ignoring the protection bit.
+func (h RecordHeader) FileTypeName() string {
+	switch h.FileType &^ 0x01 {
+	case FileTypeBasic:
+		return "BASIC"
+	case FileTypeBinary:
+		return "Binary"
+	case FileTypeASCII:
+		return "ASCII"
+	default:
+		return "Unknown"
+	}
+}
+
+// Bytes returns the 128 byte header record.
+func (h RecordHeader) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, h)
+	return buf.Bytes()
 }
 
 // When a file without a header is opened for input a fake header is constructed in store.
@@ -143,3 +230,100 @@ type PcwSpectrumDPB struct {
 	//        (the bootstrap code is in the remainder of the sector)
 	Checksum uint8
 }
+
+// Offsets of the PCW16 extended boot record signatures and disc specification.
+const (
+	pcw16LabelOffset     = 0x2B
+	pcw16SignatureOffset = 0x7C
+	pcw16SpecOffset      = 0x80
+)
+
+// ReadPcwSpectrumDPB reads the disc specification from the boot sector data
+// (track 0, head 0, physical sector 1). When the sector carries a PCW16
+// extended boot record the specification is read from offset 80h.
+//
+// If all bytes of the specification are E5h the default 173k PCW/Spectrum +3
+// disc specification is returned.
+func ReadPcwSpectrumDPB(sector []byte) (PcwSpectrumDPB, error) {
+	spec := sector
+	if isPcw16BootRecord(sector) {
+		spec = sector[pcw16SpecOffset:]
+	}
+
+	if len(spec) < 16 {
+		return PcwSpectrumDPB{}, errors.Errorf("boot sector too short: %d bytes", len(spec))
+	}
+
+	if bytes.Equal(spec[0:16], bytes.Repeat([]byte{0xE5}, 16)) {
+		return DefaultPcwSpectrumDPB, nil
+	}
+
+	dpb := PcwSpectrumDPB{}
+	if err := binary.Read(bytes.NewReader(spec[0:16]), binary.LittleEndian, &dpb); err != nil {
+		return dpb, err
+	}
+
+	if dpb.FormatNumber != 0 && dpb.FormatNumber != 3 {
+		return dpb, errors.Errorf("unsupported disc specification format number: %d", dpb.FormatNumber)
+	}
+
+	return dpb, nil
+}
+
+// BootSectorChecksum returns the 8-bit checksum of the boot sector, which is
+// the sum of all the sector bytes, modulo 256.
+func BootSectorChecksum(sector []byte) uint8 {
+	var sum uint8
+	for _, b := range sector {
+		sum += b
+	}
+	return sum
+}
+
+// Bootstrap returns the machine whose bootstrap code the boot sector carries,
+// as indicated by the sector checksum. An empty string is returned for
+// non-bootable sectors.
+func Bootstrap(sector []byte) string {
+	switch BootSectorChecksum(sector) {
+	case 1:
+		return "PCW9512"
+	case 3:
+		return "Spectrum +3"
+	case 255:
+		return "PCW8256"
+	default:
+		return ""
+	}
+}
+
+// DefaultPcwSpectrumDPB is the 173k PCW/Spectrum +3 format, assumed when the
+// boot sector disc specification is all E5h bytes.
+var DefaultPcwSpectrumDPB = PcwSpectrumDPB{
+	FormatNumber:        0,
+	MediaType:           0,
+	TrackCountPerSide:   40,
+	SectorCountPerTrack: 9,
+	PhysicalShift:       2, // 512-byte sectors
+	ReservedTracks:      1,
+	BlockShift:          3, // 1k blocks
+	DirectoryBlockCount: 2,
+	ReadWriteGap:        0x2A,
+	FormatGap:           0x52,
+}
+
+// isPcw16BootRecord checks for the PCW16 extended boot sector signatures.
+func isPcw16BootRecord(sector []byte) bool {
+	if len(sector) < pcw16SpecOffset+16 {
+		return false
+	}
+	if sector[0] != 0xE9 && sector[0] != 0xEB {
+		return false
+	}
+
+	label := sector[pcw16LabelOffset : pcw16LabelOffset+11]
+	if !bytes.HasPrefix(label, []byte("CP/M")) || !bytes.HasSuffix(label, []byte("DSK")) {
+		return false
+	}
+
+	return bytes.Equal(sector[pcw16SignatureOffset:pcw16SignatureOffset+4], []byte("CP/M"))
+}
@@ -0,0 +1,56 @@
+package amsdos
+
+import (
+	"testing"
+)
+
+// bootSector returns a boot sector, with the disc specification of the 173k
+// format and some bootstrap code, and the last byte set to give the checksum.
+func bootSector(checksum uint8) []byte {
+	sector := make([]byte, 512)
+	copy(sector, []byte{0x00, 0x00, 0x28, 0x09, 0x02, 0x01, 0x03, 0x02, 0x2a, 0x52})
+	copy(sector[0x10:], []byte{0xf3, 0x31, 0x00, 0xc0, 0xc3, 0x00, 0xc0}) // DI; LD SP,C000h; JP C000h
+	sector[len(sector)-1] = checksum - BootSectorChecksum(sector)
+	return sector
+}
+
+func TestBootSectorChecksum(t *testing.T) {
+	tests := []struct {
+		name   string
+		sector []byte
+		want   uint8
+	}{
+		{"empty", []byte{}, 0},
+		{"formatted", []byte{0xe5, 0xe5, 0xe5, 0xe5}, 0x94},
+		{"wraps at 256", []byte{0xff, 0x02}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BootSectorChecksum(tt.sector); got != tt.want {
+				t.Errorf("got checksum %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		sector []byte
+		want   string
+	}{
+		{"PCW9512", bootSector(1), "PCW9512"},
+		{"Spectrum +3", bootSector(3), "Spectrum +3"},
+		{"PCW8256", bootSector(255), "PCW8256"},
+		{"not bootable", bootSector(2), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Bootstrap(tt.sector); got != tt.want {
+				t.Errorf("got machine %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
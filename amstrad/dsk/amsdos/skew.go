@@ -0,0 +1,31 @@
+package amsdos
+
+// SkewTable returns the CP/M sector translation table (XLT) for a track of the
+// given number of sectors, where the physical sector index of each logical
+// sector is skew sectors after the previous one. When the skew wraps around
+// on to a sector already used, the next unused sector is taken, as done by the
+// DISKDEF macro of CP/M 2.2.
+//
+// The Amstrad formats have no software skew, as the sectors are interleaved
+// when formatting, by their sector IDs, so nil is returned for a skew of 0 or
+// 1, meaning the logical and physical sectors are the same.
+func SkewTable(sectors, skew int) []uint8 {
+	if skew <= 1 || sectors <= 1 {
+		return nil
+	}
+
+	table := make([]uint8, sectors)
+	next, base := 0, 0
+	for i := range table {
+		table[i] = uint8(next)
+		next += skew
+		if next >= sectors {
+			next -= sectors
+			if next == base {
+				base++
+				next = base
+			}
+		}
+	}
+	return table
+}
@@ -0,0 +1,38 @@
+package amsdos
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkewTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		sectors int
+		skew    int
+		want    []uint8
+	}{
+		// the standard 8" single density disc of CP/M 2.2, as generated by
+		// DISKDEF 0,1,26,6, with the sectors counted from 0
+		{"CP/M 2.2 8 inch", 26, 6, []uint8{
+			0, 6, 12, 18, 24, 4, 10, 16, 22, 2, 8, 14, 20,
+			1, 7, 13, 19, 25, 5, 11, 17, 23, 3, 9, 15, 21,
+		}},
+		{"9 sectors, skew 2", 9, 2, []uint8{0, 2, 4, 6, 8, 1, 3, 5, 7}},
+		// the skew wraps on to a used sector, so the next one is taken
+		{"10 sectors, skew 2", 10, 2, []uint8{0, 2, 4, 6, 8, 1, 3, 5, 7, 9}},
+		{"9 sectors, skew 3", 9, 3, []uint8{0, 3, 6, 1, 4, 7, 2, 5, 8}},
+		{"no skew", 9, 1, nil},
+		{"zero skew", 9, 0, nil},
+		{"single sector", 1, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SkewTable(tt.sectors, tt.skew)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
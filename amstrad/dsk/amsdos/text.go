@@ -0,0 +1,54 @@
+package amsdos
+
+import (
+	"bytes"
+)
+
+// SoftEOF is the Ctrl-Z character marking the real end of an ASCII file. As
+// files are stored in whole 128 byte records, the bytes of the last record
+// following it are unused.
+const SoftEOF = 0x1A
+
+// IsText reports whether the headerless file data is an ASCII text file: all
+// the bytes up to the soft EOF are printable ASCII characters, tabs, form
+// feeds, or line endings, with at least one line ending, and the soft EOF, if
+// any, is within the last record. This avoids mistaking a short binary file
+// containing a 0x1A byte for text.
+func IsText(data []byte) bool {
+	text := TrimSoftEOF(data)
+	if len(text) == 0 {
+		return false
+	}
+	if len(text) < len(data) && len(data)-len(text) > CpmRecordSize {
+		return false
+	}
+
+	lines := false
+	for _, b := range text {
+		switch {
+		case b >= 0x20 && b < 0x7F, b == '\t', b == '\f':
+		case b == '\r', b == '\n':
+			lines = true
+		default:
+			return false
+		}
+	}
+	return lines
+}
+
+// TrimSoftEOF returns the ASCII file data up to the soft EOF, removing the
+// unused bytes of the last record. Data without a soft EOF is returned as is.
+//
+// This must only be used with text files, as the 0x1A byte is a normal value
+// in binary files.
+func TrimSoftEOF(data []byte) []byte {
+	if i := bytes.IndexByte(data, SoftEOF); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
+
+// UnixLineEndings converts the CR+LF line endings of an ASCII file to LF.
+func UnixLineEndings(data []byte) []byte {
+	return bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+}
@@ -0,0 +1,71 @@
+package amsdos
+
+import (
+	"bytes"
+	"testing"
+)
+
+// record pads the data to whole records with the byte, as stored on the disk.
+func record(data string, pad byte) []byte {
+	b := []byte(data)
+	for len(b)%CpmRecordSize != 0 {
+		b = append(b, pad)
+	}
+	return b
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"text with soft EOF", record("10 PRINT \"HELLO\"\r\n20 GOTO 10\r\n\x1a", 0x1a), true},
+		{"text with unused bytes", record("LINE 1\r\nLINE 2\r\n\x1a", 0xe5), true},
+		{"text filling the record", record("TEXT\r\n", ' '), true},
+		{"tabs and form feeds", record("A\tB\r\n\fC\r\n\x1a", 0), true},
+		{"no line endings", record("NO LINES\x1a", 0x1a), false},
+		{"empty", []byte{}, false},
+		{"only a soft EOF", record("\x1a", 0x1a), false},
+		{"binary", record("\xc3\x00\x40\x1a\xcd\x5a\xbb\r\n", 0), false},
+		{"binary with a 0x1A byte", []byte{0x21, 0x00, 0xc0, 0x1a, 0x77, 0xc9, '\r', '\n'}, false},
+		{"soft EOF before the last record", append(record("TEXT\r\n\x1a", 0), record("MORE", 0)...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsText(tt.data); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimSoftEOF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"soft EOF padding", record("HELLO\r\n\x1a", 0x1a), "HELLO\r\n"},
+		{"garbage after the soft EOF", record("HELLO\r\n\x1a", 0xe5), "HELLO\r\n"},
+		{"no soft EOF", []byte("HELLO\r\n"), "HELLO\r\n"},
+		{"empty", []byte{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrimSoftEOF(tt.data); string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnixLineEndings(t *testing.T) {
+	data := []byte("10 CLS\r\n20 PRINT \"A\rB\"\r\n\r\n")
+	want := []byte("10 CLS\n20 PRINT \"A\rB\"\n\n")
+	if got := UnixLineEndings(data); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
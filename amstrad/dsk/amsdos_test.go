@@ -0,0 +1,345 @@
+package dsk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"retroio/amstrad/dsk/amsdos"
+	"retroio/amstrad/dsk/amsdos/cat"
+)
+
+// testData returns n bytes of data which differ in every block.
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i ^ i>>8)
+	}
+	return data
+}
+
+// fileExtents returns the directory entries of the named file.
+func fileExtents(a AmsDos, name string) []amsdos.Directory {
+	filename, fileType, _ := cpmFilename(name)
+
+	var extents []amsdos.Directory
+	for _, dir := range a.Directories {
+		if !dir.Deleted() && dir.Filename == filename && dir.FileType == fileType {
+			extents = append(extents, dir)
+		}
+	}
+	return extents
+}
+
+func TestAddFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		disk    string
+		file    string
+		size    int
+		header  bool
+		entries int
+		// blockShift gives a larger block size than the disk format, with the
+		// matching extent mask, and the disk is not read back
+		blockShift uint8
+	}{
+		{"small file", "files", "NEW.BIN", 1000, false, 1, 0},
+		{"empty file", "files", "EMPTY.TXT", 0, false, 1, 0},
+		{"with header", "files", "CODE.BIN", 100, true, 1, 0},
+		{"two extents", "files", "BIG.BIN", 20000, false, 2, 0},
+		{"full extent", "files", "FULL.BIN", 16384, false, 1, 0},
+		{"16-bit blocks", "pcw720", "LARGE.DAT", 40000, false, 3, 0},
+		{"2K blocks, 8-bit", "files", "WIDE.BIN", 40000, false, 2, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := readDisk(t, fixture(t, tt.disk))
+			if tt.blockShift > 0 {
+				dpb := &disk.AmsDos.DPB
+				dpb.ExtentMask = (1 << (tt.blockShift - 3)) - 1
+				dpb.BlockCount = (dpb.BlockCount+1)>>(tt.blockShift-dpb.BlockShift) - 1
+				dpb.BlockShift = tt.blockShift
+				dpb.BlockMask = 1<<tt.blockShift - 1
+			}
+
+			data := testData(tt.size)
+			if err := disk.AddFile(tt.file, data, tt.header); err != nil {
+				t.Fatalf("AddFile: %v", err)
+			}
+
+			if tt.blockShift == 0 {
+				var buf bytes.Buffer
+				if err := disk.Write(&buf); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+				disk = readDisk(t, buf.Bytes())
+			}
+
+			if extents := fileExtents(disk.AmsDos, tt.file); len(extents) != tt.entries {
+				t.Errorf("got %d directory entries, want %d", len(extents), tt.entries)
+			}
+
+			got, err := disk.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if tt.header {
+				if len(got) < 128 {
+					t.Fatalf("got %d bytes, want an AMSDOS header", len(got))
+				}
+				h, err := amsdos.ReadRecordHeader(got[:128])
+				if err != nil {
+					t.Fatalf("ReadRecordHeader: %v", err)
+				}
+				if h.Length() != tt.size {
+					t.Errorf("header length %d, want %d", h.Length(), tt.size)
+				}
+				got = got[128:]
+			}
+			if len(got) < len(data) || !bytes.Equal(got[:len(data)], data) {
+				t.Errorf("read back %d bytes, which differ from the %d bytes added", len(got), len(data))
+			}
+
+			records := (tt.size + 127) / 128
+			if tt.header {
+				records = (tt.size + 128 + 127) / 128
+			}
+			catalog, err := cat.CommandCat(disk.AmsDos.DPB, disk.AmsDos.Directories, 0)
+			if err != nil {
+				t.Fatalf("CommandCat: %v", err)
+			}
+			found := false
+			for _, r := range catalog.Records {
+				if strings.TrimSpace(r.Filename)+"."+strings.TrimSpace(r.FileType) == tt.file {
+					found = true
+					if int(r.RecordCount) != records {
+						t.Errorf("catalog has %d records, want %d", r.RecordCount, records)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("%s not found in the catalog", tt.file)
+			}
+		})
+	}
+}
+
+func TestAddFileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		size   int
+		header bool
+		want   string
+	}{
+		{"no space", "HUGE.BIN", 200 * 1024, false, "not enough free space"},
+		{"existing file", "HELLO.BAS", 10, false, "file already exists"},
+		{"header too large", "LONG.BIN", 0x10000, true, "file too large"},
+		{"empty name", "", 10, false, `invalid CP/M filename: ""`},
+		{"long name", "TOOLONGNAME.BIN", 10, false, `invalid CP/M filename: "TOOLONGNAME.BIN"`},
+		{"long type", "FILE.DATA", 10, false, `invalid CP/M filename: "FILE.DATA"`},
+		{"bad characters", "A*B.BIN", 10, false, `invalid characters in CP/M filename: "A*B.BIN"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := readDisk(t, fixture(t, "files"))
+			err := disk.AddFile(tt.file, testData(tt.size), tt.header)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeletedFiles(t *testing.T) {
+	disk := readDisk(t, fixture(t, "deleted"))
+
+	type file struct {
+		name        string
+		recoverable bool
+	}
+	tests := []struct {
+		name string
+		user int
+		want []file
+	}{
+		{"user 0", 0, []file{{"GAME    .BIN", false}, {"HELLO   .BAS", false}}},
+		{"all users", cat.AllUsers, []file{{"GAME    .BIN", false}, {"HELLO   .BAS", false}}},
+		{"deleted", cat.DeletedFiles, []file{{"GONE    .TXT", true}, {"OLD     .BIN", false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog, err := cat.CommandCat(disk.AmsDos.DPB, disk.AmsDos.Directories, tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(catalog.Records) != len(tt.want) {
+				t.Fatalf("got %d files, want %d", len(catalog.Records), len(tt.want))
+			}
+			for i, want := range tt.want {
+				record := catalog.Records[i]
+				if name := record.Filename + "." + record.FileType; name != want.name || record.Recoverable != want.recoverable {
+					t.Errorf("file %d: got %q recoverable %v, want %q recoverable %v", i, name, record.Recoverable, want.name, want.recoverable)
+				}
+			}
+
+			// the blocks of the deleted files are free
+			if catalog.FreeSpace != 161 {
+				t.Errorf("got %dK free, want 161K", catalog.FreeSpace)
+			}
+		})
+	}
+}
+
+func TestCatalogFormats(t *testing.T) {
+	tests := []struct {
+		name       string
+		blockSize  int
+		dirEntries int
+		files      []string
+		free       uint16
+	}{
+		{"files", 1024, 64, []string{"0:GAME    .BIN", "0:HELLO   .BAS", "1:USER1   .BIN"}, 157},
+		{"plus3", 1024, 64, []string{"0:GAME    .BIN", "0:HELLO   .BAS", "2:LATE    .DAT"}, 151},
+		{"pcw720", 2048, 256, []string{"0:FIRST   .TXT", "0:LATE    .BIN", "3:OTHER   .DAT"}, 684},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := readDisk(t, fixture(t, tt.name))
+			dpb := disk.AmsDos.DPB
+
+			if dpb.BlockSize() != tt.blockSize || int(dpb.DirectoryCount)+1 != tt.dirEntries {
+				t.Errorf("got %d byte blocks and %d directory entries, want %d and %d",
+					dpb.BlockSize(), dpb.DirectoryCount+1, tt.blockSize, tt.dirEntries)
+			}
+			if len(disk.AmsDos.Directories) != tt.dirEntries {
+				t.Errorf("got %d directory entries read, want %d", len(disk.AmsDos.Directories), tt.dirEntries)
+			}
+
+			catalog, err := cat.CommandCat(dpb, disk.AmsDos.Directories, cat.AllUsers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, record := range catalog.Records {
+				files = append(files, fmt.Sprintf("%d:%s.%s", record.User, record.Filename, record.FileType))
+			}
+			if strings.Join(files, ",") != strings.Join(tt.files, ",") {
+				t.Errorf("got files %q, want %q", files, tt.files)
+			}
+			if catalog.FreeSpace != tt.free {
+				t.Errorf("got %dK free, want %dK", catalog.FreeSpace, tt.free)
+			}
+		})
+	}
+}
+
+func TestReadFileSoftEOF(t *testing.T) {
+	text := []byte("10 MODE 1\r\n20 PRINT \"HELLO\"\r\n")
+	binary := []byte{0x21, 0x00, 0xc0, 0x36, 0x1a, 0x23, 0x10, 0xfb, 0xc9, 0x0d, 0x0a, 0x00}
+
+	tests := []struct {
+		name string
+		file string
+		data []byte
+		text bool
+		want []byte
+	}{
+		// the soft EOF marks the end of the text, within the last record
+		{"text", "README.TXT", append(append([]byte{}, text...), amsdos.SoftEOF), true, text},
+		// a 0x1A byte in a binary file is data, so it is not trimmed
+		{"binary", "CODE.BIN", binary, false, binary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := readDisk(t, fixture(t, "files"))
+			if err := disk.AddFile(tt.file, tt.data, false); err != nil {
+				t.Fatalf("AddFile: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := disk.Write(&buf); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			disk = readDisk(t, buf.Bytes())
+
+			// the file is read in whole records, including the unused bytes
+			got, err := disk.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if len(got) != amsdos.CpmRecordSize {
+				t.Fatalf("got %d bytes, want a whole record of %d", len(got), amsdos.CpmRecordSize)
+			}
+
+			if amsdos.IsText(got) != tt.text {
+				t.Fatalf("got text %v, want %v", amsdos.IsText(got), tt.text)
+			}
+			if tt.text {
+				got = amsdos.TrimSoftEOF(got)
+			} else {
+				got = got[:len(tt.data)]
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got % X, want % X", got, tt.want)
+			}
+			if tt.text {
+				want := "10 MODE 1\n20 PRINT \"HELLO\"\n"
+				if lines := string(amsdos.UnixLineEndings(got)); lines != want {
+					t.Errorf("got %q with converted line endings, want %q", lines, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetSkew(t *testing.T) {
+	// the disc is written with a software skew of 2, with the directory entry
+	// in its last sector, and each sector of the file filled with its index
+	disk := readDisk(t, fixture(t, "skew"))
+
+	if _, err := disk.ReadFile("SKEW.BIN"); err == nil {
+		t.Error("expected the file not to be found without the skew")
+	}
+
+	if err := disk.SetSkew(2); err != nil {
+		t.Fatalf("SetSkew: %v", err)
+	}
+	want := []uint8{0, 2, 4, 6, 8, 1, 3, 5, 7}
+	if !bytes.Equal(disk.AmsDos.SectorTranslation, want) {
+		t.Errorf("got sector translation %v, want %v", disk.AmsDos.SectorTranslation, want)
+	}
+
+	data, err := disk.ReadFile("SKEW.BIN")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(data) != 12*512 {
+		t.Fatalf("got %d bytes, want %d", len(data), 12*512)
+	}
+	// the file crosses from the first track on to the second
+	for i := 0; i < 12; i++ {
+		if sector := data[i*512 : (i+1)*512]; !bytes.Equal(sector, bytes.Repeat([]byte{byte(i)}, 512)) {
+			t.Errorf("sector %d of the file starts with %02X, want %02X", i, sector[0], i)
+		}
+	}
+
+	if err := disk.SetSkew(0); err != nil {
+		t.Fatalf("SetSkew: %v", err)
+	}
+	if disk.AmsDos.SectorTranslation != nil {
+		t.Errorf("got sector translation %v, want none", disk.AmsDos.SectorTranslation)
+	}
+	if _, err := disk.ReadFile("SKEW.BIN"); err == nil {
+		t.Error("expected the file not to be found once the skew is removed")
+	}
+}
@@ -0,0 +1,158 @@
+package dsk
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"retroio/amstrad/dsk/amsdos"
+	"retroio/amstrad/dsk/amsdos/cat"
+)
+
+// catalogDocument is the structured catalog of the disk, with the disk
+// parameters needed to reconstruct the geometry.
+type catalogDocument struct {
+	Format    string        `json:"format"`
+	DPB       dpbDocument   `json:"dpb"`
+	FreeSpace int           `json:"free_k"`
+	Files     []catalogFile `json:"files"`
+}
+
+// dpbDocument holds the Disk Parameter Block fields, along with the Amstrad
+// extended (XDPB) fields and the values derived from them.
+type dpbDocument struct {
+	RecordsPerTrack      uint16   `json:"records_per_track"`
+	BlockShift           uint8    `json:"block_shift"`
+	BlockMask            uint8    `json:"block_mask"`
+	ExtentMask           uint8    `json:"extent_mask"`
+	BlockCount           uint16   `json:"block_count"`
+	DirectoryCount       uint16   `json:"directory_count"`
+	AllocationBitmap     [2]uint8 `json:"allocation_bitmap"`
+	Checksum             uint16   `json:"checksum"`
+	ReservedTracksOffset uint16   `json:"reserved_tracks"`
+	PhysicalShift        uint8    `json:"physical_shift"`
+	PhysicalMask         uint8    `json:"physical_mask"`
+
+	MediaType           uint8  `json:"media_type"`
+	TrackCountPerSide   uint8  `json:"tracks_per_side"`
+	SectorCountPerTrack uint8  `json:"sectors_per_track"`
+	FirstSectorNumber   uint8  `json:"first_sector"`
+	SectorSize          uint16 `json:"sector_size"`
+	ReadWriteGap        uint8  `json:"read_write_gap"`
+	FormatGap           uint8  `json:"format_gap"`
+	MultiTrackFlags     uint8  `json:"multi_track_flags"`
+	FreezeFlag          uint8  `json:"freeze_flag"`
+
+	BlockSize       int `json:"block_size"`
+	DirectoryBlocks int `json:"directory_blocks"`
+}
+
+type catalogFile struct {
+	User     uint8           `json:"user"`
+	Filename string          `json:"filename"`
+	Type     string          `json:"type"`
+	Size     int             `json:"size_k"`
+	Records  uint16          `json:"records"`
+	ReadOnly bool            `json:"read_only"`
+	Hidden   bool            `json:"hidden"`
+	Archived bool            `json:"archived"`
+	Extents  []catalogExtent `json:"extents"`
+}
+
+type catalogExtent struct {
+	Extent  int   `json:"extent"`
+	Records int   `json:"records"`
+	Blocks  []int `json:"blocks"`
+}
+
+// CatalogJSON returns the catalog of the files of all users as a JSON
+// document, with the extents and allocation blocks of each file, along with
+// the Disk Parameter Block, so the geometry can be reconstructed. Deleted
+// files are not included.
+func (d DSK) CatalogJSON() ([]byte, error) {
+	catalog, err := cat.CommandCat(d.AmsDos.DPB, d.AmsDos.Directories, cat.AllUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	dpb := d.AmsDos.DPB
+	doc := catalogDocument{
+		Format: d.AmsDos.FormatName(),
+		DPB: dpbDocument{
+			RecordsPerTrack:      dpb.RecordsPerTrack,
+			BlockShift:           dpb.BlockShift,
+			BlockMask:            dpb.BlockMask,
+			ExtentMask:           dpb.ExtentMask,
+			BlockCount:           dpb.BlockCount,
+			DirectoryCount:       dpb.DirectoryCount,
+			AllocationBitmap:     [2]uint8{dpb.AllocationBitmap0, dpb.AllocationBitmap1},
+			Checksum:             dpb.Checksum,
+			ReservedTracksOffset: dpb.ReservedTracksOffset,
+			PhysicalShift:        dpb.PhysicalShift,
+			PhysicalMask:         dpb.PhysicalMask,
+			MediaType:            dpb.MediaType,
+			TrackCountPerSide:    dpb.TrackCountPerSide,
+			SectorCountPerTrack:  dpb.SectorCountPerTrack,
+			FirstSectorNumber:    dpb.FirstSectorNumber,
+			SectorSize:           dpb.SectorSize,
+			ReadWriteGap:         dpb.ReadWriteGap,
+			FormatGap:            dpb.FormatGap,
+			MultiTrackFlags:      dpb.MultiTrackFlags,
+			FreezeFlag:           dpb.FreezeFlag,
+			BlockSize:            dpb.BlockSize(),
+			DirectoryBlocks:      dpb.DirectoryBlocks(),
+		},
+		FreeSpace: int(catalog.FreeSpace),
+		Files:     []catalogFile{},
+	}
+
+	for _, r := range catalog.Records {
+		file := catalogFile{
+			User:     r.User,
+			Filename: strings.TrimSpace(r.Filename),
+			Type:     strings.TrimSpace(r.FileType),
+			Size:     r.Size(),
+			Records:  r.RecordCount,
+			ReadOnly: r.ReadOnly,
+			Hidden:   r.Hidden,
+			Archived: r.Archived,
+			Extents:  []catalogExtent{},
+		}
+
+		for _, dir := range d.AmsDos.fileExtents(r) {
+			file.Extents = append(file.Extents, catalogExtent{
+				Extent:  dir.ExtentNumber(),
+				Records: dir.Records(dpb.ExtentMask),
+				Blocks:  dpb.AllocatedBlocks(dir),
+			})
+		}
+
+		doc.Files = append(doc.Files, file)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// fileExtents returns the directory entries of the catalog record, in extent
+// order, matching the user number, filename and file type, ignoring the
+// attribute bits.
+func (a AmsDos) fileExtents(r cat.DirectoryRecord) []amsdos.Directory {
+	var extents []amsdos.Directory
+	for _, dir := range a.Directories {
+		if dir.Deleted() || dir.UserNumber != r.User {
+			continue
+		}
+		var fileType [3]byte
+		for i, c := range dir.FileType {
+			fileType[i] = c &^ 0x80
+		}
+		if string(dir.Filename[:]) == r.Filename && string(fileType[:]) == r.FileType {
+			extents = append(extents, dir)
+		}
+	}
+
+	sort.Slice(extents, func(i, j int) bool {
+		return extents[i].ExtentNumber() < extents[j].ExtentNumber()
+	})
+	return extents
+}
@@ -0,0 +1,50 @@
+package dsk
+
+import (
+	"bytes"
+	"strings"
+
+	"retroio/storage"
+)
+
+// creatorQuirk is a known problem with the disk images written by a tool,
+// identified by the start of the creator string.
+type creatorQuirk struct {
+	prefix string // start of the creator string, matched ignoring case
+	quirk  string // what to watch for in the images it creates
+}
+
+// creatorQuirks lists the tools known to write subtly malformed images. To
+// add a tool, add its creator string prefix, and a description of the quirk.
+// A creator may match more than one entry.
+var creatorQuirks = []creatorQuirk{
+	{"CPDRead v3.1", "weak sectors are stored as a single copy, as multiple copies are only written from v3.24"},
+	{"CPDRead v3.0", "weak sectors are stored as a single copy, as multiple copies are only written from v3.24"},
+	{"CPDRead", "sectors read with CRC errors may hold incomplete data, check the sector ST1/ST2 flags"},
+	{"SAMdisk", "sectors of copy-protected tracks may include the gap data, giving a data length larger than the sector size"},
+	{"MAKEDSK", "the track size may not include the 256 byte track information block, misaligning the tracks"},
+}
+
+// CreatorName returns the name of the tool that wrote the disk image, from
+// the creator string, without the padding.
+func (d DiskInformation) CreatorName() string {
+	creator := bytes.TrimRight(d.Creator[:], "\x00 ")
+	return strings.TrimSpace(storage.DecodeLatin1(creator))
+}
+
+// CreatorQuirks returns the known problems with the images written by the
+// creator of the disk, which explain why an image may parse oddly.
+func (d DiskInformation) CreatorQuirks() []string {
+	name := strings.ToLower(d.CreatorName())
+	if name == "" {
+		return nil
+	}
+
+	var quirks []string
+	for _, q := range creatorQuirks {
+		if strings.HasPrefix(name, strings.ToLower(q.prefix)) {
+			quirks = append(quirks, q.quirk)
+		}
+	}
+	return quirks
+}
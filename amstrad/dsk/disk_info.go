@@ -1,8 +1,10 @@
 package dsk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 
 	"retroio/storage"
 )
@@ -23,8 +25,8 @@ type DiskInformation struct {
 	Creator    [14]byte  // name of creator
 	Tracks     uint8     // number of tracks
 	Sides      uint8     // number of sides
-	TrackSize  uint16    // size of a track
-	Padding    [204]byte // unused padding, to Track Info block at 0x0100
+	TrackSize  uint16    // size of a track, unused in extended images
+	Padding    [204]byte // extended images: track size table, otherwise unused padding
 }
 
 // Read the disk information header.
@@ -32,6 +34,32 @@ func (d *DiskInformation) Read(reader *storage.Reader) error {
 	return binary.Read(reader, binary.LittleEndian, d)
 }
 
+// Write the disk information header.
+func (d DiskInformation) Write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, d)
+}
+
+// Extended reports whether this is an extended disk image (EDSK), where the
+// track sizes are given in a table, and each sector has its own data length.
+func (d DiskInformation) Extended() bool {
+	return bytes.HasPrefix(d.Identifier[:], []byte("EXTENDED"))
+}
+
+// trackSize returns the size of the track at the index, in the order the
+// tracks are stored. For extended images this is read from the track size
+// table following the disk information, with a size of zero for unformatted
+// tracks, which are not stored in the image.
+func (d DiskInformation) trackSize(index int) int {
+	if !d.Extended() {
+		return int(d.TrackSize)
+	}
+	if index < 0 || index >= len(d.Padding) {
+		return 0
+	}
+	// the table holds the high byte of each track size
+	return int(d.Padding[index]) << 8
+}
+
 // Amstrad disc media type (sidedness)
 // See `docs.md` for more information on the type value.
 func (d *DiskInformation) mediaType() uint8 {
@@ -47,7 +75,7 @@ func (d *DiskInformation) mediaType() uint8 {
 func (d DiskInformation) String() string {
 	str := ""
 	str += fmt.Sprintf("Identifier: %s\n", reformatIdentifier(d.Identifier[:]))
-	str += fmt.Sprintf("Creator:    %s\n", d.Creator)
+	str += fmt.Sprintf("Creator:    %s\n", storage.DisplayText(d.CreatorName()))
 	str += fmt.Sprintf("Tracks:     %d\n", d.Tracks)
 	str += fmt.Sprintf("Sides:      %d\n", d.Sides)
 	str += fmt.Sprintf("Track Size: %d\n", d.TrackSize)
@@ -6,10 +6,12 @@ package dsk
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/pkg/errors"
 
+	"retroio/amstrad/dsk/amsdos"
 	"retroio/amstrad/dsk/amsdos/cat"
 	"retroio/storage"
 )
@@ -34,26 +36,126 @@ type DSK struct {
 	Tracks []TrackInformation
 
 	AmsDos AmsDos
+
+	problems []string // inconsistencies between the disk information and the tracks read
+}
+
+// trackSignature starts the Track Information Block of every stored track.
+const trackSignature = "Track-Info"
+
+// ErrBadGeometry is returned when the disk geometry is invalid or inconsistent,
+// such as an unknown sector size, or a missing track or sector.
+// Use errors.As to retrieve it from a wrapped error.
+type ErrBadGeometry struct {
+	Reason string
+}
+
+func (e ErrBadGeometry) Error() string {
+	return "bad disk geometry: " + e.Reason
+}
+
+func badGeometry(format string, args ...interface{}) error {
+	return ErrBadGeometry{Reason: fmt.Sprintf(format, args...)}
 }
 
 func New(reader *storage.Reader) *DSK {
 	return &DSK{reader: reader}
 }
 
+// Read the disk image, including the sector data of every track.
 func (d *DSK) Read() error {
+	return d.read(false)
+}
+
+// ReadLazy reads the disk and track information, but only reads the sector
+// data of a track when it is used, such as the directory tracks when listing
+// the catalog, or the tracks of a file when it is extracted. This avoids
+// loading the whole of a large disk image into memory.
+//
+// The reader must support seeking, otherwise the disk is read as with Read.
+func (d *DSK) ReadLazy() error {
+	return d.read(d.reader.Seekable())
+}
+
+func (d *DSK) read(lazy bool) error {
 	d.Info = DiskInformation{}
+	d.problems = nil
 	if err := d.Info.Read(d.reader); err != nil {
 		return errors.Wrap(err, "error reading the disk information block")
 	}
 
-	for i := 0; i < int(d.Info.Tracks); i++ {
-		track := TrackInformation{}
-		if err := track.Read(d.reader); err != nil {
+	// double sided disks store both sides of each track
+	sides := int(d.Info.Sides)
+	if sides < 1 {
+		sides = 1
+	}
+
+	extended := d.Info.Extended()
+	var outOfSequence []string
+
+	for i := 0; i < int(d.Info.Tracks)*sides; i++ {
+		offset := d.reader.Offset()
+		track := TrackInformation{extended: extended, size: d.Info.trackSize(i)}
+		if extended && track.size == 0 {
+			// unformatted tracks are not stored in extended images
+			track.Track = uint8(i / sides)
+			track.Side = uint8(i % sides)
+			d.Tracks = append(d.Tracks, track)
+			continue
+		}
+
+		// without the signature, the track count or a track size is wrong,
+		// and reading on would only give garbage geometry
+		if signature, err := d.reader.Peek(len(trackSignature)); err == nil && !validTrackSignature(signature) {
+			err := badGeometry(
+				"track #%d has no %s signature at offset %d, found %q, so only %d of the %d tracks given in the disk information were read",
+				i+1, trackSignature, offset, signature, len(d.Tracks), int(d.Info.Tracks)*sides,
+			)
+			if err := d.reader.Tolerate(err); err != nil {
+				return err
+			}
+			break
+		}
+
+		var err error
+		if lazy {
+			err = track.ReadLazy(d.reader)
+		} else {
+			err = track.Read(d.reader)
+		}
+		if err == nil {
+			// skip any padding, or the sectors not read, up to the track size
+			if padding := offset + int64(track.size) - d.reader.Offset(); padding > 0 {
+				_, err = d.reader.Discard(int(padding))
+			}
+		}
+		if err == nil {
+			err = d.reader.Err()
+		}
+		if storage.IsEOF(err) {
+			// Make a best effort to read the catalogue from the tracks that are
+			// present, but the truncation is the error worth reporting.
+			_ = d.AmsDos.Read(d)
+			return storage.TruncatedError{Blocks: len(d.Tracks), Offset: offset}
+		} else if err != nil {
 			return errors.Wrapf(err, "error reading track #%d", i+1)
 		}
+		if int(track.Track) != i/sides || int(track.Side) != i%sides {
+			outOfSequence = append(outOfSequence, fmt.Sprintf("track #%d, which is track %d side %d, expected track %d side %d", i+1, track.Track, track.Side, i/sides, i%sides))
+		}
 		d.Tracks = append(d.Tracks, track)
 	}
 
+	if len(outOfSequence) > 0 {
+		d.problems = append(d.problems, fmt.Sprintf("the tracks are out of sequence from %s (%d out of sequence)", outOfSequence[0], len(outOfSequence)))
+	}
+	if signature, err := d.reader.Peek(len(trackSignature)); err == nil && validTrackSignature(signature) {
+		d.problems = append(d.problems, fmt.Sprintf(
+			"another track follows the last of the %d tracks given in the disk information, at offset %d, so the track count may be wrong",
+			d.Info.Tracks, d.reader.Offset(),
+		))
+	}
+
 	// Read the contents of the disk as AMSDOS format
 	d.AmsDos = AmsDos{}
 	if err := d.AmsDos.Read(d); err != nil {
@@ -63,10 +165,112 @@ func (d *DSK) Read() error {
 	return nil
 }
 
+// validTrackSignature reports whether the data starts with the Track-Info
+// signature, ignoring the case, as written by some tools.
+func validTrackSignature(data []byte) bool {
+	return strings.EqualFold(string(data), trackSignature)
+}
+
+// loadedTrack returns the track at the index, reading its sector data first
+// when the disk was read lazily.
+func (d *DSK) loadedTrack(index int) (*TrackInformation, error) {
+	if index < 0 || index >= len(d.Tracks) {
+		return nil, badGeometry("track %d is beyond the last track", index)
+	}
+
+	track := &d.Tracks[index]
+	if err := track.Load(d.reader); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// Write the disk image back out, reconstructing the Disc Information Block,
+// each Track Information Block and its sector data.
+//
+// Each track is padded out to the track size given in the disk information,
+// or the track size table of an extended image, so an unmodified DSK is
+// written back byte-for-byte.
+func (d DSK) Write(w io.Writer) error {
+	if err := d.Info.Write(w); err != nil {
+		return errors.Wrap(err, "error writing the disk information block")
+	}
+
+	for i := range d.Tracks {
+		if d.Info.Extended() && d.Info.trackSize(i) == 0 {
+			continue
+		}
+		track, err := d.loadedTrack(i)
+		if err != nil {
+			return errors.Wrapf(err, "error reading track #%d", i+1)
+		}
+		if err := track.Write(w, d.Info.trackSize(i)); err != nil {
+			return errors.Wrapf(err, "error writing track #%d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// AddFile writes a new file to the disk, creating the directory entries for it.
+// The name must be a valid CP/M "NAME.EXT" filename. When header is true an
+// AMSDOS binary file header is prepended to the data.
+func (d *DSK) AddFile(name string, data []byte, header bool) error {
+	return d.AmsDos.AddFile(d, name, data, header)
+}
+
+// ReadFile returns the data for the named file, which must be a valid CP/M
+// "NAME.EXT" filename.
+func (d DSK) ReadFile(name string) ([]byte, error) {
+	return d.AmsDos.ReadFile(&d, name)
+}
+
+// SetSkew sets the software sector skew used to read the files of the disc,
+// reading the directory again, see AmsDos.SetSkew.
+func (d *DSK) SetSkew(skew int) error {
+	return d.AmsDos.SetSkew(d, skew)
+}
+
+// IsBootable reports whether the disc is bootable, along with the machine the
+// bootstrap is for.
+//
+// CPC discs are bootable when using the SYSTEM format (first sector ID 41h).
+// PCW and Spectrum +3 discs are identified by the 8-bit checksum of the boot
+// sector (track 0, head 0, sector 1): 1 for the PCW9512, 3 for the Spectrum +3,
+// and 255 for the PCW8256.
+func (d DSK) IsBootable() (bool, string) {
+	if len(d.Tracks) == 0 || len(d.Tracks[0].Sectors) == 0 {
+		return false, ""
+	}
+	firstID := firstSectorID(&d.Tracks[0])
+
+	switch firstID {
+	case 0x41:
+		return true, "Amstrad CPC"
+	case 0xC1:
+		return false, ""
+	}
+
+	sector := d.Tracks[0].sectorData(firstID)
+	if sector == nil {
+		return false, ""
+	}
+
+	machine := amsdos.Bootstrap(sector)
+	return machine != "", machine
+}
+
 // DisplayGeometry prints the disk, track and sector metadata to the terminal.
 func (d DSK) DisplayGeometry() {
 	fmt.Println("DISK INFORMATION:")
-	fmt.Println(d.Info)
+	fmt.Print(d.Info)
+	fmt.Printf("Format:     %s\n", d.AmsDos.FormatName())
+	if bootable, machine := d.IsBootable(); bootable {
+		fmt.Printf("Bootable:   yes (%s)\n", machine)
+	} else {
+		fmt.Println("Bootable:   no")
+	}
+	fmt.Println()
 
 	for _, track := range d.Tracks {
 		sectorSize, _ := sectorSizeMap[track.SectorSize]
@@ -77,32 +281,109 @@ func (d DSK) DisplayGeometry() {
 		}
 		str += fmt.Sprintf("%02d sectors", track.SectorsCount)
 		str += fmt.Sprintf(" (%d bytes)", sectorSize)
-		if int(track.SectorsCount) != len(track.Sectors) {
-			str += fmt.Sprintf(" WARNING only %d sectors read", len(track.Sectors))
+		if ids := track.SectorsWithErrors(); len(ids) > 0 {
+			str += fmt.Sprintf(", %d with errors", len(ids))
+		}
+		weak := 0
+		for _, sector := range track.Sectors {
+			if len(track.SectorCopies(sector.ID)) > 1 {
+				weak++
+			}
+		}
+		if weak > 0 {
+			str += fmt.Sprintf(", %d weak", weak)
 		}
 		fmt.Println(str)
 	}
 }
 
-// CommandDir displays the disk directory to the terminal.
-func (d DSK) CommandDir() {
-	commandCat, err := cat.CommandCat(d.AmsDos.DPB.BlockCount, d.AmsDos.Directories)
+// Warnings returns the problems found with the disk, which did not stop it
+// from being read, such as tracks with fewer sectors than given in the track
+// header, sectors flagged with errors by the disc controller, a change of
+// format at the data area, and the known quirks of the tool that created the
+// image.
+func (d DSK) Warnings() []string {
+	warnings := append([]string{}, d.problems...)
+
+	for _, quirk := range d.Info.CreatorQuirks() {
+		warnings = append(warnings, fmt.Sprintf("created by %s: %s", d.Info.CreatorName(), quirk))
+	}
+
+	if d.AmsDos.directoryError != nil {
+		warnings = append(warnings, fmt.Sprintf(
+			"the directory is incomplete, only %d entries read: %s",
+			len(d.AmsDos.Directories), d.AmsDos.directoryError,
+		))
+	}
+
+	if d.AmsDos.FormatChange != nil {
+		warnings = append(warnings, d.AmsDos.FormatChange.String()+", the data area is read using the new format")
+	}
+
+	for _, track := range d.Tracks {
+		if missing := track.MissingSectors(); missing > 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"side %d, track %02d: only %d of %d sectors read, the track header gives more sectors than are stored",
+				track.Side, track.Track, int(track.SectorsCount)-missing, track.SectorsCount,
+			))
+		}
+		if ids := track.SectorsWithErrors(); len(ids) > 0 {
+			var list []string
+			for _, id := range ids {
+				list = append(list, fmt.Sprintf("%02X", id))
+			}
+			warnings = append(warnings, fmt.Sprintf(
+				"side %d, track %02d: sectors with CRC errors or missing address marks: %s",
+				track.Side, track.Track, strings.Join(list, ", "),
+			))
+		}
+	}
+
+	return warnings
+}
+
+// CommandDir displays the disk directory of the user number to the terminal,
+// or the files of all users, grouped by user, when cat.AllUsers is given.
+// With cat.DeletedFiles the deleted files are listed, and whether they can be
+// recovered.
+func (d DSK) CommandDir(user int) {
+	commandCat, err := cat.CommandCat(d.AmsDos.DPB, d.AmsDos.Directories, user)
 	if err != nil {
 		fmt.Printf("CAT command error: %s", err)
 		return
 	}
 
-	fmt.Printf("Drive %c: user %d\n", commandCat.Drive, commandCat.User)
-	fmt.Println()
+	switch user {
+	case cat.DeletedFiles:
+		fmt.Printf("Drive %c: deleted files\n", commandCat.Drive)
+		fmt.Println()
+		for _, record := range commandCat.Records {
+			status := "overwritten"
+			if record.Recoverable {
+				status = "recoverable"
+			}
+			fmt.Printf("%s  %s\n", record.String(), status)
+		}
+		if len(commandCat.Records) == 0 {
+			fmt.Println("No deleted files found")
+		}
+	case cat.AllUsers:
+		fmt.Printf("Drive %c: all users\n", commandCat.Drive)
 
-	// Print listing in two columns
-	maxRowsLeft, maxRowsRight := recordRowCounts(len(commandCat.Records))
-	for i := 0; i < maxRowsLeft; i++ {
-		row := commandCat.Records[i].String()
-		if i < maxRowsRight {
-			row += fmt.Sprintf("   %s", commandCat.Records[maxRowsLeft+i].String())
+		// Print a listing in two columns for each user
+		for start := 0; start < len(commandCat.Records); {
+			end := start
+			for end < len(commandCat.Records) && commandCat.Records[end].User == commandCat.Records[start].User {
+				end++
+			}
+			fmt.Println()
+			printCatalogRecords(commandCat.Records[start:end], true)
+			start = end
 		}
-		fmt.Println(row)
+	default:
+		fmt.Printf("Drive %c: user %d\n", commandCat.Drive, commandCat.User)
+		fmt.Println()
+		printCatalogRecords(commandCat.Records, false)
 	}
 
 	fmt.Println()
@@ -119,6 +400,26 @@ func (d DSK) CommandDir() {
 	}
 }
 
+// printCatalogRecords prints the listing in two columns, optionally with the
+// user number of each file.
+func printCatalogRecords(records []cat.DirectoryRecord, withUser bool) {
+	format := func(r cat.DirectoryRecord) string {
+		if withUser {
+			return r.UserString()
+		}
+		return r.String()
+	}
+
+	maxRowsLeft, maxRowsRight := recordRowCounts(len(records))
+	for i := 0; i < maxRowsLeft; i++ {
+		row := format(records[i])
+		if i < maxRowsRight {
+			row += fmt.Sprintf("   %s", format(records[maxRowsLeft+i]))
+		}
+		fmt.Println(row)
+	}
+}
+
 func recordRowCounts(records int) (int, int) {
 	left := records / 2
 	if records%2 > 0 {
@@ -137,7 +438,7 @@ func reformatIdentifier(identifier []byte) string {
 		}
 	}
 
-	id := strings.Trim(string(idBytes), "\r\n")
+	id := strings.Trim(storage.DisplayText(storage.DecodeLatin1(idBytes)), "\r\n")
 	parts := strings.Split(id, "\r\n")
 
 	return strings.Join(parts, ", ")
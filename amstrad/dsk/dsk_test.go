@@ -0,0 +1,279 @@
+package dsk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// fixture returns the uncompressed contents of a gzipped test disk image.
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	f, err := ioutil.ReadFile(filepath.Join("testdata", name+".dsk.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readDisk reads the disk image data.
+func readDisk(t *testing.T, data []byte) *DSK {
+	t.Helper()
+
+	disk := New(storage.NewReader(bytes.NewReader(data)))
+	if err := disk.Read(); err != nil {
+		t.Fatalf("error reading disk: %v", err)
+	}
+	return disk
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		lazy bool
+	}{
+		{"files", false},
+		{"files", true},
+		{"weak", false},
+		{"weak", true},
+	}
+
+	for _, tt := range tests {
+		data := fixture(t, tt.name)
+
+		disk := New(storage.NewReader(bytes.NewReader(data)))
+		var err error
+		if tt.lazy {
+			err = disk.ReadLazy()
+		} else {
+			err = disk.Read()
+		}
+		if err != nil {
+			t.Fatalf("%s: error reading disk: %v", tt.name, err)
+		}
+
+		var buf bytes.Buffer
+		if err := disk.Write(&buf); err != nil {
+			t.Fatalf("%s: error writing disk: %v", tt.name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%s (lazy %v): written disk differs from the original, got %d bytes, want %d", tt.name, tt.lazy, buf.Len(), len(data))
+		}
+	}
+}
+
+func TestSectorCopies(t *testing.T) {
+	disk := readDisk(t, fixture(t, "weak"))
+	track := disk.Tracks[1]
+
+	// the weak sector has 3 copies, the second with every bit inverted
+	copies := track.SectorCopies(0xC1)
+	if len(copies) != 3 {
+		t.Fatalf("got %d copies of the weak sector, want 3", len(copies))
+	}
+	for i, c := range copies {
+		if len(c) != 512 {
+			t.Errorf("copy %d: got %d bytes, want 512", i, len(c))
+		}
+	}
+	if !bytes.Equal(copies[0], copies[2]) || copies[0][0] != ^copies[1][0] {
+		t.Error("the copies do not match the fixture data")
+	}
+	if !bytes.Equal(copies[0], track.sectorData(0xC1)) {
+		t.Error("the first copy is not the sector data")
+	}
+
+	if ids := track.SectorsWithErrors(); len(ids) != 1 || ids[0] != 0xC1 {
+		t.Errorf("got sectors with errors %X, want C1", ids)
+	}
+
+	// other sectors have a single copy
+	if copies := track.SectorCopies(0xC2); len(copies) != 1 {
+		t.Errorf("got %d copies of a normal sector, want 1", len(copies))
+	}
+	if copies := track.SectorCopies(0x01); copies != nil {
+		t.Errorf("got %d copies of a missing sector, want nil", len(copies))
+	}
+}
+
+func TestTrackCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		disk    string
+		tracks  int
+		warning string // expected warning, or empty for none
+	}{
+		{"consistent", "files", 40, ""},
+		// the disk information gives 38 tracks, with 40 stored
+		{"extra track", "extra", 38, "another track follows the last of the 38 tracks"},
+		// track 7 is stored in place of track 3
+		{"out of sequence", "sequence", 40, "the tracks are out of sequence from track #4, which is track 7 side 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disk := readDisk(t, fixture(t, tt.disk))
+			if len(disk.Tracks) != tt.tracks {
+				t.Errorf("got %d tracks, want %d", len(disk.Tracks), tt.tracks)
+			}
+
+			warnings := disk.Warnings()
+			if tt.warning == "" && len(warnings) != 0 {
+				t.Errorf("got warnings %q, want none", warnings)
+			} else if tt.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning)) {
+				t.Errorf("got warnings %q, want %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestTrackCountMissingTrack(t *testing.T) {
+	// the disk information gives 41 tracks, with only 40 stored, followed by
+	// the sector data of a track without its Track-Info block
+	data := fixture(t, "missing")
+
+	disk := New(storage.NewReader(bytes.NewReader(data)))
+	err := disk.Read()
+	var badGeometry ErrBadGeometry
+	if !errors.As(err, &badGeometry) {
+		t.Fatalf("got error %v, want a bad geometry error", err)
+	}
+	if !strings.Contains(err.Error(), "track #41 has no Track-Info signature") {
+		t.Errorf("got error %q, want the missing signature of track #41", err)
+	}
+
+	reader := storage.NewReader(bytes.NewReader(data))
+	reader.SetMode(storage.Lenient)
+	disk = New(reader)
+	if err := disk.Read(); err != nil {
+		t.Fatalf("got error %v in lenient mode, want a warning", err)
+	}
+	if len(disk.Tracks) != 40 {
+		t.Errorf("got %d tracks, want the 40 before the missing track", len(disk.Tracks))
+	}
+	if warnings := reader.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "only 40 of the 41 tracks") {
+		t.Errorf("got warnings %q, want the missing track", warnings)
+	}
+	if _, err := disk.ReadFile("HELLO.BAS"); err != nil {
+		t.Errorf("ReadFile: %v", err)
+	}
+}
+
+func TestOverstatedSectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		disk     string
+		warnings []string
+		files    []string // files readable from the sectors present
+		missing  []string // files on the missing sectors
+	}{
+		// the track header gives 10 sectors, with the data of 9 stored
+		{"sector data", "over", []string{
+			"side 0, track 00: only 9 of 10 sectors read",
+		}, []string{"HELLO.BAS"}, []string{"GAME.BIN"}},
+		{"standard sector data", "overstd", []string{
+			"side 0, track 00: only 9 of 10 sectors read",
+		}, []string{"HELLO.BAS"}, []string{"GAME.BIN"}},
+		// only the first directory sector is stored
+		{"directory", "shortdir", []string{
+			"the directory is incomplete, only 16 entries read",
+			"side 0, track 00: only 2 of 9 sectors read",
+		}, nil, []string{"HELLO.BAS", "GAME.BIN"}},
+	}
+
+	for _, tt := range tests {
+		for _, lazy := range []bool{false, true} {
+			disk := New(storage.NewReader(bytes.NewReader(fixture(t, tt.disk))))
+			read := disk.Read
+			if lazy {
+				read = disk.ReadLazy
+			}
+			if err := read(); err != nil {
+				t.Fatalf("%s (lazy %v): error reading disk: %v", tt.name, lazy, err)
+			}
+
+			warnings := disk.Warnings()
+			if len(warnings) != len(tt.warnings) {
+				t.Errorf("%s (lazy %v): got warnings %q, want %q", tt.name, lazy, warnings, tt.warnings)
+			} else {
+				for i, w := range tt.warnings {
+					if !strings.Contains(warnings[i], w) {
+						t.Errorf("%s (lazy %v): got warning %q, want %q", tt.name, lazy, warnings[i], w)
+					}
+				}
+			}
+
+			if len(disk.AmsDos.Directories) == 0 {
+				t.Errorf("%s (lazy %v): got no directory entries", tt.name, lazy)
+			}
+			for _, name := range tt.files {
+				if _, err := disk.ReadFile(name); err != nil {
+					t.Errorf("%s (lazy %v): ReadFile(%q): %v", tt.name, lazy, name, err)
+				}
+			}
+			for _, name := range tt.missing {
+				_, err := disk.ReadFile(name)
+				var badGeometry ErrBadGeometry
+				if !errors.As(err, &badGeometry) {
+					t.Errorf("%s (lazy %v): ReadFile(%q): got error %v, want a bad geometry error", tt.name, lazy, name, err)
+				}
+			}
+		}
+	}
+}
+
+func TestOverstatedSectorsHeader(t *testing.T) {
+	// the track header of a standard image gives 40 sectors, more than fit in
+	// the sector information list, with the data of 9 stored
+	disk := readDisk(t, fixture(t, "count40"))
+
+	if got := len(disk.Tracks[0].Sectors); got != maxSectors {
+		t.Errorf("got %d sectors, want the %d which fit in the track header", got, maxSectors)
+	}
+	if len(disk.Tracks) != 40 {
+		t.Errorf("got %d tracks, want 40", len(disk.Tracks))
+	}
+
+	warnings := disk.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "side 0, track 00: only 9 of 40 sectors read") {
+		t.Errorf("got warnings %q, want the missing sectors of track 0", warnings)
+	}
+	if _, err := disk.ReadFile("HELLO.BAS"); err != nil {
+		t.Errorf("ReadFile: %v", err)
+	}
+}
+
+func TestIsBootable(t *testing.T) {
+	tests := []struct {
+		disk     string
+		bootable bool
+		machine  string
+	}{
+		{"plus3", true, "Spectrum +3"},
+		{"files", false, ""},
+	}
+
+	for _, tt := range tests {
+		disk := readDisk(t, fixture(t, tt.disk))
+		bootable, machine := disk.IsBootable()
+		if bootable != tt.bootable || machine != tt.machine {
+			t.Errorf("%s: got bootable %v (%q), want %v (%q)", tt.disk, bootable, machine, tt.bootable, tt.machine)
+		}
+	}
+}
@@ -5,6 +5,8 @@ package dsk
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
+	"strings"
 
 	"retroio/storage"
 )
@@ -29,7 +31,10 @@ type SectorInformation struct {
 	Size   uint8  // N   Number of data bytes written to sector (enum 0-3)
 	ST1    uint8  // ST1 Error Status Register 1
 	ST2    uint8  // ST2 Error Status Register 2
-	Unused uint16 // not used (0)
+
+	// Extended images: the actual length of the sector data in bytes,
+	// otherwise not used (0).
+	DataLength uint16
 }
 
 // Read the track information header.
@@ -37,19 +42,42 @@ func (s *SectorInformation) Read(reader *storage.Reader) error {
 	return binary.Read(reader, binary.LittleEndian, s)
 }
 
-// dataRead reads the data from the disk
-func (s *SectorInformation) dataRead(reader *storage.Reader) ([]byte, error) {
+// Write the sector information header.
+func (s SectorInformation) Write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+// dataSize returns the size of the sector data in bytes.
+func (s SectorInformation) dataSize() (int, error) {
 	if s.Size > 3 {
-		return nil, fmt.Errorf("unknown sector size value 0x%02X", s.Size)
+		return 0, badGeometry("unknown sector size value 0x%02X", s.Size)
 	}
 
 	sectorSize, ok := sectorSizeMap[s.Size]
 	if !ok {
-		return nil, fmt.Errorf("invalid sector size byte")
+		return 0, badGeometry("invalid sector size byte")
+	}
+	return int(sectorSize), nil
+}
+
+// storedSize returns the size of the sector data stored in the image, which
+// for extended images is the actual data length, when given.
+func (s SectorInformation) storedSize(extended bool) (int, error) {
+	if extended && s.DataLength > 0 {
+		return int(s.DataLength), nil
+	}
+	return s.dataSize()
+}
+
+// dataRead reads the data from the disk
+func (s *SectorInformation) dataRead(reader *storage.Reader, extended bool) ([]byte, error) {
+	sectorSize, err := s.storedSize(extended)
+	if err != nil {
+		return nil, err
 	}
 
 	data := make([]byte, sectorSize)
-	err := binary.Read(reader, binary.LittleEndian, data)
+	err = binary.Read(reader, binary.LittleEndian, data)
 
 	return data, err
 }
@@ -79,26 +107,7 @@ func (s SectorInformation) String() string {
 // MA: If the FDC cannot detect the ID Address Mark after encountering the index hole twice, then th is flag is set.
 //     If the FDC cannot detect the Data Address Mark or Deleted Data Address Mark, this flag is set. Also at the same time, the MD (Missing Address Mark in Data Field) of Status Register 2 is set.
 func (s SectorInformation) st1Label() string {
-	var label string
-
-	switch s.ST1 {
-	case 0xB7:
-		label = "EN (End of Cylinder)"
-	case 0xB5:
-		label = "DE (Data Error)"
-	case 0xB4:
-		label = "OR (Over Run)"
-	case 0xB2:
-		label = "ND (No Data)"
-	case 0xB1:
-		label = "NW (Not Writable)"
-	case 0xB0:
-		label = "MA (Missing Address Mark)"
-	default:
-		label = "unknown"
-	}
-
-	return label
+	return statusLabel(s.ST1, st1Flags)
 }
 
 // NEC765 specification for "Status Register 2"
@@ -111,26 +120,77 @@ func (s SectorInformation) st1Label() string {
 // BC: This bit is related with the ND bit, and when the content of C on the medium is different from that stored in the IDR and the content of C is FF, then this flag is set.
 // MD: When data is read from the medium, if the FDC cannot find a Data Address Mark or Deleted Data Address Mark, then this flag is set.
 func (s SectorInformation) st2Label() string {
-	var label string
-
-	switch s.ST2 {
-	case 0xB6:
-		label = "CM (Control Mark)"
-	case 0xB5:
-		label = "DD (Data Error in Data field)"
-	case 0xB4:
-		label = "WC (Wrong Cylinder)"
-	case 0xB3:
-		label = "SH (Scan Equal Hit)"
-	case 0xB2:
-		label = "SN (Scan Not Satisfied)"
-	case 0xB1:
-		label = "BC (Bad Cylinder)"
-	case 0xB0:
-		label = "MD (Missing address Mark in Data field)"
-	default:
-		label = "unknown"
+	return statusLabel(s.ST2, st2Flags)
+}
+
+// Status Register 1 bits.
+const (
+	ST1EndOfCylinder      uint8 = 0x80 // EN
+	ST1DataError          uint8 = 0x20 // DE
+	ST1OverRun            uint8 = 0x10 // OR
+	ST1NoData             uint8 = 0x04 // ND
+	ST1NotWritable        uint8 = 0x02 // NW
+	ST1MissingAddressMark uint8 = 0x01 // MA
+)
+
+// Status Register 2 bits.
+const (
+	ST2ControlMark            uint8 = 0x40 // CM
+	ST2DataErrorInDataField   uint8 = 0x20 // DD
+	ST2WrongCylinder          uint8 = 0x10 // WC
+	ST2ScanEqualHit           uint8 = 0x08 // SH
+	ST2ScanNotSatisfied       uint8 = 0x04 // SN
+	ST2BadCylinder            uint8 = 0x02 // BC
+	ST2MissingAddressMarkData uint8 = 0x01 // MD
+)
+
+type statusFlag struct {
+	bit   uint8
+	label string
+}
+
+var st1Flags = []statusFlag{
+	{ST1EndOfCylinder, "EN (End of Cylinder)"},
+	{ST1DataError, "DE (Data Error)"},
+	{ST1OverRun, "OR (Over Run)"},
+	{ST1NoData, "ND (No Data)"},
+	{ST1NotWritable, "NW (Not Writable)"},
+	{ST1MissingAddressMark, "MA (Missing Address Mark)"},
+}
+
+var st2Flags = []statusFlag{
+	{ST2ControlMark, "CM (Control Mark)"},
+	{ST2DataErrorInDataField, "DD (Data Error in Data field)"},
+	{ST2WrongCylinder, "WC (Wrong Cylinder)"},
+	{ST2ScanEqualHit, "SH (Scan Equal Hit)"},
+	{ST2ScanNotSatisfied, "SN (Scan Not Satisfied)"},
+	{ST2BadCylinder, "BC (Bad Cylinder)"},
+	{ST2MissingAddressMarkData, "MD (Missing address Mark in Data field)"},
+}
+
+// statusLabel lists the flags set in the status register, as each bit is an
+// independent flag.
+func statusLabel(status uint8, flags []statusFlag) string {
+	if status == 0 {
+		return "none"
 	}
 
-	return label
+	var labels []string
+	for _, flag := range flags {
+		if status&flag.bit > 0 {
+			labels = append(labels, flag.label)
+		}
+	}
+	if len(labels) == 0 {
+		return "unknown"
+	}
+	return strings.Join(labels, ", ")
+}
+
+// HasError reports whether the disc controller flagged the sector as bad when
+// the image was made: a CRC error in the ID or data field, or a missing
+// address mark. These are often deliberate, as a form of copy protection.
+func (s SectorInformation) HasError() bool {
+	return s.ST1&(ST1DataError|ST1MissingAddressMark) > 0 ||
+		s.ST2&(ST2DataErrorInDataField|ST2MissingAddressMarkData) > 0
 }
@@ -13,7 +13,10 @@
 package dsk
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 
@@ -25,6 +28,10 @@ const (
 	sectorDataStartAddress     = 0x0100 // 256-bytes
 )
 
+// maxSectors is the most sector information blocks which fit in the track
+// header, before the sector data at 0x0100.
+const maxSectors = (sectorDataStartAddress - trackInformationHeaderSize) / sectorInformationBlockSize
+
 // Track information block
 //
 // * "sector size" parameter is used to calculate the location of each sector's
@@ -44,21 +51,26 @@ type TrackInformation struct {
 
 	Sectors    []SectorInformation // Sector Information List
 	SectorData [][]byte            // Sector data, starting at 0x0100 from start of Track
+
+	// Extended images store weak sectors as multiple copies of the data, the
+	// first copy is in SectorData, and any further copies are held here.
+	extraCopies [][]byte
+	extended    bool
+	size        int // track size from the track size table, 0 when unknown
+
+	// Number of sectors with their data stored in the track, which may be
+	// fewer than the sectors given in a damaged image.
+	storedCount int
+
+	// When read lazily, the file offset of the sector data, which is only
+	// read once the track is used.
+	dataOffset int64
+	unloaded   bool
 }
 
-// Read the track information header.
+// Read the track information header, and the sector data.
 func (t *TrackInformation) Read(reader *storage.Reader) error {
-	copy(t.Identifier[:], reader.ReadBytes(13))
-	copy(t.Unused1[:], reader.ReadBytes(3))
-	t.Track = reader.ReadByte()
-	t.Side = reader.ReadByte()
-	copy(t.Unused2[:], reader.ReadBytes(2))
-	t.SectorSize = reader.ReadByte()
-	t.SectorsCount = reader.ReadByte()
-	t.GapLength = reader.ReadByte()
-	t.FillerByte = reader.ReadByte()
-
-	if err := t.readSectorInformationBlocks(reader); err != nil {
+	if err := t.readHeader(reader); err != nil {
 		return err
 	}
 
@@ -69,8 +81,71 @@ func (t *TrackInformation) Read(reader *storage.Reader) error {
 	return nil
 }
 
+// ReadLazy reads the track information header, but skips the sector data,
+// recording its offset, so it can be read on demand with Load.
+func (t *TrackInformation) ReadLazy(reader *storage.Reader) error {
+	if err := t.readHeader(reader); err != nil {
+		return err
+	}
+
+	if err := t.setBufferToDataAddress(reader); err != nil {
+		return err
+	}
+	t.dataOffset = reader.Offset()
+
+	count, size, err := t.storedSectors()
+	if err != nil {
+		return err
+	}
+	t.storedCount = count
+
+	if _, err := reader.Discard(size); err != nil {
+		return err
+	}
+	t.unloaded = true
+
+	return nil
+}
+
+// Load reads the sector data of a lazily read track, by seeking back to the
+// data offset. Tracks that are already loaded are not read again.
+func (t *TrackInformation) Load(reader *storage.Reader) error {
+	if !t.unloaded {
+		return nil
+	}
+
+	if _, err := reader.Seek(t.dataOffset, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "error seeking to the data of track %d", t.Track)
+	}
+	if err := t.readSectors(reader); err != nil {
+		return err
+	}
+	t.unloaded = false
+
+	return nil
+}
+
+// readHeader reads the track information header and sector information list.
+func (t *TrackInformation) readHeader(reader *storage.Reader) error {
+	copy(t.Identifier[:], reader.ReadBytes(13))
+	copy(t.Unused1[:], reader.ReadBytes(3))
+	t.Track = reader.ReadUint8()
+	t.Side = reader.ReadUint8()
+	copy(t.Unused2[:], reader.ReadBytes(2))
+	t.SectorSize = reader.ReadUint8()
+	t.SectorsCount = reader.ReadUint8()
+	t.GapLength = reader.ReadUint8()
+	t.FillerByte = reader.ReadUint8()
+
+	return t.readSectorInformationBlocks(reader)
+}
+
+// readSectorInformationBlocks reads the sector information list. A damaged
+// track may give more sectors than fit in the header, so only those before the
+// sector data are read.
 func (t *TrackInformation) readSectorInformationBlocks(reader *storage.Reader) error {
-	for i := 0; i < int(t.SectorsCount); i++ {
+	t.Sectors = nil
+	for i := 0; i < int(t.SectorsCount) && i < maxSectors; i++ {
 		sector := SectorInformation{}
 		if err := sector.Read(reader); err != nil {
 			return errors.Wrapf(err, "error reading sector #%d", i+1)
@@ -84,19 +159,115 @@ func (t *TrackInformation) readSectorData(reader *storage.Reader) error {
 	if err := t.setBufferToDataAddress(reader); err != nil {
 		return err
 	}
+	return t.readSectors(reader)
+}
 
-	for i, s := range t.Sectors {
-		data, err := s.dataRead(reader)
+// readSectors reads the data of each sector, from the start of the sector data.
+// Sectors with data beyond the end of the track have no data.
+func (t *TrackInformation) readSectors(reader *storage.Reader) error {
+	count, _, err := t.storedSectors()
+	if err != nil {
+		return err
+	}
+	t.storedCount = count
+
+	t.SectorData = nil
+	t.extraCopies = nil
+	for i, s := range t.Sectors[:count] {
+		data, err := s.dataRead(reader, t.extended)
 		if err != nil {
 			return errors.Wrapf(err, "error reading sector #%d", i)
 		}
+
+		var extra []byte
+		if size, err := s.dataSize(); err == nil && len(data) > size && len(data)%size == 0 {
+			data, extra = data[:size], data[size:]
+		}
 		t.SectorData = append(t.SectorData, data)
+		t.extraCopies = append(t.extraCopies, extra)
 	}
 	return nil
 }
 
+// storedSectors returns the number of sectors with their data stored in the
+// track, and the size of that data. When the track size is known, the data of
+// the sectors is only read up to the end of the track, as a damaged track may
+// give more sectors, or longer sectors, than are stored.
+func (t TrackInformation) storedSectors() (int, int, error) {
+	remaining := t.size - sectorDataStartAddress
+
+	size := 0
+	for i, s := range t.Sectors {
+		sectorSize, err := s.storedSize(t.extended)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "error reading sector #%d", i)
+		}
+		if t.size > 0 && size+sectorSize > remaining {
+			return i, size, nil
+		}
+		size += sectorSize
+	}
+	return len(t.Sectors), size, nil
+}
+
+// MissingSectors returns the number of sectors given in the track header with
+// no data stored in the track, either as the sector information list would
+// overrun the sector data, or the data would overrun the end of the track.
+func (t TrackInformation) MissingSectors() int {
+	return int(t.SectorsCount) - t.storedCount
+}
+
+// SectorCopies returns every copy of the data for the sector with the given
+// ID, or nil when no such sector is found on the track.
+//
+// Extended images store a weak (or fuzzy) sector, which reads differently
+// each time, as several copies of its data. The number of copies is the
+// ratio of the actual data length to the sector size, e.g. a 512 byte sector
+// with a data length of 1536 bytes has 3 copies. When the data length is not
+// a whole multiple of the sector size, the data is a single copy.
+func (t TrackInformation) SectorCopies(id uint8) [][]byte {
+	for i, s := range t.Sectors {
+		if s.ID != id || i >= len(t.SectorData) {
+			continue
+		}
+
+		copies := [][]byte{t.SectorData[i]}
+		if i < len(t.extraCopies) {
+			size := len(t.SectorData[i])
+			for extra := t.extraCopies[i]; size > 0 && len(extra) >= size; extra = extra[size:] {
+				copies = append(copies, extra[:size])
+			}
+		}
+		return copies
+	}
+	return nil
+}
+
+// sectorData returns the data for the sector with the given ID, or nil when no
+// such sector is found on the track.
+func (t TrackInformation) sectorData(id uint8) []byte {
+	for i, s := range t.Sectors {
+		if s.ID == id && i < len(t.SectorData) {
+			return t.SectorData[i]
+		}
+	}
+	return nil
+}
+
+// SectorsWithErrors returns the IDs of the sectors flagged with a CRC error or
+// missing address mark in their ST1/ST2 status bytes.
+func (t TrackInformation) SectorsWithErrors() []uint8 {
+	var ids []uint8
+	for _, s := range t.Sectors {
+		if s.HasError() {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
 func (t TrackInformation) setBufferToDataAddress(reader *storage.Reader) error {
-	blockSize := int(t.SectorsCount) * sectorInformationBlockSize
+	blockSize := len(t.Sectors) * sectorInformationBlockSize
 	usedBytes := trackInformationHeaderSize + blockSize
 
 	_, err := reader.Discard(sectorDataStartAddress - usedBytes)
@@ -107,6 +278,48 @@ func (t TrackInformation) setBufferToDataAddress(reader *storage.Reader) error {
 	return nil
 }
 
+// Write the track information header, the sector information list and the
+// sector data. The track is padded with zeros up to the given track size.
+func (t TrackInformation) Write(w io.Writer, trackSize int) error {
+	buf := &bytes.Buffer{}
+
+	header := []interface{}{
+		t.Identifier, t.Unused1, t.Track, t.Side, t.Unused2,
+		t.SectorSize, t.SectorsCount, t.GapLength, t.FillerByte,
+	}
+	for _, field := range header {
+		if err := binary.Write(buf, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	for i, s := range t.Sectors {
+		if err := s.Write(buf); err != nil {
+			return errors.Wrapf(err, "error writing sector #%d", i+1)
+		}
+	}
+
+	// sector data always starts at 0x0100
+	if buf.Len() > sectorDataStartAddress {
+		return errors.Errorf("sector information list overruns the sector data address: %d bytes", buf.Len())
+	}
+	buf.Write(make([]byte, sectorDataStartAddress-buf.Len()))
+
+	for i, data := range t.SectorData {
+		buf.Write(data)
+		if i < len(t.extraCopies) {
+			buf.Write(t.extraCopies[i])
+		}
+	}
+
+	if buf.Len() < trackSize {
+		buf.Write(make([]byte, trackSize-buf.Len()))
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 func (t TrackInformation) String() string {
 	sectorSize, _ := sectorSizeMap[t.SectorSize]
 
@@ -3,5 +3,6 @@ package amstrad
 type Image interface {
 	Read() error
 	DisplayGeometry()
-	CommandDir()
+	CommandDir(user int)
+	Warnings() []string
 }
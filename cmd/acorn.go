@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var acornMediaType string
+
+// acornCmd represents the acorn command
+var acornCmd = &cobra.Command{
+	Use:     "acorn",
+	Aliases: []string{"bbc"},
+	Short:   "System command for the Acorn BBC Micro and Electron",
+	Long: `The computer system command for working with tape images for the Acorn
+BBC Micro and Electron 8-bit home computers.
+
+This is a top-level system command only and requires a sub-command.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(acornCmd)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"retroio/acorn/uef"
+	"retroio/storage"
+)
+
+var acornExtractOutput string
+
+var acornExtractCmd = &cobra.Command{
+	Use:   "extract FILE NAME",
+	Short: "Extract a file from an Acorn tape",
+	Long: `Extract a file saved by the cassette filing system from an Acorn UEF tape,
+saving it to the current directory, or the file given with --output.
+
+The blocks of the file are joined, so the file is saved as it would be loaded.
+Files with missing blocks, or blocks with a CRC error, are still extracted, with
+a warning. The load and execution addresses are shown, as they are not saved.`,
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+		name := args[1]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(acornMediaType, filename, reader)
+		if dskType != "uef" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		tape := uef.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		file, err := tape.File(name)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if file.Missing {
+			fmt.Println("WARNING: blocks of the file are missing.")
+		}
+		if file.BadCRC {
+			fmt.Println("WARNING: blocks of the file have a CRC error.")
+		}
+
+		output := acornExtractOutput
+		if output == "" {
+			output = file.Name
+		}
+		if err := ioutil.WriteFile(output, file.Data, 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Extracted %d bytes to '%s', load &%08X, exec &%08X\n", len(file.Data), output, file.LoadAddress, file.ExecAddress)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	acornExtractCmd.Flags().StringVarP(&acornMediaType, "media", "m", "", `Media type, default: file extension`)
+	acornExtractCmd.Flags().StringVarP(&acornExtractOutput, "output", "o", "", `Output file, default: the NAME of the file`)
+	acornCmd.AddCommand(acornExtractCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"retroio/acorn"
+	"retroio/acorn/uef"
+	"retroio/storage"
+)
+
+var acornGeometryCmd = &cobra.Command{
+	Use:   "geometry FILE",
+	Short: "Read the Acorn tape file geometry",
+	Long: `Read the geometry - header and chunks - from an Acorn UEF tape file, followed by
+the files saved on the tape by the cassette filing system.
+
+UEF files are usually gzip compressed, which is handled automatically.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		var tape acorn.Image
+		dskType := detectMediaType(acornMediaType, filename, reader)
+
+		switch dskType {
+		case "uef":
+			tape = uef.New(reader)
+		default:
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		if structuredOutput() {
+			displayTable(acornTable(tape))
+			displayWarnings(reader, tape)
+			return
+		}
+
+		tape.DisplayGeometry()
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	acornGeometryCmd.Flags().StringVarP(&acornMediaType, "media", "m", "", `Media type, default: file extension`)
+	acornCmd.AddCommand(acornGeometryCmd)
+}
+
+// acornTable returns the chunks of a UEF tape.
+func acornTable(image acorn.Image) *outputTable {
+	switch t := image.(type) {
+	case *uef.UEF:
+		table := newOutputTable("chunk", "offset", "id", "name", "summary")
+		for i, c := range t.Chunks {
+			table.add(i+1, c.Offset, fmt.Sprintf("%04X", c.ID), c.Name(), c.Details())
+		}
+		return table
+	}
+	return newOutputTable()
+}
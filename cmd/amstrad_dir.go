@@ -3,34 +3,63 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"retroio/amstrad"
 	"retroio/amstrad/dsk"
+	"retroio/amstrad/dsk/amsdos/cat"
 	"retroio/storage"
 )
 
+var (
+	amstradDirUser     int
+	amstradDirAllUsers bool
+	amstradDirDeleted  bool
+)
+
 var amstradCommandDir = &cobra.Command{
-	Use:                   "dir FILE",
-	Aliases:               []string{"cat"},
-	Short:                 "Displays the directory of a DSK image",
-	Long:                  `Reads and displays the directory listing found on an Amstrad emulator DSK image file.`,
+	Use:     "dir FILE",
+	Aliases: []string{"cat"},
+	Short:   "Displays the directory of a DSK image",
+	Long: `Reads and displays the directory listing found on an Amstrad emulator DSK image file.
+
+Only the files of user 0 are listed, unless another user number is given with
+--user, as with the CPC |USER command. Use --all-users for the files of every
+user, grouped by user number.
+
+Use --deleted to list the deleted files instead, along with whether they can be
+recovered, as none of their blocks have been reused by another file.
+
+With --format json the whole catalog is written as a single JSON document,
+with the files of all users, the extents and allocation blocks of each file,
+and the Disk Parameter Block of the disk.`,
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
 
-		f, err := os.Open(filename)
+		user := amstradDirUser
+		if amstradDirDeleted {
+			user = cat.DeletedFiles
+		} else if amstradDirAllUsers {
+			user = cat.AllUsers
+		} else if user < 0 || user > 15 {
+			fmt.Println("The user number must be between 0 and 15.")
+			os.Exit(1)
+		}
+
+		f, filename, err := openMedia(filename)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 		defer f.Close()
-		reader := storage.NewReader(f)
+		reader := newReader(f)
 
 		var disk amstrad.Image
-		dskType := mediaType(amstradMediaType, filename)
+		dskType := detectMediaType(amstradMediaType, filename, reader)
 
 		switch dskType {
 		case "dsk":
@@ -40,17 +69,86 @@ var amstradCommandDir = &cobra.Command{
 			return
 		}
 
-		if err := disk.Read(); err != nil {
+		// only the directory tracks are needed for the catalog
+		read := disk.Read
+		if d, ok := disk.(*dsk.DSK); ok {
+			read = d.ReadLazy
+		}
+
+		if err := read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
 			fmt.Println("Media read error!")
-			fmt.Println(err)
+			displayReadError(err)
 			os.Exit(1)
 		}
 
-		disk.CommandDir()
+		if d, ok := disk.(*dsk.DSK); ok && amstradSkew > 1 {
+			if err := d.SetSkew(amstradSkew); err != nil {
+				fmt.Println("Media read error!")
+				displayReadError(err)
+				os.Exit(1)
+			}
+		}
+
+		if structuredOutput() {
+			d, ok := disk.(*dsk.DSK)
+			if !ok {
+				fmt.Fprintln(messages(), "directory listing unsupported for tapes")
+				os.Exit(1)
+			}
+			if outputFormat == formatJSON && user != cat.DeletedFiles {
+				doc, err := d.CatalogJSON()
+				if err != nil {
+					fmt.Fprintf(messages(), "CAT command error: %s\n", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(doc))
+			} else {
+				displayTable(catalogTable(d, user))
+			}
+			displayWarnings(reader, disk)
+			return
+		}
+
+		disk.CommandDir(user)
+		displayWarnings(reader, disk)
 	},
 }
 
 func init() {
 	amstradCommandDir.Flags().StringVarP(&amstradMediaType, "media", "m", "", `Media type, default: file extension`)
+	amstradCommandDir.Flags().IntVarP(&amstradDirUser, "user", "u", 0, `User number of the files to list, 0-15`)
+	amstradCommandDir.Flags().BoolVarP(&amstradDirAllUsers, "all-users", "a", false, `List the files of all users`)
+	amstradCommandDir.Flags().BoolVarP(&amstradDirDeleted, "deleted", "d", false, `List the deleted files that may be recovered`)
+	amstradCommandDir.Flags().IntVar(&amstradSkew, "skew", 0, `Software sector skew of CP/M discs from other systems, the Amstrad formats have none`)
 	amstradCmd.AddCommand(amstradCommandDir)
 }
+
+// catalogTable returns the catalog of the disk for the user number, with the
+// recoverable column when the deleted files are listed.
+func catalogTable(d *dsk.DSK, user int) *outputTable {
+	columns := []string{"user", "filename", "type", "size_k", "read_only", "hidden", "archived"}
+	if user == cat.DeletedFiles {
+		columns = append(columns, "recoverable")
+	}
+	table := newOutputTable(columns...)
+
+	catalog, err := cat.CommandCat(d.AmsDos.DPB, d.AmsDos.Directories, user)
+	if err != nil {
+		fmt.Fprintf(messages(), "CAT command error: %s\n", err)
+		return table
+	}
+
+	for _, r := range catalog.Records {
+		row := []interface{}{
+			r.User, strings.TrimSpace(r.Filename), strings.TrimSpace(r.FileType), r.Size(),
+			r.ReadOnly, r.Hidden, r.Archived,
+		}
+		if user == cat.DeletedFiles {
+			row = append(row, r.Recoverable)
+		}
+		table.add(row...)
+	}
+	return table
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"retroio/amstrad/dsk"
+	"retroio/amstrad/dsk/amsdos"
+	"retroio/storage"
+)
+
+var (
+	amstradExtractOutput      string
+	amstradExtractConvertText bool
+	amstradSkew               int
+)
+
+var amstradCommandExtract = &cobra.Command{
+	Use:   "extract FILE NAME",
+	Short: "Extract a file from an Amstrad DSK image",
+	Long: `Extract a file from an Amstrad DSK disk image, saving it to the current
+directory, or the file given with --output.
+
+When the file has an AMSDOS header it is removed, and the file is trimmed to the
+length given in the header.
+
+ASCII files, which have no header, end at the soft EOF (Ctrl-Z) within the last
+record, and are trimmed to it. Their CR+LF line endings are converted to those
+of the host with --convert-text. Binary files are saved as they are stored on
+the disk.
+
+The sectors of the Amstrad formats are interleaved by their sector IDs, which
+are always read in order. CP/M discs from other systems may instead use a
+software sector skew, which is given with --skew.`,
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+		name := args[1]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(amstradMediaType, filename, reader)
+		if dskType != "dsk" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+		disk := dsk.New(reader)
+
+		// only the directory tracks, and those of the file, are read
+		if err := disk.ReadLazy(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Media read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		if amstradSkew > 1 {
+			if err := disk.SetSkew(amstradSkew); err != nil {
+				fmt.Println("Media read error!")
+				displayReadError(err)
+				os.Exit(1)
+			}
+		}
+
+		data, err := disk.ReadFile(name)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		data, text := amsdosFileData(data)
+		if text && amstradExtractConvertText && runtime.GOOS != "windows" {
+			data = amsdos.UnixLineEndings(data)
+		}
+
+		output := amstradExtractOutput
+		if output == "" {
+			output = name
+		}
+		if err := ioutil.WriteFile(output, data, 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Extracted %d bytes to '%s'\n", len(data), output)
+		displayWarnings(reader, disk)
+	},
+}
+
+// amsdosFileData returns the data of an AMSDOS file, without its header, or
+// for an ASCII text file, up to the soft EOF, reporting whether it is text.
+// Any other files are returned as they are stored on the disk.
+func amsdosFileData(data []byte) ([]byte, bool) {
+	if header, err := amsdos.ReadRecordHeader(data); err == nil {
+		fmt.Printf("AMSDOS header: %s file, %d bytes, load address &%04X\n",
+			header.FileTypeName(), header.Length(), header.DataLocation)
+
+		data = data[amsdos.CpmRecordSize:]
+		if length := header.Length(); length < len(data) {
+			data = data[:length]
+		}
+		return data, header.FileType&^0x01 == amsdos.FileTypeASCII
+	}
+
+	if amsdos.IsText(data) {
+		fmt.Println("No AMSDOS header, extracting the file as ASCII text.")
+		return amsdos.TrimSoftEOF(data), true
+	}
+
+	fmt.Println("No AMSDOS header, extracting the file as stored on the disk.")
+	return data, false
+}
+
+func init() {
+	amstradCommandExtract.Flags().StringVarP(&amstradMediaType, "media", "m", "", `Media type, default: file extension`)
+	amstradCommandExtract.Flags().StringVarP(&amstradExtractOutput, "output", "o", "", `Output file, default: the NAME of the file`)
+	amstradCommandExtract.Flags().BoolVar(&amstradExtractConvertText, "convert-text", false, `Convert the line endings of ASCII files to those of the host`)
+	amstradCommandExtract.Flags().IntVar(&amstradSkew, "skew", 0, `Software sector skew of CP/M discs from other systems, the Amstrad formats have none`)
+	amstradCmd.AddCommand(amstradCommandExtract)
+}
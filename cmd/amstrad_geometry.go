@@ -26,34 +26,47 @@ NOTE: the CDT geometry is identical to that of the ZX Spectrum TZX format.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
 
-		f, err := os.Open(filename)
+		f, filename, err := openMedia(filename)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 		defer f.Close()
-		reader := storage.NewReader(f)
+		reader := newReader(f)
 
 		var disk amstrad.Image
-		dskType := mediaType(amstradMediaType, filename)
+		dskType := detectMediaType(amstradMediaType, filename, reader)
 
 		switch dskType {
 		case "dsk":
 			disk = dsk.New(reader)
-		case "cdt":
+		case "cdt", "tzx":
 			disk = cdt.New(reader)
 		default:
 			fmt.Printf("Unsupported media type: '%s'", dskType)
 			return
 		}
 
-		if err := disk.Read(); err != nil {
+		if err := disk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
 			fmt.Println("Media read error!")
-			fmt.Println(err)
+			displayReadError(err)
 			os.Exit(1)
 		}
 
+		if structuredOutput() {
+			if d, ok := disk.(*dsk.DSK); ok {
+				displayTable(trackTable(d))
+			} else {
+				displayTable(blockSummaryTable(disk, 0, nil))
+			}
+			displayWarnings(reader, disk)
+			return
+		}
+
 		disk.DisplayGeometry()
+		displayWarnings(reader, disk)
 	},
 }
 
@@ -61,3 +74,12 @@ func init() {
 	amstradGeometryCmd.Flags().StringVarP(&amstradMediaType, "media", "m", "", `Media type, default: file extension`)
 	amstradCmd.AddCommand(amstradGeometryCmd)
 }
+
+// trackTable returns the side, track number and sector details of each track.
+func trackTable(d *dsk.DSK) *outputTable {
+	table := newOutputTable("side", "track", "sectors", "sectors_read", "sector_size")
+	for _, track := range d.Tracks {
+		table.add(track.Side, track.Track, track.SectorsCount, len(track.Sectors), 128<<track.SectorSize)
+	}
+	return table
+}
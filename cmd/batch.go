@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+var (
+	batchRecursive bool
+	batchGlob      string
+	batchJSON      bool
+)
+
+// batchResult is the summary of a single media file.
+type batchResult struct {
+	File   string
+	Format string
+	Title  string
+	Status string
+	Error  string
+}
+
+// titled is implemented by the media images that store a title.
+type titled interface {
+	Title() string
+}
+
+var batchCmd = &cobra.Command{
+	Use:   "batch DIR",
+	Short: "Summarise all media files in a directory",
+	Long: `Read every supported media file in a directory, and output a summary table of
+the filename, format, title, and integrity status of each file. Files that can
+not be read are reported, and do not stop the processing of the other files.
+
+Use --format json to output the summary as JSON lines, one object per file, or
+--format csv for a CSV table. The --json flag is the same as --format json.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		root := args[0]
+
+		var results []batchResult
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				results = append(results, batchResult{File: path, Status: "error", Error: err.Error()})
+				return nil
+			}
+			if info.IsDir() {
+				if path != root && !batchRecursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if batchGlob != "" {
+				if ok, err := filepath.Match(batchGlob, info.Name()); err != nil {
+					return err
+				} else if !ok {
+					return nil
+				}
+			}
+
+			if result, ok := batchFile(path); ok {
+				results = append(results, result)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if batchJSON || structuredOutput() {
+			if batchJSON {
+				outputFormat = formatJSON
+			}
+			table := newOutputTable("file", "format", "title", "status", "error")
+			for _, r := range results {
+				table.add(r.File, r.Format, r.Title, r.Status, r.Error)
+			}
+			displayTable(table)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FILE\tFORMAT\tTITLE\tSTATUS")
+		for _, r := range results {
+			status := r.Status
+			if r.Error != "" {
+				// keep multi-line errors on the table row
+				status += ": " + strings.Join(strings.Fields(r.Error), " ")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.File, r.Format, r.Title, status)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	batchCmd.Flags().BoolVarP(&batchRecursive, "recursive", "r", false, `Include files in all sub-directories`)
+	batchCmd.Flags().StringVarP(&batchGlob, "glob", "g", "", `Only read files with names matching the pattern, e.g. "*.tzx"`)
+	batchCmd.Flags().BoolVar(&batchJSON, "json", false, `Output JSON lines instead of a table`)
+	rootCmd.AddCommand(batchCmd)
+}
+
+// batchFile reads a single media file, returning false when the file is not
+// a supported media type. Any read errors, or even a panic, are recorded in
+// the result so the batch can continue.
+func batchFile(path string) (result batchResult, ok bool) {
+	result = batchResult{File: path}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = "error"
+			result.Error = fmt.Sprintf("%v", r)
+		}
+	}()
+
+	f, filename, err := openMedia(path)
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		return result, true
+	}
+	defer f.Close()
+	reader := newReader(f)
+
+	result.Format = detectInfoMediaType(filename, reader)
+	format, ok := mediaFormats[result.Format]
+	if !ok {
+		return result, false
+	}
+
+	disk := format.image(reader)
+	err = disk.Read()
+
+	if t, ok := disk.(titled); ok {
+		result.Title = t.Title()
+	}
+
+	switch {
+	case storage.IsTruncated(err):
+		result.Status = "truncated"
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = "error"
+		result.Error = err.Error()
+	case len(reader.Warnings()) > 0:
+		result.Status = "warnings"
+		result.Error = fmt.Sprintf("%d format errors", len(reader.Warnings()))
+	default:
+		result.Status = "ok"
+	}
+
+	return result, true
+}
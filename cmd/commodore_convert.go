@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/commodore/t64"
+	"retroio/commodore/tap"
+	"retroio/storage"
+)
+
+var (
+	commodoreConvertOutput string
+	commodoreConvertNTSC   bool
+	commodoreConvertGapMs  int
+)
+
+var commodoreConvertCmd = &cobra.Command{
+	Use:   "convert FILE",
+	Short: "Convert a T64 tape to a TAP for writing to a real Datasette",
+	Long: `Convert the programs of a Commodore C64 T64 tape to a TAP tape, named after the
+tape, or the file given with --output, which can be exported to a WAV file and
+recorded to a real Datasette.
+
+A T64 holds only the program data, so the tape is encoded as saved by the
+standard Kernal routines, with a header and data block for each program. Custom
+fast loaders are not reproduced. The pulse lengths are the same for PAL and NTSC
+machines; --ntsc only changes the clock used for the gap between the programs.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(commodoreMediaType, filename, reader)
+		if dskType != "t64" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		tape := t64.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is converted")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		encoding := t64.KernalEncoding()
+		if commodoreConvertNTSC {
+			encoding.Clock = tap.NTSCClock
+		}
+		encoding.GapMs = commodoreConvertGapMs
+
+		converted, err := tape.ToTAPWith(encoding)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		output := commodoreConvertOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".tap"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		if err := converted.Write(out); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Converted the tape to '%s'\n", output)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	commodoreConvertCmd.Flags().StringVarP(&commodoreMediaType, "media", "m", "", `Media type, default: file extension`)
+	commodoreConvertCmd.Flags().StringVarP(&commodoreConvertOutput, "output", "o", "", `Output file, default: the tape filename with a .tap extension`)
+	commodoreConvertCmd.Flags().BoolVar(&commodoreConvertNTSC, "ntsc", false, `Use the NTSC clock rate, instead of PAL`)
+	commodoreConvertCmd.Flags().IntVar(&commodoreConvertGapMs, "gap-ms", 1000, `Silence between the programs (ms)`)
+	commodoreCmd.AddCommand(commodoreConvertCmd)
+}
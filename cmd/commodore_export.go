@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/commodore/tap"
+	"retroio/storage"
+)
+
+var (
+	commodoreExportOutput     string
+	commodoreExportSampleRate int
+	commodoreExportNTSC       bool
+)
+
+var commodoreExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Create a WAV file for writing a tape to a real Datasette",
+	Long: `Play a Commodore C64 TAP tape to a WAV file, named after the tape, or the file
+given with --output, which can be recorded to a real Datasette.
+
+The pulse lengths are converted using the PAL clock rate, unless --ntsc is given
+for tapes dumped from NTSC machines.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(commodoreMediaType, filename, reader)
+		if dskType != "tap" && dskType != "c64tap" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		tape := tap.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is exported")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		output := commodoreExportOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".wav"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		if err := tape.ExportWAV(out, commodoreExportSampleRate, !commodoreExportNTSC); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported the tape to '%s'\n", output)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	commodoreExportCmd.Flags().StringVarP(&commodoreMediaType, "media", "m", "", `Media type, default: file extension`)
+	commodoreExportCmd.Flags().StringVarP(&commodoreExportOutput, "output", "o", "", `Output file, default: the tape filename with a .wav extension`)
+	commodoreExportCmd.Flags().IntVar(&commodoreExportSampleRate, "rate", 44100, `Sample rate of the WAV file`)
+	commodoreExportCmd.Flags().BoolVar(&commodoreExportNTSC, "ntsc", false, `Use the NTSC clock rate, instead of PAL`)
+	commodoreCmd.AddCommand(commodoreExportCmd)
+}
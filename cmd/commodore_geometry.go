@@ -3,10 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"retroio/commodore"
+	"retroio/commodore/crt"
 	"retroio/commodore/t64"
 	"retroio/commodore/tap"
 	"retroio/storage"
@@ -16,42 +18,53 @@ var commodoreMediaType string
 
 var commodoreGeometryCmd = &cobra.Command{
 	Use:   "geometry FILE",
-	Short: "Read the Commodore tape file geometry",
+	Short: "Read the Commodore tape or cartridge file geometry",
 	Long: `Read the geometry - headers and data blocks - from a Commodore emulator TAP
-or T64 tape file.`,
+or T64 tape file, or the header and CHIP packets of a CRT cartridge file.`,
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
 
-		f, err := os.Open(filename)
+		f, filename, err := openMedia(filename)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 		defer f.Close()
-		reader := storage.NewReader(f)
+		reader := newReader(f)
 
 		var dsk commodore.Image
-		dskType := mediaType(commodoreMediaType, filename)
+		dskType := detectMediaType(commodoreMediaType, filename, reader)
 
 		switch dskType {
 		case "t64":
 			dsk = t64.New(reader)
-		case "tap":
+		case "tap", "c64tap":
 			dsk = tap.New(reader)
+		case "crt":
+			dsk = crt.New(reader)
 		default:
 			fmt.Printf("Unsupported media type: '%s'", dskType)
 			return
 		}
 
-		if err := dsk.Read(); err != nil {
+		if err := dsk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
 			fmt.Println("Storage read error!")
-			fmt.Println(err)
+			displayReadError(err)
 			os.Exit(1)
 		}
 
+		if structuredOutput() {
+			displayTable(commodoreTable(dsk))
+			displayWarnings(reader, dsk)
+			return
+		}
+
 		dsk.DisplayGeometry()
+		displayWarnings(reader, dsk)
 	},
 }
 
@@ -59,3 +72,27 @@ func init() {
 	commodoreGeometryCmd.Flags().StringVarP(&commodoreMediaType, "media", "m", "", `Media type, default: file extension`)
 	commodoreCmd.AddCommand(commodoreGeometryCmd)
 }
+
+// commodoreTable returns the records of a T64 tape, the header of a raw TAP
+// tape, or the CHIP packets of a CRT cartridge.
+func commodoreTable(image commodore.Image) *outputTable {
+	switch t := image.(type) {
+	case *t64.T64:
+		table := newOutputTable("record", "filename", "type", "start_address", "end_address", "offset", "data_length")
+		for i, r := range t.Records {
+			table.add(i, r.Name(), r.FileTypeName(), r.StartAddress, r.EndAddress, r.Offset, r.Size())
+		}
+		return table
+	case *tap.TAP:
+		table := newOutputTable("signature", "version", "data_size", "data_read")
+		table.add(strings.TrimSpace(string(t.Signature[:])), t.Version, t.DataSize, len(t.Data))
+		return table
+	case *crt.CRT:
+		table := newOutputTable("chip", "type", "bank", "load_address", "size")
+		for i, c := range t.Chips {
+			table.add(i, c.ChipTypeName(), c.Bank, c.LoadAddress, c.ImageSize)
+		}
+		return table
+	}
+	return newOutputTable()
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/pzx"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+var diffVerbose bool
+
+// diffBlock is a tape data block, along with the data used for its hash.
+type diffBlock struct {
+	tap.BlockHash
+	data []byte
+}
+
+// diffEntry is a single difference between the tapes; a and b are nil when
+// the block is only found on the other tape.
+type diffEntry struct {
+	kind string // same, changed, moved, removed, added
+	a, b *diffBlock
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff FILE1 FILE2",
+	Short: "Compare the data blocks of two tapes",
+	Long: `Compare the data blocks of two ZX Spectrum or Amstrad tapes, reporting any blocks
+that have been added, removed, moved, or have changed data. Blocks are compared
+by their data hash, so pauses, descriptions, and the block types used for the
+data are ignored, which allows a TAP to be compared with a TZX or PZX.
+
+Use --verbose for a report of each block. The exit status is 1 when the tapes
+contain different data.`,
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		a, err := readDiffBlocks(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		b, err := readDiffBlocks(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		entries := diffBlocks(a, b)
+
+		counts := make(map[string]int)
+		for _, e := range entries {
+			counts[e.kind]++
+		}
+
+		fmt.Printf("FILE1: %s (%d data blocks)\n", args[0], len(a))
+		fmt.Printf("FILE2: %s (%d data blocks)\n", args[1], len(b))
+		fmt.Println()
+
+		if diffVerbose {
+			fmt.Println("BLOCKS:")
+			for _, e := range entries {
+				fmt.Println(e)
+			}
+			fmt.Println()
+		}
+
+		fmt.Printf("Same: %d, Changed: %d, Moved: %d, Removed: %d, Added: %d\n",
+			counts["same"], counts["changed"], counts["moved"], counts["removed"], counts["added"])
+
+		if counts["same"] == len(entries) {
+			fmt.Println("The tapes contain identical data.")
+			return
+		}
+		fmt.Println("The tapes contain different data.")
+		os.Exit(1)
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolVarP(&diffVerbose, "verbose", "v", false, `Report the differences of each block`)
+	rootCmd.AddCommand(diffCmd)
+}
+
+func (e diffEntry) String() string {
+	switch e.kind {
+	case "same":
+		return fmt.Sprintf("  = #%02d -> #%02d %-19s : %s", e.a.Block, e.b.Block, e.a.Name, e.a.Hash)
+	case "changed":
+		count, offset := byteDifferences(e.a.data, e.b.data)
+		return fmt.Sprintf("  ~ #%02d -> #%02d %-19s : %d bytes differ, first at offset %d (%d -> %d bytes)",
+			e.a.Block, e.b.Block, e.a.Name, count, offset, len(e.a.data), len(e.b.data))
+	case "moved":
+		return fmt.Sprintf("  > #%02d -> #%02d %-19s : %s", e.a.Block, e.b.Block, e.a.Name, e.a.Hash)
+	case "removed":
+		return fmt.Sprintf("  - #%02d        %-19s : %s", e.a.Block, e.a.Name, e.a.Hash)
+	default:
+		return fmt.Sprintf("  +        #%02d %-19s : %s", e.b.Block, e.b.Name, e.b.Hash)
+	}
+}
+
+// readDiffBlocks reads the data blocks of a TAP, TZX/CDT or PZX tape.
+func readDiffBlocks(filename string) ([]diffBlock, error) {
+	f, filename, err := openMedia(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := newReader(f)
+
+	var list []diffBlock
+
+	switch media := detectMediaType("", filename, reader); media {
+	case "tap":
+		tape := tap.New(reader)
+		err = tape.Read()
+		for i, h := range tape.BlockHashes() {
+			list = append(list, diffBlock{BlockHash: h, data: tap.BlockBytes(tape.Blocks[i].TapeData)})
+		}
+	case "tzx", "cdt":
+		tape := tzx.New(reader)
+		err = tape.Read()
+
+		// the hashes are only given for the blocks storing tape data
+		var data [][]byte
+		for _, block := range tape.Blocks() {
+			if b, ok := block.(interface{ DataHash() string }); ok && b.DataHash() != "" {
+				data = append(data, tzxBlockData(block))
+			}
+		}
+		for i, h := range tape.BlockHashes() {
+			list = append(list, diffBlock{BlockHash: h, data: data[i]})
+		}
+	case "pzx":
+		tape := pzx.New(reader)
+		err = tape.Read()
+		var data [][]byte
+		for _, block := range tape.Blocks {
+			if b, ok := block.(*pzx.DataBlock); ok {
+				data = append(data, b.Data)
+			}
+		}
+		for i, h := range tape.BlockHashes() {
+			list = append(list, diffBlock{BlockHash: h, data: data[i]})
+		}
+	default:
+		return nil, errors.Errorf("unsupported media type '%s' for %s", media, filename)
+	}
+
+	if storage.IsTruncated(err) {
+		fmt.Printf("WARNING: %s is truncated, only the recoverable data is compared.\n", filename)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", filename)
+	}
+
+	return list, nil
+}
+
+// tzxBlockData returns the data of a TZX block, as used for its hash.
+func tzxBlockData(block tzx.Block) []byte {
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		return tap.BlockBytes(b.DataBlock)
+	case *blocks.TurboSpeedData:
+		return b.DataBlock
+	case *blocks.PureData:
+		return b.DataBlock
+	case *blocks.DirectRecording:
+		return b.Data
+	case *blocks.CswRecording:
+		return b.Data
+	default:
+		return nil
+	}
+}
+
+// diffBlocks aligns the blocks of the two tapes by the longest common
+// sequence of hashes. Blocks found on both tapes but outside this sequence
+// are moved, and any other blocks at the same position are paired as changed.
+func diffBlocks(a, b []diffBlock) []diffEntry {
+	// lengths of the longest common sequence of the tape endings
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].Hash == b[j].Hash {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// collect the unmatched blocks between each pair of matching blocks
+	type gap struct {
+		a, b []*diffBlock
+		same *diffEntry // matching blocks following the gap
+	}
+	var gaps []gap
+	current := gap{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i].Hash == b[j].Hash:
+			current.same = &diffEntry{kind: "same", a: &a[i], b: &b[j]}
+			gaps = append(gaps, current)
+			current = gap{}
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			current.a = append(current.a, &a[i])
+			i++
+		default:
+			current.b = append(current.b, &b[j])
+			j++
+		}
+	}
+	gaps = append(gaps, current)
+
+	// blocks removed from one position and added at another
+	added := make(map[string][]*diffBlock)
+	for _, g := range gaps {
+		for _, block := range g.b {
+			added[block.Hash] = append(added[block.Hash], block)
+		}
+	}
+	movedTo := make(map[*diffBlock]*diffBlock)
+	moved := make(map[*diffBlock]bool)
+	for _, g := range gaps {
+		for _, block := range g.a {
+			if to := added[block.Hash]; len(to) > 0 {
+				movedTo[block] = to[0]
+				moved[to[0]] = true
+				added[block.Hash] = to[1:]
+			}
+		}
+	}
+
+	var entries []diffEntry
+	for _, g := range gaps {
+		var removed []*diffBlock
+		for _, block := range g.a {
+			if to, ok := movedTo[block]; ok {
+				entries = append(entries, diffEntry{kind: "moved", a: block, b: to})
+			} else {
+				removed = append(removed, block)
+			}
+		}
+
+		var inserted []*diffBlock
+		for _, block := range g.b {
+			if !moved[block] {
+				inserted = append(inserted, block)
+			}
+		}
+
+		for k := 0; k < len(removed) || k < len(inserted); k++ {
+			switch {
+			case k < len(removed) && k < len(inserted):
+				entries = append(entries, diffEntry{kind: "changed", a: removed[k], b: inserted[k]})
+			case k < len(removed):
+				entries = append(entries, diffEntry{kind: "removed", a: removed[k]})
+			default:
+				entries = append(entries, diffEntry{kind: "added", b: inserted[k]})
+			}
+		}
+
+		if g.same != nil {
+			entries = append(entries, *g.same)
+		}
+	}
+
+	return entries
+}
+
+// byteDifferences returns the number of bytes that differ, including any
+// difference in length, and the offset of the first difference.
+func byteDifferences(a, b []byte) (int, int) {
+	count, first := 0, -1
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i >= len(a) || i >= len(b) || a[i] != b[i] {
+			count++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	return count, first
+}
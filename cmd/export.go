@@ -0,0 +1,469 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/amstrad/cdt"
+	"retroio/amstrad/dsk"
+	"retroio/amstrad/dsk/amsdos"
+	"retroio/amstrad/dsk/amsdos/cat"
+	"retroio/commodore/t64"
+	"retroio/spectrum/plus3dos"
+	"retroio/spectrum/pzx"
+	"retroio/spectrum/screen"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+	"retroio/spectrum/tzx"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+// manifestFilename is the name of the manifest written to the output directory.
+const manifestFilename = "manifest.json"
+
+// Checksum status of the exported files.
+const (
+	checksumOK   = "ok"
+	checksumBad  = "bad"
+	checksumNone = "none" // the data has no checksum
+)
+
+var exportDirectory string
+
+// exportManifest lists the files written by the export command.
+type exportManifest struct {
+	Source string         `json:"source"`
+	Format string         `json:"format"`
+	Files  []exportedFile `json:"files"`
+}
+
+// exportedFile maps a file written to the block or directory entry it was
+// recovered from, along with its metadata.
+type exportedFile struct {
+	File        string `json:"file"`
+	Source      string `json:"source"`           // block number or directory entry
+	Header      string `json:"header,omitempty"` // block number of the tape header
+	Name        string `json:"name,omitempty"`   // filename given by the header or directory
+	Type        string `json:"type"`
+	LoadAddress *int   `json:"load_address,omitempty"`
+	AutoStart   *int   `json:"autostart,omitempty"`
+	Length      int    `json:"length"`
+	Checksum    string `json:"checksum"`
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Export every file and data block from any supported media file",
+	Long: `Detect the format of a media file from its contents, and write everything that
+can be recovered from it to the directory given with --out, or a directory named
+after the file: each file on a DSK disk or T64 tape, and each data block on a
+TAP, TZX, PZX or CDT tape, named by the filename of its header. Any SCREEN$ is
+also exported as a PNG image.
+
+A manifest.json is written along with the files, mapping each file to the block
+or directory entry it came from, with its type, load address and checksum
+status. Filenames are made safe for the file system, and a number is added to
+any filename already used.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, filename, err := openMedia(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		format, ok := mediaFormats[detectInfoMediaType(filename, reader)]
+		if !ok {
+			fmt.Printf("Unable to identify the media format of '%s', it may not be supported.\n", filename)
+			os.Exit(1)
+		}
+
+		image := format.image(reader)
+		if err := image.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is exported")
+		} else if err != nil {
+			fmt.Println("Media read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		items, isTape := tapeItems(image)
+		_, isDisk := image.(*dsk.DSK)
+		_, isT64 := image.(*t64.T64)
+		if !isTape && !isDisk && !isT64 {
+			fmt.Printf("No files can be exported from a %s.\n", format.name)
+			os.Exit(1)
+		}
+
+		directory := exportDirectory
+		if directory == "" {
+			directory = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		}
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		e := newExporter(directory, filepath.Base(filename), format.name)
+		switch img := image.(type) {
+		case *dsk.DSK:
+			err = e.exportDisk(img)
+		case *t64.T64:
+			err = e.exportT64(img)
+		default:
+			err = e.exportTape(items)
+		}
+		if err == nil {
+			err = e.writeManifest()
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported %d files to '%s'\n", len(e.manifest.Files), directory)
+		displayWarnings(reader, image)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportDirectory, "out", "o", "", `Output directory, default: the filename without its extension`)
+	rootCmd.AddCommand(exportCmd)
+}
+
+// exporter writes the files recovered from a media image to the output
+// directory, giving each a unique and safe filename, and records them in the
+// manifest.
+type exporter struct {
+	directory string
+	manifest  exportManifest
+	used      map[string]bool // filenames used, in lower case
+}
+
+func newExporter(directory, source, format string) *exporter {
+	return &exporter{
+		directory: directory,
+		manifest:  exportManifest{Source: source, Format: format, Files: []exportedFile{}},
+		used:      map[string]bool{manifestFilename: true},
+	}
+}
+
+// write saves the data to a file named from the name and extension, using the
+// fallback when nothing is left of the name once made safe, and adds the file
+// to the manifest.
+func (e *exporter) write(name, fallback, ext string, data []byte, file exportedFile) error {
+	base := safeFilename(name)
+	if base == "" {
+		base = fallback
+	}
+
+	filename := base + ext
+	for i := 2; e.used[strings.ToLower(filename)]; i++ {
+		filename = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	e.used[strings.ToLower(filename)] = true
+
+	if err := ioutil.WriteFile(filepath.Join(e.directory, filename), data, 0644); err != nil {
+		return err
+	}
+
+	file.File = filename
+	file.Length = len(data)
+	e.manifest.Files = append(e.manifest.Files, file)
+	return nil
+}
+
+// writeManifest saves the manifest of the exported files as JSON.
+func (e exporter) writeManifest() error {
+	data, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(e.directory, manifestFilename), append(data, '\n'), 0644)
+}
+
+// safeFilename returns the name with any characters that are not letters,
+// digits, dots, dashes or underscores replaced by underscores. Leading dots
+// are removed, so the file is never hidden, or outside the directory.
+func safeFilename(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimSpace(name))
+	return strings.TrimLeft(safe, ".")
+}
+
+// tapeItem is a block of a tape, which is either a TAP block, the TAP data of
+// a turbo or pure data block, or any other data.
+type tapeItem struct {
+	number  int
+	name    string
+	block   tap.Block
+	data    []byte
+	tapData bool // the data has the flag and checksum bytes
+}
+
+// tapeItems returns the blocks with data of the TAP, TZX, PZX or CDT tape, or
+// false for other images. Blocks without any data, such as pulses and pauses,
+// are not included, so each header is followed by its data block.
+func tapeItems(image mediaImage) ([]tapeItem, bool) {
+	var items []tapeItem
+
+	switch t := image.(type) {
+	case *tap.TAP:
+		for i, b := range t.Blocks {
+			items = append(items, tapeItem{number: i + 1, name: b.TapeData.Name(), block: b.TapeData})
+		}
+	case *cdt.CDT:
+		return tzxItems(t.TZX), true
+	case *tzx.TZX:
+		return tzxItems(t), true
+	case *pzx.PZX:
+		for i, b := range t.Blocks {
+			item := tapeItem{number: i + 2, name: b.Name(), block: b.BlockData()}
+			if d, ok := b.(*pzx.DataBlock); ok && item.block == nil {
+				item.data = d.Data
+			}
+			if item.block != nil || len(item.data) > 0 {
+				items = append(items, item)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return items, true
+}
+
+func tzxItems(t *tzx.TZX) []tapeItem {
+	var items []tapeItem
+
+	_ = t.Walk(func(number int, b tzx.Block) error {
+		item := tapeItem{number: number, name: b.Name(), block: b.BlockData()}
+		if item.block == nil {
+			switch d := b.(type) {
+			case *blocks.TurboSpeedData:
+				item.data, item.tapData = d.DataBlock, true
+			case *blocks.PureData:
+				item.data, item.tapData = d.DataBlock, true
+			default:
+				buf := &bytes.Buffer{}
+				if err := t.ExportBlock(item.number, buf); err == nil {
+					item.data = buf.Bytes()
+				}
+			}
+		}
+		if item.block != nil || len(item.data) > 0 {
+			items = append(items, item)
+		}
+		return nil
+	})
+
+	return items
+}
+
+// exportTape writes the data of each block, named by the filename of the
+// header before it, if any. Headers are only included in the manifest entry
+// of their data.
+func (e *exporter) exportTape(items []tapeItem) error {
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		var header *tapeItem
+
+		if item.block != nil && tap.IsHeader(item.block) {
+			if i+1 >= len(items) || (items[i+1].block != nil && tap.IsHeader(items[i+1].block)) {
+				continue // a header without any data
+			}
+			header = &items[i]
+			i++
+			item = items[i]
+		}
+
+		if err := e.exportTapeItem(header, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) exportTapeItem(header *tapeItem, item tapeItem) error {
+	file := exportedFile{Source: fmt.Sprintf("block #%d", item.number), Type: item.name, Checksum: checksumNone}
+
+	var data []byte
+	switch {
+	case item.block != nil:
+		data = item.block.BlockData()
+		if len(tap.BlockBytes(item.block)) >= 2 {
+			file.Checksum = checksumStatus(tap.VerifyChecksum(item.block))
+		}
+	case item.tapData && len(item.data) >= 2:
+		data = item.data[1 : len(item.data)-1]
+		var sum uint8
+		for _, b := range item.data {
+			sum ^= b
+		}
+		file.Checksum = checksumStatus(sum == 0)
+	default:
+		data = item.data
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	name, fallback, ext := "", fmt.Sprintf("block%02d", item.number), ".bin"
+	if header != nil {
+		file.Header = fmt.Sprintf("block #%d", header.number)
+		file.Name = strings.TrimSpace(header.block.Filename())
+		file.Type = header.block.Name()
+		if !tap.VerifyChecksum(header.block) {
+			file.Checksum = checksumBad
+		}
+		name = file.Name
+
+		switch h := header.block.(type) {
+		case *headers.ProgramData:
+			ext = ".bas"
+			if line, ok := h.AutoStart(); ok {
+				autostart := int(line)
+				file.AutoStart = &autostart
+			}
+		case *headers.ByteData:
+			if h.Name() == "SCREEN$" {
+				ext = ".scr"
+			}
+			address := int(h.StartAddress)
+			file.LoadAddress = &address
+		case *headers.NumericData, *headers.AlphanumericData:
+			ext = ".dat"
+		}
+	}
+
+	if err := e.write(name, fallback, ext, data, file); err != nil {
+		return err
+	}
+
+	if len(data) == screen.Size {
+		buf := &bytes.Buffer{}
+		if err := screen.ExportScreenPNG(data, buf); err != nil {
+			return err
+		}
+		file.Type = "SCREEN$ image"
+		return e.write(name, fallback, ".png", buf.Bytes(), file)
+	}
+	return nil
+}
+
+// exportDisk writes each file of every user on the disk, without any +3DOS
+// or AMSDOS header, which is included in the manifest entry instead.
+func (e *exporter) exportDisk(d *dsk.DSK) error {
+	catalog, err := cat.CommandCat(d.AmsDos.DPB, d.AmsDos.Directories, cat.AllUsers)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range catalog.Records {
+		name := strings.TrimSpace(record.Filename)
+		ext := safeFilename(strings.TrimSpace(record.FileType))
+		if ext != "" {
+			ext = "." + ext
+		}
+
+		data, err := d.ReadFile(name + ext)
+		if err != nil {
+			fmt.Printf("%s%s: %s\n", name, ext, err)
+			continue
+		}
+
+		file := exportedFile{
+			Source:   fmt.Sprintf("user %d: %s%s", record.User, name, ext),
+			Name:     name + ext,
+			Type:     "CP/M file",
+			Checksum: checksumNone,
+		}
+
+		if header, err := plus3dos.ReadHeader(data); err == nil {
+			file.Type = "+3DOS " + header.FileTypeName()
+			file.Checksum = checksumOK
+			switch header.FileType {
+			case 0:
+				if line, ok := header.AutoStart(); ok {
+					autostart := int(line)
+					file.AutoStart = &autostart
+				}
+			case 3:
+				address := int(header.Param1)
+				file.LoadAddress = &address
+			}
+			data = plus3dos.Strip(data)
+		} else if header, err := amsdos.ReadRecordHeader(data); err == nil {
+			file.Type = "AMSDOS " + header.FileTypeName()
+			file.Checksum = checksumOK
+			address := int(header.DataLocation)
+			file.LoadAddress = &address
+			data = data[amsdos.CpmRecordSize:]
+			if length := header.Length(); length < len(data) {
+				data = data[:length]
+			}
+		} else if amsdos.IsText(data) {
+			file.Type = "ASCII text"
+			data = amsdos.TrimSoftEOF(data)
+		}
+
+		if err := e.write(name, "file", ext, data, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportT64 writes each record of the tape as a PRG file, with the two byte
+// load address before the data, or the data only for SEQ files.
+func (e *exporter) exportT64(t *t64.T64) error {
+	for i, data := range t.Data {
+		record := t.Records[i]
+		name := record.Name()
+
+		file := exportedFile{
+			Source:   fmt.Sprintf("record #%d", i),
+			Name:     name,
+			Type:     record.FileTypeName(),
+			Checksum: checksumNone,
+		}
+
+		ext := ".prg"
+		if record.FileTypeName() == "SEQ" {
+			ext = ".seq"
+		} else {
+			address := int(record.StartAddress)
+			file.LoadAddress = &address
+			data = append([]byte{uint8(record.StartAddress), uint8(record.StartAddress >> 8)}, data...)
+		}
+
+		if err := e.write(name, fmt.Sprintf("record%02d", i), ext, data, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checksumStatus(valid bool) string {
+	if valid {
+		return checksumOK
+	}
+	return checksumBad
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+var (
+	hexdumpWidth  int
+	hexdumpOffset int64
+	hexdumpLength int64
+)
+
+var hexdumpCmd = &cobra.Command{
+	Use:   "hexdump FILE",
+	Short: "Display a hex dump of any media file",
+	Long: `Display the contents of a file as a hex dump, with offsets and a Latin-1
+character column. The file is not parsed, so this can be used to inspect
+malformed or unsupported media images.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if hexdumpWidth <= 0 {
+			fmt.Println("The width must be greater than zero.")
+			return
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := storage.NewReader(f)
+
+		if hexdumpOffset > 0 {
+			if _, err := reader.Discard(int(hexdumpOffset)); err != nil {
+				fmt.Printf("Unable to seek to offset %d: %s\n", hexdumpOffset, err)
+				return
+			}
+		}
+
+		if err := hexdump(reader, hexdumpOffset, hexdumpLength, hexdumpWidth); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	hexdumpCmd.Flags().IntVarP(&hexdumpWidth, "width", "w", 16, `Number of bytes per line`)
+	hexdumpCmd.Flags().Int64VarP(&hexdumpOffset, "offset", "o", 0, `Start offset in bytes`)
+	hexdumpCmd.Flags().Int64VarP(&hexdumpLength, "length", "l", 0, `Number of bytes to display, default: to end of file`)
+	rootCmd.AddCommand(hexdumpCmd)
+}
+
+// hexdump prints lines of offset, hex bytes and Latin-1 characters until the
+// length has been displayed, or the end of the reader is reached.
+// A length of zero dumps everything.
+func hexdump(reader *storage.Reader, offset, length int64, width int) error {
+	line := make([]byte, width)
+	remaining := length
+
+	for length == 0 || remaining > 0 {
+		size := width
+		if length > 0 && int64(size) > remaining {
+			size = int(remaining)
+		}
+
+		n, err := reader.Read(line[:size])
+		if n > 0 {
+			fmt.Println(hexdumpLine(offset, line[:n], width))
+			offset += int64(n)
+			remaining -= int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hexdumpLine(offset int64, data []byte, width int) string {
+	var hex, chars strings.Builder
+
+	for i := 0; i < width; i++ {
+		if i > 0 && i%8 == 0 {
+			hex.WriteByte(' ')
+		}
+		if i < len(data) {
+			hex.WriteString(fmt.Sprintf("%02X ", data[i]))
+		} else {
+			hex.WriteString("   ")
+		}
+	}
+
+	for _, b := range data {
+		// Latin-1 maps directly to the first 256 unicode code points
+		if (b >= 0x20 && b < 0x7F) || b >= 0xA0 {
+			chars.WriteRune(rune(b))
+		} else {
+			chars.WriteByte('.')
+		}
+	}
+
+	return fmt.Sprintf("%08X  %s |%s|", offset, hex.String(), chars.String())
+}
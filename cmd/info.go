@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/acorn/uef"
+	"retroio/amstrad/cdt"
+	"retroio/amstrad/dsk"
+	"retroio/amstrad/dsk/amsdos/cat"
+	"retroio/commodore/crt"
+	"retroio/commodore/t64"
+	c64tap "retroio/commodore/tap"
+	"retroio/spectrum/p"
+	"retroio/spectrum/pzx"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+// mediaImage is implemented by the media images of all systems.
+type mediaImage interface {
+	Read() error
+	DisplayGeometry()
+	Warnings() []string
+}
+
+// mediaFormat describes a media type supported by the info command.
+type mediaFormat struct {
+	name    string
+	machine string
+	image   func(reader *storage.Reader) mediaImage
+}
+
+var mediaFormats = map[string]mediaFormat{
+	"tzx":    {"TZX tape", "ZX Spectrum", func(r *storage.Reader) mediaImage { return tzx.New(r) }},
+	"cdt":    {"CDT tape", "Amstrad CPC", func(r *storage.Reader) mediaImage { return cdt.New(r) }},
+	"tsx":    {"TSX tape", "MSX", func(r *storage.Reader) mediaImage { return tzx.NewTSX(r) }},
+	"pzx":    {"PZX tape", "ZX Spectrum", func(r *storage.Reader) mediaImage { return pzx.New(r) }},
+	"p":      {"P tape", "ZX81", func(r *storage.Reader) mediaImage { return p.New(r) }},
+	"p81":    {"P81 tape", "ZX81", func(r *storage.Reader) mediaImage { return p.NewP81(r) }},
+	"tap":    {"TAP tape", "ZX Spectrum", func(r *storage.Reader) mediaImage { return tap.New(r) }},
+	"dsk":    {"DSK disk", "Amstrad CPC / PCW / Spectrum +3", func(r *storage.Reader) mediaImage { return dsk.New(r) }},
+	"t64":    {"T64 tape", "Commodore 64", func(r *storage.Reader) mediaImage { return t64.New(r) }},
+	"c64tap": {"TAP raw tape", "Commodore 64", func(r *storage.Reader) mediaImage { return c64tap.New(r) }},
+	"crt":    {"CRT cartridge", "Commodore 64", func(r *storage.Reader) mediaImage { return crt.New(r) }},
+	"uef":    {"UEF tape", "Acorn BBC Micro / Electron", func(r *storage.Reader) mediaImage { return uef.New(r) }},
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info FILE",
+	Short: "Display a summary of any supported media file",
+	Long: `Detect the format of a media file from its contents, and display a concise
+summary of the file: the format and machine, the title, the number of blocks or
+files, and the playing time of tapes. This works for all supported systems, so
+there is no need to select the system command first.
+
+Use the geometry command of the system for the full details of the file.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, filename, err := openMedia(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		format, ok := mediaFormats[detectInfoMediaType(filename, reader)]
+		if !ok {
+			fmt.Printf("Unable to identify the media format of '%s', it may not be supported.\n", filename)
+			os.Exit(1)
+		}
+
+		disk := format.image(reader)
+
+		if err := disk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Media read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		machine := format.machine
+		if m, ok := disk.(interface{ Machine() string }); ok {
+			machine = m.Machine()
+		}
+		summary := summarise(disk)
+
+		if structuredOutput() {
+			var duration interface{}
+			if summary.duration > 0 {
+				duration = math.Round(summary.duration*100) / 100
+			}
+			table := newOutputTable("file", "format", "machine", "title", "count", "count_of", "duration", "size")
+			table.add(filename, format.name, machine, summary.title, summary.count, summary.unit, duration, reader.Offset())
+			displayTable(table)
+			displayWarnings(reader, disk)
+			return
+		}
+
+		fmt.Println("MEDIA INFORMATION:")
+		fmt.Printf("File:     %s\n", filename)
+		fmt.Printf("Format:   %s\n", format.name)
+		fmt.Printf("Machine:  %s\n", machine)
+		if summary.title != "" {
+			fmt.Printf("Title:    %s\n", storage.DisplayText(summary.title))
+		}
+		if summary.unit != "" {
+			fmt.Printf("%-9s %d\n", strings.Title(summary.unit)+":", summary.count)
+		}
+		if summary.duration > 0 {
+			fmt.Printf("Duration: %s\n", formatDuration(summary.duration, summary.complete))
+		}
+		fmt.Printf("Size:     %d bytes\n", reader.Offset())
+
+		displayWarnings(reader, disk)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+// detectInfoMediaType detects the media type from the file contents. As CDT
+// and TSX files are identical to TZX, these are only identified by the file
+// extension.
+func detectInfoMediaType(filename string, reader *storage.Reader) string {
+	media := detectMediaType("", filename, reader)
+	if media == "tzx" && mediaType("", filename) == "cdt" {
+		return "cdt"
+	}
+	if media == "tzx" && mediaType("", filename) == "tsx" {
+		return "tsx"
+	}
+	return media
+}
+
+// mediaSummary is the summary of a media image shown by the info command.
+type mediaSummary struct {
+	title    string
+	count    int     // number of blocks, files, etc.
+	unit     string  // what is counted, or empty when not known
+	duration float64 // playing time of a tape in seconds, 0 when not known
+	complete bool    // false when the tape loops forever, so the duration is a minimum
+}
+
+// summarise returns the title, the number of blocks or files, and the playing
+// time of the media image, when these are known for the format.
+func summarise(image mediaImage) mediaSummary {
+	const spectrumClock = 3500000 // T-states per second
+
+	summary := mediaSummary{complete: true}
+	if t, ok := image.(interface{ Title() string }); ok {
+		summary.title = strings.TrimSpace(t.Title())
+	}
+
+	switch m := image.(type) {
+	case *tzx.TZX:
+		summary.count, summary.unit = len(m.Blocks()), "blocks"
+		tstates, complete := m.Duration()
+		summary.duration, summary.complete = float64(tstates)/spectrumClock, complete
+	case *cdt.CDT:
+		summary.count, summary.unit = len(m.Blocks()), "blocks"
+		tstates, complete := m.Duration()
+		summary.duration, summary.complete = float64(tstates)/spectrumClock, complete
+	case *pzx.PZX:
+		summary.count, summary.unit = len(m.Blocks), "blocks"
+		summary.duration = float64(m.Duration()) / spectrumClock
+	case *tap.TAP:
+		summary.count, summary.unit = len(m.Blocks), "blocks"
+		if tstates, ok := tapDuration(m); ok {
+			summary.duration = float64(tstates) / spectrumClock
+		}
+	case *p.P:
+		summary.count, summary.unit = len(m.Programs), "programs"
+	case *dsk.DSK:
+		if catalog, err := cat.CommandCat(m.AmsDos.DPB, m.AmsDos.Directories, cat.AllUsers); err == nil {
+			summary.count, summary.unit = len(catalog.Records), "files"
+		}
+	case *t64.T64:
+		summary.title = strings.TrimSpace(storage.DecodeLatin1(m.Header.Name[:]))
+		summary.count, summary.unit = len(m.Records), "files"
+	case *c64tap.TAP:
+		summary.duration = m.Duration(c64tap.PALClock)
+	case *crt.CRT:
+		summary.title = m.Header.CartridgeName()
+		summary.count, summary.unit = len(m.Chips), "chips"
+	case *uef.UEF:
+		summary.count, summary.unit = len(m.Files()), "files"
+	}
+
+	return summary
+}
+
+// tapDuration returns the playing time of a TAP tape in T-states, played with
+// the ROM timings and a 1 second pause after each block, as when converted to
+// a TZX.
+func tapDuration(t *tap.TAP) (uint64, bool) {
+	builder := tzx.NewBuilder()
+	for _, block := range t.Blocks {
+		builder.AddBlock(tap.BlockBytes(block.TapeData), 1000)
+	}
+	tape, err := builder.TZX()
+	if err != nil {
+		return 0, false
+	}
+	tstates, _ := tape.Duration()
+	return tstates, true
+}
+
+// formatDuration returns the seconds as minutes and seconds, e.g. "4m 05s",
+// noting when the tape loops forever.
+func formatDuration(seconds float64, complete bool) string {
+	total := int(seconds + 0.5)
+	str := fmt.Sprintf("%dm %02ds", total/60, total%60)
+	if !complete {
+		str += " (or more, the tape loops)"
+	}
+	return str
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// mediaExtensions are the file extensions of all the supported media types,
+// used for finding the media files stored in ZIP archives.
+var mediaExtensions = []string{"cdt", "crt", "dsk", "p", "p81", "pzx", "t64", "tap", "tsx", "tzx", "uef"}
+
+// openMedia opens a media file for reading, returning the reader along with
+// the name of the media file, which is used for selecting the media type.
+//
+// Gzip compressed files are detected by their magic bytes, not the file
+// extension, and are transparently decompressed.
+//
+// Media files can be read directly from a ZIP archive, with the file given
+// as `archive.zip#file.tzx`. When no file is given, the archive must contain
+// exactly one media file.
+func openMedia(filename string) (io.ReadCloser, string, error) {
+	archive, entry := splitArchivePath(filename)
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, "", err
+	}
+
+	magic := make([]byte, len(zipMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		_ = f.Close()
+		return nil, "", err
+	}
+	magic = magic[:n]
+
+	if bytes.HasPrefix(magic, zipMagic) {
+		_ = f.Close()
+		return openZipEntry(archive, entry)
+	} else if entry != "" {
+		_ = f.Close()
+		return nil, "", errors.Errorf("not a ZIP archive: %s", archive)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, "", err
+	}
+
+	if !bytes.HasPrefix(magic, gzipMagic) {
+		return f, filename, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, "", errors.Wrap(err, "unable to decompress gzip file")
+	}
+
+	return &gzipFile{Reader: gz, file: f}, filename, nil
+}
+
+// splitArchivePath splits an `archive.zip#file.tzx` path into the archive
+// and file names. Paths for files that exist are never split, so file names
+// containing a `#` can still be read.
+func splitArchivePath(filename string) (string, string) {
+	if _, err := os.Stat(filename); err == nil {
+		return filename, ""
+	}
+
+	i := strings.LastIndex(filename, "#")
+	if i < 0 {
+		return filename, ""
+	}
+	return filename[:i], filename[i+1:]
+}
+
+// openZipEntry opens the named file in the ZIP archive. When no name is given,
+// the archive must contain exactly one media file, otherwise the error lists
+// all the candidates.
+func openZipEntry(archive, name string) (io.ReadCloser, string, error) {
+	z, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "unable to read ZIP archive")
+	}
+
+	var candidates []*zip.File
+	for _, f := range z.File {
+		if name != "" && f.Name == name {
+			candidates = []*zip.File{f}
+			break
+		} else if name == "" && isMediaFile(f.Name) {
+			candidates = append(candidates, f)
+		}
+	}
+
+	if len(candidates) != 1 {
+		_ = z.Close()
+
+		if name != "" {
+			return nil, "", errors.Errorf("file '%s' not found in ZIP archive", name)
+		} else if len(candidates) == 0 {
+			return nil, "", errors.New("no supported media files found in ZIP archive")
+		}
+
+		var names []string
+		for _, f := range candidates {
+			names = append(names, "  "+archive+"#"+f.Name)
+		}
+		sort.Strings(names)
+		return nil, "", errors.Errorf("ZIP archive contains multiple media files, select one of:\n%s", strings.Join(names, "\n"))
+	}
+
+	r, err := candidates[0].Open()
+	if err != nil {
+		_ = z.Close()
+		return nil, "", errors.Wrapf(err, "unable to read '%s' from ZIP archive", candidates[0].Name)
+	}
+
+	return &zipFile{ReadCloser: r, archive: z}, candidates[0].Name, nil
+}
+
+func isMediaFile(filename string) bool {
+	media := mediaType("", filename)
+	for _, ext := range mediaExtensions {
+		if media == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipFile closes both the gzip reader and the underlying file.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	if err := g.Reader.Close(); err != nil {
+		_ = g.file.Close()
+		return err
+	}
+	return g.file.Close()
+}
+
+// zipFile closes both the archived file and the ZIP archive.
+type zipFile struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z *zipFile) Close() error {
+	if err := z.ReadCloser.Close(); err != nil {
+		_ = z.archive.Close()
+		return err
+	}
+	return z.archive.Close()
+}
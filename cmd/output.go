@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output formats selected with the --format flag.
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var outputFormat string
+
+// structuredOutput reports whether JSON or CSV output was selected, instead of
+// the usual text output of the commands.
+func structuredOutput() bool {
+	return outputFormat == formatJSON || outputFormat == formatCSV
+}
+
+// messages returns the writer for the warnings and notes of a command, which
+// are written to stderr with structured output, keeping stdout parseable.
+func messages() io.Writer {
+	if structuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// outputTable is the structured output of a command: a list of rows, each with
+// a value for every column.
+type outputTable struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+func newOutputTable(columns ...string) *outputTable {
+	return &outputTable{columns: columns}
+}
+
+// add appends a row, with the values in the same order as the columns.
+func (t *outputTable) add(values ...interface{}) {
+	t.rows = append(t.rows, values)
+}
+
+// write outputs the table as JSON lines, one object per row, or as CSV with
+// a header row of the column names.
+func (t outputTable) write(w io.Writer) error {
+	if outputFormat == formatCSV {
+		c := csv.NewWriter(w)
+		if err := c.Write(t.columns); err != nil {
+			return err
+		}
+		for _, row := range t.rows {
+			record := make([]string, len(row))
+			for i, value := range row {
+				record[i] = fmt.Sprint(value)
+			}
+			if err := c.Write(record); err != nil {
+				return err
+			}
+		}
+		c.Flush()
+		return c.Error()
+	}
+
+	for _, row := range t.rows {
+		// objects are written by hand to keep the columns in order
+		buf := &bytes.Buffer{}
+		buf.WriteByte('{')
+		for i, value := range row {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(t.columns[i])
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(b)
+		}
+		buf.WriteString("}\n")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayTable writes the table to stdout, exiting on any error.
+func displayTable(t *outputTable) {
+	if err := t.write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
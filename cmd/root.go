@@ -5,11 +5,22 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"retroio/amstrad/dsk"
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var (
+	strictParsing  bool
+	lenientParsing bool
 )
 
 // rootCmd represents the base command when called without any sub commands
@@ -19,6 +30,17 @@ var rootCmd = &cobra.Command{
 	Short:   "CLI utility for reading emulator disk and tape images",
 	Long: `RetroIO (rio) is a command line utility for reading emulator storage media
 (disks and cassette tape images) of home computers from the 1980s.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if strictParsing && lenientParsing {
+			return fmt.Errorf("the --strict and --lenient flags can not be used together")
+		}
+		switch outputFormat {
+		case formatText, formatJSON, formatCSV:
+		default:
+			return fmt.Errorf("unknown output format '%s', expected text, json, or csv", outputFormat)
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(cmd.ValidArgs) == 0 {
 			_ = cmd.Help()
@@ -36,9 +58,94 @@ func Execute() {
 	}
 }
 
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&strictParsing, "strict", false, `Stop on any media format error (default)`)
+	rootCmd.PersistentFlags().BoolVar(&lenientParsing, "lenient", false, `Warn on media format errors, and continue reading`)
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", formatText, `Output format of the read commands: text, json, or csv`)
+	rootCmd.PersistentFlags().BoolVar(&storage.ASCIIOnly, "ascii", false, `Replace non-ASCII characters in text, for terminals without UTF-8`)
+}
+
+// newReader returns a storage reader using the parsing mode selected
+// with the --strict/--lenient flags.
+func newReader(r io.Reader) *storage.Reader {
+	reader := storage.NewReader(r)
+	if lenientParsing {
+		reader.SetMode(storage.Lenient)
+	}
+	return reader
+}
+
+// displayWarnings outputs any format errors tolerated in lenient mode, along
+// with the problems found with the media images.
+func displayWarnings(reader *storage.Reader, images ...interface{ Warnings() []string }) {
+	warnings := reader.Warnings()
+	for _, image := range images {
+		warnings = append(warnings, image.Warnings()...)
+	}
+	if len(warnings) == 0 {
+		return
+	}
+
+	out := messages()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "WARNINGS:")
+	for _, w := range warnings {
+		fmt.Fprintf(out, "  - %s\n", w)
+	}
+}
+
+// displayTruncated outputs the warning for a truncated media file, with the
+// read error and what the command does with the data recovered from it.
+func displayTruncated(err error, recovered string) {
+	out := messages()
+	fmt.Fprintf(out, "WARNING: the file is truncated, %s.\n", recovered)
+	fmt.Fprintln(out, err)
+	fmt.Fprintln(out)
+}
+
+// displayReadError outputs the media read error, along with a friendlier
+// explanation for the known error types.
+func displayReadError(err error) {
+	fmt.Println(err)
+
+	var unknown tzx.ErrUnknownBlock
+	var badGeometry dsk.ErrBadGeometry
+	switch {
+	case errors.As(err, &unknown):
+		switch {
+		case !unknown.Extension():
+			fmt.Println("The tape uses a block type that can not be skipped, as the length of the block is not known.")
+		case unknown.Deprecated:
+			fmt.Println("The tape uses a deprecated block type, use --lenient to skip these blocks.")
+		default:
+			fmt.Println("The tape uses a block type that is not supported, use --lenient to skip these blocks.")
+		}
+	case errors.As(err, &badGeometry):
+		fmt.Println("The disk layout is not valid, the image may be corrupt or copy protected.")
+	}
+
+	if errors.Is(err, storage.ErrUnexpectedEOF) {
+		fmt.Println("The file ends unexpectedly, it may be truncated.")
+	}
+}
+
+// mediaType returns the media flag value, or the file extension when no media
+// type was given. A `.gz` suffix is ignored, as those files are decompressed.
 func mediaType(media, filename string) string {
 	if media == "" {
-		media = path.Ext(filename)
+		media = path.Ext(strings.TrimSuffix(strings.ToLower(filename), ".gz"))
 	}
 	return strings.TrimPrefix(strings.ToLower(media), ".")
 }
+
+// detectMediaType returns the media flag value when given, otherwise the media
+// type is detected from the file contents, falling back to the file extension
+// when the format is not recognised.
+func detectMediaType(media, filename string, reader *storage.Reader) string {
+	if media == "" {
+		if format, err := storage.DetectFormat(reader); err == nil {
+			return format
+		}
+	}
+	return mediaType(media, filename)
+}
@@ -2,11 +2,24 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
 )
 
 var (
-	spectrumMediaType  string
-	spectrumBasListing bool
+	spectrumMediaType   string
+	spectrumBasListing  bool
+	spectrumBasOutput   string
+	spectrumBasSeparate bool
+	spectrumControlFlow bool
+	spectrumVerify      bool
+	spectrumLint        bool
+	spectrumSummary     bool
+	spectrumBlockHashes bool
+	spectrumSimulate    bool
+	spectrumPreview     int
+	spectrumOnlyBlocks  string
 )
 
 // spectrumCmd represents the spectrum command
@@ -26,3 +39,13 @@ This is a top-level system command only and requires a sub-command.`,
 func init() {
 	rootCmd.AddCommand(spectrumCmd)
 }
+
+// newTZXTape returns the reader for a TZX tape, or for a TSX tape, the MSX
+// variant of TZX, when selected with the media type, or by the file extension,
+// as the two share the same signature.
+func newTZXTape(reader *storage.Reader, dskType, filename string) *tzx.TZX {
+	if dskType == "tsx" || mediaType("", filename) == "tsx" {
+		return tzx.NewTSX(reader)
+	}
+	return tzx.New(reader)
+}
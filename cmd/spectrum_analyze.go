@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var speccyAnalyzeCmd = &cobra.Command{
+	Use:   "analyze FILE",
+	Short: "Estimate how reliably a ZX Spectrum tape will load on real hardware",
+	Long: `Inspect the block timings and structure of a ZX Spectrum TZX tape, reporting a
+score out of 100 for how reliably it is likely to load on real hardware, with an
+explanation of each issue found.
+
+Short or similar bit pulses, pilot tones that are too short, data blocks with no
+pause before the next pilot tone, direct recordings at odd sample rates, and any
+block length or control flow errors, each reduce the score.
+
+The score is a heuristic, and worth confirming by loading the tape on real
+hardware.
+
+With --simulate, the pulses of the tape are instead played through a simplified
+model of the ROM loader, reporting for each data block whether it loads cleanly,
+or fails with lost sync, a checksum mismatch, or a timeout. Turbo blocks are
+loaded with their own timings.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" {
+			fmt.Println("Analysis is only available for TZX tapes.")
+			return
+		}
+
+		tape := tzx.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is analysed")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		if spectrumSimulate {
+			displayLoadSimulation(reader, tape)
+			return
+		}
+
+		analysis := tape.Analyze()
+
+		if structuredOutput() {
+			table := newOutputTable("block", "name", "problem", "explanation", "penalty")
+			for _, issue := range analysis.Issues {
+				table.add(issue.Block, issue.Name, issue.Problem, issue.Explanation, issue.Penalty)
+			}
+			fmt.Fprintf(messages(), "Score: %d/100 (%s)\n", analysis.Score, analysis.Rating())
+			displayTable(table)
+			displayWarnings(reader, tape)
+			return
+		}
+
+		fmt.Println("LOADING RELIABILITY:")
+		fmt.Printf("Score: %d/100 (%s)\n", analysis.Score, analysis.Rating())
+		fmt.Println()
+
+		if len(analysis.Issues) == 0 {
+			fmt.Println("No loading problems found.")
+		} else {
+			fmt.Println("ISSUES:")
+			for _, issue := range analysis.Issues {
+				fmt.Printf("  %s (-%d)\n", issue, issue.Penalty)
+				fmt.Printf("    %s\n", issue.Explanation)
+			}
+		}
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccyAnalyzeCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyAnalyzeCmd.Flags().BoolVar(&spectrumSimulate, "simulate", false, `Simulate loading the tape with the ROM loader`)
+	spectrumCmd.AddCommand(speccyAnalyzeCmd)
+}
+
+// displayLoadSimulation plays the tape through the loader simulation, listing
+// the result of each data block.
+func displayLoadSimulation(reader *storage.Reader, tape *tzx.TZX) {
+	result, err := tape.SimulateLoad()
+	if err != nil {
+		fmt.Printf("Simulation error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if structuredOutput() {
+		table := newOutputTable("block", "name", "length", "loaded", "reason", "detail")
+		for _, b := range result.Blocks {
+			table.add(b.Block, b.Name, b.Length, b.Loaded, b.Reason, b.Detail)
+		}
+		displayTable(table)
+		displayWarnings(reader, tape)
+		return
+	}
+
+	fmt.Println("LOADER SIMULATION:")
+	if len(result.Blocks) == 0 {
+		fmt.Println("No data blocks were played.")
+	}
+	for _, b := range result.Blocks {
+		status := "OK  "
+		if !b.OK() {
+			status = "FAIL"
+		}
+		fmt.Printf("  %s %s\n", status, b)
+	}
+	fmt.Println()
+	fmt.Printf("%d of %d blocks loaded cleanly.\n", len(result.Blocks)-len(result.Failed()), len(result.Blocks))
+	displayWarnings(reader, tape)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+var (
+	spectrumBlockIndex        int
+	spectrumBlockOutput       string
+	spectrumBlockWithChecksum bool
+)
+
+var speccyBlockCmd = &cobra.Command{
+	Use:   "block FILE",
+	Short: "Export the raw data of a TZX block",
+	Long: `Export the raw data of a single ZX Spectrum TZX block, given by its block number
+(as shown by the geometry command) with --index, to a file named after the tape
+and block number, or the file given with --output.
+
+For the data blocks stored as in TAP files, the flag and checksum bytes are only
+included when --with-checksum is given. Blocks without any data, such as pauses
+and text descriptions, can not be exported.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		if spectrumBlockIndex < 1 {
+			fmt.Println("A block number of 1 or more must be given with --index.")
+			os.Exit(1)
+		}
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" && dskType != "tsx" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		tape := newTZXTape(reader, dskType, filename)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is exported")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		output := spectrumBlockOutput
+		if output == "" {
+			base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+			output = fmt.Sprintf("%s-block%02d.bin", base, spectrumBlockIndex)
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		w := bufio.NewWriter(out)
+		if spectrumBlockWithChecksum {
+			err = tape.ExportBlockWithChecksum(spectrumBlockIndex, w)
+		} else {
+			err = tape.ExportBlock(spectrumBlockIndex, w)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			out.Close()
+			_ = os.Remove(output)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported block #%d to '%s'\n", spectrumBlockIndex, output)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccyBlockCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyBlockCmd.Flags().IntVarP(&spectrumBlockIndex, "index", "i", 0, `Block number to export, starting from 1`)
+	speccyBlockCmd.Flags().StringVarP(&spectrumBlockOutput, "output", "o", "", `Output file, default: the tape filename and block number with a .bin extension`)
+	speccyBlockCmd.Flags().BoolVar(&spectrumBlockWithChecksum, "with-checksum", false, `Include the flag and checksum bytes of TAP data blocks`)
+	spectrumCmd.AddCommand(speccyBlockCmd)
+}
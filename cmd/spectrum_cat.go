@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum"
+	"retroio/spectrum/pzx"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var speccyCatCmd = &cobra.Command{
+	Use:   "cat FILE",
+	Short: "List the programs saved on a ZX Spectrum tape",
+	Long: `List the programs saved on a ZX Spectrum TAP, TZX or PZX tape, as tapes often
+hold several complete programs back-to-back.
+
+A Program or Bytes header starts a new program, and the data blocks following it
+belong to that program, up to the next header. Array headers belong to the
+program they follow. Any data blocks before the first header are listed as a
+program without a header.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		var dsk spectrum.Image
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+
+		switch dskType {
+		case "tap":
+			dsk = tap.New(reader)
+		case "tzx":
+			dsk = tzx.New(reader)
+		case "pzx":
+			dsk = pzx.New(reader)
+		default:
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		if err := dsk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		programs := tap.GroupPrograms(spectrumDataBlocks(dsk))
+
+		if structuredOutput() {
+			table := newOutputTable("program", "filename", "type", "blocks", "data_length", "paged_ram")
+			for i, program := range programs {
+				name, kind := programName(program)
+				table.add(i+1, name, kind, len(program), programDataLength(program), programPagedRAM(program))
+			}
+			displayTable(table)
+			displayWarnings(reader, dsk)
+			return
+		}
+
+		if len(programs) == 0 {
+			fmt.Println("No programs found.")
+		} else {
+			fmt.Println("PROGRAMS:")
+			for i, program := range programs {
+				name, kind := programName(program)
+				note := ""
+				if programPagedRAM(program) {
+					note = ", paged RAM (bank unknown)"
+				}
+				fmt.Printf("  Program %d: %-10s  %-13s  %d blocks, %d bytes%s\n",
+					i+1, storage.DisplayText(name), kind, len(program), programDataLength(program), note)
+			}
+		}
+		displayWarnings(reader, dsk)
+	},
+}
+
+func init() {
+	speccyCatCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	spectrumCmd.AddCommand(speccyCatCmd)
+}
+
+// programName returns the filename and block name of the program header, or
+// a placeholder for the data blocks found before the first header.
+func programName(program []tap.Block) (string, string) {
+	if len(program) == 0 || !tap.IsHeader(program[0]) {
+		return "(none)", "Headerless"
+	}
+	return strings.TrimSpace(program[0].Filename()), program[0].Name()
+}
+
+// programDataLength returns the total length of the data blocks of the
+// program, without the headers.
+func programDataLength(program []tap.Block) int {
+	length := 0
+	for _, block := range program {
+		if !tap.IsHeader(block) {
+			length += len(block.BlockData())
+		}
+	}
+	return length
+}
+
+// programPagedRAM returns true when the program is a CODE block loading into
+// the top 16K of memory, the paged RAM of a 128K Spectrum. This is only a guess
+// from the start address, as the bank is set by the loader.
+func programPagedRAM(program []tap.Block) bool {
+	if len(program) == 0 {
+		return false
+	}
+	header, ok := program[0].(*headers.ByteData)
+	return ok && header.PagedRAM()
+}
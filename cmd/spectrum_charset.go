@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/screen"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+	"retroio/storage"
+)
+
+var (
+	spectrumCharsetOutput  string
+	spectrumCharsetAddress int
+	spectrumCharsetGlyphs  int
+	spectrumCharsetColumns int
+	spectrumCharsetScale   int
+)
+
+var speccyCharsetCmd = &cobra.Command{
+	Use:   "charset FILE",
+	Short: "Export a character set or UDGs from a ZX Spectrum tape as a PNG",
+	Long: `Export the glyphs of a character set or UDGs (User Defined Graphics) saved in
+a CODE block on a ZX Spectrum TAP, TZX, or PZX tape, as a PNG sheet named after
+the tape, or the file given with --output.
+
+The first CODE block loaded at the standard UDG address, 65368 (or 32600 on a
+16K machine), or with the 768 bytes of a full character set, is exported, unless
+the load address of another block is given with --address.
+
+Each glyph is 8 bytes, and all the glyphs in the block are included, unless the
+number is given with --glyphs. The glyphs are arranged in a grid of --columns,
+with each pixel scaled by --scale.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		blocks, ok, err := spectrumTapeBlocks(dskType, reader)
+		if !ok {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		if storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is used")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		name, data := charsetBlock(blocks, spectrumCharsetAddress)
+		if data == nil {
+			if spectrumCharsetAddress > 0 {
+				fmt.Printf("No CODE block loaded at address %d found on the tape.\n", spectrumCharsetAddress)
+			} else {
+				fmt.Println("No character set or UDG CODE block found on the tape.")
+			}
+			os.Exit(1)
+		}
+
+		output := spectrumCharsetOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".png"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		err = screen.ExportCharsetPNG(data, spectrumCharsetGlyphs, spectrumCharsetColumns, spectrumCharsetScale, out)
+		if err != nil {
+			out.Close()
+			_ = os.Remove(output)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported the glyphs of '%s' to '%s'\n", strings.TrimSpace(name), output)
+		displayWarnings(reader)
+	},
+}
+
+// charsetBlock returns the filename and data of the first CODE block loaded
+// at the address, or when address is zero, at a standard UDG address or with
+// the size of a full character set.
+func charsetBlock(blocks []tap.Block, address int) (string, []byte) {
+	for i, block := range blocks {
+		header, ok := block.(*headers.ByteData)
+		if !ok || i+1 >= len(blocks) || blocks[i+1] == nil {
+			continue
+		}
+
+		match := int(header.StartAddress) == address
+		if address == 0 {
+			match = header.StartAddress == screen.UDGAddress ||
+				header.StartAddress == screen.UDGAddress16K ||
+				header.DataLength == screen.CharsetSize
+		}
+		if match {
+			return header.Filename(), blocks[i+1].BlockData()
+		}
+	}
+	return "", nil
+}
+
+func init() {
+	speccyCharsetCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyCharsetCmd.Flags().StringVarP(&spectrumCharsetOutput, "output", "o", "", `Output file, default: the tape filename with a .png extension`)
+	speccyCharsetCmd.Flags().IntVarP(&spectrumCharsetAddress, "address", "a", 0, `Load address of the CODE block, default: a UDG or character set block`)
+	speccyCharsetCmd.Flags().IntVarP(&spectrumCharsetGlyphs, "glyphs", "n", 0, `Number of glyphs to export, default: all glyphs in the block`)
+	speccyCharsetCmd.Flags().IntVarP(&spectrumCharsetColumns, "columns", "c", 16, `Number of glyphs in each row of the sheet`)
+	speccyCharsetCmd.Flags().IntVarP(&spectrumCharsetScale, "scale", "s", 4, `Pixel scale of the glyphs`)
+	spectrumCmd.AddCommand(speccyCharsetCmd)
+}
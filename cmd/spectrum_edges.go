@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var (
+	spectrumEdgesOutput string
+	spectrumEdgesLimit  int
+)
+
+var speccyEdgesCmd = &cobra.Command{
+	Use:   "edges FILE",
+	Short: "Export the signal edges of a ZX Spectrum tape as CSV",
+	Long: `Play a ZX Spectrum TZX tape and export each change of the signal level to a CSV
+file, named after the tape, or the file given with --out, for plotting the
+waveform in other tools or debugging loaders.
+
+Each row has the time of the edge in T-states from the start of the tape, the
+level after the edge (1 for high, 0 for low), and the index of the block being
+played. The first row is the level at the start of the tape.
+
+Tapes that loop forever are stopped after --limit edges; use 0 for no limit.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" && dskType != "tsx" {
+			fmt.Println("Edge lists are only available for TZX tapes.")
+			return
+		}
+
+		tape := newTZXTape(reader, dskType, filename)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is exported")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		output := spectrumEdgesOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".csv"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		buf := bufio.NewWriter(out)
+		c := csv.NewWriter(buf)
+		_ = c.Write([]string{"time", "level", "block"})
+
+		count := 0
+		stream := tape.EdgeStream(0)
+		for spectrumEdgesLimit <= 0 || count < spectrumEdgesLimit {
+			edge, ok := stream.Next()
+			if !ok {
+				break
+			}
+			level := "0"
+			if edge.High {
+				level = "1"
+			}
+			_ = c.Write([]string{strconv.FormatUint(edge.Time, 10), level, strconv.Itoa(edge.Block)})
+			count++
+		}
+		c.Flush()
+		if err = c.Error(); err == nil {
+			err = buf.Flush()
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if count == spectrumEdgesLimit {
+			fmt.Printf("WARNING: the export was stopped after %d edges.\n", count)
+		}
+		fmt.Printf("Exported %d edges, %.2f seconds, to '%s'\n", count, float64(stream.Duration())/3500000, output)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccyEdgesCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyEdgesCmd.Flags().StringVarP(&spectrumEdgesOutput, "out", "o", "", `Output file, default: the tape filename with a .csv extension`)
+	speccyEdgesCmd.Flags().IntVar(&spectrumEdgesLimit, "limit", tzx.MaxEdges, `Maximum number of edges to export, 0 for no limit`)
+	spectrumCmd.AddCommand(speccyEdgesCmd)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/wav"
+	"retroio/storage"
+)
+
+var (
+	spectrumExportOutput     string
+	spectrumExportSampleRate int
+	spectrumExportPauseMs    int
+	spectrumExportPauseScale float64
+	spectrumExportLeadIn     int
+)
+
+var speccyExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Create a WAV file for loading a tape on a real ZX Spectrum",
+	Long: `Play a ZX Spectrum TAP or TZX tape to a WAV file, named after the tape, or the
+file given with --output, which can be loaded by a real ZX Spectrum.
+
+Loaders on some machines fail with the pauses given on the tape. All pauses can
+be replaced using --pause-ms, or scaled with --pause-scale, and silence added
+before the first block with --lead-in. Pauses of zero length are not changed, as
+these mean the next block follows immediately.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		if spectrumExportPauseScale < 0 || spectrumExportLeadIn < 0 || spectrumExportSampleRate <= 0 {
+			fmt.Println("The --pause-scale, --lead-in, and --rate values must be positive.")
+			os.Exit(1)
+		}
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		encoder := wav.NewEncoder()
+		encoder.SampleRate = spectrumExportSampleRate
+		encoder.PauseMs = spectrumExportPauseMs
+		encoder.PauseScale = spectrumExportPauseScale
+		encoder.LeadIn = spectrumExportLeadIn
+
+		output := spectrumExportOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".wav"
+		}
+
+		var encode func(w *bufio.Writer) error
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		switch dskType {
+		case "tap":
+			tape := tap.New(reader)
+			err = tape.Read()
+			encode = func(w *bufio.Writer) error { return encoder.EncodeTAP(w, tape) }
+		case "tzx", "tsx":
+			tape := newTZXTape(reader, dskType, filename)
+			err = tape.Read()
+			encode = func(w *bufio.Writer) error { return encoder.EncodeTZX(w, tape) }
+		default:
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		if storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is exported")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		w := bufio.NewWriter(out)
+		if err := encode(w); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := w.Flush(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported the tape to '%s'\n", output)
+		displayWarnings(reader)
+	},
+}
+
+func init() {
+	speccyExportCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyExportCmd.Flags().StringVarP(&spectrumExportOutput, "output", "o", "", `Output file, default: the tape filename with a .wav extension`)
+	speccyExportCmd.Flags().IntVar(&spectrumExportSampleRate, "rate", 44100, `Sample rate of the WAV file`)
+	speccyExportCmd.Flags().IntVar(&spectrumExportPauseMs, "pause-ms", -1, `Replace all pauses with this length (ms), default: the tape pauses`)
+	speccyExportCmd.Flags().Float64Var(&spectrumExportPauseScale, "pause-scale", 1, `Multiply the length of all pauses, e.g. 1.5`)
+	speccyExportCmd.Flags().IntVar(&spectrumExportLeadIn, "lead-in", 0, `Silence before the first block (ms)`)
+	spectrumCmd.AddCommand(speccyExportCmd)
+}
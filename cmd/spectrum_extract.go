@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"retroio/amstrad/dsk"
+	"retroio/spectrum/plus3dos"
+	"retroio/storage"
+)
+
+var spectrumExtractOutput string
+
+var speccyExtractCmd = &cobra.Command{
+	Use:   "extract FILE NAME",
+	Short: "Extract a file from a ZX Spectrum +3 disk",
+	Long: `Extract a file from a ZX Spectrum +3 DSK disk image, saving it to the current
+directory, or the file given with --output.
+
+When the file has a +3DOS header it is removed, and the file is trimmed to the
+real length given in the header. Files without a header (CP/M files) are saved
+as they are stored on the disk.`,
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+		name := args[1]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "dsk" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+		disk := dsk.New(reader)
+
+		// only the directory tracks, and those of the file, are read
+		if err := disk.ReadLazy(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		data, err := disk.ReadFile(name)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if header, err := plus3dos.ReadHeader(data); err == nil {
+			fmt.Println("+3DOS HEADER:")
+			fmt.Println(header)
+			data = plus3dos.Strip(data)
+		} else {
+			fmt.Println("No +3DOS header, extracting the file as stored on the disk.")
+		}
+
+		output := spectrumExtractOutput
+		if output == "" {
+			output = name
+		}
+		if err := ioutil.WriteFile(output, data, 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Extracted %d bytes to '%s'\n", len(data), output)
+		displayWarnings(reader, disk)
+	},
+}
+
+func init() {
+	speccyExtractCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyExtractCmd.Flags().StringVarP(&spectrumExtractOutput, "output", "o", "", `Output file, default: the NAME of the file`)
+	spectrumCmd.AddCommand(speccyExtractCmd)
+}
@@ -3,12 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"retroio/spectrum"
+	"retroio/spectrum/p"
+	"retroio/spectrum/pzx"
 	"retroio/spectrum/tap"
-	"retroio/spectrum/tzx"
 	"retroio/storage"
 )
 
@@ -20,40 +22,156 @@ ZX Spectrum emulator TZX or TAP file.`,
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
+		if spectrumPreview < 0 || spectrumPreview > maxPreviewLength {
+			fmt.Printf("The preview length must be from 0 to %d bytes.\n", maxPreviewLength)
+			return
+		}
+
 		filename := args[0]
 
-		f, err := os.Open(filename)
+		f, filename, err := openMedia(filename)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 		defer f.Close()
-		reader := storage.NewReader(f)
+		reader := newReader(f)
 
 		var dsk spectrum.Image
-		dskType := mediaType(spectrumMediaType, filename)
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
 
 		switch dskType {
 		case "tap":
 			dsk = tap.New(reader)
-		case "tzx":
-			dsk = tzx.New(reader)
+		case "tzx", "tsx":
+			dsk = newTZXTape(reader, dskType, filename)
+		case "pzx":
+			dsk = pzx.New(reader)
+		case "p":
+			dsk = p.New(reader)
+		case "p81":
+			dsk = p.NewP81(reader)
 		default:
 			fmt.Printf("Unsupported media type: '%s'", dskType)
 			return
 		}
 
-		if err := dsk.Read(); err != nil {
+		if err := dsk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
 			fmt.Println("Storage read error!")
-			fmt.Println(err)
+			displayReadError(err)
 			os.Exit(1)
 		}
 
+		if structuredOutput() {
+			if spectrumBlockHashes {
+				displayTable(blockHashTable(dsk))
+			} else {
+				displayTable(blockSummaryTable(dsk, spectrumPreview, nil))
+			}
+			displayWarnings(reader, dsk)
+			return
+		}
+
 		dsk.DisplayGeometry()
+
+		if spectrumBlockHashes {
+			if t, ok := dsk.(interface{ BlockHashes() []tap.BlockHash }); ok {
+				fmt.Println()
+				fmt.Println("BLOCK HASHES (CRC32):")
+				for _, h := range t.BlockHashes() {
+					fmt.Printf("#%02d %-19s : %s\n", h.Block, h.Name, h.Hash)
+				}
+			}
+		}
+
+		if spectrumPreview > 0 {
+			if t, ok := dsk.(interface{ BlockSummaries() []tap.BlockSummary }); ok {
+				fmt.Println()
+				fmt.Printf("BLOCK DATA PREVIEW (first %d bytes):\n", spectrumPreview)
+				for _, b := range t.BlockSummaries() {
+					if len(b.Data) > 0 {
+						fmt.Printf("#%02d %-19s : %s\n", b.Block, b.Name, previewHex(b.Data, spectrumPreview))
+					}
+				}
+			}
+		}
+
+		displayWarnings(reader, dsk)
 	},
 }
 
 func init() {
 	speccyGeometryCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyGeometryCmd.Flags().BoolVar(&spectrumBlockHashes, "hashes", false, `Display the CRC32 hash of each data block`)
+	speccyGeometryCmd.Flags().IntVar(&spectrumPreview, "preview", 0, `Display the first N bytes of each data block as hex, up to 64`)
 	spectrumCmd.AddCommand(speccyGeometryCmd)
 }
+
+// maxPreviewLength is the most bytes of each block shown by --preview.
+const maxPreviewLength = 64
+
+// blockSummaryTable returns the metadata of each block on the tape. When the
+// preview is greater than zero, the first preview bytes of the block data are
+// added as hex, in a separate column. Only the blocks matching the filter are
+// included, or all blocks when the filter is nil.
+func blockSummaryTable(image interface{}, preview int, only *blockFilter) *outputTable {
+	t, ok := image.(interface{ BlockSummaries() []tap.BlockSummary })
+	if !ok {
+		return newOutputTable("block", "name", "summary")
+	}
+
+	summaries := t.BlockSummaries()
+	withOffset := len(summaries) > 0 && summaries[0].Offset >= 0
+
+	columns := []string{"block", "name", "summary"}
+	if withOffset {
+		columns = []string{"block", "offset", "name", "summary"}
+	}
+	if preview > 0 {
+		columns = append(columns, "preview")
+	}
+
+	table := newOutputTable(columns...)
+	for _, b := range summaries {
+		if !only.match(b.ID, b.Name) {
+			continue
+		}
+		values := []interface{}{b.Block, b.Name, b.Summary}
+		if withOffset {
+			values = []interface{}{b.Block, b.Offset, b.Name, b.Summary}
+		}
+		if preview > 0 {
+			values = append(values, previewHex(b.Data, preview))
+		}
+		table.add(values...)
+	}
+	return table
+}
+
+// previewHex returns the first length bytes of the data as hex, followed by
+// "..." when the data is longer.
+func previewHex(data []byte, length int) string {
+	more := ""
+	if len(data) > length {
+		data, more = data[:length], " ..."
+	}
+
+	hex := make([]string, len(data))
+	for i, b := range data {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, " ") + more
+}
+
+// blockHashTable returns the data hash of each block on the tape.
+func blockHashTable(image interface{}) *outputTable {
+	table := newOutputTable("block", "name", "crc32")
+	if t, ok := image.(interface{ BlockHashes() []tap.BlockHash }); ok {
+		for _, h := range t.BlockHashes() {
+			table.add(h.Block, h.Name, h.Hash)
+		}
+	}
+	return table
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/wav"
+	"retroio/storage"
+)
+
+var (
+	spectrumImportOutput    string
+	spectrumImportThreshold int16
+	spectrumImportTolerance float64
+)
+
+var speccyImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Create a TAP file from a WAV cassette recording",
+	Long: `Decode the standard speed blocks from a mono PCM WAV recording of a ZX Spectrum
+cassette, saving them as a TAP file, named after the WAV file, or the file given
+with --output.
+
+Blocks with an incorrect checksum are reported, as these usually indicate a poor
+quality recording. Adjusting the --threshold, for noisy recordings, or the pulse
+--tolerance, for tapes that have stretched, may help to recover these blocks.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+
+		decoder := wav.NewDecoder()
+		decoder.Threshold = spectrumImportThreshold
+		decoder.Tolerance = spectrumImportTolerance
+
+		data, err := decoder.DecodeBytes(f)
+		var checksumErr wav.ChecksumError
+		if errors.As(err, &checksumErr) {
+			fmt.Println("WARNING: the recording has errors, the blocks may not load.")
+			fmt.Println(err)
+			fmt.Println()
+		} else if err != nil {
+			fmt.Println("WAV decoding error!")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		reader := storage.NewReader(bytes.NewReader(data))
+		reader.SetMode(storage.Lenient)
+		tape := tap.New(reader)
+		if err := tape.Read(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		tape.DisplayGeometry()
+
+		output := spectrumImportOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".tap"
+		}
+		if err := ioutil.WriteFile(output, data, 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\nSaved %d blocks to '%s'\n", len(tape.Blocks), output)
+	},
+}
+
+func init() {
+	defaults := wav.NewDecoder()
+	speccyImportCmd.Flags().StringVarP(&spectrumImportOutput, "output", "o", "", `Output file, default: the WAV filename with a .tap extension`)
+	speccyImportCmd.Flags().Int16Var(&spectrumImportThreshold, "threshold", defaults.Threshold, `Minimum 16-bit sample amplitude for an edge`)
+	speccyImportCmd.Flags().Float64Var(&spectrumImportTolerance, "tolerance", defaults.Tolerance, `Allowed deviation from the ROM pulse lengths`)
+	spectrumCmd.AddCommand(speccyImportCmd)
+}
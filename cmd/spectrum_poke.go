@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var (
+	spectrumPokeBlock   int
+	spectrumPokeAddress uint16
+	spectrumPokeValue   uint8
+	spectrumPokeOutput  string
+)
+
+var speccyPokeCmd = &cobra.Command{
+	Use:   "poke FILE",
+	Short: "Apply a POKE to a code block of a TZX tape",
+	Long: `Apply a POKE, such as an infinite lives cheat, to a ZX Spectrum TZX tape, by
+changing the byte at the memory address given with --addr to --value, in the
+code block given by its block number (as shown by the geometry command) with
+--block. The checksum of the block is fixed, so the tape still loads.
+
+The block must follow its CODE header, which gives the load address, and the
+address must be within the memory the block loads to. Addresses and values may
+be given in decimal, or hexadecimal with a 0x prefix.
+
+The patched tape is written to a file named after the tape, or the file given
+with --output.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		if spectrumPokeBlock < 1 {
+			fmt.Println("A block number of 1 or more must be given with --block.")
+			os.Exit(1)
+		}
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+
+		// the original data is kept for writing the patched tape
+		original, err := ioutil.ReadAll(f)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		reader := newReader(bytes.NewReader(original))
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		tape := tzx.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable blocks can be patched")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		if err := tape.ApplyPoke(spectrumPokeBlock, spectrumPokeAddress, spectrumPokeValue); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		output := spectrumPokeOutput
+		if output == "" {
+			base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+			output = base + "-poked.tzx"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		w := bufio.NewWriter(out)
+		err = tape.WritePatched(original, w)
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			out.Close()
+			_ = os.Remove(output)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("POKE %d,%d applied to block #%d, written to '%s'\n", spectrumPokeAddress, spectrumPokeValue, spectrumPokeBlock, output)
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccyPokeCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyPokeCmd.Flags().IntVarP(&spectrumPokeBlock, "block", "b", 0, `Block number of the code block, starting from 1`)
+	speccyPokeCmd.Flags().Uint16VarP(&spectrumPokeAddress, "addr", "a", 0, `Memory address to POKE`)
+	speccyPokeCmd.Flags().Uint8VarP(&spectrumPokeValue, "value", "v", 0, `Value to POKE`)
+	speccyPokeCmd.Flags().StringVarP(&spectrumPokeOutput, "output", "o", "", `Output file, default: the tape filename with a -poked.tzx suffix`)
+	spectrumCmd.AddCommand(speccyPokeCmd)
+}
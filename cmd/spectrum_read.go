@@ -1,12 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"retroio/spectrum"
+	"retroio/spectrum/basic"
+	"retroio/spectrum/p"
+	"retroio/spectrum/pzx"
 	"retroio/spectrum/tap"
 	"retroio/spectrum/tzx"
 	"retroio/storage"
@@ -21,38 +31,136 @@ var speccyReadCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
 
-		f, err := os.Open(filename)
+		f, filename, err := openMedia(filename)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 		defer f.Close()
-		reader := storage.NewReader(f)
+		reader := newReader(f)
 
 		var dsk spectrum.Image
-		dskType := mediaType(spectrumMediaType, filename)
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
 
 		switch dskType {
 		case "tap":
 			dsk = tap.New(reader)
-		case "tzx":
-			dsk = tzx.New(reader)
+		case "tzx", "tsx":
+			dsk = newTZXTape(reader, dskType, filename)
+		case "pzx":
+			dsk = pzx.New(reader)
+		case "p":
+			dsk = p.New(reader)
+		case "p81":
+			dsk = p.NewP81(reader)
 		default:
 			fmt.Printf("Unsupported media type: '%s'", dskType)
 			return
 		}
 
-		if err := dsk.Read(); err != nil {
+		if err := dsk.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
 			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		only, err := parseBlockFilter(spectrumOnlyBlocks)
+		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
+		if spectrumBasListing && spectrumBasOutput != "" {
+			if err := writeBasicListings(dsk, spectrumBasOutput, spectrumBasSeparate); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			displayWarnings(reader, dsk)
+			return
+		}
+
+		if structuredOutput() {
+			displaySpectrumReadTable(dsk, only)
+			displayWarnings(reader, dsk)
+			return
+		}
+
 		if spectrumBasListing {
 			dsk.DisplayBASIC()
+			displayWarnings(reader, dsk)
+		} else if spectrumControlFlow {
+			if t, ok := dsk.(interface{ ControlFlowGraph() string }); ok {
+				fmt.Print(t.ControlFlowGraph())
+			} else {
+				fmt.Println("Control flow is only available for TZX tapes.")
+			}
+			displayWarnings(reader, dsk)
+		} else if spectrumVerify {
+			t, ok := dsk.(tapeValidator)
+			if !ok {
+				fmt.Println("Verification is only available for TZX tapes.")
+				return
+			}
+			problems := t.Validate()
+			flowProblems := t.ValidateControlFlow()
+			displayWarnings(reader, dsk)
+			if len(problems) == 0 && len(flowProblems) == 0 {
+				fmt.Println("All block lengths and control flow targets are valid.")
+				return
+			}
+			if len(problems) > 0 {
+				fmt.Println("BLOCK LENGTH ERRORS:")
+				for _, problem := range problems {
+					fmt.Printf("  %s\n", problem)
+				}
+			}
+			if len(flowProblems) > 0 {
+				fmt.Println("CONTROL FLOW ERRORS:")
+				for _, problem := range flowProblems {
+					fmt.Printf("  %s\n", problem)
+				}
+			}
+			os.Exit(1)
+		} else if spectrumLint {
+			t, ok := dsk.(interface{ LintTimings() []tzx.Warning })
+			if !ok {
+				fmt.Println("Timing checks are only available for TZX tapes.")
+				return
+			}
+			warnings := t.LintTimings()
+			if len(warnings) == 0 {
+				fmt.Println("All block timings are standard.")
+			} else {
+				fmt.Println("TIMING WARNINGS:")
+				for _, w := range warnings {
+					fmt.Printf("  %s\n", w)
+				}
+			}
+			displayWarnings(reader, dsk)
+		} else if spectrumSummary {
+			t, ok := dsk.(*tzx.TZX)
+			if !ok {
+				fmt.Println("The block summary is only available for TZX tapes.")
+				return
+			}
+			summary := t.BlockSummary()
+			var counts []string
+			for _, id := range sortedBlockIDs(summary) {
+				if only.match(int(id), tzx.BlockName(id)) {
+					counts = append(counts, fmt.Sprintf("%02Xh %s x%d", id, tzx.BlockName(id), summary[id]))
+				}
+			}
+			fmt.Println(strings.Join(counts, ", "))
+			fmt.Printf("Data payload: %d of %d bytes (%.1f%%)\n", t.TotalDataBytes(), reader.Offset(), t.DataDensity()*100)
+			displayWarnings(reader, dsk)
+		} else if only != nil {
+			displayBlockList(dsk, only)
+			displayWarnings(reader, dsk)
 		} else {
 			cmd.Help()
-			fmt.Println("\nPlease select '--bas' for BASIC program listing.")
+			fmt.Println("\nPlease select '--bas' for BASIC program listing, '--flow' for the control flow, '--verify' to check the block lengths, '--lint' to check the timings, '--summary' for the block types, or '--only' to list the blocks of the given types.")
 		}
 	},
 }
@@ -60,5 +168,260 @@ var speccyReadCmd = &cobra.Command{
 func init() {
 	speccyReadCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
 	speccyReadCmd.Flags().BoolVar(&spectrumBasListing, "bas", false, `BASIC program listing`)
+	speccyReadCmd.Flags().StringVar(&spectrumBasOutput, "out", "", `Write the BASIC listing to a file, e.g. listing.bas`)
+	speccyReadCmd.Flags().BoolVar(&spectrumBasSeparate, "separate", false, `With --out, write each BASIC program to a separate numbered file`)
+	speccyReadCmd.Flags().BoolVar(&spectrumControlFlow, "flow", false, `TZX control flow of the loop, jump, call and select blocks`)
+	speccyReadCmd.Flags().BoolVar(&spectrumVerify, "verify", false, `Check the TZX block length fields against the data read`)
+	speccyReadCmd.Flags().BoolVar(&spectrumLint, "lint", false, `Warn about TZX pulse timings that are non-standard or unreliable`)
+	speccyReadCmd.Flags().BoolVar(&spectrumSummary, "summary", false, `Count the TZX blocks of each block type`)
+	speccyReadCmd.Flags().StringVar(&spectrumOnlyBlocks, "only", "", `Only the blocks with these IDs or names, e.g. 0x10,0x30 or text,standard`)
 	spectrumCmd.AddCommand(speccyReadCmd)
 }
+
+// sortedBlockIDs returns the block IDs of the summary in order.
+func sortedBlockIDs(summary map[uint8]int) []uint8 {
+	var ids []uint8
+	for id := range summary {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// blockFilter selects blocks by their TZX block ID, or by a part of their name,
+// ignoring case, e.g. "text" for the Text Description blocks.
+type blockFilter struct {
+	ids   map[int]bool
+	names []string
+}
+
+// parseBlockFilter returns the filter for a comma separated list of block IDs
+// and names, or nil for an empty list. The IDs may be given in decimal, or in
+// hex with a 0x prefix.
+func parseBlockFilter(list string) (*blockFilter, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	f := &blockFilter{ids: make(map[int]bool)}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if id, err := strconv.ParseUint(item, 0, 8); err == nil {
+			f.ids[int(id)] = true
+		} else if item[0] >= '0' && item[0] <= '9' {
+			return nil, errors.Errorf("invalid block ID '%s', expected 0x00 to 0xFF", item)
+		} else {
+			f.names = append(f.names, item)
+		}
+	}
+	return f, nil
+}
+
+// match returns true when the block ID or name is selected by the filter, or
+// always for a nil filter. Blocks without an ID, -1, only match by name.
+func (f *blockFilter) match(id int, name string) bool {
+	if f == nil {
+		return true
+	}
+	if id >= 0 && f.ids[id] {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, n := range f.names {
+		if strings.Contains(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayBlockList prints the metadata of the blocks matching the filter, one
+// line for each block.
+func displayBlockList(image spectrum.Image, only *blockFilter) {
+	t, ok := image.(interface{ BlockSummaries() []tap.BlockSummary })
+	if !ok {
+		fmt.Println("The block list is not available for this media type.")
+		return
+	}
+
+	found := false
+	for _, b := range t.BlockSummaries() {
+		if !only.match(b.ID, b.Name) {
+			continue
+		}
+		if !found {
+			fmt.Println("BLOCKS:")
+			found = true
+		}
+		fmt.Printf("  #%02d %s: %s\n", b.Block, b.Name, b.Summary)
+	}
+	if !found {
+		fmt.Println("No matching blocks found.")
+	}
+}
+
+// tapeValidator checks the block lengths and control flow of a tape.
+type tapeValidator interface {
+	Validate() []error
+	ValidateControlFlow() []error
+}
+
+// displaySpectrumReadTable outputs the BASIC programs, timing warnings, block
+// length errors, or block type counts as JSON or CSV, or the block metadata
+// when none of these are selected. The control flow is only available as text.
+// The block type counts and metadata only include the blocks matching the
+// filter.
+func displaySpectrumReadTable(image spectrum.Image, only *blockFilter) {
+	var table *outputTable
+
+	switch {
+	case spectrumBasListing:
+		table = newOutputTable("program", "line", "text")
+		for _, program := range basicPrograms(spectrumDataBlocks(image)) {
+			lines, err := basic.DecodeLines(program.data)
+			if err != nil {
+				fmt.Fprintf(messages(), "%s: %s\n", program.filename, err)
+				continue
+			}
+			for _, line := range lines {
+				table.add(program.filename, line.Number, line.Text())
+			}
+		}
+	case spectrumControlFlow:
+		fmt.Fprintln(messages(), "The control flow is only available as text.")
+		os.Exit(1)
+	case spectrumVerify:
+		table = newOutputTable("error")
+		if t, ok := image.(tapeValidator); ok {
+			for _, problem := range append(t.Validate(), t.ValidateControlFlow()...) {
+				table.add(problem.Error())
+			}
+		}
+	case spectrumLint:
+		table = newOutputTable("block", "name", "message")
+		if t, ok := image.(interface{ LintTimings() []tzx.Warning }); ok {
+			for _, w := range t.LintTimings() {
+				table.add(w.Block, w.Name, w.Message)
+			}
+		}
+	case spectrumSummary:
+		table = newOutputTable("id", "name", "count", "data_bytes")
+		if t, ok := image.(*tzx.TZX); ok {
+			summary := t.BlockSummary()
+			payload := make(map[uint8]int)
+			_ = t.Walk(func(index int, b tzx.Block) error {
+				payload[uint8(b.Id())] += tzx.DataBytes(b)
+				return nil
+			})
+			for _, id := range sortedBlockIDs(summary) {
+				if only.match(int(id), tzx.BlockName(id)) {
+					table.add(fmt.Sprintf("%02X", id), tzx.BlockName(id), summary[id], payload[id])
+				}
+			}
+		}
+	default:
+		table = blockSummaryTable(image, 0, only)
+	}
+
+	displayTable(table)
+}
+
+// basicProgram is the data of a BASIC program, and the filename of its header.
+type basicProgram struct {
+	filename string
+	data     []byte
+}
+
+// basicPrograms returns the programs of the tape: the data block paired with
+// each program header, without the variables area.
+func basicPrograms(blocks []tap.Block) []basicProgram {
+	var programs []basicProgram
+	for _, p := range tap.BasicPrograms(blocks) {
+		data, _ := p.Header.SplitVariables(p.Data.BlockData())
+		programs = append(programs, basicProgram{
+			filename: strings.TrimSpace(p.Header.Filename()),
+			data:     data,
+		})
+	}
+	return programs
+}
+
+// writeBasicListings writes the BASIC programs of the tape to the output file,
+// separated by a line with the program name, or with separate, to a numbered
+// file for each program, e.g. listing-1.bas, listing-2.bas.
+func writeBasicListings(image spectrum.Image, output string, separate bool) error {
+	programs := basicPrograms(spectrumDataBlocks(image))
+	if len(programs) == 0 {
+		return errors.New("no BASIC programs found")
+	}
+
+	var listing bytes.Buffer
+	written := 0
+	for i, program := range programs {
+		lines, err := basic.Decode(program.data)
+		if err != nil {
+			fmt.Printf("%s: %s\n", program.filename, err)
+			continue
+		}
+
+		written++
+		if !separate {
+			if listing.Len() > 0 {
+				listing.WriteString("\n")
+			}
+			fmt.Fprintf(&listing, "==== %s ====\n", storage.DisplayText(program.filename))
+			if err := basic.WriteListing(&listing, lines); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ext := filepath.Ext(output)
+		filename := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(output, ext), i+1, ext)
+		var buf bytes.Buffer
+		if err := basic.WriteListing(&buf, lines); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Written '%s' to '%s'\n", storage.DisplayText(program.filename), filename)
+	}
+
+	if separate {
+		return nil
+	}
+	if err := ioutil.WriteFile(output, listing.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Written %d BASIC programs to '%s'\n", written, output)
+	return nil
+}
+
+// spectrumDataBlocks returns the TAP data of each block of the tape, skipping
+// the blocks without any.
+func spectrumDataBlocks(image spectrum.Image) []tap.Block {
+	var blocks []tap.Block
+	switch t := image.(type) {
+	case *tap.TAP:
+		for _, b := range t.Blocks {
+			blocks = append(blocks, b.TapeData)
+		}
+	case *tzx.TZX:
+		for _, b := range t.Blocks() {
+			if b.BlockData() != nil {
+				blocks = append(blocks, b.BlockData())
+			}
+		}
+	case *pzx.PZX:
+		for _, b := range t.Blocks {
+			if b.BlockData() != nil {
+				blocks = append(blocks, b.BlockData())
+			}
+		}
+	}
+	return blocks
+}
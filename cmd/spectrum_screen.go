@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/pzx"
+	"retroio/spectrum/screen"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var (
+	spectrumScreenOutput string
+	spectrumScreenANSI   bool
+)
+
+var speccyScreenCmd = &cobra.Command{
+	Use:   "screen FILE",
+	Short: "Export the SCREEN$ from a ZX Spectrum tape as a GIF",
+	Long: `Export the first SCREEN$, usually the loading screen, from a ZX Spectrum TAP,
+TZX, or PZX tape as a GIF image, named after the tape, or the file given with
+--output.
+
+Screens using the FLASH attribute are exported as an animated GIF, alternating
+the flash states at the same rate as the ZX Spectrum.
+
+Use --ansi to preview the screen in the terminal instead, using 24-bit colour.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		blocks, ok, err := spectrumTapeBlocks(dskType, reader)
+		if !ok {
+			fmt.Printf("Unsupported media type: '%s'", dskType)
+			return
+		}
+
+		if storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is used")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		var data []byte
+		for _, b := range blocks {
+			if b != nil && len(b.BlockData()) == screen.Size {
+				data = b.BlockData()
+				break
+			}
+		}
+		if data == nil {
+			fmt.Println("No SCREEN$ found on the tape.")
+			os.Exit(1)
+		}
+
+		if spectrumScreenANSI {
+			fmt.Print(screen.RenderScreenANSI(data))
+			displayWarnings(reader)
+			return
+		}
+
+		output := spectrumScreenOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".gif"
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer out.Close()
+
+		if err := screen.ExportScreenGIF(data, out); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Exported the SCREEN$ to '%s'\n", output)
+		displayWarnings(reader)
+	},
+}
+
+func init() {
+	speccyScreenCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyScreenCmd.Flags().StringVarP(&spectrumScreenOutput, "output", "o", "", `Output file, default: the tape filename with a .gif extension`)
+	speccyScreenCmd.Flags().BoolVar(&spectrumScreenANSI, "ansi", false, `Print the screen to the terminal, instead of saving a GIF`)
+	spectrumCmd.AddCommand(speccyScreenCmd)
+}
+
+// spectrumTapeBlocks reads a TAP, TZX, or PZX tape, returning the TAP data of
+// each block, which is nil for the blocks without any. The returned bool is
+// false when the media type is not one of these tapes.
+func spectrumTapeBlocks(dskType string, reader *storage.Reader) ([]tap.Block, bool, error) {
+	var blocks []tap.Block
+	var err error
+
+	switch dskType {
+	case "tap":
+		tape := tap.New(reader)
+		err = tape.Read()
+		for _, b := range tape.Blocks {
+			blocks = append(blocks, b.TapeData)
+		}
+	case "tzx":
+		tape := tzx.New(reader)
+		err = tape.Read()
+		for _, b := range tape.Blocks() {
+			blocks = append(blocks, b.BlockData())
+		}
+	case "pzx":
+		tape := pzx.New(reader)
+		err = tape.Read()
+		for _, b := range tape.Blocks {
+			blocks = append(blocks, b.BlockData())
+		}
+	default:
+		return nil, false, nil
+	}
+
+	return blocks, true, err
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+var spectrumScreensOutput string
+
+var speccyScreensCmd = &cobra.Command{
+	Use:   "screens FILE",
+	Short: "Export all the loading screens from a ZX Spectrum tape as PNGs",
+	Long: `Find every SCREEN$ on a ZX Spectrum TZX tape, and export each as a PNG named
+after the tape and numbered in tape order, into the current directory, or the
+directory given with --out. The loading screen of most games is the first.
+
+A screen is a 6912 byte data block loaded at 16384, the display file, after a
+CODE header, or a headerless 6912 byte data block. Compressed screens are
+unpacked by the game's loader, so can not be found.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" {
+			fmt.Println("Extracting the loading screens is only available for TZX tapes.")
+			return
+		}
+
+		tape := tzx.New(reader)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is used")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		images, err := tape.ExtractLoadingScreens()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(images) == 0 {
+			fmt.Println("No SCREEN$ found on the tape.")
+			os.Exit(1)
+		}
+
+		if spectrumScreensOutput != "" {
+			if err := os.MkdirAll(spectrumScreensOutput, 0755); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		for i, img := range images {
+			output := filepath.Join(spectrumScreensOutput, fmt.Sprintf("%s-%d.png", name, i+1))
+
+			out, err := os.Create(output)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			err = png.Encode(out, img)
+			_ = out.Close()
+			if err != nil {
+				fmt.Printf("Unable to write '%s': %s\n", output, err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Exported SCREEN$ %d to '%s'\n", i+1, output)
+		}
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccyScreensCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccyScreensCmd.Flags().StringVar(&spectrumScreensOutput, "out", "", `Output directory, default: the current directory`)
+	spectrumCmd.AddCommand(speccyScreensCmd)
+}
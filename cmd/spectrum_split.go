@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+var spectrumSplitOutput string
+
+var speccySplitCmd = &cobra.Command{
+	Use:   "split FILE",
+	Short: "Split a multiload ZX Spectrum TZX tape at its stop blocks",
+	Long: `Divide a ZX Spectrum TZX tape at the points where the tape is stopped, a Pause
+block of zero duration, or a Stop the Tape if in 48K Mode block, writing each
+segment as a TZX file named after the tape and numbered in tape order, into the
+current directory, or the directory given with --out.
+
+Multiload tapes, with a segment for each level, are easier to work with split.
+Each segment has a TZX header, and the archive info of the tape, when it has
+one. The stop blocks are not included.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, filename, err := openMedia(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		data, err := ioutil.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		reader := newReader(bytes.NewReader(data))
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" && dskType != "tsx" {
+			fmt.Println("Splitting is only available for TZX tapes.")
+			return
+		}
+
+		tape := newTZXTape(reader, dskType, filename)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "the incomplete block is not included")
+			if truncated, ok := err.(storage.TruncatedError); ok {
+				data = data[:truncated.Offset]
+			}
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		segments, err := tape.SplitAtStops(data)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if spectrumSplitOutput != "" {
+			if err := os.MkdirAll(spectrumSplitOutput, 0755); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		for i, segment := range segments {
+			output := filepath.Join(spectrumSplitOutput, fmt.Sprintf("%s-%d.tzx", name, i+1))
+			if err := ioutil.WriteFile(output, segment, 0644); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Printf("Wrote segment %d to '%s', %d bytes\n", i+1, output, len(segment))
+		}
+
+		fmt.Printf("Split the tape into %d segments.\n", len(segments))
+		displayWarnings(reader, tape)
+	},
+}
+
+func init() {
+	speccySplitCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	speccySplitCmd.Flags().StringVar(&spectrumSplitOutput, "out", "", `Output directory, default: the current directory`)
+	spectrumCmd.AddCommand(speccySplitCmd)
+}
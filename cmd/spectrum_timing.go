@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+var speccyTimingCmd = &cobra.Command{
+	Use:   "timing FILE",
+	Short: "Export the block timings of a ZX Spectrum tape",
+	Long: `Export the timing profile of each block of a ZX Spectrum TZX tape: the pilot
+pulse length and count, the sync pulses, the zero and one bit pulses, and the
+pause, all in T-states. This is the data the tape engine of an emulator needs to
+replay turbo loaders.
+
+The Standard Speed, Turbo Speed, Pure Tone, Pulse Sequence and Pure Data blocks
+have their timings listed, as do the pauses; other blocks have zeroed timings.
+
+Use --format json or csv to export the profile for use by other tools.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		f, filename, err := openMedia(filename)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		reader := newReader(f)
+
+		dskType := detectMediaType(spectrumMediaType, filename, reader)
+		if dskType != "tzx" && dskType != "tsx" {
+			fmt.Println("Timing profiles are only available for TZX tapes.")
+			return
+		}
+
+		tape := newTZXTape(reader, dskType, filename)
+		if err := tape.Read(); storage.IsTruncated(err) {
+			displayTruncated(err, "only the recoverable data is shown")
+		} else if err != nil {
+			fmt.Println("Storage read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		profile := tape.TimingProfile()
+
+		if structuredOutput() {
+			table := newOutputTable("block", "name", "pilot_pulse", "pilot_pulses", "sync_pulses", "zero_bit_pulse", "one_bit_pulse", "pause")
+			for _, t := range profile {
+				table.add(t.Block, t.Name, t.PilotPulse, t.PilotPulses, pulseList(t.SyncPulses), t.ZeroBitPulse, t.OneBitPulse, t.Pause)
+			}
+			displayTable(table)
+			displayWarnings(reader, tape)
+			return
+		}
+
+		fmt.Println("TIMING PROFILE (T-states):")
+		fmt.Printf("  %-5s %-26s %6s %6s %-12s %5s %5s %8s\n", "BLOCK", "NAME", "PILOT", "COUNT", "SYNC", "ZERO", "ONE", "PAUSE")
+		for _, t := range profile {
+			fmt.Printf("  #%02d   %-26s %6d %6d %-12s %5d %5d %8d\n",
+				t.Block, t.Name, t.PilotPulse, t.PilotPulses, pulseList(t.SyncPulses), t.ZeroBitPulse, t.OneBitPulse, t.Pause)
+		}
+		displayWarnings(reader, tape)
+	},
+}
+
+// pulseList returns the pulse lengths as a comma separated list.
+func pulseList(pulses []uint16) string {
+	lengths := make([]string, len(pulses))
+	for i, p := range pulses {
+		lengths[i] = fmt.Sprint(p)
+	}
+	return strings.Join(lengths, ",")
+}
+
+func init() {
+	speccyTimingCmd.Flags().StringVarP(&spectrumMediaType, "media", "m", "", `Media type, default: file extension`)
+	spectrumCmd.AddCommand(speccyTimingCmd)
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/spf13/cobra"
+
+	"retroio/storage"
+)
+
+// mediaWriter is implemented by the media images that can be written back out.
+type mediaWriter interface {
+	Write(w io.Writer) error
+}
+
+var verifyRoundTripCmd = &cobra.Command{
+	Use:   "verify-roundtrip FILE",
+	Short: "Check that a media file is written back without losing data",
+	Long: `Read a media file, write it back out, then read the written bytes again, and
+check the two parsed images are equal, reporting the first field that differs.
+The byte offset of the first difference between the original and written files
+is also reported, as unmodified images are expected to be written back
+byte-for-byte.
+
+This is a self-test of the media writers, and formats without write support are
+skipped. The exit status is 1 when a difference is found.`,
+	Hidden:                true,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		f, filename, err := openMedia(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		original, err := ioutil.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		reader := newReader(bytes.NewReader(original))
+
+		media := detectInfoMediaType(filename, reader)
+		format, ok := mediaFormats[media]
+		if !ok {
+			fmt.Printf("Unable to identify the media format of '%s', it may not be supported.\n", filename)
+			os.Exit(1)
+		}
+
+		image := format.image(reader)
+		writer, ok := image.(mediaWriter)
+		if !ok {
+			fmt.Printf("Skipped: writing %s files is not supported.\n", format.name)
+			return
+		}
+
+		if err := image.Read(); err != nil {
+			fmt.Println("Media read error!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		var written bytes.Buffer
+		if err := writer.Write(&written); err != nil {
+			fmt.Printf("Write error: %s\n", err)
+			os.Exit(1)
+		}
+
+		reread := format.image(newReader(bytes.NewReader(written.Bytes())))
+		if err := reread.Read(); err != nil {
+			fmt.Println("Error reading the written file!")
+			displayReadError(err)
+			os.Exit(1)
+		}
+
+		failed := false
+		if field := firstDifference(format.name, reflect.ValueOf(image), reflect.ValueOf(reread)); field != "" {
+			fmt.Printf("MISMATCH: %s\n", field)
+			failed = true
+		} else {
+			fmt.Println("The parsed images are equal.")
+		}
+
+		if offset := firstByteDifference(original, written.Bytes()); offset >= 0 {
+			fmt.Printf("The written file differs from the original at offset %d (0x%X), %d bytes written of %d.\n",
+				offset, offset, written.Len(), len(original))
+		} else {
+			fmt.Printf("The written file is identical to the original, %d bytes.\n", len(original))
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyRoundTripCmd)
+}
+
+var storageReaderType = reflect.TypeOf(&storage.Reader{})
+
+// firstDifference compares the two values field by field, including the
+// unexported fields, returning the path and values of the first field that
+// differs, or an empty string when they are equal. The readers the images
+// were read from are ignored.
+func firstDifference(path string, a, b reflect.Value) string {
+	if a.Type() == storageReaderType {
+		return ""
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return fmt.Sprintf("%s: nil and non-nil", path)
+			}
+			return ""
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return fmt.Sprintf("%s: type %s and %s", path, a.Elem().Type(), b.Elem().Type())
+		}
+		return firstDifference(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := path + "." + a.Type().Field(i).Name
+			if diff := firstDifference(field, a.Field(i), b.Field(i)); diff != "" {
+				return diff
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return fmt.Sprintf("%s: length %d and %d", path, a.Len(), b.Len())
+		}
+		for i := 0; i < a.Len(); i++ {
+			if diff := firstDifference(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i)); diff != "" {
+				return diff
+			}
+		}
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return fmt.Sprintf("%s: length %d and %d", path, a.Len(), b.Len())
+		}
+		for _, key := range a.MapKeys() {
+			value := b.MapIndex(key)
+			if !value.IsValid() {
+				return fmt.Sprintf("%s[%v]: missing", path, key)
+			}
+			if diff := firstDifference(fmt.Sprintf("%s[%v]", path, key), a.MapIndex(key), value); diff != "" {
+				return diff
+			}
+		}
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			return fmt.Sprintf("%s: %t and %t", path, a.Bool(), b.Bool())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			return fmt.Sprintf("%s: %d and %d", path, a.Int(), b.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			return fmt.Sprintf("%s: %d and %d", path, a.Uint(), b.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		if a.Float() != b.Float() {
+			return fmt.Sprintf("%s: %g and %g", path, a.Float(), b.Float())
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			return fmt.Sprintf("%s: %q and %q", path, a.String(), b.String())
+		}
+	}
+
+	return ""
+}
+
+// firstByteDifference returns the offset of the first byte that differs, or
+// of the end of the shorter data, or -1 when the data is identical.
+func firstByteDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return len(a)
+		}
+		return len(b)
+	}
+	return -1
+}
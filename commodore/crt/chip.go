@@ -0,0 +1,76 @@
+package crt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"retroio/storage"
+)
+
+// ChipSignature is the signature of each CHIP packet.
+const ChipSignature = "CHIP"
+
+// chipHeaderSize is the size of the ChipHeader, before the ROM data.
+const chipHeaderSize = 0x10
+
+// ChipHeader is the header of a CHIP packet.
+type ChipHeader struct {
+	Signature    [4]byte // "CHIP"
+	PacketLength uint32  // Total packet length, including this header
+	ChipType     uint16  // 0 = ROM, 1 = RAM (no data), 2 = Flash ROM, 3 = EEPROM
+	Bank         uint16  // Bank number, 0 for normal cartridges
+	LoadAddress  uint16  // Starting load address
+	ImageSize    uint16  // ROM image size in bytes, usually $2000 or $4000
+}
+
+// Chip is a CHIP packet, holding the contents of a ROM, RAM or Flash chip,
+// or of one bank of it.
+type Chip struct {
+	ChipHeader
+	Data []byte
+}
+
+// Read the packet header and the ROM data. Any packet data following the ROM
+// image, as given by the packet length, is skipped.
+func (c *Chip) Read(reader *storage.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &c.ChipHeader); err != nil {
+		return err
+	}
+	if string(c.Signature[:]) != ChipSignature {
+		return fmt.Errorf("invalid CHIP packet signature: %q", c.Signature)
+	}
+
+	c.Data = reader.ReadBytes(int(c.ImageSize))
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	if extra := int(c.PacketLength) - chipHeaderSize - int(c.ImageSize); extra > 0 {
+		if _, err := reader.Discard(extra); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ChipTypeName returns the name of the chip type.
+func (c Chip) ChipTypeName() string {
+	switch c.ChipType {
+	case 0:
+		return "ROM"
+	case 1:
+		return "RAM"
+	case 2:
+		return "Flash"
+	case 3:
+		return "EEPROM"
+	default:
+		return "Unknown"
+	}
+}
+
+func (c Chip) String() string {
+	return fmt.Sprintf("%-6s bank %-3d $%04x-$%04x %d bytes",
+		c.ChipTypeName(), c.Bank, c.LoadAddress, int(c.LoadAddress)+int(c.ImageSize)-1, c.ImageSize)
+}
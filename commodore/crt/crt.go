@@ -0,0 +1,130 @@
+// Package crt implements reading of Commodore C64 cartridge files, in the CRT
+// format created for the CCS64 emulator, as specified at:
+// https://vice-emu.sourceforge.io/vice_17.html#SEC391
+//
+// The file header is followed by one or more CHIP packets, each holding the
+// contents of a ROM chip, or one bank of it, along with its load address.
+//
+// Note: unlike most other formats, all WORD and DWORD values are stored in
+// high/low (big endian) byte order.
+package crt
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// CRT cartridge file structure
+type CRT struct {
+	reader *storage.Reader
+
+	Header Header
+	Chips  []Chip
+}
+
+func New(reader *storage.Reader) *CRT {
+	return &CRT{reader: reader}
+}
+
+// Read the cartridge header, followed by the CHIP packets up to the end of the
+// file. Only the structure is read, the cartridge hardware is not emulated.
+func (c *CRT) Read() error {
+	c.Header = Header{}
+	if err := c.Header.Read(c.reader); err != nil {
+		return errors.Wrap(err, "error reading the cartridge header")
+	}
+	if string(c.Header.Signature[:]) != Signature {
+		return fmt.Errorf("invalid cartridge signature: %q", c.Header.Signature)
+	}
+
+	if extra := int(c.Header.HeaderLength) - headerSize; extra > 0 {
+		if _, err := c.reader.Discard(extra); err != nil {
+			return storage.TruncatedError{Offset: c.reader.Offset()}
+		}
+	}
+
+	c.Chips = nil
+	for {
+		if _, err := c.reader.Peek(1); err == io.EOF {
+			break
+		}
+
+		offset := c.reader.Offset()
+		chip := Chip{}
+		if err := chip.Read(c.reader); storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: len(c.Chips), Offset: offset}
+		} else if err != nil {
+			return errors.Wrapf(err, "error reading CHIP packet #%d", len(c.Chips))
+		}
+		c.Chips = append(c.Chips, chip)
+	}
+
+	return nil
+}
+
+// DisplayGeometry prints the cartridge header, and the CHIP packets with their
+// type, bank, load address and size, to the terminal.
+func (c CRT) DisplayGeometry() {
+	fmt.Println("HEADER INFORMATION:")
+	fmt.Println(c.Header)
+
+	fmt.Println("CHIP PACKETS:")
+	fmt.Println("  #  TYPE    BANK  LOAD   END    SIZE")
+	for i, chip := range c.Chips {
+		fmt.Printf("  %-2d %-7s %-5d $%04x  $%04x  %d\n",
+			i, chip.ChipTypeName(), chip.Bank, chip.LoadAddress, int(chip.LoadAddress)+int(chip.ImageSize)-1, chip.ImageSize)
+	}
+}
+
+// Warnings returns the problems found with the cartridge, which did not stop
+// it from being read, such as a header length shorter than the header, and
+// for normal cartridges, chips loaded to an address not mapped by the 8K, 16K
+// or Ultimax configuration given by the EXROM and GAME lines.
+func (c CRT) Warnings() []string {
+	var warnings []string
+
+	if c.Header.HeaderLength < headerSize {
+		warnings = append(warnings, fmt.Sprintf("header length $%02x is shorter than the $%02x byte header", c.Header.HeaderLength, headerSize))
+	}
+
+	if len(c.Chips) == 0 {
+		warnings = append(warnings, "no CHIP packets found")
+	}
+
+	for i, chip := range c.Chips {
+		if chip.ChipType > 3 {
+			warnings = append(warnings, fmt.Sprintf("CHIP packet #%d: unknown chip type %d", i, chip.ChipType))
+		}
+		if c.Header.HardwareType == 0 && !c.mapped(chip) {
+			warnings = append(warnings, fmt.Sprintf(
+				"CHIP packet #%d: load address $%04x is not mapped in the %s configuration",
+				i, chip.LoadAddress, c.Header.Configuration(),
+			))
+		}
+	}
+
+	return warnings
+}
+
+// mapped reports whether the chip of a normal cartridge is mapped into memory
+// by its EXROM and GAME configuration: ROML at $8000 for 8K cartridges, ROML
+// and ROMH at $8000-$BFFF for 16K cartridges, and ROML at $8000 with ROMH at
+// $E000 for Ultimax cartridges, such as the MAX Machine games.
+func (c CRT) mapped(chip Chip) bool {
+	start := int(chip.LoadAddress)
+	end := start + int(chip.ImageSize)
+
+	switch c.Header.Configuration() {
+	case "8K":
+		return start >= 0x8000 && end <= 0xA000
+	case "16K":
+		return start >= 0x8000 && end <= 0xC000
+	case "Ultimax":
+		return start >= 0x8000 && end <= 0xA000 || start >= 0xE000 && end <= 0x10000
+	}
+	return true
+}
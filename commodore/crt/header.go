@@ -0,0 +1,112 @@
+package crt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"retroio/storage"
+)
+
+// Signature of the cartridge header, padded with spaces.
+const Signature = "C64 CARTRIDGE   "
+
+// headerSize is the size of the Header, which is the usual header length.
+const headerSize = 0x40
+
+// Cartridge Header
+type Header struct {
+	Signature        [16]byte // "C64 CARTRIDGE   "
+	HeaderLength     uint32   // File header length {$40}
+	Version          uint16   // Cartridge version, high byte major, low byte minor {$0100}
+	HardwareType     uint16   // Cartridge hardware type, 0 is a normal cartridge
+	EXROM            uint8    // EXROM line status, 0 = active (low)
+	GAME             uint8    // GAME line status, 0 = active (low)
+	HardwareRevision uint8    // Cartridge hardware revision, from version 1.01
+	Reserved         [5]byte
+	Name             [32]byte // Cartridge name, padded with $00
+}
+
+func (h *Header) Read(reader *storage.Reader) error {
+	return binary.Read(reader, binary.BigEndian, h)
+}
+
+// CartridgeName returns the name of the cartridge without the padding.
+func (h Header) CartridgeName() string {
+	return storage.DecodeLatin1(bytes.TrimRight(h.Name[:], "\x00 "))
+}
+
+// HardwareTypeName returns the name of the cartridge hardware type.
+func (h Header) HardwareTypeName() string {
+	if int(h.HardwareType) < len(hardwareTypes) {
+		return hardwareTypes[h.HardwareType]
+	}
+	return "Unknown"
+}
+
+// Configuration returns the memory configuration selected by the EXROM and
+// GAME lines at power on: 8K, 16K, Ultimax, or none for a cartridge that is
+// not visible until it is banked in, such as most freezer cartridges.
+func (h Header) Configuration() string {
+	switch {
+	case h.EXROM == 0 && h.GAME == 0:
+		return "16K"
+	case h.EXROM == 0:
+		return "8K"
+	case h.GAME == 0:
+		return "Ultimax"
+	default:
+		return "none"
+	}
+}
+
+func (h Header) String() string {
+	str := ""
+	str += fmt.Sprintf("Name:          %s\n", storage.DisplayText(h.CartridgeName()))
+	str += fmt.Sprintf("Version:       %d.%02d\n", h.Version>>8, h.Version&0xFF)
+	str += fmt.Sprintf("Hardware Type: %d (%s)\n", h.HardwareType, h.HardwareTypeName())
+	str += fmt.Sprintf("EXROM/GAME:    %d/%d (%s)\n", h.EXROM, h.GAME, h.Configuration())
+	return str
+}
+
+// hardwareTypes are the names of the cartridge hardware types, as given in the
+// CRT specification.
+var hardwareTypes = []string{
+	"Normal cartridge",
+	"Action Replay",
+	"KCS Power Cartridge",
+	"Final Cartridge III",
+	"Simons' BASIC",
+	"Ocean type 1",
+	"Expert Cartridge",
+	"Fun Play, Power Play",
+	"Super Games",
+	"Atomic Power",
+	"Epyx Fastload",
+	"Westermann Learning",
+	"Rex Utility",
+	"Final Cartridge I",
+	"Magic Formel",
+	"C64 Game System, System 3",
+	"Warp Speed",
+	"Dinamic",
+	"Zaxxon, Super Zaxxon (Sega)",
+	"Magic Desk, Domark, HES Australia",
+	"Super Snapshot V5",
+	"Comal-80",
+	"Structured BASIC",
+	"Ross",
+	"Dela EP64",
+	"Dela EP7x8",
+	"Dela EP256",
+	"Rex EP256",
+	"Mikro Assembler",
+	"Final Cartridge Plus",
+	"Action Replay 4",
+	"Stardos",
+	"EasyFlash",
+	"EasyFlash Xbank",
+	"Capture",
+	"Action Replay 3",
+	"Retro Replay",
+}
@@ -3,4 +3,5 @@ package commodore
 type Image interface {
 	Read() error
 	DisplayGeometry()
+	Warnings() []string
 }
@@ -3,7 +3,7 @@ package t64
 import (
 	"encoding/binary"
 	"fmt"
-	"io"
+	"strings"
 
 	"retroio/storage"
 )
@@ -32,7 +32,7 @@ import (
 // - C64 filename (in PETASCII, padded with $20, not $A0)
 type Record struct {
 	Type         uint8    // C64s entry type
-	FileType     uint8    // 1541 file type: PRG: 0x82, SEQ: 0x81, etc. (USR ?)
+	FileType     uint8    // 1541 file type: DEL: 0x80, SEQ: 0x81, PRG: 0x82, USR: 0x83, REL: 0x84
 	StartAddress uint16   // Start address (or Load address)
 	EndAddress   uint16   // End address (actual end address in memory if the file was loaded into a C64).
 	Unused1      uint16   // unused value
@@ -50,13 +50,15 @@ func (r *Record) Read(reader *storage.Reader) error {
 func (r *Record) DataRead(reader *storage.Reader, dataOffset int) ([]byte, error) {
 	length := int(r.EndAddress - r.StartAddress)
 
+	// The end address is often wrong in T64 files, so a short final record is
+	// returned with the data that is available.
 	data := make([]byte, length)
-	_, err := reader.Read(data)
-	if err != nil && err != io.EOF {
+	n, err := reader.Read(data)
+	if err != nil && !storage.IsEOF(err) {
 		return nil, err
 	}
 
-	return data, nil
+	return data[:n], nil
 }
 
 func (r Record) String() string {
@@ -65,11 +67,27 @@ func (r Record) String() string {
 	str += fmt.Sprintf("Type:          %s: %s\n", r.fileTypeLabel(r.FileType), r.entryTypeLabel(r.Type))
 	str += fmt.Sprintf("Start Address: %d\n", r.StartAddress)
 	str += fmt.Sprintf("End Address:   %d\n", r.EndAddress)
-	str += fmt.Sprintf("Data length:   %d\n", r.EndAddress-r.StartAddress)
+	str += fmt.Sprintf("Data length:   %d\n", r.Size())
 	str += fmt.Sprintf("T64 Offset:    %d\n", r.Offset)
 	return str
 }
 
+// Name returns the C64 filename, without the padding.
+func (r Record) Name() string {
+	return strings.TrimRight(string(r.Filename[:]), " \xa0\x00")
+}
+
+// Size returns the length of the file data in bytes, as given by the start
+// and end addresses.
+func (r Record) Size() int {
+	return int(r.EndAddress - r.StartAddress)
+}
+
+// FileTypeName returns the 1541 file type, e.g. "PRG".
+func (r Record) FileTypeName() string {
+	return r.fileTypeLabel(r.FileType)
+}
+
 func (r Record) entryTypeLabel(id byte) string {
 	var label string
 	switch id {
@@ -91,15 +109,32 @@ func (r Record) entryTypeLabel(id byte) string {
 	return label
 }
 
+// fileTypeLabel decodes the 1541 file type from the lower 3 bits, as on a
+// D64 disk, where bit 7 is set for a properly closed file. A zero file type
+// with an entry type greater than 1 is a C64s frozen session snapshot, and
+// any other value is seen as a PRG.
 func (r Record) fileTypeLabel(id byte) string {
+	if id == 0x00 && r.Type > 0x01 {
+		return "FRZ"
+	}
+	if id&0x80 == 0 {
+		return "PRG"
+	}
+
 	var label string
-	switch id {
-	case 0x81:
+	switch id & 0x07 {
+	case 0x00:
+		label = "DEL"
+	case 0x01:
 		label = "SEQ"
-	case 0x82:
+	case 0x02:
 		label = "PRG"
+	case 0x03:
+		label = "USR"
+	case 0x04:
+		label = "REL"
 	default:
-		label = "???" // what about USR value?
+		label = "PRG"
 	}
 	return label
 }
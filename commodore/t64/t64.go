@@ -39,8 +39,11 @@ func (t *T64) Read() error {
 
 	// Read headers for the records
 	for i := 0; i < int(t.Header.UsedEntries); i++ {
+		offset := t.reader.Offset()
 		r := Record{}
-		if err := r.Read(t.reader); err != nil {
+		if err := r.Read(t.reader); storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: len(t.Records), Offset: offset}
+		} else if err != nil {
 			return fmt.Errorf("binary.Read failed: %v", err)
 		}
 		t.Records = append(t.Records, r)
@@ -58,19 +61,40 @@ func (t *T64) Read() error {
 	return nil
 }
 
-// DisplayGeometry prints the tape metadata and record headers to the terminal.
+// DisplayGeometry prints the tape metadata, and a catalog of the records with
+// their file type, load address and size, to the terminal.
 func (t T64) DisplayGeometry() {
 	fmt.Println("HEADER INFORMATION:")
 	fmt.Println(t.Header)
 
+	fmt.Println("RECORDS:")
+	fmt.Println("  #  NAME              TYPE  START  END    SIZE   OFFSET")
 	for i, r := range t.Records {
-		fmt.Printf("RECORD #%d:\n", i)
-		fmt.Println(r)
+		fmt.Printf("  %-2d %-17s %-5s $%04x  $%04x  %-6d %d\n",
+			i, fmt.Sprintf("%q", r.Name()), r.FileTypeName(), r.StartAddress, r.EndAddress, r.Size(), r.Offset)
+	}
+}
+
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read, such as more used entries than the directory can hold, or
+// records with less data than given by their start and end addresses.
+func (t T64) Warnings() []string {
+	var warnings []string
+
+	if t.Header.UsedEntries > t.Header.MaxEntries {
+		warnings = append(warnings, fmt.Sprintf(
+			"%d used entries, but the directory only has %d entries", t.Header.UsedEntries, t.Header.MaxEntries,
+		))
 	}
 
-	for i, r := range t.Data {
-		fmt.Printf("BINARY DATA #%d = %d bytes\n", i, len(r))
+	for i, data := range t.Data {
+		r := t.Records[i]
+		if length := r.Size(); len(data) != length {
+			warnings = append(warnings, fmt.Sprintf("record #%d: only %d of %d bytes read", i, len(data), length))
+		}
 	}
+
+	return warnings
 }
 
 // readDataEntries reads the data for each record.
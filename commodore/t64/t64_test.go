@@ -0,0 +1,86 @@
+package t64
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"retroio/storage"
+)
+
+func TestReadCatalog(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "multi.t64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := strings.TrimRight(string(tape.Header.Name[:]), " "); name != "MULTI" {
+		t.Errorf("got tape name %q, want %q", name, "MULTI")
+	}
+
+	tests := []struct {
+		name     string
+		fileType string
+		start    uint16
+		end      uint16
+		size     int
+		data     string
+	}{
+		{"HELLO", "PRG", 0x0801, 0x080d, 12, "\x0b\x08\x0a\x00\x99\"HI\"\x00\x00\x00"},
+		{"NOTES", "SEQ", 0xc000, 0xc009, 9, "SOME TEXT"},
+		{"SNAPSHOT", "FRZ", 0x0000, 0x0000, 0, ""},
+		{"ODD TYPE", "PRG", 0x1000, 0x1010, 16, string(make([]byte, 16))},
+	}
+
+	if len(tape.Records) != len(tests) || len(tape.Data) != len(tests) {
+		t.Fatalf("got %d records and %d data entries, want %d", len(tape.Records), len(tape.Data), len(tests))
+	}
+	for i, tt := range tests {
+		r := tape.Records[i]
+		if r.Name() != tt.name || r.FileTypeName() != tt.fileType {
+			t.Errorf("record %d: got %q %s, want %q %s", i, r.Name(), r.FileTypeName(), tt.name, tt.fileType)
+		}
+		if r.StartAddress != tt.start || r.EndAddress != tt.end || r.Size() != tt.size {
+			t.Errorf("record %d: got $%04x-$%04x, %d bytes, want $%04x-$%04x, %d bytes",
+				i, r.StartAddress, r.EndAddress, r.Size(), tt.start, tt.end, tt.size)
+		}
+		if string(tape.Data[i]) != tt.data {
+			t.Errorf("record %d: got data %q, want %q", i, tape.Data[i], tt.data)
+		}
+	}
+
+	if warnings := tape.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q, want none", warnings)
+	}
+}
+
+func TestFileTypeName(t *testing.T) {
+	tests := []struct {
+		entryType uint8
+		fileType  uint8
+		want      string
+	}{
+		{0x01, 0x80, "DEL"},
+		{0x01, 0x81, "SEQ"},
+		{0x01, 0x82, "PRG"},
+		{0x01, 0x83, "USR"},
+		{0x01, 0x84, "REL"},
+		{0x01, 0x01, "PRG"}, // any value without bit 7 set is a PRG
+		{0x01, 0x44, "PRG"},
+		{0x01, 0x00, "PRG"},
+		{0x03, 0x00, "FRZ"},
+	}
+
+	for _, tt := range tests {
+		r := Record{Type: tt.entryType, FileType: tt.fileType}
+		if got := r.FileTypeName(); got != tt.want {
+			t.Errorf("type $%02x/$%02x: got %s, want %s", tt.entryType, tt.fileType, got, tt.want)
+		}
+	}
+}
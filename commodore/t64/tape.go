@@ -0,0 +1,189 @@
+package t64
+
+import (
+	"github.com/pkg/errors"
+
+	"retroio/commodore/tap"
+)
+
+// Kernal tape header types, saved in the first byte of the header block.
+const (
+	relocatableProgram = 0x01 // BASIC program, loaded to the start of BASIC unless LOAD"",1,1
+	absoluteProgram    = 0x03 // machine code, always loaded to its start address
+)
+
+// basicStart is the load address of a BASIC program.
+const basicStart = 0x0801
+
+// headerLength is the length of the data of a Kernal header block: the type,
+// start and end addresses, and the filename padded with spaces.
+const headerLength = 192
+
+// TapeEncoding holds the pulse lengths, in clock cycles, and the number of
+// pulses of each part of a tape, used when converting to a TAP.
+//
+// KernalEncoding gives the timings of the standard Kernal tape routines, and
+// the fields can be changed for the machine or loader the tape is for.
+type TapeEncoding struct {
+	Clock int // CPU clock rate (Hz), used for the gap between files
+
+	// Pulses, each a full wave. A 0 bit is a short then a medium pulse, and a
+	// 1 bit a medium then a short pulse. A long then a medium pulse marks the
+	// start of each byte, and a long then a short pulse the end of the data.
+	Short  uint32
+	Medium uint32
+	Long   uint32
+
+	HeaderLeader int // Short pulses before the first copy of a header block
+	DataLeader   int // Short pulses before the first copy of a data block
+	RepeatLeader int // Short pulses between the first copy and the repeat
+	Trailer      int // Short pulses after the repeat
+
+	GapMs int // Silence between files (ms)
+}
+
+// KernalEncoding returns the encoding of the standard Kernal tape routines,
+// with a PAL clock rate. The pulse lengths, being counted in clock cycles by
+// the Kernal, are the same on NTSC machines, where only the gap between files
+// is slightly shorter.
+func KernalEncoding() TapeEncoding {
+	return TapeEncoding{
+		Clock:        tap.PALClock,
+		Short:        0x30 * 8,
+		Medium:       0x42 * 8,
+		Long:         0x56 * 8,
+		HeaderLeader: 0x6A00,
+		DataLeader:   0x1A00,
+		RepeatLeader: 0x4F,
+		Trailer:      0x4E,
+		GapMs:        1000,
+	}
+}
+
+// ToTAP converts the tape to a TAP, as saved by the Kernal SAVE command, for
+// writing the programs to a real Datasette, using the standard Kernal
+// encoding, see ToTAPWith.
+func (t T64) ToTAP() (*tap.TAP, error) {
+	return t.ToTAPWith(KernalEncoding())
+}
+
+// ToTAPWith converts the tape to a TAP, using the encoding. A T64 holds only
+// the program data, without any timing, so the tape is reconstructed as saved
+// by the standard Kernal loader, which any real C64 can load.
+//
+// Each program is saved as a header block, holding the type, start and end
+// addresses and the filename, followed by the data block, with each block
+// saved twice. Programs loading to $0801 are saved as relocatable BASIC
+// programs, and all others as absolute machine code. Snapshots and non-PRG
+// records can not be loaded by the Kernal, so are not included.
+func (t T64) ToTAPWith(enc TapeEncoding) (*tap.TAP, error) {
+	if enc.Short == 0 || enc.Medium == 0 || enc.Long == 0 {
+		return nil, errors.New("the pulse lengths must be greater than zero")
+	}
+
+	var pulses []uint32
+	converted := 0
+
+	for i, r := range t.Records {
+		if i >= len(t.Data) {
+			break
+		}
+		if (r.Type != 1 && r.Type != 2) || r.FileTypeName() != "PRG" {
+			continue
+		}
+		data := t.Data[i]
+
+		if converted > 0 && enc.GapMs > 0 {
+			pulses = append(pulses, uint32(int64(enc.GapMs)*int64(enc.Clock)/1000))
+		}
+		pulses = enc.block(pulses, kernalHeader(r, len(data)), enc.HeaderLeader)
+		pulses = enc.block(pulses, data, enc.DataLeader)
+		converted++
+	}
+
+	if converted == 0 {
+		return nil, errors.New("the tape has no programs that can be saved by the Kernal")
+	}
+
+	return tap.FromPulses(pulses), nil
+}
+
+// kernalHeader returns the data of the header block for the record.
+func kernalHeader(r Record, length int) []byte {
+	header := make([]byte, headerLength)
+	for i := range header {
+		header[i] = ' '
+	}
+
+	header[0] = absoluteProgram
+	if r.StartAddress == basicStart {
+		header[0] = relocatableProgram
+	}
+
+	end := int(r.StartAddress) + length
+	header[1], header[2] = byte(r.StartAddress), byte(r.StartAddress>>8)
+	header[3], header[4] = byte(end), byte(end>>8)
+	copy(header[5:21], r.Name())
+
+	return header
+}
+
+// block appends the pulses of the data saved as a Kernal block: the leader,
+// the countdown sync bytes, the data and its checksum, and the end of data
+// marker. The block is then repeated, with a shorter leader, and sync bytes
+// without the high bit set, for the loader to correct any read errors.
+func (enc TapeEncoding) block(pulses []uint32, data []byte, leader int) []uint32 {
+	var checksum byte
+	for _, b := range data {
+		checksum ^= b
+	}
+
+	for copyNumber := 0; copyNumber < 2; copyNumber++ {
+		for i := 0; i < leader; i++ {
+			pulses = append(pulses, enc.Short)
+		}
+
+		for sync := byte(9); sync >= 1; sync-- {
+			if copyNumber == 0 {
+				pulses = enc.byte(pulses, sync|0x80)
+			} else {
+				pulses = enc.byte(pulses, sync)
+			}
+		}
+		for _, b := range data {
+			pulses = enc.byte(pulses, b)
+		}
+		pulses = enc.byte(pulses, checksum)
+		pulses = append(pulses, enc.Long, enc.Short)
+
+		leader = enc.RepeatLeader
+	}
+
+	for i := 0; i < enc.Trailer; i++ {
+		pulses = append(pulses, enc.Short)
+	}
+
+	return pulses
+}
+
+// byte appends the pulses of a byte: the byte marker, the 8 bits with the
+// least significant bit first, and the odd parity bit.
+func (enc TapeEncoding) byte(pulses []uint32, b byte) []uint32 {
+	pulses = append(pulses, enc.Long, enc.Medium)
+
+	parity := byte(1)
+	for i := 0; i < 8; i++ {
+		bit := (b >> uint(i)) & 1
+		parity ^= bit
+		pulses = enc.bit(pulses, bit)
+	}
+	return enc.bit(pulses, parity)
+}
+
+// bit appends the pulses of a single bit.
+func (enc TapeEncoding) bit(pulses []uint32, bit byte) []uint32 {
+	if bit == 0 {
+		return append(pulses, enc.Short, enc.Medium)
+	}
+	return append(pulses, enc.Medium, enc.Short)
+}
@@ -0,0 +1,58 @@
+package tap
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// signature identifies the file as a C64 TAP tape.
+const signature = "C64-TAPE-RAW"
+
+// maxOverflow is the longest pulse stored by a single overflow, in cycles.
+const maxOverflow = 0xFFFFFF
+
+// FromPulses returns a version 1 tape of the full wave pulses, each given in
+// clock cycles. Pulses too long for a single byte, such as pauses, are stored
+// as an overflow: a zero byte followed by the exact length in 3 bytes, split
+// into several overflows when longer than 3 bytes can hold.
+func FromPulses(pulses []uint32) *TAP {
+	t := &TAP{Version: 1}
+	copy(t.Signature[:], signature)
+
+	for _, length := range pulses {
+		for length > maxOverflow {
+			t.Data = append(t.Data, 0, 0xFF, 0xFF, 0xFF)
+			length -= maxOverflow
+		}
+		units := (length + 4) / 8
+		if units == 0 || units > 0xFF {
+			t.Data = append(t.Data, 0, byte(length), byte(length>>8), byte(length>>16))
+			continue
+		}
+		t.Data = append(t.Data, byte(units))
+	}
+	t.DataSize = uint32(len(t.Data))
+
+	return t
+}
+
+// Write writes the tape as a TAP file: the 20 byte header, followed by the
+// pulse data.
+func (t TAP) Write(w io.Writer) error {
+	header := struct {
+		Signature [12]byte
+		Version   uint8
+		Unused    [3]byte
+		DataSize  uint32
+	}{t.Signature, t.Version, t.Unused, uint32(len(t.Data))}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return errors.Wrap(err, "unable to write the TAP header")
+	}
+	if _, err := w.Write(t.Data); err != nil {
+		return errors.Wrap(err, "unable to write the TAP data")
+	}
+	return nil
+}
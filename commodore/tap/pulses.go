@@ -0,0 +1,59 @@
+package tap
+
+// Pulses returns the length of each pulse in clock cycles.
+//
+// In version 0 and 1 tapes each pulse is a full square wave cycle, while in
+// version 2 tapes, made from the C16 and other machines which can trigger on
+// both edges of the signal, each pulse is a half-wave, see HalfWaves.
+//
+// Each data byte is the pulse length in units of 8 clock cycles. A zero byte
+// is an overflow: in version 0 tapes the pulse is longer than 255*8 cycles,
+// and given as 256*8 cycles, and in later versions it is followed by the exact
+// length in 3 bytes (little endian), not divided by 8. A truncated overflow at
+// the end of the data is ignored.
+func (t TAP) Pulses() []uint32 {
+	var pulses []uint32
+
+	for i := 0; i < len(t.Data); i++ {
+		length := uint32(t.Data[i]) * 8
+		if t.Data[i] == 0 {
+			if t.Version == 0 {
+				length = overflowCycles
+			} else if i+3 < len(t.Data) {
+				length = uint32(t.Data[i+1]) | uint32(t.Data[i+2])<<8 | uint32(t.Data[i+3])<<16
+				i += 3
+			} else {
+				break // truncated overflow
+			}
+		}
+		pulses = append(pulses, length)
+	}
+
+	return pulses
+}
+
+// HalfWaves reports whether each pulse is a half-wave, as in version 2 tapes,
+// rather than a full square wave cycle.
+func (t TAP) HalfWaves() bool {
+	return t.Version == 2
+}
+
+// PulseCount returns the number of full wave pulses, where each pair of half
+// waves of a version 2 tape counts as one pulse.
+func (t TAP) PulseCount() int {
+	count := len(t.Pulses())
+	if t.HalfWaves() {
+		count /= 2
+	}
+	return count
+}
+
+// Duration returns the playing time of the tape in seconds, at the given
+// clock rate.
+func (t TAP) Duration(clock int) float64 {
+	var cycles uint64
+	for _, length := range t.Pulses() {
+		cycles += uint64(length)
+	}
+	return float64(cycles) / float64(clock)
+}
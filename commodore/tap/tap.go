@@ -17,7 +17,6 @@ package tap
 
 import (
 	"fmt"
-	"io"
 
 	"retroio/storage"
 )
@@ -27,7 +26,7 @@ type TAP struct {
 	reader *storage.Reader
 
 	Signature [12]byte // File signature "C64-TAPE-RAW"
-	Version   uint8    // TAP version: $00 original layout, $01 updated.
+	Version   uint8    // TAP version: $00 original layout, $01 updated, $02 half-waves.
 	Unused    [3]byte  // Future expansion
 	DataSize  uint32   // File data size (not including this header)
 	Data      []byte   // File data: 0014-xxxx
@@ -41,17 +40,23 @@ func (t *TAP) Read() error {
 	if _, err := t.reader.Read(t.Signature[:]); err != nil {
 		return err
 	}
-	t.Version = t.reader.ReadByte()
+	t.Version = t.reader.ReadUint8()
 	if _, err := t.reader.Read(t.Unused[:]); err != nil {
 		return err
 	}
 	t.DataSize = t.reader.ReadLong()
 
+	if err := t.reader.Err(); err != nil {
+		return storage.TruncatedError{Blocks: 0, Offset: t.reader.Offset()}
+	}
+
+	// A truncated data section is kept, and reported as a size mismatch.
 	t.Data = make([]byte, t.DataSize)
-	_, err := t.reader.Read(t.Data)
-	if err != nil && err != io.EOF {
+	n, err := t.reader.Read(t.Data)
+	if err != nil && !storage.IsEOF(err) {
 		return err
 	}
+	t.Data = t.Data[:n]
 
 	return nil
 }
@@ -67,13 +72,37 @@ func (t TAP) String() string {
 	str += fmt.Sprintf("Signature  %s\n", t.Signature)
 	str += fmt.Sprintf("Version:   $%02x (%s)\n", t.Version, t.tapType(t.Version))
 	str += fmt.Sprintf("Data Size: %d bytes\n", t.DataSize)
+	if t.Version <= 2 {
+		str += fmt.Sprintf("Pulses:    %d", t.PulseCount())
+		if t.HalfWaves() {
+			str += fmt.Sprintf(" (%d half-waves)", len(t.Pulses()))
+		}
+		str += "\n"
+		str += fmt.Sprintf("Duration:  %.2f seconds (PAL)\n", t.Duration(PALClock))
+	}
+	return str
+}
+
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read, such as a data size that differs from the header, or an
+// unknown version.
+func (t TAP) Warnings() []string {
+	var warnings []string
 
 	dataLenDiff := int(t.DataSize) - len(t.Data)
 	if dataLenDiff != 0 {
-		str += "\n"
-		str += fmt.Sprintf("WARNING: data size mismatch, found %d bytes, %d difference\n", len(t.Data), dataLenDiff)
+		warnings = append(warnings, fmt.Sprintf(
+			"data size mismatch, found %d bytes, %d difference", len(t.Data), dataLenDiff,
+		))
 	}
-	return str
+
+	if t.Version > 2 {
+		warnings = append(warnings, fmt.Sprintf(
+			"unknown TAP version $%02x, the pulses can not be decoded", t.Version,
+		))
+	}
+
+	return warnings
 }
 
 func (t TAP) tapType(id byte) string {
@@ -83,6 +112,8 @@ func (t TAP) tapType(id byte) string {
 		label = "Original Layout"
 	case 0x01:
 		label = "Updated Layout"
+	case 0x02:
+		label = "Half-wave Layout"
 	default:
 		label = "Unknown Layout"
 	}
@@ -0,0 +1,65 @@
+package tap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"retroio/storage"
+)
+
+func TestReadVersions(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   uint8
+		pulses    []uint32
+		count     int
+		halfWaves bool
+		layout    string
+	}{
+		{"v0.tap", 0, []uint32{384, 512, overflowCycles, 384}, 4, false, "Original Layout"},
+		{"v1.tap", 1, []uint32{384, 10000, 512}, 3, false, "Updated Layout"},
+		{"v2.tap", 2, []uint32{192, 192, 256, 256, 10000, 384}, 3, true, "Half-wave Layout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tape := New(storage.NewReader(bytes.NewReader(data)))
+			if err := tape.Read(); err != nil {
+				t.Fatal(err)
+			}
+
+			if tape.Version != tt.version {
+				t.Errorf("got version %d, want %d", tape.Version, tt.version)
+			}
+			if pulses := tape.Pulses(); !reflect.DeepEqual(pulses, tt.pulses) {
+				t.Errorf("got pulses %v, want %v", pulses, tt.pulses)
+			}
+			if tape.PulseCount() != tt.count || tape.HalfWaves() != tt.halfWaves {
+				t.Errorf("got %d pulses, half-waves %v, want %d, %v", tape.PulseCount(), tape.HalfWaves(), tt.count, tt.halfWaves)
+			}
+
+			var cycles uint32
+			for _, p := range tt.pulses {
+				cycles += p
+			}
+			if d := tape.Duration(PALClock); d != float64(cycles)/PALClock {
+				t.Errorf("got duration %f, want %f", d, float64(cycles)/PALClock)
+			}
+
+			if !strings.Contains(tape.String(), tt.layout) {
+				t.Errorf("geometry does not give the %q version", tt.layout)
+			}
+			if len(tape.Warnings()) != 0 {
+				t.Errorf("got warnings %q, want none", tape.Warnings())
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package tap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// C64 CPU clock rates (Hz), used for the pulse lengths, which are given in
+// clock cycles.
+const (
+	PALClock  = 985248  // PAL: 17.734475 MHz crystal / 18
+	NTSCClock = 1022727 // NTSC: 14.31818 MHz crystal / 14
+)
+
+// overflowCycles is the pulse length used for a version 0 overflow byte
+// (0x00), which only records that the pulse was longer than 255*8 cycles.
+const overflowCycles = 256 * 8
+
+// Sample values for the low and high signal levels, as 8-bit unsigned samples.
+const (
+	lowLevel  = 0x20
+	highLevel = 0xe0
+)
+
+// ExportWAV writes the tape as a mono 8-bit PCM WAV file, for writing to a
+// real Datasette, at the sample rate, using the PAL or NTSC clock rate.
+//
+// Each full wave pulse is played as half a cycle high followed by half a cycle
+// low, while the half-wave pulses of version 2 tapes alternate between high
+// and low. See Pulses for how the pulse lengths are decoded.
+func (t TAP) ExportWAV(w io.Writer, sampleRate int, pal bool) error {
+	if t.Version > 2 {
+		return errors.Errorf("unsupported TAP version $%02x for WAV export", t.Version)
+	}
+	if sampleRate <= 0 {
+		return errors.Errorf("invalid sample rate: %d", sampleRate)
+	}
+
+	clock := float64(NTSCClock)
+	if pal {
+		clock = PALClock
+	}
+
+	var samples []byte
+	var cycles float64 // total length of the played pulses
+
+	play := func(length float64, level byte) {
+		cycles += length
+		end := int(cycles * float64(sampleRate) / clock)
+		for len(samples) < end {
+			samples = append(samples, level)
+		}
+	}
+
+	level := byte(highLevel)
+	for _, length := range t.Pulses() {
+		if t.HalfWaves() {
+			play(float64(length), level)
+			if level == highLevel {
+				level = lowLevel
+			} else {
+				level = highLevel
+			}
+			continue
+		}
+
+		play(float64(length)/2, highLevel)
+		play(float64(length)/2, lowLevel)
+	}
+
+	return writeWAV(w, sampleRate, samples)
+}
+
+// writeWAV writes the WAV header and the 8-bit mono samples.
+func writeWAV(w io.Writer, sampleRate int, samples []byte) error {
+	buf := &bytes.Buffer{}
+
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+len(samples)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(buf, binary.LittleEndian, struct {
+		Size          uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}{16, 1, 1, uint32(sampleRate), uint32(sampleRate), 1, 8})
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(samples)))
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "unable to write WAV header")
+	}
+	if _, err := bw.Write(samples); err != nil {
+		return errors.Wrap(err, "unable to write WAV data")
+	}
+	return bw.Flush()
+}
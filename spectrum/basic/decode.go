@@ -6,11 +6,52 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// Line is a single line of a BASIC program, with the tokenized data as
+// stored in memory, including the ENTER (0x0D) at the end of the line.
+type Line struct {
+	Number uint16
+	Data   []byte
+}
+
+// String returns the line number followed by the decoded line.
+func (l Line) String() string {
+	return fmt.Sprintf("%4d %s", l.Number, decodeBasicBytes(l.Data))
+}
+
+// Text returns the decoded line, without the line number.
+func (l Line) Text() string {
+	return strings.TrimSpace(decodeBasicBytes(l.Data))
+}
+
+// Bytes returns the line as stored in memory: the line number (big endian),
+// the length of the line data (little endian), followed by the data.
+func (l Line) Bytes() []byte {
+	b := make([]byte, 4, 4+len(l.Data))
+	binary.BigEndian.PutUint16(b[0:2], l.Number)
+	binary.LittleEndian.PutUint16(b[2:4], uint16(len(l.Data)))
+	return append(b, l.Data...)
+}
+
 // Decode as ZX Spectrum BASIC program
 func Decode(programData []byte) ([]string, error) {
+	lines, err := DecodeLines(programData)
+	if err != nil {
+		return nil, err
+	}
+
 	var basic []string
+	for _, line := range lines {
+		basic = append(basic, line.String())
+	}
+	return basic, nil
+}
+
+// DecodeLines splits the ZX Spectrum BASIC program into its lines.
+func DecodeLines(programData []byte) ([]Line, error) {
+	var lines []Line
 
 	reader := bytes.NewReader(programData)
 	for {
@@ -20,23 +61,29 @@ func Decode(programData []byte) ([]string, error) {
 		if err != nil {
 			if err == io.EOF {
 				// all done, return the results
-				return basic, nil
+				return lines, nil
 			} else if err != io.ErrUnexpectedEOF {
 				return nil, err
 			}
 		}
 
 		data := make([]byte, lineLen)
-		_, err = reader.Read(data)
+		n, err := reader.Read(data)
 		if err != nil && err != io.EOF {
 			return nil, err
 		}
 
-		basicString := decodeBasicBytes(data)
-		line := fmt.Sprintf("%4d %s", lineNum, basicString)
+		lines = append(lines, Line{Number: lineNum, Data: data[:n]})
+	}
+}
 
-		basic = append(basic, line)
+// EncodeLines joins the lines into a BASIC program, as stored in memory.
+func EncodeLines(lines []Line) []byte {
+	var program []byte
+	for _, line := range lines {
+		program = append(program, line.Bytes()...)
 	}
+	return program
 }
 
 func getLineNumAndLen(reader *bytes.Reader) (lineNumber, lineLength uint16, err error) {
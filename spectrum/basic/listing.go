@@ -0,0 +1,20 @@
+package basic
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// WriteListing writes the decoded lines of a program, as returned by Decode,
+// as a text file. Each line is ended with a single LF, and the padding left
+// after any trailing keyword is removed.
+func WriteListing(w io.Writer, lines []string) error {
+	buf := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := buf.WriteString(strings.TrimRight(line, " \r\n") + "\n"); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
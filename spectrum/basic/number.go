@@ -0,0 +1,47 @@
+package basic
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// NumberLength is the length of a number in the ROM's 5 byte floating point
+// form, as hidden after each number in a BASIC line, and as stored for the
+// numeric variables.
+const NumberLength = 5
+
+// DecodeNumber returns the value of a number in the 5 byte form used by the
+// ROM calculator.
+//
+// Small integers, from -65535 to 65535, are stored with a zero exponent byte,
+// followed by a sign byte (0x00 or 0xFF), the value as a little endian word
+// (as two's complement for negative numbers), and a zero byte.
+//
+// All other numbers are stored as a floating point number: the exponent byte,
+// biased by 128, followed by the 4 byte big endian mantissa, with the leading
+// 1 bit of the mantissa replaced by the sign bit.
+func DecodeNumber(b []byte) (float64, error) {
+	if len(b) < NumberLength {
+		return 0, errors.Errorf("expected %d bytes for a number, got %d", NumberLength, len(b))
+	}
+
+	if b[0] == 0 {
+		value := float64(binary.LittleEndian.Uint16(b[2:4]))
+		if b[1] == 0xFF {
+			value -= 65536
+		}
+		return value, nil
+	}
+
+	mantissa := binary.BigEndian.Uint32(b[1:5])
+	negative := mantissa&0x80000000 != 0
+	mantissa |= 0x80000000
+
+	value := math.Ldexp(float64(mantissa), int(b[0])-128-32)
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
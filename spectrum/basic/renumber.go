@@ -0,0 +1,200 @@
+package basic
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// MaxLineNumber is the highest line number allowed by ZX Spectrum BASIC.
+const MaxLineNumber = 9999
+
+// Tokens followed by a line number, whose targets are rewritten when renumbering.
+var lineNumberTokens = map[byte]bool{
+	0xCA: true, // LINE, as in SAVE "name" LINE 10
+	0xE1: true, // LLIST
+	0xE5: true, // RESTORE
+	0xEC: true, // GO TO
+	0xED: true, // GO SUB
+	0xF0: true, // LIST
+	0xF7: true, // RUN
+}
+
+// Tokens and control codes found in the line data.
+const (
+	tokenNumber = 0x0E // followed by the 5 byte hidden binary number
+	tokenEnter  = 0x0D
+	tokenQuote  = '"'
+	tokenColon  = ':'
+	tokenREM    = 0xEA
+)
+
+// Renumber reassigns the line numbers, starting at start and incrementing by
+// step, and rewrites the targets of any GO TO, GO SUB, RESTORE, RUN, LIST,
+// LLIST and LINE that are literal line numbers.
+//
+// Each literal number is stored as the ASCII digits, followed by the 0x0E
+// marker and the hidden 5 byte binary number, which is the value actually
+// used by the ROM, so both are rewritten. Targets that are expressions, such as
+// GO TO 100+x, can not be renumbered, and are left unchanged. A target with no
+// matching line is changed to the line that follows it, as this is the line
+// the ROM jumps to.
+//
+// An error is returned when the last line number would be greater than 9999.
+func Renumber(lines []Line, start, step uint16) ([]Line, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	if step == 0 {
+		return nil, errors.New("the line number step must be greater than zero")
+	}
+
+	last := int(start) + (len(lines)-1)*int(step)
+	if last > MaxLineNumber {
+		return nil, errors.Errorf("renumbering %d lines from %d by %d exceeds the maximum line number, %d > %d",
+			len(lines), start, step, last, MaxLineNumber)
+	}
+
+	renumbered := make([]Line, len(lines))
+	for i := range lines {
+		renumbered[i].Number = start + uint16(i)*step
+	}
+
+	// all the new numbers are needed first, for the forward jumps
+	for i, line := range lines {
+		renumbered[i].Data = renumberTargets(line.Data, lines, renumbered)
+	}
+
+	return renumbered, nil
+}
+
+// renumberTargets returns a copy of the line data with the literal line number
+// targets changed to the new line numbers.
+func renumberTargets(data []byte, lines, renumbered []Line) []byte {
+	var out []byte
+
+	for pos := 0; pos < len(data); {
+		char := data[pos]
+
+		switch {
+		case char == tokenREM:
+			return append(out, data[pos:]...)
+		case char == tokenQuote:
+			end := pos + 1
+			for end < len(data) && data[end] != tokenQuote && data[end] != tokenEnter {
+				end++
+			}
+			if end < len(data) && data[end] == tokenQuote {
+				end++
+			}
+			out = append(out, data[pos:end]...)
+			pos = end
+			continue
+		case char == tokenNumber:
+			end := pos + 6
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[pos:end]...)
+			pos = end
+			continue
+		case char >= 0x10 && char <= 0x15:
+			end := pos + 2
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[pos:end]...)
+			pos = end
+			continue
+		case char == 0x16, char == 0x17:
+			end := pos + 3
+			if end > len(data) {
+				end = len(data)
+			}
+			out = append(out, data[pos:end]...)
+			pos = end
+			continue
+		}
+
+		out = append(out, char)
+		pos++
+
+		if !lineNumberTokens[char] {
+			continue
+		}
+
+		target, end, ok := literalLineNumber(data, pos)
+		if !ok {
+			continue
+		}
+
+		// any spaces before the number are kept
+		for pos < len(data) && data[pos] == ' ' {
+			out = append(out, ' ')
+			pos++
+		}
+		out = append(out, encodeNumber(newLineNumber(target, lines, renumbered))...)
+		pos = end
+	}
+
+	return out
+}
+
+// literalLineNumber returns the literal number following a token at pos, and
+// the position following its hidden binary number. Numbers that are part of
+// an expression, or not stored as a small integer, are not returned.
+func literalLineNumber(data []byte, pos int) (uint16, int, bool) {
+	for pos < len(data) && data[pos] == ' ' {
+		pos++
+	}
+
+	digits := pos
+	for pos < len(data) && data[pos] >= '0' && data[pos] <= '9' {
+		pos++
+	}
+	if pos == digits || pos+6 > len(data) || data[pos] != tokenNumber {
+		return 0, 0, false
+	}
+
+	// small integers are stored as: 0x00, sign, low byte, high byte, 0x00
+	number := data[pos+1 : pos+6]
+	if number[0] != 0x00 || number[1] != 0x00 || number[4] != 0x00 {
+		return 0, 0, false
+	}
+	pos += 6
+
+	// the number must be the whole statement, not the start of an expression
+	if pos < len(data) && data[pos] != tokenColon && data[pos] != tokenEnter {
+		return 0, 0, false
+	}
+
+	return uint16(number[2]) | uint16(number[3])<<8, pos, true
+}
+
+// newLineNumber returns the new number of the line the target jumps to, which
+// is the first line with a number equal to, or greater than, the target.
+// Targets after the last line are changed to follow the new last line, so they
+// still stop the program.
+func newLineNumber(target uint16, lines, renumbered []Line) uint16 {
+	for i, line := range lines {
+		if line.Number >= target {
+			return renumbered[i].Number
+		}
+	}
+
+	last := renumbered[len(renumbered)-1].Number
+	if target > last {
+		return target
+	}
+	if last < MaxLineNumber {
+		return last + 1
+	}
+	return last
+}
+
+// encodeNumber returns the ASCII digits of the number, followed by the hidden
+// 5 byte binary number, as a small integer.
+func encodeNumber(n uint16) []byte {
+	b := []byte(strconv.Itoa(int(n)))
+	return append(b, tokenNumber, 0x00, 0x00, byte(n), byte(n>>8), 0x00)
+}
@@ -0,0 +1,91 @@
+package basic
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+// number returns a literal number as stored in a line: the ASCII digits,
+// followed by the hidden binary number as a small integer.
+func number(n int) []byte {
+	return append([]byte(strconv.Itoa(n)), 0x0E, 0x00, 0x00, byte(n), byte(n>>8), 0x00)
+}
+
+// line joins the parts into the line data, ending with ENTER.
+func line(parts ...[]byte) []byte {
+	return append(bytes.Join(parts, nil), 0x0D)
+}
+
+func TestRenumber(t *testing.T) {
+	const (
+		tokenGoTo    = 0xEC
+		tokenGoSub   = 0xED
+		tokenRestore = 0xE5
+		tokenPrint   = 0xF5
+		tokenReturn  = 0xFE
+	)
+
+	program := []Line{
+		{10, line([]byte{tokenPrint, '"', 'H', 'I', '"', ':', tokenGoSub}, number(40))},
+		{20, line([]byte{tokenGoTo}, number(25))},
+		{30, line([]byte{tokenREM, tokenGoTo}, []byte("10"))},
+		{40, line([]byte{tokenRestore}, number(50), []byte{':', tokenReturn})},
+		{50, line([]byte{tokenGoTo}, number(10))},
+		{60, line([]byte{tokenGoTo}, number(10), []byte{'+', 'x'})},
+		{70, line([]byte{tokenGoTo}, number(9000))},
+	}
+
+	want := []Line{
+		{100, line([]byte{tokenPrint, '"', 'H', 'I', '"', ':', tokenGoSub}, number(130))}, // forward
+		{110, line([]byte{tokenGoTo}, number(120))},                                       // no line 25, the following line
+		{120, line([]byte{tokenREM, tokenGoTo}, []byte("10"))},                            // REM is unchanged
+		{130, line([]byte{tokenRestore}, number(140), []byte{':', tokenReturn})},          // forward
+		{140, line([]byte{tokenGoTo}, number(100))},                                       // backward
+		{150, line([]byte{tokenGoTo}, number(10), []byte{'+', 'x'})},                      // expression is unchanged
+		{160, line([]byte{tokenGoTo}, number(9000))},                                      // after the last line
+	}
+
+	got, err := Renumber(program, 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Number != want[i].Number || !bytes.Equal(got[i].Data, want[i].Data) {
+			t.Errorf("line %d:\n got %d % X\nwant %d % X", i, got[i].Number, got[i].Data, want[i].Number, want[i].Data)
+		}
+	}
+
+	// the original lines are not changed
+	if program[0].Number != 10 || !bytes.Equal(program[0].Data[7:], line(number(40))) {
+		t.Errorf("the original line was changed: %d % X", program[0].Number, program[0].Data)
+	}
+}
+
+func TestRenumberErrors(t *testing.T) {
+	lines := []Line{{10, []byte{0x0D}}, {20, []byte{0x0D}}, {30, []byte{0x0D}}}
+
+	tests := []struct {
+		name  string
+		start uint16
+		step  uint16
+		ok    bool
+	}{
+		{"last line is 9999", 9979, 10, true},
+		{"last line over 9999", 9980, 10, false},
+		{"step over 9999", 1, 5000, false},
+		{"zero step", 10, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Renumber(lines, tt.start, tt.step)
+			if (err == nil) != tt.ok {
+				t.Errorf("got error %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
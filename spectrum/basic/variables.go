@@ -0,0 +1,272 @@
+package basic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// variablesEnd marks the end of the variables area.
+const variablesEnd = 0x80
+
+// VariableType is the kind of variable, given by the top 3 bits of the first
+// byte of each variable, the lower 5 bits being the letter of the name.
+type VariableType uint8
+
+const (
+	StringVariable  VariableType = 0x40 // 010: string, e.g. a$
+	NumberVariable  VariableType = 0x60 // 011: number with a single letter name
+	NumberArray     VariableType = 0x80 // 100: array of numbers, e.g. a(10)
+	LongNumber      VariableType = 0xA0 // 101: number with a longer name
+	CharacterArray  VariableType = 0xC0 // 110: array of characters, e.g. a$(10)
+	ForLoopVariable VariableType = 0xE0 // 111: control variable of a FOR loop
+)
+
+// Masks of the first byte of a variable.
+const (
+	variableTypeMask   = 0xE0
+	variableLetterMask = 0x1F
+)
+
+// Variable is a single variable from the variables area of a BASIC program,
+// saved with the program, holding its state when it was saved.
+type Variable struct {
+	Type VariableType
+	Name string // Lower case name, with a $ for strings and character arrays
+
+	Value      float64   // Numbers, and the value of a FOR loop
+	Text       string    // Strings
+	Dimensions []uint16  // Arrays, the size of each dimension
+	Numbers    []float64 // Number arrays, the elements with the last index varying fastest
+	Characters []byte    // Character arrays, the elements with the last index varying fastest
+
+	// FOR loops
+	Limit     float64
+	Step      float64
+	Line      uint16 // Line of the FOR statement, looped back to by NEXT
+	Statement uint8  // Statement number within the line, after the FOR statement
+}
+
+// String returns the variable as it could be assigned in BASIC, or the FOR
+// statement for loop variables. Array elements are listed in storage order.
+func (v Variable) String() string {
+	switch v.Type {
+	case StringVariable:
+		return fmt.Sprintf("%s = %q", v.Name, v.Text)
+	case NumberArray, CharacterArray:
+		var dims []string
+		for _, d := range v.Dimensions {
+			dims = append(dims, strconv.Itoa(int(d)))
+		}
+		name := fmt.Sprintf("%s(%s)", v.Name, strings.Join(dims, ","))
+		if v.Type == CharacterArray {
+			return fmt.Sprintf("%s = %q", name, decodeText(v.Characters))
+		}
+		var values []string
+		for _, n := range v.Numbers {
+			values = append(values, formatNumber(n))
+		}
+		return fmt.Sprintf("%s = %s", name, strings.Join(values, ", "))
+	case ForLoopVariable:
+		return fmt.Sprintf("FOR %s = %s TO %s STEP %s, line %d:%d",
+			v.Name, formatNumber(v.Value), formatNumber(v.Limit), formatNumber(v.Step), v.Line, v.Statement)
+	default:
+		return fmt.Sprintf("%s = %s", v.Name, formatNumber(v.Value))
+	}
+}
+
+// DecodeVariables decodes the variables area of the program data, which
+// starts at the variables offset given in the program header, directly after
+// the program lines, and ends with an 0x80 byte. No variables are returned
+// when the offset is at the end of the data.
+//
+// Each variable is stored as a letter byte, with the variable type in the top
+// 3 bits, followed by:
+//
+//	number:          5 byte value
+//	long number:     the rest of the name, the last character with bit 7
+//	                 set, then the 5 byte value
+//	string:          2 byte length, then the text
+//	number array:    2 byte length of the rest, 1 byte number of dimensions,
+//	                 2 bytes for each dimension, then 5 bytes per element
+//	character array: as a number array, with 1 byte per element
+//	FOR loop:        5 byte value, limit and step, 2 byte line number, and
+//	                 1 byte statement number
+//
+// The variables decoded before any error are returned with the error.
+func DecodeVariables(data []byte, varOffset uint16) ([]Variable, error) {
+	if int(varOffset) > len(data) {
+		return nil, errors.Errorf("variables offset %d is beyond the end of the program data, %d bytes", varOffset, len(data))
+	}
+
+	var variables []Variable
+	for pos := int(varOffset); pos < len(data) && data[pos] != variablesEnd; {
+		variable, length, err := decodeVariable(data[pos:])
+		if err != nil {
+			return variables, errors.Wrapf(err, "variable at offset %d", pos)
+		}
+		variables = append(variables, variable)
+		pos += length
+	}
+
+	return variables, nil
+}
+
+// decodeVariable decodes the variable at the start of the data, returning
+// the variable and its length.
+func decodeVariable(data []byte) (Variable, int, error) {
+	v := Variable{
+		Type: VariableType(data[0] & variableTypeMask),
+		Name: string(rune('a' - 1 + data[0]&variableLetterMask)),
+	}
+	pos := 1
+
+	need := func(n int) error {
+		if pos+n > len(data) {
+			return errors.Errorf("%s is incomplete, expected %d more bytes, got %d", v.Name, n, len(data)-pos)
+		}
+		return nil
+	}
+	number := func() float64 {
+		n, _ := DecodeNumber(data[pos : pos+NumberLength])
+		pos += NumberLength
+		return n
+	}
+	word := func() uint16 {
+		w := binary.LittleEndian.Uint16(data[pos : pos+2])
+		pos += 2
+		return w
+	}
+
+	switch v.Type {
+	case NumberVariable:
+		if err := need(NumberLength); err != nil {
+			return v, 0, err
+		}
+		v.Value = number()
+
+	case LongNumber:
+		for {
+			if err := need(1); err != nil {
+				return v, 0, err
+			}
+			char := data[pos]
+			pos++
+			v.Name += strings.ToLower(string(rune(char & 0x7F)))
+			if char&0x80 != 0 {
+				break
+			}
+		}
+		if err := need(NumberLength); err != nil {
+			return v, 0, err
+		}
+		v.Value = number()
+
+	case StringVariable:
+		v.Name += "$"
+		if err := need(2); err != nil {
+			return v, 0, err
+		}
+		length := int(word())
+		if err := need(length); err != nil {
+			return v, 0, err
+		}
+		v.Text = decodeText(data[pos : pos+length])
+		pos += length
+
+	case NumberArray, CharacterArray:
+		if v.Type == CharacterArray {
+			v.Name += "$"
+		}
+		if err := need(3); err != nil {
+			return v, 0, err
+		}
+		length := int(word())
+		if err := need(length); err != nil {
+			return v, 0, err
+		}
+		end := pos + length
+
+		count := int(data[pos])
+		pos++
+		if pos+2*count > end {
+			return v, 0, errors.Errorf("%s has %d dimensions, which overrun the array", v.Name, count)
+		}
+		elements := 1
+		for i := 0; i < count; i++ {
+			d := word()
+			v.Dimensions = append(v.Dimensions, d)
+			elements *= int(d)
+		}
+
+		size := 1
+		if v.Type == NumberArray {
+			size = NumberLength
+		}
+		if pos+elements*size > end {
+			return v, 0, errors.Errorf("%s has %d elements, which overrun the array", v.Name, elements)
+		}
+		for i := 0; i < elements; i++ {
+			if v.Type == NumberArray {
+				v.Numbers = append(v.Numbers, number())
+			} else {
+				v.Characters = append(v.Characters, data[pos])
+				pos++
+			}
+		}
+		pos = end
+
+	case ForLoopVariable:
+		if err := need(3*NumberLength + 3); err != nil {
+			return v, 0, err
+		}
+		v.Value = number()
+		v.Limit = number()
+		v.Step = number()
+		v.Line = word()
+		v.Statement = data[pos]
+		pos++
+
+	default:
+		return v, 0, errors.Errorf("unknown variable type, letter byte 0x%02X", data[0])
+	}
+
+	return v, pos, nil
+}
+
+// decodeText returns the characters of a string, without any keyword padding.
+func decodeText(data []byte) string {
+	var text strings.Builder
+	for _, char := range data {
+		text.WriteString(CharacterSet[char])
+	}
+	return text.String()
+}
+
+// formatNumber returns the number as printed by BASIC, with up to 9
+// significant digits.
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'g', 9, 64)
+}
+
+// ListVariables returns the listing of a variables area, as shown after the
+// program lines, with a line for each variable, or an empty string when there
+// are no variables. Any error is shown after the variables decoded before it.
+func ListVariables(area []byte) string {
+	variables, err := DecodeVariables(area, 0)
+	if len(variables) == 0 && err == nil {
+		return ""
+	}
+
+	listing := "VARIABLES:\n"
+	for _, v := range variables {
+		listing += fmt.Sprintf("    %s\n", v)
+	}
+	if err != nil {
+		listing += fmt.Sprintf("    %s\n", err)
+	}
+	return listing
+}
@@ -0,0 +1,121 @@
+package basic
+
+import (
+	"testing"
+)
+
+func TestDecodeVariables(t *testing.T) {
+	// 10 CLS, as the program lines before the variables
+	program := []byte{0x00, 0x0A, 0x02, 0x00, 0xFB, 0x0D}
+
+	tests := []struct {
+		name string
+		area []byte
+		want []Variable
+	}{
+		{"no variables", []byte{0x80}, nil},
+		{"small integer", []byte{0x61, 0x00, 0x00, 0x05, 0x00, 0x00, 0x80}, []Variable{
+			{Type: NumberVariable, Name: "a", Value: 5},
+		}},
+		{"negative small integer", []byte{0x7A, 0x00, 0xFF, 0x9C, 0xFF, 0x00, 0x80}, []Variable{
+			{Type: NumberVariable, Name: "z", Value: -100},
+		}},
+		{"floating point", []byte{0x62, 0x81, 0x40, 0x00, 0x00, 0x00, 0x80}, []Variable{
+			{Type: NumberVariable, Name: "b", Value: 1.5},
+		}},
+		{"negative floating point", []byte{0x62, 0x82, 0xA0, 0x00, 0x00, 0x00, 0x80}, []Variable{
+			{Type: NumberVariable, Name: "b", Value: -2.5},
+		}},
+		{"long name", []byte{0xB3, 'C', 'O', 'R', 0xC5, 0x00, 0x00, 0x10, 0x27, 0x00, 0x80}, []Variable{
+			{Type: LongNumber, Name: "score", Value: 10000},
+		}},
+		{"string", []byte{0x41, 0x05, 0x00, 'H', 'E', 'L', 'L', 'O', 0x80}, []Variable{
+			{Type: StringVariable, Name: "a$", Text: "HELLO"},
+		}},
+		{"empty string", []byte{0x4E, 0x00, 0x00, 0x80}, []Variable{
+			{Type: StringVariable, Name: "n$", Text: ""},
+		}},
+		{"string with a keyword and a graphic", []byte{0x54, 0x03, 0x00, 'A', 0xF5, 0x8F, 0x80}, []Variable{
+			{Type: StringVariable, Name: "t$", Text: "APRINT█"},
+		}},
+		{"number and string", []byte{
+			0x41, 0x02, 0x00, 'H', 'I',
+			0x61, 0x00, 0x00, 0x2A, 0x00, 0x00,
+			0x80,
+		}, []Variable{
+			{Type: StringVariable, Name: "a$", Text: "HI"},
+			{Type: NumberVariable, Name: "a", Value: 42},
+		}},
+		{"no end marker", []byte{0x61, 0x00, 0x00, 0x07, 0x00, 0x00}, []Variable{
+			{Type: NumberVariable, Name: "a", Value: 7},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(append([]byte{}, program...), tt.area...)
+			got, err := DecodeVariables(data, uint16(len(program)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d variables, want %d", len(got), len(tt.want))
+			}
+			for i, v := range got {
+				want := tt.want[i]
+				if v.Type != want.Type || v.Name != want.Name || v.Value != want.Value || v.Text != want.Text {
+					t.Errorf("got variable %d of type %02X %q = %v %q, want type %02X %q = %v %q",
+						i+1, v.Type, v.Name, v.Value, v.Text, want.Type, want.Name, want.Value, want.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeVariablesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		varOffset uint16
+		decoded   int // variables decoded before the error
+	}{
+		{"offset beyond the data", []byte{0x80}, 2, 0},
+		{"truncated number", []byte{0x61, 0x00, 0x00, 0x05}, 0, 0},
+		{"truncated string length", []byte{0x41, 0x05}, 0, 0},
+		{"truncated string", []byte{0x41, 0x05, 0x00, 'H', 'E'}, 0, 0},
+		{"truncated long name", []byte{0xB3, 'C', 'O'}, 0, 0},
+		{"string after a number", []byte{0x61, 0x00, 0x00, 0x05, 0x00, 0x00, 0x41, 0x09, 0x00, 'A'}, 0, 1},
+		{"unknown type", []byte{0x21, 0x00, 0x80}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeVariables(tt.data, tt.varOffset)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(got) != tt.decoded {
+				t.Errorf("got %d variables with the error, want %d", len(got), tt.decoded)
+			}
+		})
+	}
+}
+
+func TestVariableString(t *testing.T) {
+	tests := []struct {
+		variable Variable
+		want     string
+	}{
+		{Variable{Type: NumberVariable, Name: "a", Value: 5}, "a = 5"},
+		{Variable{Type: NumberVariable, Name: "b", Value: 0.1}, "b = 0.1"},
+		{Variable{Type: LongNumber, Name: "score", Value: 10000}, "score = 10000"},
+		{Variable{Type: StringVariable, Name: "a$", Text: "HELLO"}, `a$ = "HELLO"`},
+		{Variable{Type: StringVariable, Name: "n$"}, `n$ = ""`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.variable.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
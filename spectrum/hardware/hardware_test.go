@@ -0,0 +1,58 @@
+package hardware
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		hwType byte
+		hwID   byte
+		want   string
+	}{
+		{Computers, 0x00, "ZX Spectrum 16k"},
+		{Computers, 0x04, "ZX Spectrum 128k +2 (grey case)"},
+		{Computers, 0x05, "ZX Spectrum 128k +2A, +3"},
+		{Computers, 0x2d, "ZX-97"},
+		{ExternalStorage, 0x02, "MGT Disciple"},
+		{ExternalStorage, 0x0c, "ZX Spectrum +3 disk drive"},
+		{RomRamAddOns, 0x01, "Multiface ONE"},
+		{SoundDevices, 0x00, "Classic AY hardware (compatible with 128k ZXs)"},
+		{SoundDevices, 0x02, "Currah microSpeech"},
+		{Joysticks, 0x00, "Kempston"},
+		{Mice, 0x01, "Kempston mouse"},
+		{SoundDevices, 0xfe, "Unknown Sound devices (ID FE)"},
+		{0x7f, 0x01, "Unknown hardware (type 7F, ID 01)"},
+	}
+
+	for _, tt := range tests {
+		if got := Describe(tt.hwType, tt.hwID); got != tt.want {
+			t.Errorf("Describe(%02X, %02X) = %q, want %q", tt.hwType, tt.hwID, got, tt.want)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		hwType byte
+		want   string
+	}{
+		{Computers, "Computers"},
+		{RomRamAddOns, "ROM/RAM type add-ons"},
+		{Graphics, "Graphics"},
+		{0x11, "Unknown type (11)"},
+	}
+
+	for _, tt := range tests {
+		if got := TypeName(tt.hwType); got != tt.want {
+			t.Errorf("TypeName(%02X) = %q, want %q", tt.hwType, got, tt.want)
+		}
+	}
+}
+
+func TestInformation(t *testing.T) {
+	if got := Information(0x03); got != "The tape DOESN'T RUN on this machine or with this hardware." {
+		t.Errorf("Information(03) = %q", got)
+	}
+	if got := Information(0x04); got != "Unknown information (04)" {
+		t.Errorf("Information(04) = %q", got)
+	}
+}
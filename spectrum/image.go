@@ -4,4 +4,5 @@ type Image interface {
 	Read() error
 	DisplayGeometry()
 	DisplayBASIC()
+	Warnings() []string
 }
@@ -0,0 +1,258 @@
+package p
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ZX81 character codes with a special meaning in a BASIC line.
+const (
+	charNewline = 0x76 // end of the line
+	charNumber  = 0x7E // followed by the 5 byte floating point form of a number
+)
+
+// CharacterSet is the ZX81 character set, which is not based on ASCII. Codes
+// 0x80 to 0xBF are the inverse video forms of codes 0x00 to 0x3F, and codes
+// from 0xC0 are the BASIC tokens, see decodeChar.
+var CharacterSet = map[byte]string{
+	0x00: " ",
+	// Block graphics
+	0x01: "▘",
+	0x02: "▝",
+	0x03: "▀",
+	0x04: "▖",
+	0x05: "▌",
+	0x06: "▞",
+	0x07: "▛",
+	0x08: "▒",
+	0x09: "▄", // grey top half, black bottom half
+	0x0A: "▀", // black top half, grey bottom half
+	0x0B: `"`,
+	0x0C: "£",
+	0x0D: "$",
+	0x0E: ":",
+	0x0F: "?",
+	0x10: "(",
+	0x11: ")",
+	0x12: ">",
+	0x13: "<",
+	0x14: "=",
+	0x15: "+",
+	0x16: "-",
+	0x17: "*",
+	0x18: "/",
+	0x19: ";",
+	0x1A: ",",
+	0x1B: ".",
+	0x1C: "0",
+	0x1D: "1",
+	0x1E: "2",
+	0x1F: "3",
+	0x20: "4",
+	0x21: "5",
+	0x22: "6",
+	0x23: "7",
+	0x24: "8",
+	0x25: "9",
+	0x26: "A",
+	0x27: "B",
+	0x28: "C",
+	0x29: "D",
+	0x2A: "E",
+	0x2B: "F",
+	0x2C: "G",
+	0x2D: "H",
+	0x2E: "I",
+	0x2F: "J",
+	0x30: "K",
+	0x31: "L",
+	0x32: "M",
+	0x33: "N",
+	0x34: "O",
+	0x35: "P",
+	0x36: "Q",
+	0x37: "R",
+	0x38: "S",
+	0x39: "T",
+	0x3A: "U",
+	0x3B: "V",
+	0x3C: "W",
+	0x3D: "X",
+	0x3E: "Y",
+	0x3F: "Z",
+	// BASIC tokens - expression
+	0x40: "RND",
+	0x41: "INKEY$",
+	0x42: "PI",
+	// BASIC tokens
+	0xC0: `""`,
+	0xC1: "AT",
+	0xC2: "TAB",
+	0xC4: "CODE",
+	0xC5: "VAL",
+	0xC6: "LEN",
+	0xC7: "SIN",
+	0xC8: "COS",
+	0xC9: "TAN",
+	0xCA: "ASN",
+	0xCB: "ACS",
+	0xCC: "ATN",
+	0xCD: "LN",
+	0xCE: "EXP",
+	0xCF: "INT",
+	0xD0: "SQR",
+	0xD1: "SGN",
+	0xD2: "ABS",
+	0xD3: "PEEK",
+	0xD4: "USR",
+	0xD5: "STR$",
+	0xD6: "CHR$",
+	0xD7: "NOT",
+	0xD8: "**",
+	0xD9: "OR",
+	0xDA: "AND",
+	0xDB: "<=",
+	0xDC: ">=",
+	0xDD: "<>",
+	0xDE: "THEN",
+	0xDF: "TO",
+	0xE0: "STEP",
+	0xE1: "LPRINT",
+	0xE2: "LLIST",
+	0xE3: "STOP",
+	0xE4: "SLOW",
+	0xE5: "FAST",
+	0xE6: "NEW",
+	0xE7: "SCROLL",
+	0xE8: "CONT",
+	0xE9: "DIM",
+	0xEA: "REM",
+	0xEB: "FOR",
+	0xEC: "GOTO",
+	0xED: "GOSUB",
+	0xEE: "INPUT",
+	0xEF: "LOAD",
+	0xF0: "LIST",
+	0xF1: "LET",
+	0xF2: "PAUSE",
+	0xF3: "NEXT",
+	0xF4: "POKE",
+	0xF5: "PRINT",
+	0xF6: "PLOT",
+	0xF7: "RUN",
+	0xF8: "SAVE",
+	0xF9: "RAND",
+	0xFA: "IF",
+	0xFB: "CLS",
+	0xFC: "UNPLOT",
+	0xFD: "CLEAR",
+	0xFE: "RETURN",
+	0xFF: "COPY",
+}
+
+// Line is a single line of a ZX81 BASIC program, with the tokenized data as
+// stored in memory, including the NEWLINE (0x76) at the end of the line.
+type Line struct {
+	Number uint16
+	Data   []byte
+}
+
+// String returns the line number followed by the decoded line.
+func (l Line) String() string {
+	return fmt.Sprintf("%4d %s", l.Number, DecodeText(l.Data))
+}
+
+// DecodeLines splits the ZX81 BASIC program into its lines. Each line is
+// stored as the line number (big endian), the length of the line data
+// (little endian), followed by the data, as with the ZX Spectrum.
+func DecodeLines(program []byte) ([]Line, error) {
+	var lines []Line
+
+	for pos := 0; pos < len(program); {
+		if len(program)-pos < 4 {
+			return lines, errors.Errorf("incomplete line header at offset %d", pos)
+		}
+		number := binary.BigEndian.Uint16(program[pos : pos+2])
+		length := int(binary.LittleEndian.Uint16(program[pos+2 : pos+4]))
+		pos += 4
+
+		if pos+length > len(program) {
+			return lines, errors.Errorf("line %d overruns the program by %d bytes", number, pos+length-len(program))
+		}
+		lines = append(lines, Line{Number: number, Data: program[pos : pos+length]})
+		pos += length
+	}
+
+	return lines, nil
+}
+
+// DecodeText returns the ZX81 text as a UTF-8 string, expanding the BASIC
+// tokens, and skipping the hidden floating point form of each number. The
+// text ends at a NEWLINE.
+//
+// Inverse video letters are shown in lower case, as the ZX81 has no lower case
+// letters. Other inverse video characters are shown as their normal form.
+func DecodeText(data []byte) string {
+	var text strings.Builder
+
+	for pos := 0; pos < len(data); pos++ {
+		char := data[pos]
+		switch {
+		case char == charNewline:
+			return strings.TrimRight(text.String(), " ")
+		case char == charNumber:
+			pos += 5
+		default:
+			last := byte(' ')
+			if s := text.String(); len(s) > 0 {
+				last = s[len(s)-1]
+			}
+			text.WriteString(decodeChar(char, last))
+		}
+	}
+
+	return strings.TrimRight(text.String(), " ")
+}
+
+// decodeChar returns the character, with a space either side of the keyword
+// tokens, unless following a space.
+func decodeChar(char, last byte) string {
+	if char >= 0x80 && char < 0xC0 {
+		decoded := CharacterSet[char&^0x80]
+		if char >= 0xA6 {
+			decoded = strings.ToLower(decoded)
+		}
+		return decoded
+	}
+
+	decoded, ok := CharacterSet[char]
+	if !ok {
+		return "?"
+	}
+
+	// only the keywords are padded, not the symbols or the quote image
+	if char < 0xC1 || !isLetter(decoded[0]) {
+		return decoded
+	}
+	if last != ' ' {
+		decoded = " " + decoded
+	}
+	return decoded + " "
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// decodeName returns a program name, as saved in the ZX81 character set, with
+// the last character in inverse video.
+func decodeName(name []byte) string {
+	var text strings.Builder
+	for _, char := range name {
+		text.WriteString(CharacterSet[char&^0x80])
+	}
+	return text.String()
+}
@@ -0,0 +1,253 @@
+// Package p implements reading of ZX81 P and P81 tape files.
+//
+// A P file is the memory image saved by the ZX81 SAVE command, without the
+// program name: the system variables from VERSN at 0x4009, the BASIC program
+// from 0x407D, the display file, and the variables, up to the address given by
+// the E_LINE system variable. The length of the data is therefore given by
+// E_LINE.
+//
+// A P81 file is the same, except each program is preceded by its name, as
+// saved on tape, in the ZX81 character set with the last character in inverse
+// video. A P81 file may hold several programs, one after the other.
+package p
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// maxNameLength is the longest program name read from a P81 file, as the end
+// of the name is only marked by an inverse video character.
+const maxNameLength = 127
+
+// P is a ZX81 tape file holding one or more programs.
+type P struct {
+	reader *storage.Reader
+	named  bool // the programs are preceded by their names, as in P81 files
+
+	Programs []Program
+}
+
+// Program is a ZX81 memory image, from the system variables up to E_LINE.
+type Program struct {
+	Name            string // Program name, only saved in P81 files
+	SystemVariables SystemVariables
+	Memory          []byte // Memory from ProgramStart up to E_LINE
+}
+
+// New returns a reader for a P file, holding a single program.
+func New(reader *storage.Reader) *P {
+	return &P{reader: reader}
+}
+
+// NewP81 returns a reader for a P81 file, holding one or more named programs.
+func NewP81(reader *storage.Reader) *P {
+	return &P{reader: reader, named: true}
+}
+
+// Read processes each program of the file.
+func (p *P) Read() error {
+	for {
+		if _, err := p.reader.PeekByte(); err == io.EOF && len(p.Programs) > 0 {
+			break // no problems, we're done!
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+
+		offset := p.reader.Offset()
+		program, err := p.readProgram()
+		if storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: len(p.Programs) + 1, Offset: offset}
+		} else if err != nil {
+			return errors.Wrapf(err, "error reading program #%d", len(p.Programs)+1)
+		}
+		p.Programs = append(p.Programs, program)
+
+		if !p.named {
+			break
+		}
+	}
+
+	if _, err := p.reader.PeekByte(); err != io.EOF {
+		err := errors.Errorf("unexpected data after the end of the program at offset %d", p.reader.Offset())
+		if err := p.reader.Tolerate(err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readProgram reads the name, when the file has names, and the memory image.
+func (p *P) readProgram() (Program, error) {
+	var program Program
+
+	if p.named {
+		var name []byte
+		for len(name) < maxNameLength {
+			char := p.reader.ReadUint8()
+			if err := p.reader.Err(); err != nil {
+				return program, err
+			}
+			name = append(name, char)
+			if char&0x80 != 0 {
+				break
+			}
+		}
+		program.Name = decodeName(name)
+	}
+
+	if err := program.SystemVariables.Read(p.reader); err != nil {
+		return program, err
+	}
+	if err := program.SystemVariables.validate(); err != nil {
+		return program, err
+	}
+
+	program.Memory = p.reader.ReadBytes(int(program.SystemVariables.ELine) - ProgramStart)
+	return program, p.reader.Err()
+}
+
+// area returns the memory between the two addresses.
+func (p Program) area(start, end uint16) []byte {
+	return p.Memory[start-ProgramStart : end-ProgramStart]
+}
+
+// BASIC returns the BASIC program, which ends at the display file.
+func (p Program) BASIC() []byte {
+	return p.area(ProgramStart, p.SystemVariables.DFile)
+}
+
+// DisplayFile returns the display file, which starts with a NEWLINE, followed
+// by the 24 lines of the screen, each ending with a NEWLINE.
+func (p Program) DisplayFile() []byte {
+	return p.area(p.SystemVariables.DFile, p.SystemVariables.Vars)
+}
+
+// Variables returns the variables area, which ends with an 0x80 byte.
+func (p Program) Variables() []byte {
+	return p.area(p.SystemVariables.Vars, p.SystemVariables.ELine)
+}
+
+// Collapsed reports whether the display file is collapsed, as on a ZX81 with
+// less than 3.25K of memory, where each line only holds the characters up to
+// the last non-space character, rather than the full 32 characters.
+func (p Program) Collapsed() bool {
+	return len(p.DisplayFile()) < 1+24*33
+}
+
+// Screen returns the text of the display file, a line for each line of the
+// screen.
+func (p Program) Screen() []string {
+	var lines []string
+
+	display := p.DisplayFile()
+	if len(display) > 0 && display[0] == charNewline {
+		display = display[1:]
+	}
+	for len(display) > 0 && len(lines) < 24 {
+		end := 0
+		for end < len(display) && display[end] != charNewline {
+			end++
+		}
+		lines = append(lines, DecodeText(display[:end]))
+		if end < len(display) {
+			end++
+		}
+		display = display[end:]
+	}
+
+	return lines
+}
+
+// DisplayGeometry prints the system variables, and the memory areas of each
+// program.
+func (p P) DisplayGeometry() {
+	for i, program := range p.Programs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("PROGRAM #%d:\n", i+1)
+		if p.named {
+			fmt.Printf("  %-10s: %s\n", "Name", storage.DisplayText(program.Name))
+		}
+		fmt.Println()
+
+		fmt.Println("SYSTEM VARIABLES:")
+		fmt.Print(program.SystemVariables)
+		fmt.Println()
+
+		lines, _ := DecodeLines(program.BASIC())
+		display := "expanded"
+		if program.Collapsed() {
+			display = "collapsed"
+		}
+
+		vars := program.SystemVariables
+		fmt.Println("MEMORY AREAS:")
+		fmt.Printf("  %-14s: 0x%04X-0x%04X, %d bytes, %d lines\n", "BASIC program", ProgramStart, vars.DFile-1, len(program.BASIC()), len(lines))
+		fmt.Printf("  %-14s: 0x%04X-0x%04X, %d bytes, %s\n", "Display file", vars.DFile, vars.Vars-1, len(program.DisplayFile()), display)
+		fmt.Printf("  %-14s: 0x%04X-0x%04X, %d bytes\n", "Variables", vars.Vars, vars.ELine-1, len(program.Variables()))
+
+		screen := program.Screen()
+		for len(screen) > 0 && screen[len(screen)-1] == "" {
+			screen = screen[:len(screen)-1]
+		}
+		if len(screen) > 0 {
+			fmt.Println()
+			fmt.Println("SCREEN:")
+			for _, line := range screen {
+				fmt.Printf("  %s\n", storage.DisplayText(line))
+			}
+		}
+	}
+}
+
+// DisplayBASIC outputs the BASIC program of each program.
+func (p P) DisplayBASIC() {
+	if len(p.Programs) == 0 {
+		fmt.Println("Unable to decode BASIC program")
+		return
+	}
+
+	fmt.Println("BASIC PROGRAMS:")
+	fmt.Println()
+	for i, program := range p.Programs {
+		name := program.Name
+		if name == "" {
+			name = fmt.Sprintf("PROGRAM #%d", i+1)
+		}
+		fmt.Printf("%s\n", storage.DisplayText(name))
+
+		lines, err := DecodeLines(program.BASIC())
+		for _, line := range lines {
+			fmt.Println(storage.DisplayText(line.String()))
+		}
+		if err != nil {
+			fmt.Printf("    %s\n", err)
+		}
+		fmt.Println()
+	}
+}
+
+// Warnings returns the problems found with the programs, which did not stop
+// them from being read.
+func (p P) Warnings() []string {
+	var warnings []string
+	for i, program := range p.Programs {
+		if program.SystemVariables.VERSN != 0 {
+			warnings = append(warnings, fmt.Sprintf("program #%d: VERSN is %d, expected 0 for ZX81 BASIC", i+1, program.SystemVariables.VERSN))
+		}
+		if _, err := DecodeLines(program.BASIC()); err != nil {
+			warnings = append(warnings, fmt.Sprintf("program #%d: %s", i+1, err))
+		}
+		if vars := program.Variables(); len(vars) == 0 || vars[len(vars)-1] != 0x80 {
+			warnings = append(warnings, fmt.Sprintf("program #%d: the variables area does not end with 0x80", i+1))
+		}
+	}
+	return warnings
+}
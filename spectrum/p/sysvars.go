@@ -0,0 +1,123 @@
+package p
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// Memory addresses of the ZX81 memory image saved in a P file.
+const (
+	SystemVariablesStart = 0x4009 // VERSN, the first system variable saved
+	ProgramStart         = 0x407D // start of the BASIC program
+	SystemVariablesSize  = ProgramStart - SystemVariablesStart
+)
+
+// SystemVariables are the ZX81 system variables saved at the start of the
+// memory image, from VERSN at 0x4009, up to the start of the BASIC program.
+type SystemVariables struct {
+	VERSN   uint8    // 0 identifies ZX81 BASIC in saved programs
+	EPPC    uint16   // Number of the current line, with the program cursor
+	DFile   uint16   // Address of the display file
+	DFCC    uint16   // Address of the PRINT position in the display file
+	Vars    uint16   // Address of the variables area
+	Dest    uint16   // Address of the variable in assignment
+	ELine   uint16   // Address of the line being edited, the end of the saved data
+	ChAdd   uint16   // Address of the next character to be interpreted
+	XPtr    uint16   // Address of the character preceding the syntax error marker
+	StkBot  uint16   // Address of the bottom of the calculator stack
+	StkEnd  uint16   // Address of the end of the calculator stack
+	BERG    uint8    // Calculator's b register
+	Mem     uint16   // Address of the area used for the calculator's memory
+	Unused1 uint8    // Not used
+	DFSz    uint8    // Number of lines in the lower part of the screen
+	STop    uint16   // Number of the top program line in automatic listings
+	LastK   uint16   // Keyboard scan of the last key pressed
+	Debounc uint8    // Debounce status of the keyboard
+	Margin  uint8    // 55 for a 50Hz (UK) machine, 31 for 60Hz (US)
+	NxtLin  uint16   // Address of the next program line to be executed
+	OldPPC  uint16   // Line number to which CONT jumps
+	FlagX   uint8    // Various flags
+	StrLen  uint16   // Length of the string type destination in assignment
+	TAddr   uint16   // Address of the next item in the syntax table
+	Seed    uint16   // The seed for RND, set by RAND
+	Frames  uint16   // Counts the frames displayed on the television
+	Coords  [2]uint8 // x and y coordinates of the last point plotted
+	PrCC    uint8    // Less significant byte of the LPRINT position in PRBUFF
+	SPosn   [2]uint8 // Column and line number of the PRINT position
+	CDFlag  uint8    // Various flags, bit 7 is on in SLOW (compute and display) mode
+	PrBuff  [33]byte // Printer buffer, the 33rd character is ENTER
+	MemBot  [30]byte // Calculator's memory area
+	Unused2 [2]byte  // Not used
+}
+
+// Read the system variables from the memory image.
+func (s *SystemVariables) Read(reader *storage.Reader) error {
+	s.VERSN = reader.ReadUint8()
+	s.EPPC = reader.ReadShort()
+	s.DFile = reader.ReadShort()
+	s.DFCC = reader.ReadShort()
+	s.Vars = reader.ReadShort()
+	s.Dest = reader.ReadShort()
+	s.ELine = reader.ReadShort()
+	s.ChAdd = reader.ReadShort()
+	s.XPtr = reader.ReadShort()
+	s.StkBot = reader.ReadShort()
+	s.StkEnd = reader.ReadShort()
+	s.BERG = reader.ReadUint8()
+	s.Mem = reader.ReadShort()
+	s.Unused1 = reader.ReadUint8()
+	s.DFSz = reader.ReadUint8()
+	s.STop = reader.ReadShort()
+	s.LastK = reader.ReadShort()
+	s.Debounc = reader.ReadUint8()
+	s.Margin = reader.ReadUint8()
+	s.NxtLin = reader.ReadShort()
+	s.OldPPC = reader.ReadShort()
+	s.FlagX = reader.ReadUint8()
+	s.StrLen = reader.ReadShort()
+	s.TAddr = reader.ReadShort()
+	s.Seed = reader.ReadShort()
+	s.Frames = reader.ReadShort()
+	copy(s.Coords[:], reader.ReadBytes(2))
+	s.PrCC = reader.ReadUint8()
+	copy(s.SPosn[:], reader.ReadBytes(2))
+	s.CDFlag = reader.ReadUint8()
+	copy(s.PrBuff[:], reader.ReadBytes(33))
+	copy(s.MemBot[:], reader.ReadBytes(30))
+	copy(s.Unused2[:], reader.ReadBytes(2))
+
+	return reader.Err()
+}
+
+// validate checks the addresses of the memory areas are in order, from the
+// BASIC program, the display file, then the variables, up to E_LINE.
+func (s SystemVariables) validate() error {
+	if s.DFile < ProgramStart || s.Vars < s.DFile || s.ELine <= s.Vars {
+		return errors.Errorf("invalid system variables, D_FILE 0x%04X, VARS 0x%04X, E_LINE 0x%04X", s.DFile, s.Vars, s.ELine)
+	}
+	return nil
+}
+
+// Refresh returns the display refresh rate in Hz, from the MARGIN.
+func (s SystemVariables) Refresh() int {
+	if s.Margin == 31 {
+		return 60
+	}
+	return 50
+}
+
+func (s SystemVariables) String() string {
+	str := ""
+	str += fmt.Sprintf("  %-10s: %d\n", "VERSN", s.VERSN)
+	str += fmt.Sprintf("  %-10s: %d\n", "E_PPC", s.EPPC)
+	str += fmt.Sprintf("  %-10s: 0x%04X\n", "D_FILE", s.DFile)
+	str += fmt.Sprintf("  %-10s: 0x%04X\n", "VARS", s.Vars)
+	str += fmt.Sprintf("  %-10s: 0x%04X\n", "E_LINE", s.ELine)
+	str += fmt.Sprintf("  %-10s: 0x%04X\n", "NXTLIN", s.NxtLin)
+	str += fmt.Sprintf("  %-10s: %d (%dHz)\n", "MARGIN", s.Margin, s.Refresh())
+	str += fmt.Sprintf("  %-10s: 0x%02X\n", "CDFLAG", s.CDFlag)
+	return str
+}
@@ -0,0 +1,132 @@
+// Package plus3dos implements reading of the +3DOS file header, as written
+// by the ZX Spectrum +3 to the start of files saved to disc.
+//
+// Files saved from +3 BASIC have a 128 byte header, identified by the
+// `PLUS3DOS` signature. Files written by CP/M programs have no header.
+package plus3dos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderSize is the length of the +3DOS header record.
+const HeaderSize = 128
+
+var signature = []byte("PLUS3DOS")
+
+// Header is the +3DOS file header, stored in the first 128 bytes of the file.
+type Header struct {
+	Signature  [8]byte // "PLUS3DOS"
+	SoftEOF    uint8   // Always 0x1A
+	Issue      uint8   // Issue number
+	Version    uint8   // Version number
+	FileLength uint32  // Length of the file in bytes, including this header
+
+	// +3 BASIC header data, as found in tape headers
+	FileType   uint8  // 0: Program, 1: Number array, 2: Character array, 3: Code
+	DataLength uint16 // Length of the data
+	Param1     uint16 // Program: auto-start line, Code: load address
+	Param2     uint16 // Program: offset to the variables
+	Unused     uint8  // Always 0
+	Reserved   [104]byte
+	Checksum   uint8 // Sum of bytes 0..126, modulo 256
+}
+
+// ReadHeader reads the +3DOS header from the start of the file data.
+// An error is returned when the data has no valid header, e.g. a CP/M file.
+func ReadHeader(data []byte) (Header, error) {
+	h := Header{}
+
+	if len(data) < HeaderSize || !bytes.HasPrefix(data, signature) {
+		return h, errors.New("no +3DOS header found")
+	}
+
+	if err := binary.Read(bytes.NewReader(data[:HeaderSize]), binary.LittleEndian, &h); err != nil {
+		return h, errors.Wrap(err, "error reading +3DOS header")
+	}
+
+	if sum := checksum(data[:HeaderSize-1]); sum != h.Checksum {
+		return h, errors.Errorf("invalid +3DOS header checksum, expected 0x%02X, got 0x%02X", sum, h.Checksum)
+	}
+
+	return h, nil
+}
+
+// Strip returns the file data without the +3DOS header, with any padding
+// after the end of the file removed. Data without a header is returned as is.
+func Strip(data []byte) []byte {
+	h, err := ReadHeader(data)
+	if err != nil {
+		return data
+	}
+
+	data = data[HeaderSize:]
+	if length := int(h.Length()); length < len(data) {
+		data = data[:length]
+	}
+	return data
+}
+
+// Length returns the real length of the file data, excluding the header.
+func (h Header) Length() uint32 {
+	if h.FileLength < HeaderSize {
+		return 0
+	}
+	return h.FileLength - HeaderSize
+}
+
+// FileTypeName returns the +3 BASIC file type.
+func (h Header) FileTypeName() string {
+	switch h.FileType {
+	case 0:
+		return "Program"
+	case 1:
+		return "Number array"
+	case 2:
+		return "Character array"
+	case 3:
+		return "Code"
+	default:
+		return "Unknown"
+	}
+}
+
+// AutoStart returns the line a program file is run from after loading, or
+// false when it is not a program, or is not started automatically, which is
+// any line of 32768 or above.
+func (h Header) AutoStart() (uint16, bool) {
+	return h.Param1, h.FileType == 0 && h.Param1 < 32768
+}
+
+func (h Header) String() string {
+	str := ""
+	str += fmt.Sprintf("Version:      %d.%d\n", h.Issue, h.Version)
+	str += fmt.Sprintf("File Type:    %s\n", h.FileTypeName())
+	str += fmt.Sprintf("File Length:  %d bytes\n", h.Length())
+
+	switch h.FileType {
+	case 0:
+		if line, ok := h.AutoStart(); ok {
+			str += fmt.Sprintf("Auto Start:   %d\n", line)
+		} else {
+			str += "Auto Start:   none\n"
+		}
+		str += fmt.Sprintf("Variables:    offset %d\n", h.Param2)
+	case 3:
+		str += fmt.Sprintf("Load Address: %d\n", h.Param1)
+	}
+
+	return str
+}
+
+func checksum(data []byte) uint8 {
+	var sum uint8
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
@@ -0,0 +1,389 @@
+package pzx
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/storage"
+)
+
+// Block tags as given in the PZX specification.
+const (
+	headerTag = "PZXT"
+	pulseTag  = "PULS"
+	dataTag   = "DATA"
+	pauseTag  = "PAUS"
+	browseTag = "BRWS"
+	stopTag   = "STOP"
+)
+
+// tStatesPerMs is the number of T states in a millisecond, at 3.5MHz.
+const tStatesPerMs = 3500
+
+// newFromTag returns a PZX block based on the block tag.
+// Unknown blocks are returned as an Unknown block, so they can be skipped.
+func newFromTag(tag string) Block {
+	switch tag {
+	case pulseTag:
+		return &PulseSequence{}
+	case dataTag:
+		return &DataBlock{}
+	case pauseTag:
+		return &Pause{}
+	case browseTag:
+		return &BrowsePoint{}
+	case stopTag:
+		return &Stop{}
+	default:
+		return &Unknown{tag: tag}
+	}
+}
+
+// Header
+// Tag: PZXT
+// The header block identifies the file, and optionally stores information
+// about the tape, as null terminated strings. The first string is the title,
+// the remaining strings are pairs of keys and values, e.g. "Publisher", "Sinclair".
+type Header struct {
+	MajorVersion uint8    // PZX major revision number
+	MinorVersion uint8    // PZX minor revision number
+	Title        string   // Title of the tape (may be empty)
+	Info         []string // Key/value pairs of additional information
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (h *Header) Read(reader *storage.Reader, size uint32) error {
+	if size < 2 {
+		return errors.Errorf("expected header size of 2 or more, got %d", size)
+	}
+
+	h.MajorVersion = reader.ReadUint8()
+	h.MinorVersion = reader.ReadUint8()
+
+	text := splitStrings(reader.ReadBytes(int(size) - 2))
+	if len(text) > 0 {
+		h.Title = text[0]
+		h.Info = text[1:]
+	}
+
+	return nil
+}
+
+// String returns a human readable string of the header data
+func (h Header) String() string {
+	str := fmt.Sprintf("  %-10s: %s\n", "Title", h.Title)
+	for i := 0; i+1 < len(h.Info); i += 2 {
+		str += fmt.Sprintf("  %-10s: %s\n", h.Info[i], h.Info[i+1])
+	}
+	return str
+}
+
+// PulseSequence
+// Tag: PULS
+// A sequence of pulses, each with the given duration. The pulse level is low
+// at the start of the block, and toggled after each pulse.
+//
+// Pulses are stored in a compact form, where repeated pulses of the same
+// duration are stored with a repeat count.
+type PulseSequence struct {
+	Pulses []Pulse
+}
+
+// Pulse is a repeated pulse of the given duration.
+type Pulse struct {
+	Count    uint16 // Number of times the pulse is repeated
+	Duration uint32 // Duration of the pulse in T states (may be 0)
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (p *PulseSequence) Read(reader *storage.Reader, size uint32) error {
+	end := reader.Offset() + int64(size)
+
+	for reader.Offset() < end && reader.Err() == nil {
+		pulse := Pulse{Count: 1}
+
+		duration := uint32(reader.ReadShort())
+		if duration > 0x8000 {
+			pulse.Count = uint16(duration & 0x7FFF)
+			duration = uint32(reader.ReadShort())
+		}
+		if duration >= 0x8000 {
+			duration = (duration&0x7FFF)<<16 | uint32(reader.ReadShort())
+		}
+		pulse.Duration = duration
+
+		p.Pulses = append(p.Pulses, pulse)
+	}
+
+	if reader.Offset() != end && reader.Err() == nil {
+		return errors.Errorf("pulse data overruns the block size of %d bytes", size)
+	}
+
+	return nil
+}
+
+// Tag of the block as given in the PZX specification.
+func (p PulseSequence) Tag() string {
+	return pulseTag
+}
+
+// Name of the block as given in the PZX specification.
+func (p PulseSequence) Name() string {
+	return "Pulse Sequence"
+}
+
+func (p PulseSequence) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (p PulseSequence) String() string {
+	count := 0
+	for _, pulse := range p.Pulses {
+		count += int(pulse.Count)
+	}
+	return fmt.Sprintf("%-19s : %d pulses", p.Name(), count)
+}
+
+// DataBlock
+// Tag: DATA
+// A block of data bits, where each bit is encoded with its own pulse sequence,
+// one for zero bits, and another for one bits. Data saved with the standard
+// ROM routines is also available as a TAP block.
+type DataBlock struct {
+	InitialLevel uint8    // Initial pulse level: 0 or 1
+	BitCount     uint32   // Number of bits in the data stream
+	Tail         uint16   // Duration of the tail pulse in T states, following the last bit
+	ZeroPulses   []uint16 // Pulse sequence for encoding zero bits
+	OnePulses    []uint16 // Pulse sequence for encoding one bits
+	Data         []byte   // The data stream, MSb first
+
+	DataBlock tap.Block
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (d *DataBlock) Read(reader *storage.Reader, size uint32) error {
+	count := reader.ReadLong()
+	d.InitialLevel = uint8(count >> 31)
+	d.BitCount = count & 0x7FFFFFFF
+	d.Tail = reader.ReadShort()
+
+	zeroCount := reader.ReadUint8()
+	oneCount := reader.ReadUint8()
+	for i := 0; i < int(zeroCount); i++ {
+		d.ZeroPulses = append(d.ZeroPulses, reader.ReadShort())
+	}
+	for i := 0; i < int(oneCount); i++ {
+		d.OnePulses = append(d.OnePulses, reader.ReadShort())
+	}
+
+	dataSize := int(d.BitCount+7) / 8
+	headerSize := 8 + 2*(int(zeroCount)+int(oneCount))
+	if headerSize+dataSize != int(size) {
+		return errors.Errorf("expected data block size of %d bytes, got %d", headerSize+dataSize, size)
+	}
+	d.Data = reader.ReadBytes(dataSize)
+
+	// Only whole bytes can be stored in a TAP block
+	if d.BitCount%8 == 0 && dataSize > 0 {
+		if block, err := readTapBlock(d.Data); err == nil {
+			d.DataBlock = block
+		}
+	}
+
+	return nil
+}
+
+// Tag of the block as given in the PZX specification.
+func (d DataBlock) Tag() string {
+	return dataTag
+}
+
+// Name of the block as given in the PZX specification.
+func (d DataBlock) Name() string {
+	return "Data Block"
+}
+
+func (d DataBlock) BlockData() tap.Block {
+	return d.DataBlock
+}
+
+// String returns a human readable string of the block data
+func (d DataBlock) String() string {
+	str := fmt.Sprintf("%-19s : %d bits, %d bytes", d.Name(), d.BitCount, len(d.Data))
+	if d.DataBlock != nil {
+		str += fmt.Sprintf("\n    - %s", d.DataBlock)
+	}
+	return str
+}
+
+// Pause
+// Tag: PAUS
+// A pause of the given duration, with a constant pulse level.
+type Pause struct {
+	InitialLevel uint8  // Pulse level of the pause: 0 or 1
+	Duration     uint32 // Duration of the pause in T states
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (p *Pause) Read(reader *storage.Reader, size uint32) error {
+	if size != 4 {
+		return errors.Errorf("expected pause block size of 4 bytes, got %d", size)
+	}
+
+	duration := reader.ReadLong()
+	p.InitialLevel = uint8(duration >> 31)
+	p.Duration = duration & 0x7FFFFFFF
+
+	return nil
+}
+
+// Tag of the block as given in the PZX specification.
+func (p Pause) Tag() string {
+	return pauseTag
+}
+
+// Name of the block as given in the PZX specification.
+func (p Pause) Name() string {
+	return "Pause"
+}
+
+func (p Pause) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (p Pause) String() string {
+	return fmt.Sprintf("%-19s : %d ms.", p.Name(), p.Duration/tStatesPerMs)
+}
+
+// BrowsePoint
+// Tag: BRWS
+// A text description of the tape at this point, such as a level name, which
+// allows quickly finding a position on the tape.
+type BrowsePoint struct {
+	Text string
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (b *BrowsePoint) Read(reader *storage.Reader, size uint32) error {
+	b.Text = string(reader.ReadBytes(int(size)))
+	return nil
+}
+
+// Tag of the block as given in the PZX specification.
+func (b BrowsePoint) Tag() string {
+	return browseTag
+}
+
+// Name of the block as given in the PZX specification.
+func (b BrowsePoint) Name() string {
+	return "Browse Point"
+}
+
+func (b BrowsePoint) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (b BrowsePoint) String() string {
+	return fmt.Sprintf("%-19s : %s", b.Name(), storage.DisplayText(b.Text))
+}
+
+// Stop
+// Tag: STOP
+// Stop the tape, either always or only when in 48k mode.
+type Stop struct {
+	Flags uint16 // 0: always stop the tape, 1: stop the tape only in 48k mode
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the start of the block data.
+func (s *Stop) Read(reader *storage.Reader, size uint32) error {
+	if size != 2 {
+		return errors.Errorf("expected stop block size of 2 bytes, got %d", size)
+	}
+	s.Flags = reader.ReadShort()
+	return nil
+}
+
+// Tag of the block as given in the PZX specification.
+func (s Stop) Tag() string {
+	return stopTag
+}
+
+// Name of the block as given in the PZX specification.
+func (s Stop) Name() string {
+	return "Stop the Tape"
+}
+
+func (s Stop) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (s Stop) String() string {
+	if s.Flags == 1 {
+		return fmt.Sprintf("%-19s : only in 48k mode", s.Name())
+	}
+	return fmt.Sprintf("%-19s : always", s.Name())
+}
+
+// Unknown is any block not defined in the PZX specification,
+// which is skipped using the block size.
+type Unknown struct {
+	tag  string
+	Size uint32
+}
+
+// Read skips the block data.
+func (u *Unknown) Read(reader *storage.Reader, size uint32) error {
+	u.Size = size
+	_, err := reader.Discard(int(size))
+	return err
+}
+
+// Tag of the block as found in the file.
+func (u Unknown) Tag() string {
+	return u.tag
+}
+
+// Name of the block.
+func (u Unknown) Name() string {
+	return "Unknown Block"
+}
+
+func (u Unknown) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (u Unknown) String() string {
+	return fmt.Sprintf("%-19s : '%s', %d bytes skipped", u.Name(), u.tag, u.Size)
+}
+
+// splitStrings splits the data into its null terminated strings.
+// The final string may omit the terminator.
+func splitStrings(data []byte) []string {
+	var text []string
+
+	start := 0
+	for i, b := range data {
+		if b == 0 {
+			text = append(text, string(data[start:i]))
+			start = i + 1
+		}
+	}
+	if start < len(data) {
+		text = append(text, string(data[start:]))
+	}
+
+	return text
+}
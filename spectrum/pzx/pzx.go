@@ -0,0 +1,265 @@
+// Package pzx implements reading of ZX Spectrum PZX formatted files,
+// as specified in the PZX specification.
+// http://zxds.raxoft.cz/docs/pzx.txt
+//
+// PZX is a simpler alternative to the TZX format, describing a tape as a
+// sequence of pulses, rather than the many encoding specific blocks of TZX.
+//
+// Rules and Definitions
+//
+//  * The file is a sequence of blocks, each consisting of a 4 byte ASCII tag,
+//    the size of the block data as a DWORD, and then the block data itself.
+//  * The first block is always the PZXT header block.
+//  * Any value requiring more than one byte is stored in little endian format.
+//  * Durations are given in T states at 3.5MHz, i.e. (1/3500000)s.
+//  * Blocks with an unknown tag should be skipped using the block size.
+package pzx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/basic"
+	"retroio/spectrum/tap"
+	"retroio/storage"
+)
+
+const supportedMajorVersion = 1
+
+// PZX files start with the PZXT header block, followed by zero or more blocks.
+type PZX struct {
+	reader *storage.Reader
+
+	Header Header
+	Blocks []Block
+}
+
+// Block is an interface for the PZX blocks.
+type Block interface {
+	Read(reader *storage.Reader, size uint32) error
+	Tag() string
+	Name() string
+	BlockData() tap.Block
+}
+
+func New(reader *storage.Reader) *PZX {
+	return &PZX{reader: reader}
+}
+
+// Read processes the header, and then each block on the tape.
+func (p *PZX) Read() error {
+	if err := p.readHeader(); err != nil {
+		return err
+	}
+
+	if err := p.readBlocks(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readHeader reads the PZXT header block, which must be the first block.
+func (p *PZX) readHeader() error {
+	tag, size := p.readBlockHeader()
+	if err := p.reader.Err(); err != nil {
+		return errors.Wrap(err, "error reading PZX header")
+	}
+
+	if tag != headerTag {
+		return errors.Errorf("incorrect signature, got '%s'", tag)
+	}
+
+	if err := p.Header.Read(p.reader, size); err != nil {
+		return err
+	}
+	if err := p.reader.Err(); err != nil {
+		return errors.Wrap(err, "error reading PZX header")
+	}
+
+	if p.Header.MajorVersion != supportedMajorVersion {
+		err := errors.Errorf("invalid version, got v%d.%d", p.Header.MajorVersion, p.Header.MinorVersion)
+		if err := p.reader.Tolerate(err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBlocks processes each PZX block on the tape.
+func (p *PZX) readBlocks() error {
+	for {
+		if _, err := p.reader.PeekByte(); err == io.EOF {
+			break // no problems, we're done!
+		} else if err != nil {
+			return err
+		}
+
+		offset := p.reader.Offset()
+		tag, size := p.readBlockHeader()
+
+		block := newFromTag(tag)
+		err := block.Read(p.reader, size)
+		if err == nil {
+			err = p.reader.Err()
+		}
+		if storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: len(p.Blocks) + 1, Offset: offset}
+		} else if err != nil {
+			return errors.Wrapf(err, "error reading PZX block '%s'", tag)
+		}
+
+		p.Blocks = append(p.Blocks, block)
+	}
+	return nil
+}
+
+// readBlockHeader reads the tag and data size that start every block.
+func (p *PZX) readBlockHeader() (string, uint32) {
+	tag := p.reader.ReadBytes(4)
+	size := p.reader.ReadLong()
+	return string(tag), size
+}
+
+// Title returns the tape title from the header, if present.
+func (p PZX) Title() string {
+	return p.Header.Title
+}
+
+// Duration returns the playing time of the tape in T-states: the pulses of
+// the pulse sequence and data blocks, and the pauses.
+func (p PZX) Duration() uint64 {
+	var tstates uint64
+
+	for _, block := range p.Blocks {
+		switch b := block.(type) {
+		case *PulseSequence:
+			for _, pulse := range b.Pulses {
+				tstates += uint64(pulse.Count) * uint64(pulse.Duration)
+			}
+		case *DataBlock:
+			var zero, one uint64
+			for _, pulse := range b.ZeroPulses {
+				zero += uint64(pulse)
+			}
+			for _, pulse := range b.OnePulses {
+				one += uint64(pulse)
+			}
+			for i := 0; i < int(b.BitCount) && i/8 < len(b.Data); i++ {
+				if b.Data[i/8]&(0x80>>uint(i%8)) != 0 {
+					tstates += one
+				} else {
+					tstates += zero
+				}
+			}
+			tstates += uint64(b.Tail)
+		case *Pause:
+			tstates += uint64(b.Duration)
+		}
+	}
+
+	return tstates
+}
+
+// BlockHashes returns the data hash of each data block on the tape.
+func (p PZX) BlockHashes() []tap.BlockHash {
+	var hashes []tap.BlockHash
+	for i, block := range p.Blocks {
+		if b, ok := block.(*DataBlock); ok {
+			hashes = append(hashes, tap.BlockHash{Block: i + 2, Name: b.Name(), Hash: storage.DataHash(b.Data)})
+		}
+	}
+	return hashes
+}
+
+// BlockSummaries returns the metadata of each block on the tape, following
+// the header, which is block #1.
+func (p PZX) BlockSummaries() []tap.BlockSummary {
+	var summaries []tap.BlockSummary
+	for i, block := range p.Blocks {
+		summary := tap.NewBlockSummary(i+2, block.Name(), block)
+		if b, ok := block.(*DataBlock); ok {
+			summary.Data = b.Data
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// DisplayGeometry prints the header info, data blocks, etc.
+func (p PZX) DisplayGeometry() {
+	fmt.Println("HEADER INFORMATION (BLOCK #1):")
+	fmt.Println(p.Header)
+
+	fmt.Println("DATA BLOCKS:")
+	for i, block := range p.Blocks {
+		fmt.Printf("#%02d %s\n", i+2, block)
+	}
+
+	fmt.Println()
+	fmt.Printf("PZX revision: v%d.%d\n", p.Header.MajorVersion, p.Header.MinorVersion)
+}
+
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read. PZX tapes store the pulses exactly as recorded, so there is
+// nothing further to check.
+func (p PZX) Warnings() []string {
+	return nil
+}
+
+// DisplayBASIC outputs all BASIC programs
+func (p PZX) DisplayBASIC() {
+	var data []tap.Block
+	for _, block := range p.Blocks {
+		data = append(data, block.BlockData())
+	}
+
+	listing := ""
+	for _, prog := range tap.BasicPrograms(data) {
+		header := prog.Header
+		listing += fmt.Sprintf("BLK#%02d: %s\n", prog.Index+2, storage.DisplayText(strings.Trim(header.Filename(), " ")))
+		listing += fmt.Sprintf("    %s\n", header.AutoStartText())
+		program, variables := header.SplitVariables(prog.Data.BlockData())
+
+		lines, err := basic.Decode(program)
+		if err != nil {
+			listing += fmt.Sprintf("    %s\n", err)
+			continue
+		}
+
+		for _, line := range lines {
+			listing += line
+		}
+		listing += storage.DisplayText(basic.ListVariables(variables))
+		listing += "\n"
+	}
+	if len(listing) > 0 {
+		fmt.Println("BASIC PROGRAMS:")
+		fmt.Println()
+		fmt.Println(listing)
+	} else {
+		fmt.Println("Unable to decode BASIC program")
+	}
+}
+
+// readTapBlock decodes the data of a standard ROM encoded block, as found in
+// TAP files: a flag byte, the data, and a checksum byte.
+func readTapBlock(data []byte) (tap.Block, error) {
+	if len(data) > 0xFFFF {
+		return nil, errors.Errorf("data block too large for a TAP block: %d bytes", len(data))
+	}
+
+	// TAP blocks are prefixed with the data length
+	buf := make([]byte, 2, len(data)+2)
+	buf[0] = uint8(len(data))
+	buf[1] = uint8(len(data) >> 8)
+	buf = append(buf, data...)
+
+	tapReader := tap.New(storage.NewReader(bytes.NewReader(buf)))
+	return tapReader.ReadBlock()
+}
@@ -0,0 +1,101 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Character sets and UDGs (User Defined Graphics) are stored as 8 bytes per
+// glyph, one byte for each row of 8 pixels, top row first, with the most
+// significant bit on the left.
+const (
+	GlyphSize = 8
+
+	CharsetSize = 96 * GlyphSize // 768 bytes, characters 32 to 127
+	UDGSize     = 21 * GlyphSize // 168 bytes, graphics A to U
+
+	// UDGAddress is the default location of the UDGs on a 48K or 128K machine,
+	// and UDGAddress16K on a 16K machine.
+	UDGAddress    = 65368
+	UDGAddress16K = 32600
+)
+
+// charsetPalette shows the glyphs as the default black ink on white paper.
+var charsetPalette = color.Palette{Palette[7], Palette[0]}
+
+// DecodeCharset returns an 8x8 pixel image for each glyph in the data. Any
+// bytes following the last whole glyph are ignored.
+func DecodeCharset(data []byte) []image.Image {
+	var glyphs []image.Image
+
+	for offset := 0; offset+GlyphSize <= len(data); offset += GlyphSize {
+		img := image.NewPaletted(image.Rect(0, 0, GlyphSize, GlyphSize), charsetPalette)
+		for y, row := range data[offset : offset+GlyphSize] {
+			for x := 0; x < GlyphSize; x++ {
+				if row&(0x80>>uint(x)) > 0 {
+					img.SetColorIndex(x, y, 1)
+				}
+			}
+		}
+		glyphs = append(glyphs, img)
+	}
+
+	return glyphs
+}
+
+// CharsetSheet arranges the glyphs in a grid of the given number of columns,
+// with each pixel scaled to a block of scale pixels square.
+func CharsetSheet(glyphs []image.Image, columns, scale int) (*image.Paletted, error) {
+	if len(glyphs) == 0 {
+		return nil, errors.New("no glyphs to arrange")
+	}
+	if columns < 1 {
+		return nil, errors.Errorf("invalid number of columns: %d", columns)
+	}
+	if scale < 1 {
+		return nil, errors.Errorf("invalid scale: %d", scale)
+	}
+
+	if columns > len(glyphs) {
+		columns = len(glyphs)
+	}
+	rows := (len(glyphs) + columns - 1) / columns
+	size := GlyphSize * scale
+
+	sheet := image.NewPaletted(image.Rect(0, 0, columns*size, rows*size), charsetPalette)
+	for i, glyph := range glyphs {
+		left := (i % columns) * size
+		top := (i / columns) * size
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				sheet.Set(left+x, top+y, glyph.At(x/scale, y/scale))
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// ExportCharsetPNG writes a sheet of the glyphs as a PNG, arranged in a grid
+// of the given number of columns and pixel scale. When count is greater than
+// zero only that number of glyphs is included.
+func ExportCharsetPNG(data []byte, count, columns, scale int, w io.Writer) error {
+	glyphs := DecodeCharset(data)
+	if count > 0 && count < len(glyphs) {
+		glyphs = glyphs[:count]
+	}
+
+	sheet, err := CharsetSheet(glyphs, columns, scale)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(w, sheet); err != nil {
+		return errors.Wrap(err, "unable to write PNG")
+	}
+	return nil
+}
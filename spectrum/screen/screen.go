@@ -0,0 +1,217 @@
+// Package screen implements decoding of ZX Spectrum SCREEN$ data, the 6912
+// bytes of display memory, as saved to tape with `SAVE "name" SCREEN$`.
+//
+// The display is 256x192 pixels, stored as a 6144 byte bitmap, followed by
+// 768 bytes of attributes, one for each 8x8 pixel character cell:
+//
+//   bit 7    FLASH, swaps the ink and paper colours at about 1.56Hz
+//   bit 6    BRIGHT
+//   bits 3-5 PAPER colour
+//   bits 0-2 INK colour
+package screen
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	Width  = 256
+	Height = 192
+
+	BitmapSize    = Width * Height / 8
+	AttributeSize = (Width / 8) * (Height / 8)
+	Size          = BitmapSize + AttributeSize // 6912 bytes
+)
+
+// flashDelay is the time each flash state is shown, in 100ths of a second.
+// The flash state changes every 16 frames at 50Hz, i.e. every 0.32 seconds.
+const flashDelay = 32
+
+// Palette of the 8 normal colours, followed by the 8 bright colours.
+var Palette = color.Palette{
+	color.RGBA{0x00, 0x00, 0x00, 0xff}, // Black
+	color.RGBA{0x00, 0x00, 0xd7, 0xff}, // Blue
+	color.RGBA{0xd7, 0x00, 0x00, 0xff}, // Red
+	color.RGBA{0xd7, 0x00, 0xd7, 0xff}, // Magenta
+	color.RGBA{0x00, 0xd7, 0x00, 0xff}, // Green
+	color.RGBA{0x00, 0xd7, 0xd7, 0xff}, // Cyan
+	color.RGBA{0xd7, 0xd7, 0x00, 0xff}, // Yellow
+	color.RGBA{0xd7, 0xd7, 0xd7, 0xff}, // White
+	color.RGBA{0x00, 0x00, 0x00, 0xff}, // Bright Black
+	color.RGBA{0x00, 0x00, 0xff, 0xff}, // Bright Blue
+	color.RGBA{0xff, 0x00, 0x00, 0xff}, // Bright Red
+	color.RGBA{0xff, 0x00, 0xff, 0xff}, // Bright Magenta
+	color.RGBA{0x00, 0xff, 0x00, 0xff}, // Bright Green
+	color.RGBA{0x00, 0xff, 0xff, 0xff}, // Bright Cyan
+	color.RGBA{0xff, 0xff, 0x00, 0xff}, // Bright Yellow
+	color.RGBA{0xff, 0xff, 0xff, 0xff}, // Bright White
+}
+
+// Decode returns the screen image. When flashed is true, the ink and paper
+// colours are swapped for the cells with the FLASH attribute set.
+func Decode(data []byte, flashed bool) (*image.Paletted, error) {
+	if len(data) < Size {
+		return nil, errors.Errorf("SCREEN$ data too short, expected %d bytes, got %d", Size, len(data))
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, Width, Height), Palette)
+
+	for y := 0; y < Height; y++ {
+		for col := 0; col < Width/8; col++ {
+			pixels := data[bitmapAddress(y, col)]
+			attr := data[BitmapSize+(y/8)*(Width/8)+col]
+
+			ink, paper := attributeColours(attr)
+			if flashed && attr&0x80 != 0 {
+				ink, paper = paper, ink
+			}
+
+			for bit := 0; bit < 8; bit++ {
+				c := paper
+				if pixels&(0x80>>uint(bit)) != 0 {
+					c = ink
+				}
+				img.SetColorIndex(col*8+bit, y, c)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// HasFlash returns true when any of the attributes have the FLASH bit set.
+func HasFlash(data []byte) bool {
+	if len(data) < Size {
+		return false
+	}
+	for _, attr := range data[BitmapSize:Size] {
+		if attr&0x80 != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportScreenGIF writes the screen as a GIF. When any attributes have the
+// FLASH bit set the GIF is animated, alternating the two flash states at the
+// Spectrum flash rate, otherwise a single frame is written.
+func ExportScreenGIF(data []byte, w io.Writer) error {
+	frame, err := Decode(data, false)
+	if err != nil {
+		return err
+	}
+	anim := &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{0}}
+
+	if HasFlash(data) {
+		flashed, _ := Decode(data, true)
+		anim.Image = append(anim.Image, flashed)
+		anim.Delay = []int{flashDelay, flashDelay}
+	}
+
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return errors.Wrap(err, "unable to write GIF")
+	}
+	return nil
+}
+
+// ExportScreenPNG writes the screen as a PNG. As a PNG is a single image, any
+// FLASH attributes are shown in their first state.
+func ExportScreenPNG(data []byte, w io.Writer) error {
+	frame, err := Decode(data, false)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(w, frame); err != nil {
+		return errors.Wrap(err, "unable to write PNG")
+	}
+	return nil
+}
+
+// ANSI rendering of the screen, where each character is the upper half block,
+// with the foreground colour for the top pixels and the background colour for
+// the bottom pixels. Each half of a character is a block of ansiScale pixels
+// square, giving 128 columns and 48 rows.
+const (
+	ansiScale     = 2
+	ansiHalfBlock = "\u2580"
+)
+
+// RenderScreenANSI returns the screen as half block Unicode characters, using
+// 24-bit ANSI colours, for previewing the screen in a terminal. The image is
+// downsampled, with each block of pixels using its most common colour. An empty
+// string is returned when the data is too short for a screen.
+func RenderScreenANSI(data []byte) string {
+	img, err := Decode(data, false)
+	if err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for y := 0; y < Height; y += ansiScale * 2 {
+		lastFg, lastBg := -1, -1
+		for x := 0; x < Width; x += ansiScale {
+			fg := blockColour(img, x, y)
+			bg := blockColour(img, x, y+ansiScale)
+			if fg != lastFg {
+				sb.WriteString(ansiColour(38, fg))
+				lastFg = fg
+			}
+			if bg != lastBg {
+				sb.WriteString(ansiColour(48, bg))
+				lastBg = bg
+			}
+			sb.WriteString(ansiHalfBlock)
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+
+	return sb.String()
+}
+
+// blockColour returns the palette index used most often by the pixels of the
+// block at x, y.
+func blockColour(img *image.Paletted, x, y int) int {
+	var counts [16]int
+	for dy := 0; dy < ansiScale; dy++ {
+		for dx := 0; dx < ansiScale; dx++ {
+			counts[img.ColorIndexAt(x+dx, y+dy)]++
+		}
+	}
+
+	colour := 0
+	for i, count := range counts {
+		if count > counts[colour] {
+			colour = i
+		}
+	}
+	return colour
+}
+
+// ansiColour returns the 24-bit colour escape code for the palette index, where
+// mode is 38 for the foreground, or 48 for the background.
+func ansiColour(mode, index int) string {
+	r, g, b, _ := Palette[index].RGBA()
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", mode, r>>8, g>>8, b>>8)
+}
+
+// bitmapAddress returns the offset of the pixel byte for the row and column.
+// The bitmap is split into three thirds of 64 rows, and within each third the
+// rows are interleaved: each 8 rows of a character are 256 bytes apart.
+func bitmapAddress(y, col int) int {
+	return (y&0xc0)<<5 | (y&0x07)<<8 | (y&0x38)<<2 | col
+}
+
+// attributeColours returns the palette index of the ink and paper colours.
+func attributeColours(attr byte) (uint8, uint8) {
+	bright := (attr >> 3) & 0x08
+	return attr&0x07 | bright, (attr>>3)&0x07 | bright
+}
@@ -19,11 +19,15 @@ type Fragment struct {
 
 // Read block data - reads 1 byte unless fragment size is zero length.
 // It is expected that the tape pointer is at the correct position for reading.
-func (b *Fragment) Read(reader *storage.Reader) {
+func (b *Fragment) Read(reader *storage.Reader) error {
 	b.Length = reader.ReadShort()
 	if b.Length > 0 {
-		b.Data = reader.ReadBytes(int(b.Length))
+		b.Data = make([]byte, b.Length)
+		if _, err := reader.Read(b.Data); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (b Fragment) Id() uint8 {
@@ -42,6 +46,11 @@ func (b Fragment) BlockData() []byte {
 	return b.Data
 }
 
+// DataHash returns the CRC32 of the fragment data.
+func (b Fragment) DataHash() string {
+	return storage.DataHash(b.Data)
+}
+
 // String returns a formatted string for the block
 func (b Fragment) String() string {
 	return fmt.Sprintf("%-13s: %d bytes", b.Name(), len(b.Data))
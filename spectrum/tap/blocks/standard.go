@@ -2,7 +2,8 @@ package blocks
 
 import (
 	"fmt"
-	"io"
+
+	"github.com/pkg/errors"
 
 	"retroio/storage"
 )
@@ -19,17 +20,21 @@ type Standard struct {
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
-func (b *Standard) Read(reader *storage.Reader) {
+func (b *Standard) Read(reader *storage.Reader) error {
 	b.Length = reader.ReadShort()
-	b.Flag = reader.ReadByte()
+	if b.Length < 2 {
+		return errors.Errorf("expected standard data block length of 2 or more, got '%d'", b.Length)
+	}
+	b.Flag = reader.ReadUint8()
 
 	b.Data = make([]byte, b.Length-2)
-	_, err := reader.Read(b.Data)
-	if err != nil && err != io.EOF {
-		return
+	if _, err := reader.Read(b.Data); err != nil {
+		return errors.Wrap(err, "unable to read standard data block")
 	}
 
-	b.Checksum = reader.ReadByte()
+	b.Checksum = reader.ReadUint8()
+
+	return nil
 }
 
 func (b Standard) Id() uint8 {
@@ -48,7 +53,31 @@ func (b Standard) BlockData() []byte {
 	return b.Data
 }
 
+// DataHash returns the CRC32 of the block bytes: flag, data and checksum.
+func (b Standard) DataHash() string {
+	return storage.DataHash([]byte{b.Flag}, b.Data, []byte{b.Checksum})
+}
+
+// FlagNote returns a note about an unusual flag byte, or an empty string for
+// the standard data flag (255). The header flag (0) is unusual on a data block,
+// as it is only found when the block is not a valid header, and any other flag
+// is used by custom loaders.
+func (b Standard) FlagNote() string {
+	switch b.Flag {
+	case 0xFF:
+		return ""
+	case 0x00:
+		return "header flag (0x00), but not a valid header"
+	default:
+		return fmt.Sprintf("unknown flag (0x%02X)", b.Flag)
+	}
+}
+
 // String returns a formatted string for the block
 func (b Standard) String() string {
-	return fmt.Sprintf("%-13s: %d bytes", b.Name(), len(b.Data))
+	str := fmt.Sprintf("%-13s: %d bytes", b.Name(), len(b.Data))
+	if note := b.FlagNote(); note != "" {
+		str += fmt.Sprintf(", %s", note)
+	}
+	return str
 }
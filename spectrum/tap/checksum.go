@@ -0,0 +1,68 @@
+package tap
+
+import (
+	"retroio/spectrum/tap/blocks"
+	"retroio/spectrum/tap/headers"
+)
+
+// checksum returns the XOR of the flag and data bytes of the block, which is
+// the expected value of its checksum byte.
+func checksum(block Block) uint8 {
+	data := BlockBytes(block)
+
+	var sum uint8
+	for _, b := range data[:len(data)-1] {
+		sum ^= b
+	}
+	return sum
+}
+
+// VerifyChecksum returns true when the checksum byte of the block matches its
+// flag and data bytes. Fragments have no checksum, so are always valid.
+func VerifyChecksum(block Block) bool {
+	switch block.(type) {
+	case nil, *blocks.Fragment:
+		return true
+	}
+
+	data := BlockBytes(block)
+	return checksum(block) == data[len(data)-1]
+}
+
+// RepairChecksum recomputes the checksum of the block from its flag and data
+// bytes, and overwrites the checksum byte, e.g. after patching the block data.
+// It returns true when the checksum was changed.
+func RepairChecksum(block Block) bool {
+	if VerifyChecksum(block) {
+		return false
+	}
+
+	sum := checksum(block)
+	switch b := block.(type) {
+	case *blocks.Standard:
+		b.Checksum = sum
+	case *headers.ProgramData:
+		b.Checksum = sum
+	case *headers.NumericData:
+		b.Checksum = sum
+	case *headers.AlphanumericData:
+		b.Checksum = sum
+	case *headers.ByteData:
+		b.Checksum = sum
+	default:
+		return false
+	}
+	return true
+}
+
+// RepairAll repairs the checksum of every block on the tape, returning the
+// number of blocks that were changed.
+func (t *TAP) RepairAll() int {
+	repaired := 0
+	for _, block := range t.Blocks {
+		if RepairChecksum(block.TapeData) {
+			repaired++
+		}
+	}
+	return repaired
+}
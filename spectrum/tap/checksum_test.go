@@ -0,0 +1,67 @@
+package tap
+
+import (
+	"bytes"
+	"testing"
+
+	"retroio/spectrum/tap/blocks"
+	"retroio/spectrum/tap/headers"
+	"retroio/storage"
+)
+
+func TestRepairChecksum(t *testing.T) {
+	tape := readTape(t, "program.tap")
+	for i, block := range tape.Blocks {
+		if !VerifyChecksum(block.TapeData) {
+			t.Fatalf("block %d: checksum of the original tape is not valid", i+1)
+		}
+	}
+	if n := tape.RepairAll(); n != 0 {
+		t.Errorf("repaired %d blocks of a valid tape, want 0", n)
+	}
+
+	// patch a header filename, and a POKE into the code block
+	tape.Blocks[2].TapeData.(*headers.ByteData).ProgramName[0] = 'C'
+	tape.Blocks[3].TapeData.(*blocks.Standard).Data[10] ^= 0xC9
+
+	for _, i := range []int{2, 3} {
+		if VerifyChecksum(tape.Blocks[i].TapeData) {
+			t.Errorf("block %d: checksum is valid after corrupting the data", i+1)
+		}
+	}
+	if n := tape.RepairAll(); n != 2 {
+		t.Errorf("repaired %d blocks, want 2", n)
+	}
+	for i, block := range tape.Blocks {
+		if !VerifyChecksum(block.TapeData) {
+			t.Errorf("block %d: checksum is not valid after the repair", i+1)
+		}
+	}
+
+	// the repaired tape reads back with the patched data
+	var data []byte
+	for _, block := range tape.Blocks {
+		b := BlockBytes(block.TapeData)
+		data = append(data, byte(len(b)), byte(len(b)>>8))
+		data = append(data, b...)
+	}
+	repaired := New(storage.NewReader(bytes.NewReader(data)))
+	if err := repaired.Read(); err != nil {
+		t.Fatal(err)
+	}
+	for i, block := range repaired.Blocks {
+		if !VerifyChecksum(block.TapeData) {
+			t.Errorf("block %d: checksum of the written tape is not valid", i+1)
+		}
+	}
+	if name := repaired.Blocks[2].TapeData.Filename(); name != "Code      " {
+		t.Errorf("got filename %q, want the patched filename", name)
+	}
+}
+
+func TestRepairChecksumFragment(t *testing.T) {
+	fragment := &blocks.Fragment{}
+	if !VerifyChecksum(fragment) || RepairChecksum(fragment) {
+		t.Error("a fragment has no checksum, so should always be valid, and never repaired")
+	}
+}
@@ -1,9 +1,7 @@
 package headers
 
 import (
-	"encoding/binary"
 	"fmt"
-	"log"
 
 	"retroio/storage"
 )
@@ -23,17 +21,11 @@ type AlphanumericData struct {
 	Checksum     uint8    // Simply all bytes XORed (including flag byte).
 }
 
-// Read the tape and extract the data.
+// Read the tape and extract the data, tolerating headers shorter than the
+// standard length.
 // It is expected that the tape pointer is at the correct position for reading.
-func (b *AlphanumericData) Read(reader *storage.Reader) {
-	// TODO: is fatal acceptable here?
-	if length, err := reader.PeekShort(); err != nil {
-		log.Fatalf("unexpected error reading block %v.", err)
-	} else if length != 19 {
-		log.Fatalf("expected header length to be 19, got '%d'.", length)
-	}
-
-	_ = binary.Read(reader, binary.LittleEndian, b)
+func (b *AlphanumericData) Read(reader *storage.Reader) error {
+	return readHeader(reader, b)
 }
 
 func (b AlphanumericData) Id() uint8 {
@@ -45,17 +37,28 @@ func (b AlphanumericData) Name() string {
 }
 
 func (b AlphanumericData) Filename() string {
-	return string(b.ProgramName[:])
+	return storage.DecodeLatin1(b.ProgramName[:])
 }
 
 func (b AlphanumericData) BlockData() []byte {
 	return []byte{}
 }
 
+// NonStandard returns true when the header is not the standard 19 bytes long,
+// as saved by some custom loaders.
+func (b AlphanumericData) NonStandard() bool {
+	return b.Length != StandardLength
+}
+
+// DataHash returns the CRC32 of the header bytes, from the flag to the checksum.
+func (b AlphanumericData) DataHash() string {
+	return headerHash(b)
+}
+
 // String returns a formatted string for the header
 func (b AlphanumericData) String() string {
-	str := fmt.Sprintf("%s\n", b.Name())
-	str += fmt.Sprintf("    - Filename     : %s\n", b.Filename)
+	str := fmt.Sprintf("%s%s\n", b.Name(), lengthNote(b.Length))
+	str += fmt.Sprintf("    - Filename     : %s\n", storage.DisplayText(b.Filename()))
 	str += fmt.Sprintf("    - Variable Name: %c", b.VariableName-192)
 	return str
 }
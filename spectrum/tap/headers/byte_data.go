@@ -1,13 +1,15 @@
 package headers
 
 import (
-	"encoding/binary"
 	"fmt"
-	"log"
 
 	"retroio/storage"
 )
 
+// pagedRAMStart is the start of the top 16K of memory, which on a 128K
+// Spectrum holds whichever RAM bank is paged in.
+const pagedRAMStart = 0xC000
+
 // ByteData header for storing Machine Code or Screens.
 // Case #4: byte header or SCREEN$ header.
 type ByteData struct {
@@ -22,17 +24,11 @@ type ByteData struct {
 	Checksum     uint8    // Simply all bytes XORed (including flag byte).
 }
 
-// Read the tape and extract the data.
+// Read the tape and extract the data, tolerating headers shorter than the
+// standard length.
 // It is expected that the tape pointer is at the correct position for reading.
-func (b *ByteData) Read(reader *storage.Reader) {
-	// TODO: is fatal acceptable here?
-	if length, err := reader.PeekShort(); err != nil {
-		log.Fatalf("unexpected error reading block %v.", err)
-	} else if length != 19 {
-		log.Fatalf("expected header length to be 19, got '%d'.", length)
-	}
-
-	_ = binary.Read(reader, binary.LittleEndian, b)
+func (b *ByteData) Read(reader *storage.Reader) error {
+	return readHeader(reader, b)
 }
 
 func (b ByteData) Id() uint8 {
@@ -48,17 +44,39 @@ func (b ByteData) Name() string {
 }
 
 func (b ByteData) Filename() string {
-	return string(b.ProgramName[:])
+	return storage.DecodeLatin1(b.ProgramName[:])
 }
 
 func (b ByteData) BlockData() []byte {
 	return []byte{}
 }
 
+// PagedRAM returns true when the data loads into the top 16K of memory, which
+// on a 128K Spectrum may be any of the RAM banks. The bank is set by the loader
+// before the block is loaded, so is not known from the tape, and 48K programs
+// also load here, so this is only a hint that the tape may be for a 128K.
+func (b ByteData) PagedRAM() bool {
+	return b.StartAddress >= pagedRAMStart
+}
+
+// NonStandard returns true when the header is not the standard 19 bytes long,
+// as saved by some custom loaders.
+func (b ByteData) NonStandard() bool {
+	return b.Length != StandardLength
+}
+
+// DataHash returns the CRC32 of the header bytes, from the flag to the checksum.
+func (b ByteData) DataHash() string {
+	return headerHash(b)
+}
+
 // String returns a formatted string for the header
 func (b ByteData) String() string {
-	str := fmt.Sprintf("%s\n", b.Name())
-	str += fmt.Sprintf("    - Filename     : %s\n", b.ProgramName)
+	str := fmt.Sprintf("%s%s\n", b.Name(), lengthNote(b.Length))
+	str += fmt.Sprintf("    - Filename     : %s\n", storage.DisplayText(b.Filename()))
 	str += fmt.Sprintf("    - Start Address: %d", b.StartAddress)
+	if b.PagedRAM() {
+		str += "\n    - Memory       : paged RAM (bank unknown), a guess from the start address"
+	}
 	return str
 }
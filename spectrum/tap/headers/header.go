@@ -0,0 +1,61 @@
+package headers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+const (
+	// StandardLength is the length of a header block saved by the ROM: the flag
+	// byte, the 17 bytes of the header, and the checksum byte.
+	StandardLength = 19
+
+	// MinLength is the length of the shortest non-standard header that can be
+	// read, holding the flag, data type, filename and checksum bytes.
+	MinLength = 13
+)
+
+// readHeader reads a header block into the header struct.
+//
+// Some custom loaders save headers shorter than the standard 19 bytes, which
+// are read with the missing header fields left as zero, and the last byte of
+// the block as the checksum. As zero bytes do not change the XOR checksum, the
+// checksum of these headers can still be verified. The header Length gives
+// the number of bytes actually stored.
+func readHeader(reader *storage.Reader, header interface{}) error {
+	length, err := reader.PeekShort()
+	if err != nil {
+		return errors.Wrap(err, "unexpected error reading header block")
+	}
+
+	if length == StandardLength {
+		return binary.Read(reader, binary.LittleEndian, header)
+	} else if length < MinLength || length > StandardLength {
+		return errors.Errorf("expected header length to be %d to %d, got '%d'", MinLength, StandardLength, length)
+	}
+
+	data := make([]byte, 2+StandardLength)
+	if _, err := io.ReadFull(reader, data[:2+length]); err != nil {
+		return err
+	}
+
+	// move the checksum to the end of the header
+	data[len(data)-1], data[1+length] = data[1+length], 0
+
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, header)
+}
+
+// lengthNote returns a note for the headers with a non-standard length, or
+// an empty string for standard headers.
+func lengthNote(length uint16) string {
+	if length == StandardLength {
+		return ""
+	}
+	return fmt.Sprintf(" (non-standard header, %d bytes)", length)
+}
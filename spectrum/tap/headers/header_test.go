@@ -0,0 +1,126 @@
+package headers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"retroio/storage"
+)
+
+// reader is the header read by each of the header types.
+type reader interface {
+	Read(reader *storage.Reader) error
+}
+
+func TestReadShortBuffer(t *testing.T) {
+	headers := []struct {
+		name   string
+		header func() reader
+	}{
+		{"program", func() reader { return &ProgramData{} }},
+		{"numeric array", func() reader { return &NumericData{} }},
+		{"character array", func() reader { return &AlphanumericData{} }},
+		{"bytes", func() reader { return &ByteData{} }},
+	}
+
+	buffers := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"half a length", []byte{19}},
+		{"length only", []byte{19, 0}},
+		{"truncated header", []byte{19, 0, 0, 3, 'N', 'A', 'M', 'E'}},
+		{"truncated short header", []byte{15, 0, 0, 3, 'N', 'A', 'M', 'E'}},
+		{"too short", []byte{5, 0, 0, 3, 'N', 'A', 'M', 'E', 0}},
+		{"too long", append([]byte{20, 0}, make([]byte, 20)...)},
+	}
+
+	for _, h := range headers {
+		for _, b := range buffers {
+			t.Run(h.name+"/"+b.name, func(t *testing.T) {
+				r := storage.NewReader(bytes.NewReader(b.data))
+				if err := h.header().Read(r); err == nil {
+					t.Error("expected an error")
+				}
+			})
+		}
+	}
+}
+
+func TestReadNonStandardLength(t *testing.T) {
+	type header interface {
+		reader
+		Filename() string
+		NonStandard() bool
+	}
+
+	headers := []struct {
+		name     string
+		dataType byte
+		header   func() header
+	}{
+		{"program", 0, func() header { return &ProgramData{} }},
+		{"numeric array", 1, func() header { return &NumericData{} }},
+		{"character array", 2, func() header { return &AlphanumericData{} }},
+		{"bytes", 3, func() header { return &ByteData{} }},
+	}
+
+	for _, h := range headers {
+		for _, length := range []int{MinLength, 18, StandardLength} {
+			t.Run(fmt.Sprintf("%s/%d bytes", h.name, length), func(t *testing.T) {
+				// the header bytes, then the checksum, followed by the next block
+				data := []byte{byte(length), 0, 0, h.dataType}
+				data = append(data, "custom    "...)
+				for len(data) < 2+length-1 {
+					data = append(data, 0x80)
+				}
+				data = append(data, 0x5A, 0x13, 0x00)
+
+				r := storage.NewReader(bytes.NewReader(data))
+				header := h.header()
+				if err := header.Read(r); err != nil {
+					t.Fatal(err)
+				}
+
+				if r.Offset() != int64(2+length) {
+					t.Errorf("got offset %d after reading, want %d", r.Offset(), 2+length)
+				}
+				if header.NonStandard() != (length != StandardLength) {
+					t.Errorf("got non-standard %v for a %d byte header", header.NonStandard(), length)
+				}
+				if header.Filename() != "custom    " {
+					t.Errorf("got filename %q, want %q", header.Filename(), "custom    ")
+				}
+
+				// the checksum is the last byte of the block
+				buf := &bytes.Buffer{}
+				_ = binary.Write(buf, binary.LittleEndian, header)
+				if checksum := buf.Bytes()[buf.Len()-1]; checksum != 0x5A {
+					t.Errorf("got checksum %02X, want 5A", checksum)
+				}
+			})
+		}
+	}
+}
+
+func TestFilenameLatin1(t *testing.T) {
+	// a bytes header named "Café ©84 ÿ", with the characters above 0x7F
+	// stored as single Latin-1 bytes
+	data := []byte{19, 0, 0, 3}
+	data = append(data, 'C', 'a', 'f', 0xe9, ' ', 0xa9, '8', '4', 0xa0, 0xff)
+	data = append(data, 0x00, 0x40, 0x00, 0x1b, 0x00, 0x80, 0x00)
+
+	r := storage.NewReader(bytes.NewReader(data))
+	header := &ByteData{}
+	if err := header.Read(r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Café ©84\u00a0ÿ"
+	if header.Filename() != want {
+		t.Errorf("got filename %q, want %q", header.Filename(), want)
+	}
+}
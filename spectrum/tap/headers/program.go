@@ -1,9 +1,9 @@
 package headers
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
-	"log"
 
 	"retroio/storage"
 )
@@ -22,17 +22,15 @@ type ProgramData struct {
 	Checksum      uint8    // Simply all bytes XORed (including flag byte).
 }
 
-// Read the tape and extract the data.
-// It is expected that the tape pointer is at the correct position for reading.
-func (b *ProgramData) Read(reader *storage.Reader) {
-	// TODO: is fatal acceptable here?
-	if length, err := reader.PeekShort(); err != nil {
-		log.Fatalf("unexpected error reading block %v.", err)
-	} else if length != 19 {
-		log.Fatalf("expected header length to be 19, got '%d'.", length)
-	}
+// NoAutoStart is the lowest LINE parameter meaning the program is not started
+// automatically after loading.
+const NoAutoStart = 32768
 
-	_ = binary.Read(reader, binary.LittleEndian, b)
+// Read the tape and extract the data, tolerating headers shorter than the
+// standard length.
+// It is expected that the tape pointer is at the correct position for reading.
+func (b *ProgramData) Read(reader *storage.Reader) error {
+	return readHeader(reader, b)
 }
 
 func (b ProgramData) Id() uint8 {
@@ -44,17 +42,70 @@ func (b ProgramData) Name() string {
 }
 
 func (b ProgramData) Filename() string {
-	return string(b.ProgramName[:])
+	return storage.DecodeLatin1(b.ProgramName[:])
 }
 
 func (b ProgramData) BlockData() []byte {
 	return []byte{}
 }
 
+// AutoStart returns the line the program is run from after loading, or false
+// when the program is not started automatically.
+func (b ProgramData) AutoStart() (uint16, bool) {
+	return b.AutoStartLine, b.AutoStartLine < NoAutoStart
+}
+
+// AutoStartText returns the auto-start of the program, e.g. "autostart at line 10".
+func (b ProgramData) AutoStartText() string {
+	if line, ok := b.AutoStart(); ok {
+		return fmt.Sprintf("autostart at line %d", line)
+	}
+	return "no autostart"
+}
+
+// VariablesOffset returns the offset of the variables area in the program
+// data, which directly follows the program lines.
+func (b ProgramData) VariablesOffset() uint16 {
+	return b.ProgramLength
+}
+
+// SplitVariables returns the program lines and the variables area of the
+// program data. When the variables offset is beyond the end of the data, all
+// the data is returned as the program.
+func (b ProgramData) SplitVariables(data []byte) (program, variables []byte) {
+	if offset := int(b.VariablesOffset()); offset < len(data) {
+		return data[:offset], data[offset:]
+	}
+	return data, nil
+}
+
+// NonStandard returns true when the header is not the standard 19 bytes long,
+// as saved by some custom loaders.
+func (b ProgramData) NonStandard() bool {
+	return b.Length != StandardLength
+}
+
+// DataHash returns the CRC32 of the header bytes, from the flag to the checksum.
+func (b ProgramData) DataHash() string {
+	return headerHash(b)
+}
+
 // String returns a formatted string for the header
 func (b ProgramData) String() string {
-	str := fmt.Sprintf("%s\n", b.Name())
-	str += fmt.Sprintf("    - Filename        : %s\n", b.ProgramName)
-	str += fmt.Sprintf("    - AutoStartLine   : %d", b.AutoStartLine)
+	str := fmt.Sprintf("%s%s\n", b.Name(), lengthNote(b.Length))
+	str += fmt.Sprintf("    - Filename        : %s\n", storage.DisplayText(b.Filename()))
+	if line, ok := b.AutoStart(); ok {
+		str += fmt.Sprintf("    - AutoStartLine   : %d\n", line)
+	} else {
+		str += "    - AutoStartLine   : none\n"
+	}
+	str += fmt.Sprintf("    - VariablesOffset : %d", b.VariablesOffset())
 	return str
 }
+
+// headerHash returns the CRC32 of the header, excluding the block length.
+func headerHash(header interface{}) string {
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, header)
+	return storage.DataHash(buf.Bytes()[2:])
+}
@@ -0,0 +1,80 @@
+package tap
+
+import (
+	"retroio/spectrum/tap/headers"
+)
+
+// BasicProgram is a Program header, and the data block holding the program.
+type BasicProgram struct {
+	Header *headers.ProgramData
+	Index  int   // Index of the data block in the blocks
+	Data   Block // Data block: the program lines, followed by any variables
+}
+
+// BasicPrograms pairs each Program header with its data block. The blocks may
+// include nil entries, for blocks without data, which are skipped, so the
+// index of each data block can be matched to the tape block.
+//
+// A header is normally followed directly by its data, but other blocks may be
+// found in between, such as a CODE header and its data, or a further header
+// when the data of a header is missing. Each data block is therefore paired
+// with the most recent header still waiting for its data whose data length
+// matches the block. When none match, the block is only paired with a header
+// directly before it, as its length may be wrong, and is otherwise headerless.
+// Data blocks paired with a CODE or array header are not listed as programs,
+// and Program headers without any data are dropped.
+func BasicPrograms(blocks []Block) []BasicProgram {
+	var programs []BasicProgram
+	var waiting []Block
+	afterHeader := false
+
+	for i, block := range blocks {
+		if block == nil {
+			continue
+		}
+		if IsHeader(block) {
+			waiting = append(waiting, block)
+			afterHeader = true
+			continue
+		}
+
+		match := -1
+		for j := len(waiting) - 1; j >= 0; j-- {
+			if headerDataLength(waiting[j]) == len(block.BlockData()) {
+				match = j
+				break
+			}
+		}
+		if match < 0 && afterHeader {
+			match = len(waiting) - 1
+		}
+		afterHeader = false
+		if match < 0 {
+			continue // headerless data
+		}
+		header := waiting[match]
+		waiting = append(waiting[:match], waiting[match+1:]...)
+
+		if program, ok := header.(*headers.ProgramData); ok {
+			programs = append(programs, BasicProgram{Header: program, Index: i, Data: block})
+		}
+	}
+
+	return programs
+}
+
+// headerDataLength returns the length of the data block following the header,
+// as given by the header.
+func headerDataLength(header Block) int {
+	switch h := header.(type) {
+	case *headers.ProgramData:
+		return int(h.DataLength)
+	case *headers.NumericData:
+		return int(h.DataLength)
+	case *headers.AlphanumericData:
+		return int(h.DataLength)
+	case *headers.ByteData:
+		return int(h.DataLength)
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package tap
+
+import (
+	"retroio/spectrum/tap/headers"
+)
+
+// Split separates the tape into single program tapes, where each tape has a
+// header block followed by all its data blocks, up to the next header.
+// Any data blocks found before the first header are orphans, and each is given
+// its own tape, unlike GroupPrograms, which lists them as one program.
+// The blocks are shared with the original tape, not copied.
+func (t TAP) Split() []*TAP {
+	var tapes []*TAP
+	var current *TAP
+
+	for _, block := range t.Blocks {
+		if IsHeader(block.TapeData) || current == nil {
+			current = &TAP{}
+			tapes = append(tapes, current)
+		}
+		current.Blocks = append(current.Blocks, block)
+
+		// an orphan data block is not grouped with the blocks following it
+		if !IsHeader(current.Blocks[0].TapeData) {
+			current = nil
+		}
+	}
+
+	return tapes
+}
+
+// Programs groups the blocks of the tape into the programs saved on it, see
+// GroupPrograms.
+func (t TAP) Programs() [][]Block {
+	var blocks []Block
+	for _, block := range t.Blocks {
+		blocks = append(blocks, block.TapeData)
+	}
+	return GroupPrograms(blocks)
+}
+
+// GroupPrograms groups the blocks into the programs saved on a tape, where a
+// Program or Bytes header starts a new program, and the data blocks following
+// it belong to that program, up to the next Program or Bytes header. Array
+// headers, and their data, belong to the program they follow, as they usually
+// hold the data of that program. Any blocks before the first header are
+// grouped together as a program without a header.
+func GroupPrograms(blocks []Block) [][]Block {
+	var programs [][]Block
+	for _, block := range blocks {
+		switch block.(type) {
+		case *headers.ProgramData, *headers.ByteData:
+			programs = append(programs, nil)
+		default:
+			if len(programs) == 0 {
+				programs = append(programs, nil)
+			}
+		}
+		programs[len(programs)-1] = append(programs[len(programs)-1], block)
+	}
+	return programs
+}
+
+// Merge joins the tapes into a single tape, keeping the order of the blocks.
+func Merge(tapes ...*TAP) *TAP {
+	merged := &TAP{}
+	for _, t := range tapes {
+		if t != nil {
+			merged.Blocks = append(merged.Blocks, t.Blocks...)
+		}
+	}
+	return merged
+}
+
+// IsHeader returns true when the block is one of the 19-byte header blocks.
+func IsHeader(block Block) bool {
+	switch block.(type) {
+	case *headers.ProgramData, *headers.NumericData, *headers.AlphanumericData, *headers.ByteData:
+		return true
+	default:
+		return false
+	}
+}
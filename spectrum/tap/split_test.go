@@ -0,0 +1,56 @@
+package tap
+
+import (
+	"testing"
+
+	"retroio/spectrum/tap/blocks"
+	"retroio/spectrum/tap/headers"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []Block
+		want   []int // number of blocks of each tape
+	}{
+		{"empty", nil, nil},
+		{"program", []Block{&headers.ProgramData{}, &blocks.Standard{}}, []int{2}},
+		{"program and code", []Block{&headers.ProgramData{}, &blocks.Standard{}, &headers.ByteData{}, &blocks.Standard{}}, []int{2, 2}},
+		{"array after program", []Block{&headers.ProgramData{}, &blocks.Standard{}, &headers.NumericData{}, &blocks.Standard{}}, []int{2, 2}},
+		{"several data blocks", []Block{&headers.ByteData{}, &blocks.Standard{}, &blocks.Standard{}}, []int{3}},
+		// each orphan block before the first header is given its own tape
+		{"orphan blocks", []Block{&blocks.Standard{}, &blocks.Standard{}, &headers.ByteData{}, &blocks.Standard{}}, []int{1, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tape := TAP{}
+			for _, block := range tt.blocks {
+				tape.Blocks = append(tape.Blocks, TapeBlock{TapeData: block})
+			}
+
+			tapes := tape.Split()
+			if len(tapes) != len(tt.want) {
+				t.Fatalf("got %d tapes, want %d", len(tapes), len(tt.want))
+			}
+
+			// the blocks are split in order, shared with the original tape
+			next := 0
+			for i, split := range tapes {
+				if len(split.Blocks) != tt.want[i] {
+					t.Fatalf("tape %d: got %d blocks, want %d", i, len(split.Blocks), tt.want[i])
+				}
+				for j, block := range split.Blocks {
+					if block.TapeData != tt.blocks[next] {
+						t.Errorf("tape %d block %d: got a different block from block %d of the tape", i, j, next)
+					}
+					next++
+				}
+			}
+
+			if merged := Merge(tapes...); len(merged.Blocks) != len(tt.blocks) {
+				t.Errorf("got %d blocks merged, want %d", len(merged.Blocks), len(tt.blocks))
+			}
+		})
+	}
+}
@@ -13,6 +13,8 @@
 package tap
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"strings"
@@ -45,11 +47,43 @@ type TapeBlock struct {
 
 // Block is an interface for TAP header/data block
 type Block interface {
-	Read(reader *storage.Reader)
+	Read(reader *storage.Reader) error
 	Id() uint8
 	Filename() string
 	Name() string
 	BlockData() []byte
+	DataHash() string
+}
+
+// BlockHash is the data hash of a single tape block, used for identifying
+// tapes by their data, while ignoring any pauses, descriptions, etc.
+type BlockHash struct {
+	Block int    // Block number, starting from 1
+	Name  string // Block name
+	Hash  string // CRC32 of the block data
+}
+
+// BlockSummary is the metadata of a single tape block, as shown by the
+// geometry, used for the structured (JSON, CSV) output of the tape.
+type BlockSummary struct {
+	Block   int    // Block number, starting from 1
+	ID      int    // TZX block ID, or -1 for tapes without block IDs
+	Name    string // Block name
+	Summary string // Block metadata, on a single line
+	Offset  int64  // Offset of the block in the file, or -1 when not known
+	Data    []byte // Block data as played on the tape, or nil for blocks without data
+}
+
+// NewBlockSummary returns the summary of a block, where the details, which
+// may span several lines, are joined on to a single line.
+func NewBlockSummary(number int, name string, details interface{}) BlockSummary {
+	var lines []string
+	for _, line := range strings.Split(fmt.Sprint(details), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return BlockSummary{Block: number, ID: -1, Name: name, Summary: strings.Join(lines, "; "), Offset: -1}
 }
 
 func New(reader *storage.Reader) *TAP {
@@ -63,27 +97,40 @@ func (t *TAP) Read() error {
 	// e.g. Turbo Outrun.
 	blockCanBeHeader := true
 
+	if t.reader.Lenient() {
+		if err := t.skipLeadingJunk(); err != nil {
+			return err
+		}
+	}
+
 	for {
 		// Lookup the length of the block to know what type it is.
 		blockLength, err := t.reader.PeekShort()
-		if err != nil && err == io.EOF {
+		if err != nil && err == io.EOF && t.reader.Buffered() == 0 {
 			break // no problems, we're done!
+		} else if err == io.EOF {
+			// a single stray byte, not enough for the block length
+			return storage.TruncatedError{Blocks: len(t.Blocks), Offset: t.reader.Offset()}
 		} else if err != nil {
 			return err
 		}
 
 		block := TapeBlock{Length: blockLength}
+		offset := t.reader.Offset()
 
-		if block.Length == 19 && blockCanBeHeader {
-			block.TapeData, err = t.ReadHeaderBlock()
-			blockCanBeHeader = false
+		if blockCanBeHeader {
+			block.TapeData, err = t.ReadBlock()
+			blockCanBeHeader = !IsHeader(block.TapeData)
 		} else {
 			block.TapeData, err = t.ReadDataBlock()
 			blockCanBeHeader = true
 		}
 
-		if err != nil && err == io.EOF {
-			break // no problems, we're done!
+		if err == nil {
+			err = t.reader.Err()
+		}
+		if storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: len(t.Blocks), Offset: offset}
 		} else if err != nil {
 			return err
 		}
@@ -94,6 +141,83 @@ func (t *TAP) Read() error {
 	return nil
 }
 
+// Limits of the search for the first block of a TAP file with leading junk.
+const (
+	maxLeadingJunk = 256  // most bytes skipped before the first block
+	junkPeekLength = 4096 // bytes peeked, the size of the reader buffer
+)
+
+// skipLeadingJunk skips any bytes some tools add to the start of a TAP file,
+// such as a tool-specific header, which would otherwise be read as the length
+// of the first block. When the file does not start with a plausible block, the
+// first plausible block within maxLeadingJunk bytes is found, and the bytes
+// before it are skipped with a warning. Only used in lenient mode.
+func (t *TAP) skipLeadingJunk() error {
+	data, err := t.reader.Peek(junkPeekLength)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if len(data) == 0 || plausibleBlock(data) {
+		return nil
+	}
+
+	for skip := 1; skip <= maxLeadingJunk && skip < len(data); skip++ {
+		if !plausibleBlock(data[skip:]) {
+			continue
+		}
+		if _, err := t.reader.Discard(skip); err != nil {
+			return err
+		}
+		return t.reader.Tolerate(errors.Errorf("skipped %d bytes of leading junk before the first block", skip))
+	}
+	return nil
+}
+
+// plausibleBlock reports whether the data starts with a TAP block: a length
+// word, followed by a header or data flag byte, with a valid checksum. The
+// whole block must be within the data, so the checksum can be checked.
+func plausibleBlock(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	length := int(binary.LittleEndian.Uint16(data[0:2]))
+	if length < 2 || len(data) < 2+length {
+		return false
+	}
+	if flag := data[2]; flag != 0x00 && flag != 0xFF {
+		return false
+	}
+
+	var sum uint8
+	for _, b := range data[2 : 2+length] {
+		sum ^= b
+	}
+	return sum == 0
+}
+
+// ReadBlock reads a header block when the block has the length, flag byte and
+// data type of a header, otherwise a data block is read. Blocks are classified
+// by both the flag byte and the header data type, so 19-byte blocks using a
+// custom flag byte, or an unknown data type, are read as data blocks.
+//
+// Blocks with the flag byte and data type of a header, but shorter than 19
+// bytes, as saved by some custom loaders, are read as non-standard headers.
+func (t *TAP) ReadBlock() (Block, error) {
+	blockBytes, err := t.reader.Peek(4)
+	if err != nil {
+		return t.ReadDataBlock()
+	}
+
+	length := binary.LittleEndian.Uint16(blockBytes[0:2])
+	flag := blockBytes[2]
+	dataType := blockBytes[3]
+
+	if length >= headers.MinLength && length <= headers.StandardLength && flag == 0 && dataType <= 3 {
+		return t.ReadHeaderBlock()
+	}
+	return t.ReadDataBlock()
+}
+
 // ReadHeaderBlock reads the different types of 19-byte header blocks.
 func (t *TAP) ReadHeaderBlock() (Block, error) {
 	// Look up the Flag and DataType bytes, ignoring the 2-byte block Length
@@ -124,7 +248,9 @@ func (t *TAP) ReadHeaderBlock() (Block, error) {
 		return nil, errors.New(fmt.Sprintf("unknown header type '%d'", dataType))
 	}
 
-	header.Read(t.reader)
+	if err := header.Read(t.reader); err != nil {
+		return nil, err
+	}
 
 	return header, nil
 }
@@ -146,11 +272,69 @@ func (t *TAP) ReadDataBlock() (Block, error) {
 		block = &blocks.Standard{}
 	}
 
-	block.Read(t.reader)
+	if err := block.Read(t.reader); err != nil {
+		return nil, err
+	}
 
 	return block, nil
 }
 
+// Title returns the filename of the first header block, as TAP files have
+// no tape title.
+func (t TAP) Title() string {
+	for _, block := range t.Blocks {
+		if name := strings.TrimSpace(block.TapeData.Filename()); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// BlockHashes returns the data hash of each block on the tape.
+func (t TAP) BlockHashes() []BlockHash {
+	var hashes []BlockHash
+	for i, block := range t.Blocks {
+		hashes = append(hashes, BlockHash{Block: i + 1, Name: block.TapeData.Name(), Hash: block.TapeData.DataHash()})
+	}
+	return hashes
+}
+
+// BlockSummaries returns the metadata of each block on the tape.
+func (t TAP) BlockSummaries() []BlockSummary {
+	var summaries []BlockSummary
+	for i, block := range t.Blocks {
+		summary := NewBlockSummary(i+1, block.TapeData.Name(), block.TapeData)
+		summary.Data = BlockBytes(block.TapeData)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// BlockBytes returns the bytes of the block as played on the tape: the flag,
+// data and checksum bytes, without the block length.
+func BlockBytes(block Block) []byte {
+	switch b := block.(type) {
+	case nil:
+		return nil
+	case *blocks.Standard:
+		data := append([]byte{b.Flag}, b.Data...)
+		return append(data, b.Checksum)
+	case *blocks.Fragment:
+		return b.BlockData()
+	default:
+		// headers are stored as their fixed size structs
+		buf := &bytes.Buffer{}
+		_ = binary.Write(buf, binary.LittleEndian, block)
+		data := buf.Bytes()[2:]
+
+		// non-standard headers are missing the bytes before the checksum
+		if length := int(binary.LittleEndian.Uint16(buf.Bytes())); length >= headers.MinLength && length < len(data) {
+			data = append(data[:length-1], data[len(data)-1])
+		}
+		return data
+	}
+}
+
 // DisplayGeometry outputs the metadata of each data block to the terminal.
 func (t TAP) DisplayGeometry() {
 	fmt.Println("DATA BLOCKS:")
@@ -159,32 +343,49 @@ func (t TAP) DisplayGeometry() {
 	}
 }
 
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read, such as blocks with an incorrect checksum.
+func (t TAP) Warnings() []string {
+	var warnings []string
+
+	for i, block := range t.Blocks {
+		if !VerifyChecksum(block.TapeData) {
+			warnings = append(warnings, fmt.Sprintf("block #%02d %s: incorrect checksum", i+1, block.TapeData.Name()))
+		}
+	}
+
+	return warnings
+}
+
 // DisplayBASIC outputs all BASIC programs
 func (t TAP) DisplayBASIC() {
-	isProgram := false
-	filename := ""
+	var data []Block
+	for _, block := range t.Blocks {
+		data = append(data, block.TapeData)
+	}
 
 	fmt.Println("BASIC PROGRAMS:")
 	fmt.Println()
-	for i, block := range t.Blocks {
-		if isProgram == true {
-			fmt.Printf("BLK#%02d: %s\n", i+1, filename)
-			program, err := basic.Decode(block.TapeData.BlockData())
-			if err != nil {
-				fmt.Printf("    %s\n", err)
-				continue
-			}
-
-			for _, line := range program {
-				fmt.Printf("%s", line)
-			}
-			fmt.Println()
-			fmt.Println()
-
-			isProgram = false
-		} else if block.TapeData.Id() == 0 {
-			filename = strings.Trim(block.TapeData.Filename(), " ")
-			isProgram = true
+	for _, p := range BasicPrograms(data) {
+		header := p.Header
+		fmt.Printf("BLK#%02d: %s\n", p.Index+1, storage.DisplayText(strings.Trim(header.Filename(), " ")))
+		fmt.Printf("    %s\n", header.AutoStartText())
+		if b, ok := p.Data.(*blocks.Standard); ok && b.FlagNote() != "" {
+			fmt.Printf("    NOTE: %s\n", b.FlagNote())
+		}
+		program, variables := header.SplitVariables(p.Data.BlockData())
+
+		lines, err := basic.Decode(program)
+		if err != nil {
+			fmt.Printf("    %s\n", err)
+			continue
+		}
+
+		for _, line := range lines {
+			fmt.Printf("%s", line)
 		}
+		fmt.Print(storage.DisplayText(basic.ListVariables(variables)))
+		fmt.Println()
+		fmt.Println()
 	}
 }
@@ -0,0 +1,93 @@
+package tap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"retroio/spectrum/tap/blocks"
+	"retroio/spectrum/tap/headers"
+	"retroio/storage"
+)
+
+// readTape reads the tape in the testdata directory, failing the test on any error.
+func readTape(t *testing.T, name string) *TAP {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); err != nil {
+		t.Fatalf("unexpected error reading tape: %v", err)
+	}
+	return tape
+}
+
+func TestReadNonStandardFlags(t *testing.T) {
+	tape := readTape(t, "flags.tap")
+
+	tests := []struct {
+		header bool
+		note   string
+	}{
+		{false, "unknown flag (0x42)"}, // a header with a custom flag is a data block
+		{true, ""},
+		{false, "unknown flag (0x42)"}, // the program data with a custom flag
+		{false, "header flag (0x00), but not a valid header"},
+	}
+
+	if len(tape.Blocks) != len(tests) {
+		t.Fatalf("got %d blocks, want %d", len(tape.Blocks), len(tests))
+	}
+	for i, tt := range tests {
+		block := tape.Blocks[i].TapeData
+		if tt.header {
+			if _, ok := block.(*headers.ProgramData); !ok {
+				t.Errorf("block %d: got %T, want a program header", i+1, block)
+			}
+			continue
+		}
+
+		data, ok := block.(*blocks.Standard)
+		if !ok {
+			t.Errorf("block %d: got %T, want a data block", i+1, block)
+			continue
+		}
+		if note := data.FlagNote(); note != tt.note {
+			t.Errorf("block %d: got note %q, want %q", i+1, note, tt.note)
+		}
+	}
+
+	// the program data is still listed with its header
+	programs := tape.Programs()
+	if len(programs) != 2 || len(programs[1]) != 3 || programs[1][1] != tape.Blocks[2].TapeData {
+		t.Errorf("got %d programs, want the program data grouped with its header", len(programs))
+	}
+}
+
+func TestReadNonStandardHeader(t *testing.T) {
+	tape := readTape(t, "header18.tap")
+	if len(tape.Blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(tape.Blocks))
+	}
+
+	header, ok := tape.Blocks[0].TapeData.(*headers.ByteData)
+	if !ok {
+		t.Fatalf("got %T, want a bytes header", tape.Blocks[0].TapeData)
+	}
+	if header.Length != 18 || !header.NonStandard() {
+		t.Errorf("got a %d byte header, non-standard %v, want 18 bytes", header.Length, header.NonStandard())
+	}
+	if header.Filename() != "custom    " || header.StartAddress != 32768 || header.DataLength != 100 {
+		t.Errorf("got %q, start %d, length %d, want \"custom    \", 32768, 100", header.Filename(), header.StartAddress, header.DataLength)
+	}
+	if !VerifyChecksum(header) {
+		t.Error("checksum of the non-standard header is not valid")
+	}
+
+	if data, ok := tape.Blocks[1].TapeData.(*blocks.Standard); !ok || len(data.Data) != 100 {
+		t.Errorf("got %T, want a 100 byte data block", tape.Blocks[1].TapeData)
+	}
+}
@@ -0,0 +1,164 @@
+package tzx
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tzx/blocks"
+)
+
+// Score deductions for each kind of loading problem, from a perfect 100.
+const (
+	penaltyBrokenStructure = 25 // block length or control flow error
+	penaltyShortBitPulse   = 15
+	penaltyCloseBitPulses  = 15
+	penaltyShortPilotTone  = 10
+	penaltyMissingPause    = 10
+	penaltySampleRate      = 5
+)
+
+// Issue is a problem found by Analyze, with an explanation of why it makes
+// the tape less likely to load on real hardware.
+type Issue struct {
+	Block       int    // Block number, starting from 1, or zero for the whole tape
+	Name        string // Block type name
+	Problem     string
+	Explanation string
+	Penalty     int // points deducted from the score
+}
+
+func (i Issue) String() string {
+	if i.Block == 0 {
+		return i.Problem
+	}
+	return fmt.Sprintf("block #%02d %s: %s", i.Block, i.Name, i.Problem)
+}
+
+// Analysis is the estimated loading reliability of a tape.
+type Analysis struct {
+	Score  int // from 0 to 100, with 100 having no problems found
+	Issues []Issue
+}
+
+// Rating describes the score in words.
+func (a Analysis) Rating() string {
+	switch {
+	case a.Score >= 90:
+		return "good"
+	case a.Score >= 70:
+		return "fair"
+	case a.Score >= 40:
+		return "poor"
+	default:
+		return "unlikely to load"
+	}
+}
+
+// Analyze estimates how reliably the tape will load on real hardware, from
+// the block timings and structure: bit pulses that are too short or too close
+// together, pilot tones too short for the loader to lock on, data blocks
+// without a pause before the next pilot tone, and direct recordings at odd
+// sample rates. Any block length or control flow errors found by Validate and
+// ValidateControlFlow are also included.
+//
+// Each issue deducts a penalty from a score of 100. The score is a heuristic,
+// a low score does not mean the tape will fail to load, only that it is worth
+// checking on real hardware.
+func (t TZX) Analyze() Analysis {
+	var issues []Issue
+
+	for _, err := range append(t.Validate(), t.ValidateControlFlow()...) {
+		issues = append(issues, Issue{
+			Problem:     err.Error(),
+			Explanation: "The tape structure is broken, so players and emulators may skip or misplay blocks.",
+			Penalty:     penaltyBrokenStructure,
+		})
+	}
+
+	for i, block := range t.blocks {
+		add := func(penalty int, explanation, format string, args ...interface{}) {
+			issues = append(issues, Issue{
+				Block:       t.BlockNumber(i),
+				Name:        block.Name(),
+				Problem:     fmt.Sprintf(format, args...),
+				Explanation: explanation,
+				Penalty:     penalty,
+			})
+		}
+
+		switch b := block.(type) {
+		case *blocks.TurboSpeedData:
+			if b.PilotTone < minPilotTone {
+				add(penaltyShortPilotTone,
+					"The loader needs a long enough pilot tone to lock on, and to allow for tape speed variation.",
+					"pilot tone of %d pulses is too short, expected at least %d", b.PilotTone, minPilotTone)
+			}
+			analyzeBits(add, b.ZeroBitPulse, b.OneBitPulse)
+			if t.pilotFollows(i) && b.Pause == 0 {
+				addMissingPause(add)
+			}
+		case *blocks.PureData:
+			analyzeBits(add, b.ZeroBitPulse, b.OneBitPulse)
+			if t.pilotFollows(i) && b.Pause == 0 {
+				addMissingPause(add)
+			}
+		case *blocks.StandardSpeedData:
+			if t.pilotFollows(i) && b.Pause == 0 {
+				addMissingPause(add)
+			}
+		case *blocks.DirectRecording:
+			if !directRecordingRates[b.TStatesPerSample] {
+				add(penaltySampleRate,
+					"Odd sample rates may be played back with timing errors when converted to audio.",
+					"sampled at %d T-states per sample (%d Hz), expected 79 (44100 Hz) or 158 (22050 Hz)",
+					b.TStatesPerSample, sampleRate(b.TStatesPerSample))
+			}
+		}
+	}
+
+	score := 100
+	for _, issue := range issues {
+		score -= issue.Penalty
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	return Analysis{Score: score, Issues: issues}
+}
+
+// analyzeBits adds an issue when the bit pulses are too short, or too close
+// to each other to be told apart.
+func analyzeBits(add func(int, string, string, ...interface{}), zero, one uint16) {
+	if zero < minReliablePulse || one < minReliablePulse {
+		add(penaltyShortBitPulse,
+			"Short bit cells are easily distorted by the tape and the cassette player, causing load errors.",
+			"bit pulses of %d and %d T-states are too short, expected at least %d", zero, one, minReliablePulse)
+	}
+	if float64(one) < float64(zero)*minBitRatio {
+		add(penaltyCloseBitPulses,
+			"The loader tells the bits apart by their length, so similar pulses are easily misread.",
+			"one-bit pulse of %d T-states is too close to the zero-bit pulse of %d T-states", one, zero)
+	}
+}
+
+func addMissingPause(add func(int, string, string, ...interface{})) {
+	add(penaltyMissingPause,
+		"Without a pause, the loader may not be ready for the pilot tone of the next block.",
+		"no pause before the pilot tone of the next block")
+}
+
+// pilotFollows reports whether the next block starts with a pilot tone,
+// ignoring any blocks that produce no sound, such as text descriptions.
+func (t TZX) pilotFollows(index int) bool {
+	for _, block := range t.blocks[index+1:] {
+		switch block.(type) {
+		case *blocks.StandardSpeedData, *blocks.TurboSpeedData, *blocks.PureTone:
+			return true
+		case *blocks.TextDescription, *blocks.ArchiveInfo, *blocks.GroupStart, *blocks.GroupEnd,
+			*blocks.CustomInfo, *blocks.HardwareType, *blocks.GlueBlock:
+			continue
+		}
+		return false
+	}
+	return false
+}
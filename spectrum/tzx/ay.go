@@ -0,0 +1,117 @@
+package tzx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/hardware"
+	"retroio/spectrum/tzx/blocks"
+)
+
+// ErrNoAYData is returned by ExtractAYData when the tape has no AY music data.
+var ErrNoAYData = errors.New("tzx: no AY data found")
+
+// ayHardwareIDs are the sound devices using the AY-3-8912 chip.
+var ayHardwareIDs = map[uint8]bool{
+	0x00: true, // Classic AY hardware (compatible with 128k ZXs)
+	0x01: true, // Fuller Box AY sound hardware
+	0x04: true, // AY ACB stereo (A+C=left, B+C=right); Melodik
+	0x05: true, // AY ABC stereo (A+B=left, B+C=right)
+	0x0a: true, // Zon-X AY
+	0x0b: true, // QuickSilva AY
+}
+
+// Identification strings of the custom info blocks used for AY data.
+var ayCustomInfoIDs = []string{"AY", "PSG", "YM"}
+
+// UsesAY returns true when the hardware type block declares that the tape
+// runs on, or uses, an AY sound chip.
+func (t TZX) UsesAY() bool {
+	for _, block := range t.blocks {
+		hw, ok := block.(*blocks.HardwareType)
+		if !ok {
+			continue
+		}
+		for _, m := range hw.Machines {
+			if m.Type == hardware.SoundDevices && ayHardwareIDs[m.Id] && m.Information <= 0x01 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExtractAYData returns the AY music data stored in a custom info block.
+// The blocks are identified by an `AY`, `PSG` or `YM` identification string,
+// or by their data starting with a PSG or YM file signature.
+//
+// The data is returned as stored, and the format and number of frames of a
+// register dump can be found with AYFrames.
+func (t TZX) ExtractAYData() ([]byte, error) {
+	for _, block := range t.blocks {
+		info, ok := block.(*blocks.CustomInfo)
+		if !ok {
+			continue
+		}
+
+		id := strings.ToUpper(strings.TrimSpace(strings.Trim(string(info.Identification[:]), "\x00")))
+		for _, prefix := range ayCustomInfoIDs {
+			if strings.HasPrefix(id, prefix) {
+				return info.Info, nil
+			}
+		}
+		if _, _, ok := AYFrames(info.Info); ok {
+			return info.Info, nil
+		}
+	}
+
+	return nil, ErrNoAYData
+}
+
+// AYFrames returns the format and the number of frames (interrupts) of an
+// AY register dump, in one of the recognised formats: PSG, YM3, YM3b, YM5
+// and YM6. False is returned for other data, including compressed YM files.
+func AYFrames(data []byte) (string, int, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PSG\x1a")) && len(data) >= 16:
+		return "PSG", psgFrames(data[16:]), true
+	case bytes.HasPrefix(data, []byte("YM3b")):
+		// 14 registers for each frame, followed by the loop frame number
+		return "YM3b", (len(data) - 8) / 14, len(data) >= 8
+	case bytes.HasPrefix(data, []byte("YM3!")):
+		return "YM3", (len(data) - 4) / 14, true
+	case bytes.HasPrefix(data, []byte("YM5!LeOnArD!")), bytes.HasPrefix(data, []byte("YM6!LeOnArD!")):
+		if len(data) < 16 {
+			return "", 0, false
+		}
+		return string(data[:3]), int(binary.BigEndian.Uint32(data[12:16])), true
+	default:
+		return "", 0, false
+	}
+}
+
+// psgFrames counts the frames of the PSG register stream, where each frame
+// ends with 0xFF, 0xFE is followed by a count of 4 frame blocks, and 0xFD
+// marks the end of the music. Other values are a register number and value.
+func psgFrames(data []byte) int {
+	frames := 0
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case 0xff:
+			frames++
+		case 0xfe:
+			if i+1 < len(data) {
+				frames += 4 * int(data[i+1])
+			}
+			i++
+		case 0xfd:
+			return frames
+		default:
+			i++ // register value
+		}
+	}
+	return frames
+}
@@ -7,8 +7,45 @@ import (
 	"retroio/spectrum/tzx/blocks/types"
 )
 
-// newFromBlockID returns a TZX block based on the type ID byte.
-func newFromBlockID(id byte) (Block, error) {
+// ErrUnknownBlock is returned when a block ID is not supported, or the block
+// type has been deprecated in the TZX specification.
+// Use errors.As to retrieve it from a wrapped error.
+type ErrUnknownBlock struct {
+	ID         uint8 // Block ID byte
+	Offset     int64 // Offset of the block in the file
+	Deprecated bool  // Block type is deprecated
+}
+
+func (e ErrUnknownBlock) Error() string {
+	if e.Deprecated {
+		return fmt.Sprintf("TZX block ID 0x%02X at offset %d is deprecated", e.ID, e.Offset)
+	}
+	return fmt.Sprintf("TZX block ID 0x%02X at offset %d is not supported", e.ID, e.Offset)
+}
+
+// Extension returns true when the block ID follows the General Extension Rule,
+// so the block can be skipped using the length stored after the ID.
+func (e ErrUnknownBlock) Extension() bool {
+	return e.ID >= extensionBlockID
+}
+
+// BlockName returns the name of the block type with the ID, as given in the
+// TZX specification, or the TSX specification for the MSX blocks, or "Unknown"
+// for an unsupported ID.
+func BlockName(id uint8) string {
+	block, err := newFromBlockID(id, 0)
+	if err != nil {
+		if block := newTSXBlock(id); block != nil {
+			return block.Name()
+		}
+		return "Unknown"
+	}
+	return block.Name()
+}
+
+// newFromBlockID returns a TZX block based on the type ID byte. The offset
+// of the block is only used for reporting unknown blocks.
+func newFromBlockID(id byte, offset int64) (Block, error) {
 	var block Block
 
 	switch types.BlockType(id) {
@@ -64,9 +101,9 @@ func newFromBlockID(id byte) (Block, error) {
 		// (90 dec, ASCII Letter 'Z')
 		block = &blocks.GlueBlock{}
 	case types.C64RomType, types.C64TurboData, types.EmulationInfo, types.Snapshot:
-		return nil, fmt.Errorf("TZX block ID 0x%02X is deprecated", id)
+		return nil, ErrUnknownBlock{ID: id, Offset: offset, Deprecated: true}
 	default:
-		return nil, fmt.Errorf("TZX block ID 0x%02X is not supported", id)
+		return nil, ErrUnknownBlock{ID: id, Offset: offset}
 	}
 	return block, nil
 }
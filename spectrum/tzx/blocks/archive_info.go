@@ -2,6 +2,7 @@ package blocks
 
 import (
 	"fmt"
+	"strings"
 
 	"retroio/spectrum/tap"
 	"retroio/spectrum/tzx/blocks/types"
@@ -29,47 +30,59 @@ type ArchiveInfo struct {
 type Text struct {
 	TypeID     uint8  // Text identification byte
 	Length     uint8  // Length of text string
-	Characters []byte // Text string in ASCII format
+	Characters string // Text string, decoded from Latin-1
 }
 
 // Headings for the Text ID's.
 var headings = map[uint8]string{
-	0x00: "Title",     // 00 - Full title
-	0x01: "Publisher", // 01 - Software house/publisher
-	0x02: "Authors",   // 02 - Author(s)
-	0x03: "Year",      // 03 - Year of publication
-	0x04: "Language",  // 04 - Language
-	0x05: "Category",  // 05 - Game/utility type
-	0x06: "Price",     // 06 - Price
-	0x07: "Loader",    // 07 - Protection scheme/loader
-	0x08: "Origin",    // 08 - Origin
-	0xff: "Comment",   // FF - Comment(s)
+	TextTitle:     "Title",
+	TextPublisher: "Publisher",
+	TextAuthors:   "Authors",
+	TextYear:      "Year",
+	TextLanguage:  "Language",
+	TextCategory:  "Category",
+	TextPrice:     "Price",
+	TextLoader:    "Loader",
+	TextOrigin:    "Origin",
+	TextComment:   "Comment",
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (a *ArchiveInfo) Read(reader *storage.Reader) error {
-	a.BlockID = types.BlockType(reader.ReadByte())
+	a.BlockID = types.BlockType(reader.ReadUint8())
 	if a.BlockID != a.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", a.Id(), a.BlockID)
 	}
 
 	a.Length = reader.ReadShort()
-	a.StringCount = reader.ReadByte()
+	a.StringCount = reader.ReadUint8()
 
 	for i := 0; i < int(a.StringCount); i++ {
 		var t Text
-		t.TypeID = reader.ReadByte()
-		t.Length = reader.ReadByte()
-		for _, c := range reader.ReadBytes(int(t.Length)) {
-			t.Characters = append(t.Characters, c)
+		t.TypeID = reader.ReadUint8()
+		t.Length, _ = reader.PeekByte()
+		characters, err := reader.ReadLatin1String(1)
+		if err != nil {
+			return err
 		}
+		t.Characters = characters
 		a.Strings = append(a.Strings, t)
 	}
 
 	return nil
 }
 
+// Title returns the full title of the tape, or an empty string when not given.
+func (a ArchiveInfo) Title() string {
+	for _, t := range a.Strings {
+		if t.TypeID == TextTitle {
+			return t.Value()
+		}
+	}
+	return ""
+}
+
 // Id of the block as given in the TZX specification, written as a hexadecimal number.
 func (a ArchiveInfo) Id() types.BlockType {
 	return types.ArchiveInfo
@@ -85,22 +98,23 @@ func (a ArchiveInfo) BlockData() tap.Block {
 }
 
 // String returns a human readable string of the block data
-// Each character is first converted to a Rune so that Latin characters are preserved.
+// Each string is decoded as Latin-1 so that accented characters are preserved,
+// and the language, category and origin are shown with their normalised spelling.
 func (a ArchiveInfo) String() string {
 	str := ""
 	for _, b := range a.Strings {
-		var runes []rune
-
-		for _, c := range b.Characters {
-			if c == 0x0a || c == 0x0d {
-				// replace newline with comma
-				runes = append(runes, rune(0x2c)) // 44d
-			} else {
-				runes = append(runes, rune(c))
-			}
+		value := strings.Join(splitLines(b.Value()), ", ")
+
+		switch b.TypeID {
+		case TextLanguage:
+			value = normalise(languages, value)
+		case TextCategory:
+			value = normalise(categories, value)
+		case TextOrigin:
+			value = normalise(origins, value)
 		}
 
-		str += fmt.Sprintf("  %-10s: %s\n", headings[b.TypeID], string(runes))
+		str += fmt.Sprintf("  %-10s: %s\n", b.Heading(), storage.DisplayText(value))
 	}
 
 	return str
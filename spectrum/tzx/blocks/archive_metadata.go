@@ -0,0 +1,190 @@
+package blocks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Text identification bytes of the archive info strings.
+const (
+	TextTitle     uint8 = 0x00 // Full title
+	TextPublisher uint8 = 0x01 // Software house/publisher
+	TextAuthors   uint8 = 0x02 // Author(s)
+	TextYear      uint8 = 0x03 // Year of publication
+	TextLanguage  uint8 = 0x04 // Language
+	TextCategory  uint8 = 0x05 // Game/utility type
+	TextPrice     uint8 = 0x06 // Price
+	TextLoader    uint8 = 0x07 // Protection scheme/loader
+	TextOrigin    uint8 = 0x08 // Origin
+	TextComment   uint8 = 0xff // Comment(s)
+)
+
+// Metadata is the archive info of a tape as typed fields, with the common
+// languages, software types and origins normalised to a consistent spelling,
+// making it suitable for importing into a catalog.
+type Metadata struct {
+	Title     string
+	Publisher string
+	Authors   []string
+	Year      int // zero when not given, or not a year
+	Language  string
+	Category  string
+	Price     string
+	Loader    string
+	Origin    string
+	Comments  []string
+
+	// Unknown holds the strings with a text ID not given in the specification,
+	// labelled by their ID, e.g. "Unknown (0x09)".
+	Unknown map[string]string
+}
+
+// Heading returns the name of the text ID, or a label with the raw ID when
+// it is not given in the specification.
+func (t Text) Heading() string {
+	if heading, ok := headings[t.TypeID]; ok {
+		return heading
+	}
+	return fmt.Sprintf("Unknown (0x%02x)", t.TypeID)
+}
+
+// Value returns the text string, decoded as Latin-1 when read.
+func (t Text) Value() string {
+	return t.Characters
+}
+
+// Metadata returns the typed and normalised archive info.
+func (a ArchiveInfo) Metadata() Metadata {
+	var m Metadata
+
+	for _, t := range a.Strings {
+		value := strings.TrimSpace(t.Value())
+
+		switch t.TypeID {
+		case TextTitle:
+			m.Title = value
+		case TextPublisher:
+			m.Publisher = value
+		case TextAuthors:
+			m.Authors = splitLines(value)
+		case TextYear:
+			m.Year = parseYear(value)
+		case TextLanguage:
+			m.Language = normalise(languages, value)
+		case TextCategory:
+			m.Category = normalise(categories, value)
+		case TextPrice:
+			m.Price = value
+		case TextLoader:
+			m.Loader = value
+		case TextOrigin:
+			m.Origin = normalise(origins, value)
+		case TextComment:
+			m.Comments = append(m.Comments, splitLines(value)...)
+		default:
+			if m.Unknown == nil {
+				m.Unknown = make(map[string]string)
+			}
+			m.Unknown[t.Heading()] = value
+		}
+	}
+
+	return m
+}
+
+// Common values of the language, software type and origin strings, in lower
+// case, mapped to their normalised spelling.
+var (
+	languages = map[string]string{
+		"english": "English", "eng": "English", "en": "English",
+		"spanish": "Spanish", "español": "Spanish", "espanol": "Spanish", "castellano": "Spanish", "es": "Spanish",
+		"german": "German", "deutsch": "German", "de": "German",
+		"french": "French", "français": "French", "francais": "French", "fr": "French",
+		"italian": "Italian", "italiano": "Italian", "it": "Italian",
+		"portuguese": "Portuguese", "português": "Portuguese", "portugues": "Portuguese", "pt": "Portuguese",
+		"czech": "Czech", "cz": "Czech",
+		"slovak": "Slovak", "sk": "Slovak",
+		"polish": "Polish", "polski": "Polish", "pl": "Polish",
+		"russian": "Russian", "ru": "Russian",
+		"dutch": "Dutch", "nederlands": "Dutch", "nl": "Dutch",
+		"hungarian": "Hungarian", "magyar": "Hungarian", "hu": "Hungarian",
+	}
+
+	categories = map[string]string{
+		"arcade": "Arcade", "arcade game": "Arcade", "game: arcade": "Arcade",
+		"arcade adventure": "Arcade Adventure", "arcade-adventure": "Arcade Adventure",
+		"adventure": "Adventure", "text adventure": "Text Adventure", "graphic adventure": "Graphic Adventure",
+		"platform": "Platform", "platformer": "Platform", "platform game": "Platform",
+		"shoot-em-up": "Shoot-em-up", "shoot em up": "Shoot-em-up", "shoot'em up": "Shoot-em-up", "shooter": "Shoot-em-up",
+		"puzzle": "Puzzle", "puzzle game": "Puzzle",
+		"strategy": "Strategy", "strategy game": "Strategy", "wargame": "Strategy",
+		"simulation": "Simulation", "simulator": "Simulation",
+		"sport": "Sport", "sports": "Sport", "sport game": "Sport",
+		"racing": "Racing", "driving": "Racing",
+		"board game": "Board Game", "boardgame": "Board Game", "card game": "Card Game",
+		"educational": "Educational", "education": "Educational",
+		"utility": "Utility", "utilities": "Utility",
+		"word processor": "Word Processor", "wordprocessor": "Word Processor",
+		"programming": "Programming", "programming language": "Programming",
+		"demo": "Demo", "demo scene": "Demo", "music": "Music",
+		"compilation": "Compilation",
+	}
+
+	origins = map[string]string{
+		"original":            "Original",
+		"original release":    "Original",
+		"budget":              "Budget Re-release",
+		"budget re-release":   "Budget Re-release",
+		"budget rerelease":    "Budget Re-release",
+		"re-release":          "Re-release",
+		"rerelease":           "Re-release",
+		"compilation":         "Compilation",
+		"covertape":           "Covertape",
+		"cover tape":          "Covertape",
+		"magazine covertape":  "Covertape",
+		"type-in":             "Type-in",
+		"type in":             "Type-in",
+		"magazine type-in":    "Type-in",
+		"public domain":       "Public Domain",
+		"pd":                  "Public Domain",
+		"freeware":            "Freeware",
+		"shareware":           "Shareware",
+		"unreleased":          "Unreleased",
+		"unpublished":         "Unreleased",
+		"homebrew":            "Homebrew",
+		"original / homebrew": "Homebrew",
+	}
+)
+
+// normalise returns the normalised spelling of a known value, otherwise the
+// value is returned unchanged.
+func normalise(known map[string]string, value string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if normalised, ok := known[key]; ok {
+		return normalised
+	}
+	return value
+}
+
+var yearPattern = regexp.MustCompile(`\b(19|20)\d\d\b`)
+
+// parseYear returns the first four digit year found in the text, such as in
+// "1984", or "(c) 1985 Ultimate".
+func parseYear(text string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(text))
+	return year
+}
+
+// splitLines returns the non-empty lines of the text, as multiple authors and
+// comments are given on separate lines.
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
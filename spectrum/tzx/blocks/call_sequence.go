@@ -26,7 +26,7 @@ type CallSequence struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (c *CallSequence) Read(reader *storage.Reader) error {
-	c.BlockID = types.BlockType(reader.ReadByte())
+	c.BlockID = types.BlockType(reader.ReadUint8())
 	if c.BlockID != c.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", c.Id(), c.BlockID)
 	}
@@ -74,7 +74,7 @@ type ReturnFromSequence struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (r ReturnFromSequence) Read(reader *storage.Reader) error {
-	r.BlockID = types.BlockType(reader.ReadByte())
+	r.BlockID = types.BlockType(reader.ReadUint8())
 	if r.BlockID != r.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", r.Id(), r.BlockID)
 	}
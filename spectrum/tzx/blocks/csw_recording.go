@@ -1,6 +1,7 @@
 package blocks
 
 import (
+	"context"
 	"fmt"
 
 	"retroio/spectrum/tap"
@@ -15,8 +16,7 @@ type CswRecording struct {
 	BlockID          types.BlockType
 	Length           uint32  // Block length (without these four bytes)
 	Pause            uint16  // Pause after this block (in ms).
-	SampleRate       uint16  // Sampling rate
-	SampleSpareByte  uint8   // NOTE: `SampleRate` above uses only 2-bytes for value but specification says 3-bytes, so this is for the spare.
+	SampleRate       uint32  // Sampling rate (3 bytes)
 	CompressionType  uint8   // Compression type: RLE, Z-RLE
 	StoredPulseCount uint32  // Number of stored pulses (after decompression, for validation purposes)
 	Data             []uint8 // CSW data, encoded according to the CSW file format specification.
@@ -25,24 +25,34 @@ type CswRecording struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (c *CswRecording) Read(reader *storage.Reader) error {
-	c.BlockID = types.BlockType(reader.ReadByte())
+	return c.ReadContext(context.Background(), reader, nil)
+}
+
+// ReadContext reads the block like Read, where the recording data is read in
+// chunks, stopping when the context is cancelled, and calling progress (when
+// not nil) with the bytes read and the total file size.
+func (c *CswRecording) ReadContext(ctx context.Context, reader *storage.Reader, progress func(done, total int64)) error {
+	c.BlockID = types.BlockType(reader.ReadUint8())
 	if c.BlockID != c.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", c.Id(), c.BlockID)
 	}
 
 	c.Length = reader.ReadLong()
 	c.Pause = reader.ReadShort()
-	c.SampleRate = reader.ReadShort()
-	c.SampleSpareByte = reader.ReadByte()
-	c.CompressionType = reader.ReadByte()
-	c.StoredPulseCount = reader.ReadLong()
-
-	c.Data = make([]byte, c.Length)
-	if _, err := reader.Read(c.Data); err != nil {
+	sampleRate, err := reader.ReadUint24()
+	if err != nil {
 		return err
 	}
+	c.SampleRate = sampleRate
+	c.CompressionType = reader.ReadUint8()
+	c.StoredPulseCount = reader.ReadLong()
 
-	return nil
+	// the block length includes the 10 bytes of the fields above
+	if c.Length < 10 {
+		return fmt.Errorf("invalid CSW block length %d", c.Length)
+	}
+	c.Data, err = reader.ReadBytesContext(ctx, int(c.Length-10), progress)
+	return err
 }
 
 // Id of the block as given in the TZX specification, written as a hexadecimal number.
@@ -59,6 +69,11 @@ func (c CswRecording) BlockData() tap.Block {
 	return nil
 }
 
+// DataHash returns the CRC32 of the CSW data, ignoring the sample rate and pause.
+func (c CswRecording) DataHash() string {
+	return storage.DataHash(c.Data)
+}
+
 // String returns a human readable string of the block data
 func (c CswRecording) String() string {
 	str := fmt.Sprintf("%s\n", c.Name())
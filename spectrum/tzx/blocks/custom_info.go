@@ -2,6 +2,7 @@ package blocks
 
 import (
 	"fmt"
+	"strings"
 
 	"retroio/spectrum/tap"
 	"retroio/spectrum/tzx/blocks/types"
@@ -15,7 +16,7 @@ import (
 // poke data.
 type CustomInfo struct {
 	BlockID        types.BlockType
-	Identification [10]byte // Identification string (in ASCII)
+	Identification [16]byte // Identification string (in ASCII)
 	Length         uint32   // Length of the custom info
 	Info           []uint8  // Custom info
 }
@@ -23,20 +24,23 @@ type CustomInfo struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (c *CustomInfo) Read(reader *storage.Reader) error {
-	c.BlockID = types.BlockType(reader.ReadByte())
+	c.BlockID = types.BlockType(reader.ReadUint8())
 	if c.BlockID != c.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", c.Id(), c.BlockID)
 	}
 
-	for i, b := range reader.ReadBytes(10) {
+	for i, b := range reader.ReadBytes(len(c.Identification)) {
 		c.Identification[i] = b
 	}
 
-	c.Length = reader.ReadLong()
-
-	for _, b := range reader.ReadBytes(int(c.Length)) {
-		c.Info = append(c.Info, b)
+	// the info is often binary data, such as a screen or AY music, so it is
+	// not decoded as text
+	info, err := reader.ReadPrefixedBytes(4)
+	if err != nil {
+		return err
 	}
+	c.Length = uint32(len(info))
+	c.Info = info
 
 	return nil
 }
@@ -55,7 +59,34 @@ func (c CustomInfo) BlockData() tap.Block {
 	return nil
 }
 
-// String returns a human readable string of the block data
+// Identifier returns the identification string, without the padding.
+func (c CustomInfo) Identifier() string {
+	return strings.TrimRight(storage.DecodeLatin1(c.Identification[:]), " \x00")
+}
+
+// String returns a human readable string of the block data. The info of the
+// well-known identifiers is decoded, while any other info is shown as stored.
 func (c CustomInfo) String() string {
-	return fmt.Sprintf("%-19s : %s - %s", c.Name(), c.Identification, c.Info)
+	id := storage.DisplayText(c.Identifier())
+
+	switch c.Identifier() {
+	case PokesIdentifier:
+		description, trainers, err := c.Pokes()
+		str := fmt.Sprintf("%-19s : %s - %s", c.Name(), id, storage.DisplayText(description))
+		for _, trainer := range trainers {
+			str += fmt.Sprintf("\n    - %s", trainer)
+		}
+		if err != nil {
+			str += fmt.Sprintf("\n    - %s", err)
+		}
+		return str
+	case InstructionsIdentifier:
+		str := fmt.Sprintf("%-19s : %s", c.Name(), id)
+		for _, line := range splitLines(storage.DecodeLatin1(c.Info)) {
+			str += fmt.Sprintf("\n    %s", storage.DisplayText(line))
+		}
+		return str
+	}
+
+	return fmt.Sprintf("%-19s : %s - %s", c.Name(), id, storage.DisplayText(storage.DecodeLatin1(c.Info)))
 }
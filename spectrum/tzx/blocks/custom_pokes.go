@@ -0,0 +1,161 @@
+package blocks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/storage"
+)
+
+// Identification strings of the well-known custom info blocks.
+const (
+	PokesIdentifier        = "POKEs"
+	InstructionsIdentifier = "Instructions"
+)
+
+// Poke type flags of a POKEs custom info block.
+const (
+	pokeBankMask        = 0x07 // Bits 0-2: memory bank of a 128K Spectrum
+	pokeIgnoreBank      = 0x08 // Bit 3: ignore the bank, the poke is to the normal memory
+	pokeAskUser         = 0x10 // Bit 4: the value is entered by the user
+	pokeOriginalUnknown = 0x20 // Bit 5: the original value is not known
+)
+
+// pokeLength is the length of each poke: the flags, address, value and the
+// original value.
+const pokeLength = 5
+
+// Trainer is a cheat from a POKEs custom info block, such as infinite lives,
+// with the pokes that enable it.
+type Trainer struct {
+	Description string
+	Pokes       []Poke
+}
+
+// String returns the trainer description, followed by its pokes.
+func (t Trainer) String() string {
+	var pokes []string
+	for _, p := range t.Pokes {
+		pokes = append(pokes, p.String())
+	}
+	return fmt.Sprintf("%s: %s", storage.DisplayText(t.Description), strings.Join(pokes, ", "))
+}
+
+// Poke is a single memory change of a trainer.
+type Poke struct {
+	Flags    uint8  // Poke type
+	Address  uint16 // Memory address
+	Value    uint8  // Value to poke, unless entered by the user
+	Original uint8  // Value before the poke, to restore when the trainer is disabled
+}
+
+// Bank returns the 128K memory bank of the poke, or false when the poke is to
+// the normal memory, as on a 48K Spectrum.
+func (p Poke) Bank() (uint8, bool) {
+	if p.Flags&pokeIgnoreBank != 0 {
+		return 0, false
+	}
+	return p.Flags & pokeBankMask, true
+}
+
+// AskUser returns true when the value is entered by the user, such as the
+// number of lives, and the stored value is not used.
+func (p Poke) AskUser() bool {
+	return p.Flags&pokeAskUser != 0
+}
+
+// OriginalUnknown returns true when the value before the poke is not known.
+func (p Poke) OriginalUnknown() bool {
+	return p.Flags&pokeOriginalUnknown != 0
+}
+
+// String returns the poke as a BASIC POKE, e.g. `POKE 34509,0`, with a `?`
+// for values entered by the user, and the bank for 128K pokes.
+func (p Poke) String() string {
+	value := fmt.Sprintf("%d", p.Value)
+	if p.AskUser() {
+		value = "?"
+	}
+	str := fmt.Sprintf("POKE %d,%s", p.Address, value)
+	if bank, ok := p.Bank(); ok {
+		str += fmt.Sprintf(" (bank %d)", bank)
+	}
+	return str
+}
+
+// Pokes decodes the info of a POKEs custom info block: the general
+// description, followed by the number of trainers, each with a description
+// and its pokes. The descriptions are stored with a length byte:
+//
+//   BYTE       Length of the description
+//   CHAR[L]    General description
+//   BYTE       Number of trainers
+//   TRAINER[N] Trainers:
+//     BYTE     Length of the trainer description
+//     CHAR[L]  Trainer description
+//     BYTE     Number of pokes
+//     POKE[N]  Pokes: BYTE flags, WORD address, BYTE value, BYTE original value
+//
+// The trainers decoded before any error are returned with the error.
+func (c CustomInfo) Pokes() (string, []Trainer, error) {
+	if c.Identifier() != PokesIdentifier {
+		return "", nil, errors.Errorf("custom info block is '%s', not '%s'", c.Identifier(), PokesIdentifier)
+	}
+
+	data := c.Info
+	pos := 0
+	text := func() (string, error) {
+		if pos >= len(data) {
+			return "", errors.New("POKEs info is incomplete")
+		}
+		length := int(data[pos])
+		pos++
+		if pos+length > len(data) {
+			return "", errors.New("POKEs info is incomplete")
+		}
+		s := storage.DecodeLatin1(data[pos : pos+length])
+		pos += length
+		return s, nil
+	}
+
+	description, err := text()
+	if err != nil {
+		return "", nil, err
+	}
+	if pos >= len(data) {
+		return description, nil, errors.New("POKEs info is incomplete")
+	}
+	count := int(data[pos])
+	pos++
+
+	var trainers []Trainer
+	for i := 0; i < count; i++ {
+		var trainer Trainer
+		if trainer.Description, err = text(); err != nil {
+			return description, trainers, errors.Wrapf(err, "trainer #%d", i+1)
+		}
+		if pos >= len(data) {
+			return description, trainers, errors.Errorf("trainer #%d: POKEs info is incomplete", i+1)
+		}
+		pokes := int(data[pos])
+		pos++
+		if pos+pokes*pokeLength > len(data) {
+			return description, trainers, errors.Errorf("trainer #%d: POKEs info is incomplete", i+1)
+		}
+		for j := 0; j < pokes; j++ {
+			trainer.Pokes = append(trainer.Pokes, Poke{
+				Flags:    data[pos],
+				Address:  binary.LittleEndian.Uint16(data[pos+1 : pos+3]),
+				Value:    data[pos+3],
+				Original: data[pos+4],
+			})
+			pos += pokeLength
+		}
+		trainers = append(trainers, trainer)
+	}
+
+	return description, trainers, nil
+}
@@ -1,6 +1,7 @@
 package blocks
 
 import (
+	"context"
 	"fmt"
 
 	"retroio/spectrum/tap"
@@ -19,34 +20,40 @@ import (
 // Please use this block only if you cannot use any other block.
 type DirectRecording struct {
 	BlockID          types.BlockType
-	TStatesPerSample uint16   // Number of T-states per sample (bit of data)
-	Pause            uint16   // Pause after this block in milliseconds (ms.)
-	UsedBits         uint8    // Used bits (samples) in last byte of data (1-8) (e.g. if this is 2, only first two samples of the last byte will be played)
-	Length           [3]uint8 // Length of data that follows.
-	Data             []uint8  // Samples data. Each bit represents a state on the EAR port (i.e. one sample). MSb is played first.
-
-	displayLength uint32
+	TStatesPerSample uint16  // Number of T-states per sample (bit of data)
+	Pause            uint16  // Pause after this block in milliseconds (ms.)
+	UsedBits         uint8   // Used bits (samples) in last byte of data (1-8) (e.g. if this is 2, only first two samples of the last byte will be played)
+	Length           uint32  // Length of data that follows (3 bytes).
+	Data             []uint8 // Samples data. Each bit represents a state on the EAR port (i.e. one sample). MSb is played first.
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (d *DirectRecording) Read(reader *storage.Reader) error {
-	d.BlockID = types.BlockType(reader.ReadByte())
+	return d.ReadContext(context.Background(), reader, nil)
+}
+
+// ReadContext reads the block like Read, where the recording data is read in
+// chunks, stopping when the context is cancelled, and calling progress (when
+// not nil) with the bytes read and the total file size.
+func (d *DirectRecording) ReadContext(ctx context.Context, reader *storage.Reader, progress func(done, total int64)) error {
+	d.BlockID = types.BlockType(reader.ReadUint8())
 	if d.BlockID != d.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", d.Id(), d.BlockID)
 	}
 
 	d.TStatesPerSample = reader.ReadShort()
 	d.Pause = reader.ReadShort()
-	d.UsedBits = reader.ReadByte()
-
-	copy(d.Length[:], reader.ReadBytes(3))
+	d.UsedBits = reader.ReadUint8()
 
-	d.displayLength = reader.Bytes3ToLong(d.Length)
+	length, err := reader.ReadUint24()
+	if err != nil {
+		return err
+	}
+	d.Length = length
 
 	// TODO: read this as TAP data.
-	d.Data = make([]byte, d.displayLength)
-	_, err := reader.Read(d.Data)
+	d.Data, err = reader.ReadBytesContext(ctx, int(d.Length), progress)
 	return err
 }
 
@@ -64,7 +71,12 @@ func (d DirectRecording) BlockData() tap.Block {
 	return nil
 }
 
+// DataHash returns the CRC32 of the sample data, ignoring the sample rate and pause.
+func (d DirectRecording) DataHash() string {
+	return storage.DataHash(d.Data)
+}
+
 // String returns a human readable string of the block data
 func (d DirectRecording) String() string {
-	return fmt.Sprintf("%-19s : %d T-States, %d bytes", d.Name(), d.TStatesPerSample, d.displayLength)
+	return fmt.Sprintf("%-19s : %d T-States, %d bytes", d.Name(), d.TStatesPerSample, d.Length)
 }
@@ -2,7 +2,8 @@ package blocks
 
 import (
 	"fmt"
-	"log"
+
+	"github.com/pkg/errors"
 
 	"retroio/spectrum/tap"
 	"retroio/spectrum/tzx/blocks/types"
@@ -64,11 +65,145 @@ type PilotRLE struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (g *GeneralizedData) Read(reader *storage.Reader) error {
-	log.Fatal("GeneralizedData is not currently supported - unable to continue.")
+	g.BlockID = types.BlockType(reader.ReadUint8())
+	if g.BlockID != g.Id() {
+		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", g.Id(), g.BlockID)
+	}
+
+	g.Length = reader.ReadLong()
+	start := reader.Offset()
+
+	g.Pause = reader.ReadShort()
+	g.TOTP = reader.ReadLong()
+	g.NPP = reader.ReadUint8()
+	g.ASP = reader.ReadUint8()
+	g.TOTD = reader.ReadLong()
+	g.NPD = reader.ReadUint8()
+	g.ASD = reader.ReadUint8()
+
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	// the counts are not trusted until the tables are known to fit in the block
+	if length := g.tablesLength(); length > int64(g.Length) {
+		return errors.Errorf("generalized data tables overrun the block length by %d bytes", length-int64(g.Length))
+	}
+
+	if g.TOTP > 0 {
+		g.PilotSymbols = readSymbols(reader, alphabetSize(g.ASP), g.NPP)
+		g.PilotStreams = make([]PilotRLE, g.TOTP)
+		for i := range g.PilotStreams {
+			g.PilotStreams[i].Symbol = reader.ReadUint8()
+			g.PilotStreams[i].RepetitionCount = reader.ReadShort()
+		}
+	}
+
+	if g.TOTD > 0 {
+		g.DataSymbols = readSymbols(reader, alphabetSize(g.ASD), g.NPD)
+		g.DataStreams = reader.ReadBytes(g.dataStreamLength())
+	}
+
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	// skip any data remaining in the block
+	if read := reader.Offset() - start; read < int64(g.Length) {
+		if _, err := reader.Discard(int(int64(g.Length) - read)); err != nil {
+			return errors.Wrap(err, "unable to skip generalized data block")
+		}
+	}
 
 	return nil
 }
 
+// Symbols returns the data bytes reconstructed from the data stream, regardless
+// of the pulses used for each symbol.
+//
+// Each symbol gives the value of NB bits, e.g. with a 2-symbol alphabet, as used
+// by the Kansas City Standard, symbol 0 is a zero bit and symbol 1 a one bit.
+// The bits are packed MSb first, with the last byte padded with zero bits.
+func (g GeneralizedData) Symbols() ([]byte, error) {
+	if g.TOTD == 0 {
+		return nil, errors.New("generalized data block has no data symbols")
+	}
+
+	nb := g.symbolBits()
+	if len(g.DataStreams) < g.dataStreamLength() {
+		return nil, errors.Errorf("data stream too short for %d symbols of %d bits", g.TOTD, nb)
+	}
+
+	bits := storage.NewBitReader(g.DataStreams)
+	for i := 0; i < int(g.TOTD); i++ {
+		symbol, err := bits.ReadBits(nb)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading data symbol at position %d", i)
+		}
+		if int(symbol) >= alphabetSize(g.ASD) {
+			return nil, errors.Errorf("data symbol %d at position %d is not in the alphabet", symbol, i)
+		}
+	}
+	bit := nb * int(g.TOTD)
+
+	// as each symbol is stored as its NB bit value, the data stream already
+	// holds the packed bits, only the unused bits of the last byte are cleared.
+	data := make([]byte, g.dataStreamLength())
+	copy(data, g.DataStreams)
+	if unused := uint(len(data)*8 - bit); unused > 0 {
+		data[len(data)-1] &^= 1<<unused - 1
+	}
+
+	return data, nil
+}
+
+// symbolBits returns NB, the number of bits used for each data symbol.
+func (g GeneralizedData) symbolBits() int {
+	nb := 0
+	for 1<<uint(nb) < alphabetSize(g.ASD) {
+		nb++
+	}
+	return nb
+}
+
+// dataStreamLength returns DS, the length of the data stream in bytes.
+func (g GeneralizedData) dataStreamLength() int {
+	return (g.symbolBits()*int(g.TOTD) + 7) / 8
+}
+
+// tablesLength returns the length of the block fields, symbol tables and
+// streams given by the block counts, excluding the block length.
+func (g GeneralizedData) tablesLength() int64 {
+	length := int64(14) // pause, counts and alphabet sizes
+	if g.TOTP > 0 {
+		length += int64(2*int(g.NPP)+1)*int64(alphabetSize(g.ASP)) + 3*int64(g.TOTP)
+	}
+	if g.TOTD > 0 {
+		length += int64(2*int(g.NPD)+1)*int64(alphabetSize(g.ASD)) + (int64(g.symbolBits())*int64(g.TOTD)+7)/8
+	}
+	return length
+}
+
+// alphabetSize returns the number of symbols in an alphabet table, where 0 is 256.
+func alphabetSize(size uint8) int {
+	if size == 0 {
+		return 256
+	}
+	return int(size)
+}
+
+func readSymbols(reader *storage.Reader, count int, pulses uint8) []Symbol {
+	symbols := make([]Symbol, count)
+	for i := range symbols {
+		symbols[i].Flags = reader.ReadUint8()
+		symbols[i].PulseLengths = make([]uint16, pulses)
+		for j := range symbols[i].PulseLengths {
+			symbols[i].PulseLengths[j] = reader.ReadShort()
+		}
+	}
+	return symbols
+}
+
 // Id of the block as given in the TZX specification, written as a hexadecimal number.
 func (g GeneralizedData) Id() types.BlockType {
 	return types.GeneralizedData
@@ -85,5 +220,10 @@ func (g GeneralizedData) BlockData() tap.Block {
 
 // String returns a human readable string of the block data
 func (g GeneralizedData) String() string {
-	return fmt.Sprintf("%s", g.Name())
+	str := fmt.Sprintf("%-19s : %d pilot/sync symbols, %d data symbols", g.Name(), g.TOTP, g.TOTD)
+	if g.TOTD > 0 {
+		str += fmt.Sprintf(" (%d bit, %d bytes)", g.symbolBits(), g.dataStreamLength())
+	}
+	str += fmt.Sprintf(", pause for %d ms.", g.Pause)
+	return str
 }
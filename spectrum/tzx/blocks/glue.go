@@ -24,7 +24,7 @@ type GlueBlock struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (g *GlueBlock) Read(reader *storage.Reader) error {
-	g.BlockID = types.BlockType(reader.ReadByte())
+	g.BlockID = types.BlockType(reader.ReadUint8())
 	if g.BlockID != g.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", g.Id(), g.BlockID)
 	}
@@ -50,7 +50,13 @@ func (g GlueBlock) BlockData() tap.Block {
 	return nil
 }
 
+// Version returns the TZX major and minor version of the joined tape.
+func (g GlueBlock) Version() (uint8, uint8) {
+	return g.Value[7], g.Value[8]
+}
+
 // String returns a human readable string of the block data
 func (g GlueBlock) String() string {
-	return fmt.Sprintf("%s", g.Name())
+	major, minor := g.Version()
+	return fmt.Sprintf("%s: TZX v%d.%d", g.Name(), major, minor)
 }
@@ -18,22 +18,24 @@ import (
 type GroupStart struct {
 	BlockID   types.BlockType
 	Length    uint8  // Length of the group name string
-	GroupName []byte // Group name in ASCII format (please keep it under 30 characters long)
+	GroupName string // Group name, decoded from Latin-1 (please keep it under 30 characters long)
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (g *GroupStart) Read(reader *storage.Reader) error {
-	g.BlockID = types.BlockType(reader.ReadByte())
+	g.BlockID = types.BlockType(reader.ReadUint8())
 	if g.BlockID != g.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", g.Id(), g.BlockID)
 	}
 
-	g.Length = reader.ReadByte()
+	g.Length, _ = reader.PeekByte()
 
-	for _, b := range reader.ReadBytes(int(g.Length)) {
-		g.GroupName = append(g.GroupName, b)
+	name, err := reader.ReadLatin1String(1)
+	if err != nil {
+		return err
 	}
+	g.GroupName = name
 
 	return nil
 }
@@ -54,7 +56,7 @@ func (g GroupStart) BlockData() tap.Block {
 
 // String returns a human readable string of the block data
 func (g GroupStart) String() string {
-	return fmt.Sprintf("%-19s : %s", g.Name(), g.GroupName)
+	return fmt.Sprintf("%-19s : %s", g.Name(), storage.DisplayText(g.GroupName))
 }
 
 // GroupEnd
@@ -67,7 +69,7 @@ type GroupEnd struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (g *GroupEnd) Read(reader *storage.Reader) error {
-	g.BlockID = types.BlockType(reader.ReadByte())
+	g.BlockID = types.BlockType(reader.ReadUint8())
 	if g.BlockID != g.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", g.Id(), g.BlockID)
 	}
@@ -25,7 +25,7 @@ type JumpTo struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (j *JumpTo) Read(reader *storage.Reader) error {
-	j.BlockID = types.BlockType(reader.ReadByte())
+	j.BlockID = types.BlockType(reader.ReadUint8())
 	if j.BlockID != j.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", j.Id(), j.BlockID)
 	}
@@ -0,0 +1,131 @@
+package blocks
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks/types"
+	"retroio/storage"
+)
+
+// Bit and byte configuration of a Kansas City Standard block.
+const (
+	kcsZeroPulsesShift   = 4    // Bits 7-4 of the bit config: pulses in a ZERO bit
+	kcsOnePulsesMask     = 0x0F // Bits 3-0 of the bit config: pulses in a ONE bit
+	kcsLeadingBitsShift  = 6    // Bits 7-6 of the byte config: number of leading bits
+	kcsLeadingValue      = 0x20 // Bit 5 of the byte config: value of the leading bits
+	kcsTrailingBitsShift = 3    // Bits 4-3 of the byte config: number of trailing bits
+	kcsTrailingValue     = 0x04 // Bit 2 of the byte config: value of the trailing bits
+	kcsMSbFirst          = 0x01 // Bit 0 of the byte config: MSb is sent first
+)
+
+// KansasCityStandard
+// ID: 4Bh (75d)
+// This block is only found on TSX tapes, the TZX variant used for the MSX. It
+// holds data saved in the Kansas City Standard, as used by the MSX BIOS, where
+// each bit is a number of pulses of the same length, and each byte is framed
+// with start and stop bits, like a serial line.
+type KansasCityStandard struct {
+	BlockID      types.BlockType
+	Length       uint32  // Block length (without these four bytes)
+	Pause        uint16  // Pause after this block (ms.)
+	PilotPulse   uint16  // Length of PILOT pulse
+	PilotTone    uint16  // Number of pulses in the pilot tone
+	ZeroBitPulse uint16  // Length of ZERO bit pulse
+	OneBitPulse  uint16  // Length of ONE bit pulse
+	BitConfig    uint8   // Pulses in a ZERO bit (bits 7-4) and a ONE bit (bits 3-0), 0 meaning 16
+	ByteConfig   uint8   // Leading and trailing bits of each byte, and the bit order
+	DataBlock    []uint8 // Data bytes, without the leading and trailing bits
+}
+
+// Read the tape and extract the data.
+// It is expected that the tape pointer is at the correct position for reading.
+func (k *KansasCityStandard) Read(reader *storage.Reader) error {
+	k.BlockID = types.BlockType(reader.ReadUint8())
+	if k.BlockID != k.Id() {
+		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", k.Id(), k.BlockID)
+	}
+
+	k.Length = reader.ReadLong()
+	k.Pause = reader.ReadShort()
+	k.PilotPulse = reader.ReadShort()
+	k.PilotTone = reader.ReadShort()
+	k.ZeroBitPulse = reader.ReadShort()
+	k.OneBitPulse = reader.ReadShort()
+	k.BitConfig = reader.ReadUint8()
+	k.ByteConfig = reader.ReadUint8()
+
+	// the block length includes the 12 bytes of the fields above
+	if k.Length < 12 {
+		return fmt.Errorf("invalid Kansas City Standard block length %d", k.Length)
+	}
+	k.DataBlock = make([]byte, k.Length-12)
+	if _, err := reader.Read(k.DataBlock); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Id of the block as given in the TSX specification, written as a hexadecimal number.
+func (k KansasCityStandard) Id() types.BlockType {
+	return types.KansasCityStandard
+}
+
+// Name of the block as given in the TSX specification.
+func (k KansasCityStandard) Name() string {
+	return "Kansas City Standard"
+}
+
+func (k KansasCityStandard) BlockData() tap.Block {
+	return nil
+}
+
+// ZeroPulses returns the number of pulses in a ZERO bit.
+func (k KansasCityStandard) ZeroPulses() int {
+	return bitPulses(k.BitConfig >> kcsZeroPulsesShift)
+}
+
+// OnePulses returns the number of pulses in a ONE bit.
+func (k KansasCityStandard) OnePulses() int {
+	return bitPulses(k.BitConfig & kcsOnePulsesMask)
+}
+
+// bitPulses returns the number of pulses of a bit, where 0 means 16.
+func bitPulses(n uint8) int {
+	if n == 0 {
+		return 16
+	}
+	return int(n)
+}
+
+// LeadingBits returns the number and value of the bits sent before each byte,
+// the start bits.
+func (k KansasCityStandard) LeadingBits() (int, bool) {
+	return int(k.ByteConfig >> kcsLeadingBitsShift), k.ByteConfig&kcsLeadingValue != 0
+}
+
+// TrailingBits returns the number and value of the bits sent after each byte,
+// the stop bits.
+func (k KansasCityStandard) TrailingBits() (int, bool) {
+	return int(k.ByteConfig>>kcsTrailingBitsShift) & 0x03, k.ByteConfig&kcsTrailingValue != 0
+}
+
+// MSbFirst returns true when the bits of each byte are sent with the most
+// significant bit first, rather than the usual least significant bit first.
+func (k KansasCityStandard) MSbFirst() bool {
+	return k.ByteConfig&kcsMSbFirst != 0
+}
+
+// DataHash returns the CRC32 of the block data, ignoring the timing values and pause.
+func (k KansasCityStandard) DataHash() string {
+	return storage.DataHash(k.DataBlock)
+}
+
+// String returns a human readable string of the block data
+func (k KansasCityStandard) String() string {
+	leading, _ := k.LeadingBits()
+	trailing, _ := k.TrailingBits()
+	return fmt.Sprintf("%-19s : %d bytes, %d start and %d stop bits, pause for %d ms.",
+		k.Name(), len(k.DataBlock), leading, trailing, k.Pause)
+}
@@ -21,7 +21,7 @@ type LoopStart struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (l *LoopStart) Read(reader *storage.Reader) error {
-	l.BlockID = types.BlockType(reader.ReadByte())
+	l.BlockID = types.BlockType(reader.ReadUint8())
 	if l.BlockID != l.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", l.Id(), l.BlockID)
 	}
@@ -62,7 +62,7 @@ type LoopEnd struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (l *LoopEnd) Read(reader *storage.Reader) error {
-	l.BlockID = types.BlockType(reader.ReadByte())
+	l.BlockID = types.BlockType(reader.ReadUint8())
 	if l.BlockID != l.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", l.Id(), l.BlockID)
 	}
@@ -21,23 +21,25 @@ type Message struct {
 	BlockID     types.BlockType
 	DisplayTime uint8  // Time (in seconds) for which the message should be displayed
 	Length      uint8  // Length of the text message
-	Message     []byte // Message that should be displayed in ASCII format
+	Message     string // Message that should be displayed, decoded from Latin-1
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (m *Message) Read(reader *storage.Reader) error {
-	m.BlockID = types.BlockType(reader.ReadByte())
+	m.BlockID = types.BlockType(reader.ReadUint8())
 	if m.BlockID != m.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", m.Id(), m.BlockID)
 	}
 
-	m.DisplayTime = reader.ReadByte()
-	m.Length = reader.ReadByte()
+	m.DisplayTime = reader.ReadUint8()
+	m.Length, _ = reader.PeekByte()
 
-	for _, b := range reader.ReadBytes(int(m.Length)) {
-		m.Message = append(m.Message, b)
+	message, err := reader.ReadLatin1String(1)
+	if err != nil {
+		return err
 	}
+	m.Message = message
 
 	return nil
 }
@@ -59,6 +61,6 @@ func (m Message) BlockData() tap.Block {
 // String returns a human readable string of the block data
 func (m Message) String() string {
 	str := fmt.Sprintf("%-19s : display for %d seconds\n", m.Name(), m.DisplayTime)
-	str += fmt.Sprintf(" - Message: %s\n", m.Message)
+	str += fmt.Sprintf(" - Message: %s\n", storage.DisplayText(m.Message))
 	return str
 }
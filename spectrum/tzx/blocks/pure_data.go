@@ -13,35 +13,35 @@ import (
 // This is the same as in the turbo loading data block, except that it has no pilot or sync pulses.
 type PureData struct {
 	BlockID      types.BlockType
-	ZeroBitPulse uint16   // Length of ZERO bit pulse
-	OneBitPulse  uint16   // Length of ONE bit pulse
-	UsedBits     uint8    // Used bits in last byte (other bits should be 0) (e.g. if this is 6, then the bits used (x) in the last byte are: xxxxxx00, where MSb is the leftmost bit, LSb is the rightmost bit)
-	Pause        uint16   // Pause after this block (ms.)
-	Length       [3]uint8 // Length of data that follows.
-	DataBlock    []uint8  // Data as in .TAP files
-
-	displayLength uint32
+	ZeroBitPulse uint16  // Length of ZERO bit pulse
+	OneBitPulse  uint16  // Length of ONE bit pulse
+	UsedBits     uint8   // Used bits in last byte (other bits should be 0) (e.g. if this is 6, then the bits used (x) in the last byte are: xxxxxx00, where MSb is the leftmost bit, LSb is the rightmost bit)
+	Pause        uint16  // Pause after this block (ms.)
+	Length       uint32  // Length of data that follows (3 bytes).
+	DataBlock    []uint8 // Data as in .TAP files
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (p *PureData) Read(reader *storage.Reader) error {
-	p.BlockID = types.BlockType(reader.ReadByte())
+	p.BlockID = types.BlockType(reader.ReadUint8())
 	if p.BlockID != p.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", p.Id(), p.BlockID)
 	}
 
-	p.OneBitPulse = reader.ReadShort()
 	p.ZeroBitPulse = reader.ReadShort()
-	p.UsedBits = reader.ReadByte()
+	p.OneBitPulse = reader.ReadShort()
+	p.UsedBits = reader.ReadUint8()
 	p.Pause = reader.ReadShort()
-	copy(p.Length[:], reader.ReadBytes(3))
-
-	p.displayLength = reader.Bytes3ToLong(p.Length)
+	length, err := reader.ReadUint24()
+	if err != nil {
+		return err
+	}
+	p.Length = length
 
 	// TODO: read this as TAP data.
-	p.DataBlock = make([]byte, p.displayLength)
-	_, err := reader.Read(p.DataBlock)
+	p.DataBlock = make([]byte, p.Length)
+	_, err = reader.Read(p.DataBlock)
 	return err
 }
 
@@ -59,7 +59,12 @@ func (p PureData) BlockData() tap.Block {
 	return nil
 }
 
+// DataHash returns the CRC32 of the block data, ignoring the timing values and pause.
+func (p PureData) DataHash() string {
+	return storage.DataHash(p.DataBlock)
+}
+
 // String returns a human readable string of the block data
 func (p PureData) String() string {
-	return fmt.Sprintf("%-19s : %d bytes, pause for %d ms.", p.Name(), p.displayLength, p.Pause)
+	return fmt.Sprintf("%-19s : %d bytes, pause for %d ms.", p.Name(), p.Length, p.Pause)
 }
@@ -21,7 +21,7 @@ type PureTone struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (p *PureTone) Read(reader *storage.Reader) error {
-	p.BlockID = types.BlockType(reader.ReadByte())
+	p.BlockID = types.BlockType(reader.ReadUint8())
 	if p.BlockID != p.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", p.Id(), p.BlockID)
 	}
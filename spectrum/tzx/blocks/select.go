@@ -23,29 +23,31 @@ type Select struct {
 }
 
 type Selection struct {
-	RelativeOffset int16   // Relative Offset as `signed` value
-	Length         uint8   // Length of description text
-	Description    []uint8 // Description text (please use single line and max. 30 chars)
+	RelativeOffset int16  // Relative Offset as `signed` value
+	Length         uint8  // Length of description text
+	Description    string // Description text, decoded from Latin-1 (please use single line and max. 30 chars)
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (s *Select) Read(reader *storage.Reader) error {
-	s.BlockID = types.BlockType(reader.ReadByte())
+	s.BlockID = types.BlockType(reader.ReadUint8())
 	if s.BlockID != s.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", s.Id(), s.BlockID)
 	}
 
 	s.Length = reader.ReadShort()
-	s.Count = reader.ReadByte()
+	s.Count = reader.ReadUint8()
 
 	for i := 0; i < int(s.Count); i++ {
 		var selection Selection
 		selection.RelativeOffset = int16(reader.ReadShort())
-		selection.Length = reader.ReadByte()
-		for _, b := range reader.ReadBytes(int(selection.Length)) {
-			selection.Description = append(selection.Description, b)
+		selection.Length, _ = reader.PeekByte()
+		description, err := reader.ReadLatin1String(1)
+		if err != nil {
+			return err
 		}
+		selection.Description = description
 		s.Selections = append(s.Selections, selection)
 	}
 
@@ -71,7 +73,7 @@ func (s Select) String() string {
 	str := fmt.Sprintf("%s\n", s.Name())
 	for _, b := range s.Selections {
 		str += fmt.Sprintf("- Offset:      %d\n", b.RelativeOffset)
-		str += fmt.Sprintf("  Description: %s\n", b.Description)
+		str += fmt.Sprintf("  Description: %s\n", storage.DisplayText(b.Description))
 	}
 	return str
 }
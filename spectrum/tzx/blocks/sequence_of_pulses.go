@@ -21,12 +21,12 @@ type SequenceOfPulses struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (s *SequenceOfPulses) Read(reader *storage.Reader) error {
-	s.BlockID = types.BlockType(reader.ReadByte())
+	s.BlockID = types.BlockType(reader.ReadUint8())
 	if s.BlockID != s.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", s.Id(), s.BlockID)
 	}
 
-	s.Count = reader.ReadByte()
+	s.Count = reader.ReadUint8()
 
 	for i := 0; i < int(s.Count); i++ {
 		s.Lengths = append(s.Lengths, reader.ReadShort())
@@ -21,13 +21,13 @@ type SetSignalLevel struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (s *SetSignalLevel) Read(reader *storage.Reader) error {
-	s.BlockID = types.BlockType(reader.ReadByte())
+	s.BlockID = types.BlockType(reader.ReadUint8())
 	if s.BlockID != s.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", s.Id(), s.BlockID)
 	}
 
 	s.Length = reader.ReadLong()
-	s.SignalLevel = reader.ReadByte()
+	s.SignalLevel = reader.ReadUint8()
 
 	return nil
 }
@@ -48,5 +48,9 @@ func (s SetSignalLevel) BlockData() tap.Block {
 
 // String returns a human readable string of the block data
 func (s SetSignalLevel) String() string {
-	return fmt.Sprintf("%-19s : signal level: %d", s.Name(), s.SignalLevel)
+	level := "low"
+	if s.SignalLevel == 1 {
+		level = "high"
+	}
+	return fmt.Sprintf("%-19s : signal level: %s", s.Name(), level)
 }
@@ -0,0 +1,59 @@
+package blocks
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks/types"
+	"retroio/storage"
+)
+
+// Skipped
+// An unknown or deprecated block, with an ID from 30h, which was skipped using
+// the block length following the ID, as given by the General Extension Rule.
+// It is kept in place of the block, so the block numbers, and the relative
+// offsets of the jump, loop and call blocks, still count it.
+type Skipped struct {
+	BlockID types.BlockType
+	Length  uint32 // Length of the block skipped, following the ID and length
+}
+
+// Read the tape, discarding the block data.
+// It is expected that the tape pointer is at the correct position for reading.
+func (s *Skipped) Read(reader *storage.Reader) error {
+	s.BlockID = types.BlockType(reader.ReadUint8())
+
+	// the deprecated blocks do not all follow the extension rule
+	switch s.BlockID {
+	case types.EmulationInfo:
+		s.Length = 8
+	case types.Snapshot:
+		_ = reader.ReadUint8() // snapshot type
+		size, _ := reader.ReadUint24()
+		s.Length = size
+	default:
+		s.Length = reader.ReadLong()
+	}
+
+	_, err := reader.Discard(int(s.Length))
+	return err
+}
+
+// Id of the block, as read from the tape.
+func (s Skipped) Id() types.BlockType {
+	return s.BlockID
+}
+
+// Name of the block.
+func (s Skipped) Name() string {
+	return "Skipped Block"
+}
+
+func (s Skipped) BlockData() tap.Block {
+	return nil
+}
+
+// String returns a human readable string of the block data
+func (s Skipped) String() string {
+	return fmt.Sprintf("%-19s : ID 0x%02X, %d bytes skipped", s.Name(), uint8(s.BlockID), s.Length)
+}
@@ -31,7 +31,7 @@ type StandardSpeedData struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (s *StandardSpeedData) Read(reader *storage.Reader) error {
-	s.BlockID = types.BlockType(reader.ReadByte())
+	s.BlockID = types.BlockType(reader.ReadUint8())
 	if s.BlockID != s.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", s.Id(), s.BlockID)
 	}
@@ -43,11 +43,7 @@ func (s *StandardSpeedData) Read(reader *storage.Reader) error {
 	tapReader := tap.New(reader)
 	length, err := reader.PeekShort()
 	if err == nil {
-		if length == 19 {
-			s.DataBlock, err = tapReader.ReadHeaderBlock()
-		} else {
-			s.DataBlock, err = tapReader.ReadDataBlock()
-		}
+		s.DataBlock, err = tapReader.ReadBlock()
 	}
 	if err != nil {
 		return errors.Wrap(err, "unable to read TAP data for StandardSpeedData")
@@ -58,6 +54,11 @@ func (s *StandardSpeedData) Read(reader *storage.Reader) error {
 	return nil
 }
 
+// DataLength returns the length of the TAP data, as given in the block.
+func (s StandardSpeedData) DataLength() int {
+	return int(s.displayLength)
+}
+
 // Id of the block as given in the TZX specification, written as a hexadecimal number.
 func (s StandardSpeedData) Id() types.BlockType {
 	return types.StandardSpeedData
@@ -72,6 +73,14 @@ func (s StandardSpeedData) BlockData() tap.Block {
 	return s.DataBlock
 }
 
+// DataHash returns the CRC32 of the TAP block data, ignoring the pause.
+func (s StandardSpeedData) DataHash() string {
+	if s.DataBlock == nil {
+		return ""
+	}
+	return s.DataBlock.DataHash()
+}
+
 // String returns a human readable string of the block data
 func (s StandardSpeedData) String() string {
 	str := fmt.Sprintf("%-19s: %d bytes, pause for %d ms\n", s.Name(), s.displayLength, s.Pause)
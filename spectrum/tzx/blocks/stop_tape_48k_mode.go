@@ -22,7 +22,7 @@ type StopTapeWhen48kMode struct {
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (s *StopTapeWhen48kMode) Read(reader *storage.Reader) error {
-	s.BlockID = types.BlockType(reader.ReadByte())
+	s.BlockID = types.BlockType(reader.ReadUint8())
 	if s.BlockID != s.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", s.Id(), s.BlockID)
 	}
@@ -18,22 +18,24 @@ import (
 type TextDescription struct {
 	BlockID     types.BlockType
 	Length      uint8  // Length of the text description
-	Description []byte // Text description in ASCII format
+	Description string // Text description, decoded from Latin-1
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (t *TextDescription) Read(reader *storage.Reader) error {
-	t.BlockID = types.BlockType(reader.ReadByte())
+	t.BlockID = types.BlockType(reader.ReadUint8())
 	if t.BlockID != t.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", t.Id(), t.BlockID)
 	}
 
-	t.Length = reader.ReadByte()
+	t.Length, _ = reader.PeekByte()
 
-	for _, b := range reader.ReadBytes(int(t.Length)) {
-		t.Description = append(t.Description, b)
+	description, err := reader.ReadLatin1String(1)
+	if err != nil {
+		return err
 	}
+	t.Description = description
 
 	return nil
 }
@@ -54,5 +56,5 @@ func (t TextDescription) BlockData() tap.Block {
 
 // String returns a human readable string of the block data
 func (t TextDescription) String() string {
-	return fmt.Sprintf("%-19s : %s", t.Name(), t.Description)
+	return fmt.Sprintf("%-19s : %s", t.Name(), storage.DisplayText(t.Description))
 }
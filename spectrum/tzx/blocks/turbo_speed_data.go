@@ -16,24 +16,22 @@ import (
 // schemes) then use the next three blocks to describe it.
 type TurboSpeedData struct {
 	BlockID         types.BlockType
-	PilotPulse      uint16   // Length of PILOT pulse {2168}
-	SyncFirstPulse  uint16   // Length of SYNC first pulse {667}
-	SyncSecondPulse uint16   // Length of SYNC second pulse {735}
-	ZeroBitPulse    uint16   // Length of ZERO bit pulse {855}
-	OneBitPulse     uint16   // Length of ONE bit pulse {1710}
-	PilotTone       uint16   // Length of PILOT tone (number of pulses) {8063 header (flag<128), 3223 data (flag>=128)}
-	UsedBits        uint8    // Used bits in the last byte (other bits should be 0) {8} (e.g. if this is 6, then the bits used (x) in the last byte are: xxxxxx00, where MSb is the leftmost bit, LSb is the rightmost bit)
-	Pause           uint16   // Pause after this block (ms.) {1000}
-	Length          [3]uint8 // Length of data that follows.
-	DataBlock       []uint8  // Data as in .TAP files
-
-	displayLength uint32
+	PilotPulse      uint16  // Length of PILOT pulse {2168}
+	SyncFirstPulse  uint16  // Length of SYNC first pulse {667}
+	SyncSecondPulse uint16  // Length of SYNC second pulse {735}
+	ZeroBitPulse    uint16  // Length of ZERO bit pulse {855}
+	OneBitPulse     uint16  // Length of ONE bit pulse {1710}
+	PilotTone       uint16  // Length of PILOT tone (number of pulses) {8063 header (flag<128), 3223 data (flag>=128)}
+	UsedBits        uint8   // Used bits in the last byte (other bits should be 0) {8} (e.g. if this is 6, then the bits used (x) in the last byte are: xxxxxx00, where MSb is the leftmost bit, LSb is the rightmost bit)
+	Pause           uint16  // Pause after this block (ms.) {1000}
+	Length          uint32  // Length of data that follows (3 bytes).
+	DataBlock       []uint8 // Data as in .TAP files
 }
 
 // Read the tape and extract the data.
 // It is expected that the tape pointer is at the correct position for reading.
 func (t *TurboSpeedData) Read(reader *storage.Reader) error {
-	t.BlockID = types.BlockType(reader.ReadByte())
+	t.BlockID = types.BlockType(reader.ReadUint8())
 	if t.BlockID != t.Id() {
 		return fmt.Errorf("expected block ID 0x%02x, got 0x%02x", t.Id(), t.BlockID)
 	}
@@ -44,16 +42,18 @@ func (t *TurboSpeedData) Read(reader *storage.Reader) error {
 	t.ZeroBitPulse = reader.ReadShort()
 	t.OneBitPulse = reader.ReadShort()
 	t.PilotTone = reader.ReadShort()
-	t.UsedBits = reader.ReadByte()
+	t.UsedBits = reader.ReadUint8()
 	t.Pause = reader.ReadShort()
 
-	copy(t.Length[:], reader.ReadBytes(3))
-
-	t.displayLength = reader.Bytes3ToLong(t.Length)
+	length, err := reader.ReadUint24()
+	if err != nil {
+		return err
+	}
+	t.Length = length
 
 	// TODO: read this as TAP data.
-	t.DataBlock = make([]byte, t.displayLength)
-	_, err := reader.Read(t.DataBlock)
+	t.DataBlock = make([]byte, t.Length)
+	_, err = reader.Read(t.DataBlock)
 	return err
 }
 
@@ -71,7 +71,12 @@ func (t TurboSpeedData) BlockData() tap.Block {
 	return nil
 }
 
+// DataHash returns the CRC32 of the block data, ignoring the timing values and pause.
+func (t TurboSpeedData) DataHash() string {
+	return storage.DataHash(t.DataBlock)
+}
+
 // String returns a human readable string of the block data
 func (t TurboSpeedData) String() string {
-	return fmt.Sprintf("%-19s : %d bytes, pause for %d ms.", t.Name(), t.displayLength, t.Pause)
+	return fmt.Sprintf("%-19s : %d bytes, pause for %d ms.", t.Name(), t.Length, t.Pause)
 }
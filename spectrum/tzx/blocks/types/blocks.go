@@ -31,5 +31,6 @@ const (
 	EmulationInfo       BlockType = 0x34 // deprecated
 	CustomInfo          BlockType = 0x35
 	Snapshot            BlockType = 0x40 // deprecated
+	KansasCityStandard  BlockType = 0x4b // TSX (MSX) only
 	GlueBlock           BlockType = 0x5a
 )
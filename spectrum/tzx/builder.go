@@ -0,0 +1,233 @@
+package tzx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tzx/blocks/types"
+	"retroio/storage"
+)
+
+// Flag bytes of the standard ROM blocks.
+const (
+	headerFlag = 0x00
+	dataFlag   = 0xFF
+)
+
+// Data types of the standard ROM headers.
+const (
+	programHeader = 0
+	codeHeader    = 3
+)
+
+// defaultPause is the pause after each block saved by the ROM, in ms.
+const defaultPause = 1000
+
+// Builder creates a TZX tape from scratch, one block at a time, such as from
+// the build artifacts of a homebrew program. The flag and checksum bytes of
+// each data block are added by the builder.
+//
+// As with the storage.Reader, the first error is kept, and all later blocks
+// are ignored. The error is returned by Err, Bytes, Write and TZX.
+type Builder struct {
+	blocks bytes.Buffer
+	err    error
+}
+
+// NewBuilder returns a builder for an empty tape.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Err returns the first error found adding the blocks.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+func (b *Builder) setError(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// add writes the block ID followed by each field of the block.
+func (b *Builder) add(id types.BlockType, fields ...interface{}) {
+	if b.err != nil {
+		return
+	}
+	b.blocks.WriteByte(byte(id))
+	for _, field := range fields {
+		_ = binary.Write(&b.blocks, binary.LittleEndian, field)
+	}
+}
+
+// AddStandardBlock adds a Standard Speed Data block for the data, with the
+// data flag byte (0xFF) and the checksum, as saved by the ROM after a header.
+// The pause after the block is in ms.
+func (b *Builder) AddStandardBlock(data []byte, pause uint16) {
+	b.AddFlaggedBlock(dataFlag, data, pause)
+}
+
+// AddFlaggedBlock adds a Standard Speed Data block for the data, with the given
+// flag byte, and the checksum. The pause after the block is in ms.
+func (b *Builder) AddFlaggedBlock(flag uint8, data []byte, pause uint16) {
+	if len(data)+2 > 0xFFFF {
+		b.setError(errors.Errorf("standard speed data block is too long, %d bytes", len(data)))
+		return
+	}
+
+	block := append([]byte{flag}, data...)
+	var sum uint8
+	for _, v := range block {
+		sum ^= v
+	}
+	block = append(block, sum)
+
+	b.add(types.StandardSpeedData, pause, uint16(len(block)), block)
+}
+
+// AddBlock adds a Standard Speed Data block for a block as stored in a TAP
+// file, which already has its flag and checksum bytes. The pause after the
+// block is in ms.
+func (b *Builder) AddBlock(block []byte, pause uint16) {
+	if len(block) > 0xFFFF {
+		b.setError(errors.Errorf("standard speed data block is too long, %d bytes", len(block)))
+		return
+	}
+	b.add(types.StandardSpeedData, pause, uint16(len(block)), block)
+}
+
+// AddProgramHeader adds the header of a BASIC program of the given length,
+// starting at the autostart line, as saved by the ROM. A line of 0x8000 or
+// more means no autostart. The variables offset is the length of the program
+// without the variables, which is the length when it has none.
+func (b *Builder) AddProgramHeader(name string, length, autostart, variables uint16) {
+	b.addHeader(programHeader, name, length, autostart, variables)
+}
+
+// AddCodeHeader adds the header of a CODE block of the given length, loading
+// at the start address, as saved by the ROM.
+func (b *Builder) AddCodeHeader(name string, start, length uint16) {
+	b.addHeader(codeHeader, name, length, start, 0x8000)
+}
+
+// AddProgram adds a BASIC program header, followed by the program data block,
+// with the standard pauses.
+func (b *Builder) AddProgram(name string, program []byte, autostart uint16) {
+	b.AddProgramHeader(name, uint16(len(program)), autostart, uint16(len(program)))
+	b.AddStandardBlock(program, defaultPause)
+}
+
+// AddCode adds a CODE header, followed by the code data block, with the
+// standard pauses.
+func (b *Builder) AddCode(name string, start uint16, code []byte) {
+	b.AddCodeHeader(name, start, uint16(len(code)))
+	b.AddStandardBlock(code, defaultPause)
+}
+
+// addHeader adds a standard header block, with the name padded with spaces
+// to 10 characters.
+func (b *Builder) addHeader(dataType uint8, name string, length, param1, param2 uint16) {
+	filename, err := encodeText(name, 10)
+	if err != nil {
+		b.setError(errors.Wrap(err, "invalid header filename"))
+		return
+	}
+	filename = append(filename, bytes.Repeat([]byte{' '}, 10-len(filename))...)
+
+	header := &bytes.Buffer{}
+	header.WriteByte(dataType)
+	header.Write(filename)
+	_ = binary.Write(header, binary.LittleEndian, []uint16{length, param1, param2})
+
+	b.AddFlaggedBlock(headerFlag, header.Bytes(), defaultPause)
+}
+
+// AddPause adds a Pause block, stopping the tape for the given ms. A pause of
+// zero stops the tape, until the user starts it again.
+func (b *Builder) AddPause(ms uint16) {
+	b.add(types.PauseTapeCommand, ms)
+}
+
+// AddStop48K adds a Stop the Tape if in 48K Mode block.
+func (b *Builder) AddStop48K() {
+	b.add(types.StopTapeWhen48kMode, uint32(0))
+}
+
+// AddText adds a Text Description block, of up to 255 Latin-1 characters.
+func (b *Builder) AddText(s string) {
+	text, err := encodeText(s, 255)
+	if err != nil {
+		b.setError(errors.Wrap(err, "invalid text description"))
+		return
+	}
+	b.add(types.TextDescription, uint8(len(text)), text)
+}
+
+// AddGroupStart adds a Group Start block, of up to 255 Latin-1 characters.
+// The group is ended with AddGroupEnd.
+func (b *Builder) AddGroupStart(name string) {
+	text, err := encodeText(name, 255)
+	if err != nil {
+		b.setError(errors.Wrap(err, "invalid group name"))
+		return
+	}
+	b.add(types.GroupStart, uint8(len(text)), text)
+}
+
+// AddGroupEnd adds a Group End block.
+func (b *Builder) AddGroupEnd() {
+	b.add(types.GroupEnd)
+}
+
+// Bytes returns the TZX file: the header, followed by the blocks.
+func (b *Builder) Bytes() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	data := append([]byte("ZXTape!\x1a"), supportedMajorVersion, supportedMinorVersion)
+	return append(data, b.blocks.Bytes()...), nil
+}
+
+// Write writes the TZX file to w.
+func (b *Builder) Write(w io.Writer) error {
+	data, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return errors.Wrap(err, "unable to write the tape")
+}
+
+// TZX returns the tape, read back from the TZX file, as when loaded from disk.
+func (b *Builder) TZX() (*TZX, error) {
+	data, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); err != nil {
+		return nil, errors.Wrap(err, "unable to read the built tape")
+	}
+	return tape, nil
+}
+
+// encodeText returns the text in Latin-1, the encoding of all TZX text.
+func encodeText(s string, max int) ([]byte, error) {
+	var text []byte
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, errors.Errorf("character '%c' is not in the Latin-1 character set", r)
+		}
+		text = append(text, byte(r))
+	}
+	if len(text) > max {
+		return nil, errors.Errorf("text is longer than %d characters", max)
+	}
+	return text, nil
+}
@@ -0,0 +1,166 @@
+package tzx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+func TestBuilder(t *testing.T) {
+	program := []byte{0x00, 0x0a, 0x05, 0x00, 0xef, 0x22, 0x22, 0xaf, 0x0d} // 10 LOAD "" CODE
+	code := []byte{0xf3, 0x3e, 0x02, 0xd3, 0xfe, 0x18, 0xfe}
+
+	b := NewBuilder()
+	b.AddText("Café demo")
+	b.AddGroupStart("Loader")
+	b.AddProgram("loader", program, 10)
+	b.AddGroupEnd()
+	b.AddCode("démo", 32768, code)
+	b.AddPause(0)
+	b.AddStop48K()
+	b.AddFlaggedBlock(0x42, []byte{1, 2, 3}, 500)
+	b.AddBlock([]byte{0xff, 0xaa, 0x55}, 0)
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("ZXTape!\x1a\x01\x14")) {
+		t.Errorf("got header % X, want a TZX 1.20 header", buf.Bytes()[:10])
+	}
+
+	// read back as when loaded from disk
+	reader := storage.NewReader(bytes.NewReader(buf.Bytes()))
+	tape := New(reader)
+	if err := tape.Read(); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if warnings := tape.Warnings(); len(warnings) != 0 {
+		t.Errorf("got warnings %q, want none", warnings)
+	}
+
+	all := tape.Blocks()
+	if len(all) != 11 {
+		t.Fatalf("got %d blocks, want 11", len(all))
+	}
+
+	if text, ok := all[0].(*blocks.TextDescription); !ok || text.Description != "Café demo" {
+		t.Errorf("got block #1 %v, want the text description", all[0])
+	}
+	if group, ok := all[1].(*blocks.GroupStart); !ok || group.GroupName != "Loader" {
+		t.Errorf("got block #2 %v, want the group start", all[1])
+	}
+	if _, ok := all[4].(*blocks.GroupEnd); !ok {
+		t.Errorf("got block #5 %v, want the group end", all[4])
+	}
+
+	// every standard block has the flag and a valid checksum
+	data := map[int]struct {
+		flag  uint8
+		data  []byte
+		pause uint16
+	}{
+		2:  {0x00, nil, 1000},
+		3:  {0xff, program, 1000},
+		5:  {0x00, nil, 1000},
+		6:  {0xff, code, 1000},
+		9:  {0x42, []byte{1, 2, 3}, 500},
+		10: {0xff, []byte{0xaa}, 0},
+	}
+	for i, want := range data {
+		block, ok := all[i].(*blocks.StandardSpeedData)
+		if !ok {
+			t.Errorf("got block #%d %s, want a standard speed data block", i+1, all[i].Name())
+			continue
+		}
+		raw := tap.BlockBytes(block.DataBlock)
+		if raw[0] != want.flag {
+			t.Errorf("block #%d: got flag %02X, want %02X", i+1, raw[0], want.flag)
+		}
+		if !tap.VerifyChecksum(block.DataBlock) {
+			t.Errorf("block #%d: checksum is not valid", i+1)
+		}
+		if want.data != nil && !bytes.Equal(raw[1:len(raw)-1], want.data) {
+			t.Errorf("block #%d: got data % X, want % X", i+1, raw[1:len(raw)-1], want.data)
+		}
+		if block.Pause != want.pause {
+			t.Errorf("block #%d: got pause %d, want %d", i+1, block.Pause, want.pause)
+		}
+	}
+
+	header, ok := all[2].BlockData().(*headers.ProgramData)
+	if !ok {
+		t.Fatalf("got block #3 %v, want a program header", all[2].BlockData())
+	}
+	if header.Filename() != "loader    " || int(header.DataLength) != len(program) ||
+		header.AutoStartLine != 10 || int(header.ProgramLength) != len(program) {
+		t.Errorf("got program header %q, length %d, line %d, program %d",
+			header.Filename(), header.DataLength, header.AutoStartLine, header.ProgramLength)
+	}
+
+	codeHeader, ok := all[5].BlockData().(*headers.ByteData)
+	if !ok {
+		t.Fatalf("got block #6 %v, want a code header", all[5].BlockData())
+	}
+	if codeHeader.Filename() != "démo      " || codeHeader.StartAddress != 32768 || int(codeHeader.DataLength) != len(code) {
+		t.Errorf("got code header %q, start %d, length %d", codeHeader.Filename(), codeHeader.StartAddress, codeHeader.DataLength)
+	}
+
+	if pause, ok := all[7].(*blocks.PauseTapeCommand); !ok || pause.Pause != 0 {
+		t.Errorf("got block #8 %v, want a stop the tape pause", all[7])
+	}
+	if _, ok := all[8].(*blocks.StopTapeWhen48kMode); !ok {
+		t.Errorf("got block #9 %v, want a stop the tape in 48K mode", all[8])
+	}
+
+	// the tape built in memory matches the one written
+	built, err := b.TZX()
+	if err != nil {
+		t.Fatalf("TZX: %v", err)
+	}
+	if len(built.Blocks()) != len(all) {
+		t.Errorf("got %d blocks from TZX, want %d", len(built.Blocks()), len(all))
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder)
+	}{
+		{"long filename", func(b *Builder) { b.AddCode("longer than ten", 32768, []byte{0}) }},
+		{"filename not in Latin-1", func(b *Builder) { b.AddCode("€uro", 32768, []byte{0}) }},
+		{"long text", func(b *Builder) { b.AddText(strings.Repeat("A", 256)) }},
+		{"text not in Latin-1", func(b *Builder) { b.AddText("Ω") }},
+		{"long group name", func(b *Builder) { b.AddGroupStart(strings.Repeat("A", 256)) }},
+		{"long data block", func(b *Builder) { b.AddStandardBlock(make([]byte, 0xFFFE), 1000) }},
+		{"long TAP block", func(b *Builder) { b.AddBlock(make([]byte, 0x10000), 1000) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder()
+			b.AddText("before")
+			tt.build(b)
+			b.AddText("after")
+
+			if b.Err() == nil {
+				t.Fatal("expected an error")
+			}
+			if data, err := b.Bytes(); err != b.Err() || data != nil {
+				t.Errorf("got %d bytes and error %v from Bytes, want the first error", len(data), err)
+			}
+			if err := b.Write(&bytes.Buffer{}); err != b.Err() {
+				t.Errorf("got error %v from Write, want the first error", err)
+			}
+			if _, err := b.TZX(); err != b.Err() {
+				t.Errorf("got error %v from TZX, want the first error", err)
+			}
+		})
+	}
+}
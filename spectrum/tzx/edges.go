@@ -0,0 +1,72 @@
+package tzx
+
+// MaxEdges is the most edges returned by EdgeList, about 20 minutes of a
+// standard speed tape, which stops tapes that loop forever from using all of
+// the memory. Use EdgeStream for longer tapes.
+const MaxEdges = 1 << 23
+
+// Edge is a change of the tape signal level.
+type Edge struct {
+	Time  uint64 // T-states from the start of the tape
+	High  bool   // Level after the edge, true for high, false for low
+	Block int    // Index of the block being played
+}
+
+// EdgeStream plays the tape, like PulseStream, giving the edges of the signal
+// with their time from the start of the tape, for plotting the waveform. The
+// first edge is the level of the first pulse, at time 0, and pulses at the
+// same level as the one before, such as the two parts of a pause, are joined.
+type EdgeStream struct {
+	pulses *PulseStream
+	time   uint64
+	high   bool
+	first  bool
+}
+
+// EdgeStream returns a stream of the edges of the tape, starting from the
+// block index of the Blocks slice.
+func (t TZX) EdgeStream(start int) *EdgeStream {
+	return &EdgeStream{pulses: t.PulseStream(start), first: true}
+}
+
+// Next returns the next edge of the tape, or false at the end of the tape.
+func (s *EdgeStream) Next() (Edge, bool) {
+	for {
+		pulse, ok := s.pulses.Next()
+		if !ok {
+			return Edge{}, false
+		}
+
+		edge := Edge{Time: s.time, High: pulse.High, Block: s.pulses.Block()}
+		changed := s.first || pulse.High != s.high
+		s.time += uint64(pulse.Duration)
+		s.high = pulse.High
+		s.first = false
+
+		if changed {
+			return edge, true
+		}
+	}
+}
+
+// Duration returns the time from the start of the tape to the end of the
+// pulses played so far, in T-states. At the end of the stream this is the
+// length of the tape.
+func (s *EdgeStream) Duration() uint64 {
+	return s.time
+}
+
+// EdgeList returns the edges of the whole tape, see EdgeStream, up to
+// MaxEdges edges.
+func (t TZX) EdgeList() []Edge {
+	var edges []Edge
+	stream := t.EdgeStream(0)
+	for len(edges) < MaxEdges {
+		edge, ok := stream.Next()
+		if !ok {
+			break
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
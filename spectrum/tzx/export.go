@@ -0,0 +1,86 @@
+package tzx
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
+)
+
+// ExportBlock writes the raw data of the block, without the flag and checksum
+// bytes of the data blocks stored as in .TAP files. The index is the block
+// number, starting from 1, as shown in the geometry, including any archive
+// info block.
+func (t TZX) ExportBlock(index int, w io.Writer) error {
+	return t.exportBlock(index, w, false)
+}
+
+// ExportBlockWithChecksum writes the raw data of the block, as ExportBlock,
+// but including the flag and checksum bytes of the data blocks.
+func (t TZX) ExportBlockWithChecksum(index int, w io.Writer) error {
+	return t.exportBlock(index, w, true)
+}
+
+func (t TZX) exportBlock(index int, w io.Writer, withChecksum bool) error {
+	block, err := t.blockByNumber(index)
+	if err != nil {
+		return err
+	}
+
+	data, tapData, ok := blockPayload(block)
+	if !ok {
+		return errors.Errorf("block #%d (%s) has no data payload", index, block.Name())
+	}
+
+	// the first byte is the flag, and the last the checksum
+	if tapData && !withChecksum && len(data) >= 2 {
+		data = data[1 : len(data)-1]
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return errors.Wrapf(err, "unable to write block #%d", index)
+	}
+	return nil
+}
+
+// blockPayload returns the raw data of the block, and whether it is stored as
+// in .TAP files, with the flag and checksum bytes. It returns false for the
+// blocks without a data payload.
+func blockPayload(block Block) (data []byte, tapData bool, ok bool) {
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		return tap.BlockBytes(b.DataBlock), true, true
+	case *blocks.TurboSpeedData:
+		return b.DataBlock, true, true
+	case *blocks.PureData:
+		return b.DataBlock, true, true
+	case *blocks.DirectRecording:
+		return b.Data, false, true
+	case *blocks.CswRecording:
+		return b.Data, false, true
+	case *blocks.GeneralizedData:
+		return b.DataStreams, false, true
+	case *blocks.CustomInfo:
+		return b.Info, false, true
+	}
+	return nil, false, false
+}
+
+// blockByNumber returns the block for the block number, starting from 1,
+// where the archive info is always block #1 when present.
+func (t TZX) blockByNumber(number int) (Block, error) {
+	index := number - 1
+	if t.archive != nil {
+		if number == 1 {
+			return t.archive, nil
+		}
+		index--
+	}
+
+	if index < 0 || index >= len(t.blocks) {
+		return nil, errors.Errorf("block #%d not found, the tape has %d blocks", number, t.blockCount())
+	}
+	return t.blocks[index], nil
+}
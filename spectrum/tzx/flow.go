@@ -0,0 +1,254 @@
+package tzx
+
+import (
+	"fmt"
+	"strings"
+
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+// ControlFlowGraph returns a textual representation of the control flow between
+// the blocks of the tape, as given by the jump, loop, call and select blocks.
+// The relative offsets of these blocks are resolved to the absolute block
+// numbers, as output by DisplayGeometry.
+//
+// The playback of the tape is also followed, reporting any infinite loops,
+// i.e. returning to a block with the same loop and call state.
+func (t TZX) ControlFlowGraph() string {
+	var flow []string
+
+	for i, block := range t.blocks {
+		n := t.BlockNumber(i)
+
+		switch b := block.(type) {
+		case *blocks.JumpTo:
+			flow = append(flow, fmt.Sprintf("#%02d %-19s : -> %s", n, b.Name(), t.flowTarget(i+int(b.Value))))
+		case *blocks.LoopStart:
+			end := t.loopEnd(i)
+			if end < 0 {
+				flow = append(flow, fmt.Sprintf("#%02d %-19s : repeat %d times, no matching loop end", n, b.Name(), b.RepetitionCount))
+			} else {
+				flow = append(flow, fmt.Sprintf("#%02d %-19s : repeat #%02d..#%02d %d times", n, b.Name(), n+1, t.BlockNumber(end), b.RepetitionCount))
+			}
+		case *blocks.LoopEnd:
+			start := t.loopStart(i)
+			if start < 0 {
+				flow = append(flow, fmt.Sprintf("#%02d %-19s : no matching loop start", n, b.Name()))
+			} else {
+				flow = append(flow, fmt.Sprintf("#%02d %-19s : -> %s", n, b.Name(), t.flowTarget(start+1)))
+			}
+		case *blocks.CallSequence:
+			var targets []string
+			for _, offset := range b.Blocks {
+				targets = append(targets, t.flowTarget(i+int(int16(offset))))
+			}
+			flow = append(flow, fmt.Sprintf("#%02d %-19s : -> %s, then %s", n, b.Name(), strings.Join(targets, ", "), t.flowTarget(i+1)))
+		case *blocks.ReturnFromSequence:
+			flow = append(flow, fmt.Sprintf("#%02d %-19s : -> return to the call sequence", n, b.Name()))
+		case *blocks.Select:
+			var targets []string
+			for _, s := range b.Selections {
+				targets = append(targets, fmt.Sprintf("%s '%s'", t.flowTarget(i+int(s.RelativeOffset)), storage.DisplayText(s.Description)))
+			}
+			flow = append(flow, fmt.Sprintf("#%02d %-19s : -> %s", n, b.Name(), strings.Join(targets, ", ")))
+		}
+	}
+
+	if len(flow) == 0 {
+		return "No control flow blocks found.\n"
+	}
+
+	str := "CONTROL FLOW:\n"
+	str += strings.Join(flow, "\n") + "\n"
+
+	if loop := t.infiniteLoop(); loop >= 0 {
+		str += fmt.Sprintf("\nWARNING: infinite loop detected at block #%02d\n", t.BlockNumber(loop))
+	}
+
+	return str
+}
+
+// ControlFlowError is a jump, loop, call or select block whose target is not
+// a block on the tape, which would cause an emulator to hang or fail.
+type ControlFlowError struct {
+	Block   int    // Block number, starting from 1
+	Name    string // Block type name
+	Target  int    // Resolved target block number, not set for unmatched loops
+	Problem string
+}
+
+func (e ControlFlowError) Error() string {
+	return fmt.Sprintf("block #%02d %s: %s", e.Block, e.Name, e.Problem)
+}
+
+// ValidateControlFlow resolves the relative offsets of the jump, call and
+// select blocks to absolute block numbers, returning a ControlFlowError for
+// each target before the first block or beyond the end of the tape. Jumps to
+// the jump block itself, and loop blocks without a matching start or end, are
+// also reported.
+func (t TZX) ValidateControlFlow() []error {
+	var problems []error
+
+	for i, block := range t.blocks {
+		e := ControlFlowError{Block: t.BlockNumber(i), Name: block.Name()}
+		var targets []int
+
+		switch b := block.(type) {
+		case *blocks.JumpTo:
+			if b.Value == 0 {
+				e.Target, e.Problem = e.Block, "jumps to itself, looping forever"
+				problems = append(problems, e)
+			}
+			targets = append(targets, i+int(b.Value))
+		case *blocks.LoopStart:
+			if t.loopEnd(i) < 0 {
+				e.Problem = "no matching loop end"
+				problems = append(problems, e)
+			}
+		case *blocks.LoopEnd:
+			if t.loopStart(i) < 0 {
+				e.Problem = "no matching loop start"
+				problems = append(problems, e)
+			}
+		case *blocks.CallSequence:
+			for _, offset := range b.Blocks {
+				targets = append(targets, i+int(int16(offset)))
+			}
+		case *blocks.Select:
+			for _, s := range b.Selections {
+				targets = append(targets, i+int(s.RelativeOffset))
+			}
+		}
+
+		// the target may be the end of the tape, which stops playback
+		for _, target := range targets {
+			if target < 0 || target > len(t.blocks) {
+				e.Target = t.BlockNumber(target)
+				e.Problem = fmt.Sprintf(
+					"target block #%02d is outside the tape, which has blocks #%02d to #%02d",
+					e.Target, t.BlockNumber(0), t.BlockNumber(len(t.blocks)-1),
+				)
+				problems = append(problems, e)
+			}
+		}
+	}
+
+	return problems
+}
+
+// BlockNumber converts an index of the Blocks slice to the block number,
+// which starts from 1 and includes the archive info block.
+func (t TZX) BlockNumber(index int) int {
+	if t.archive != nil {
+		return index + 2
+	}
+	return index + 1
+}
+
+// flowTarget returns the block number for the target index, or a description
+// when the index is outside the tape.
+func (t TZX) flowTarget(index int) string {
+	if index == len(t.blocks) {
+		return "end of tape"
+	} else if index < 0 || index > len(t.blocks) {
+		return fmt.Sprintf("invalid block #%02d", t.BlockNumber(index))
+	}
+	return fmt.Sprintf("#%02d", t.BlockNumber(index))
+}
+
+// loopEnd returns the index of the loop end block for the loop starting at the
+// given index, or -1 when not found. Loops can not be nested.
+func (t TZX) loopEnd(start int) int {
+	for i := start + 1; i < len(t.blocks); i++ {
+		switch t.blocks[i].(type) {
+		case *blocks.LoopEnd:
+			return i
+		case *blocks.LoopStart:
+			return -1
+		}
+	}
+	return -1
+}
+
+// loopStart returns the index of the loop start block for the loop ending at
+// the given index, or -1 when not found.
+func (t TZX) loopStart(end int) int {
+	for i := end - 1; i >= 0; i-- {
+		switch t.blocks[i].(type) {
+		case *blocks.LoopStart:
+			return i
+		case *blocks.LoopEnd:
+			return -1
+		}
+	}
+	return -1
+}
+
+// infiniteLoop follows the playback of the tape, returning the index of the
+// block where an infinite loop is detected, or -1 when playback reaches the
+// end of the tape. Select blocks are not followed, as they require the user
+// to choose where to continue.
+func (t TZX) infiniteLoop() int {
+	type call struct {
+		index int // index of the call sequence block
+		next  int // current call of the sequence
+	}
+	type state struct {
+		index     int
+		loopStart int
+		loopCount int
+		calls     string
+	}
+
+	seen := make(map[state]bool)
+	loopStart, loopCount := -1, 0
+	var calls []call
+
+	for i := 0; i >= 0 && i < len(t.blocks); {
+		s := state{index: i, loopStart: loopStart, loopCount: loopCount, calls: fmt.Sprint(calls)}
+		if seen[s] {
+			return i
+		}
+		seen[s] = true
+
+		switch b := t.blocks[i].(type) {
+		case *blocks.JumpTo:
+			i += int(b.Value)
+			continue
+		case *blocks.LoopStart:
+			loopStart, loopCount = i+1, int(b.RepetitionCount)
+		case *blocks.LoopEnd:
+			if loopStart >= 0 {
+				loopCount--
+				if loopCount > 0 {
+					i = loopStart
+					continue
+				}
+				loopStart = -1
+			}
+		case *blocks.CallSequence:
+			// call sequences cannot be nested, so a call within a call is ignored
+			if len(b.Blocks) > 0 && len(calls) == 0 {
+				calls = append(calls, call{index: i})
+				i += int(int16(b.Blocks[0]))
+				continue
+			}
+		case *blocks.ReturnFromSequence:
+			if len(calls) > 0 {
+				c := &calls[len(calls)-1]
+				c.next++
+				sequence := t.blocks[c.index].(*blocks.CallSequence)
+				if c.next < len(sequence.Blocks) {
+					i = c.index + int(int16(sequence.Blocks[c.next]))
+					continue
+				}
+				i = c.index
+				calls = calls[:len(calls)-1]
+			}
+		}
+		i++
+	}
+
+	return -1
+}
@@ -0,0 +1,112 @@
+package tzx
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tzx/blocks"
+)
+
+// Standard ZX Spectrum ROM timings, in T-states, as given in the TZX specification.
+const (
+	romPilotPulse      = 2168
+	romSyncFirstPulse  = 667
+	romSyncSecondPulse = 735
+	romZeroBitPulse    = 855
+	romOneBitPulse     = 1710
+	romHeaderPilotTone = 8063 // pilot pulses of a header block, flag < 128
+	romDataPilotTone   = 3223 // pilot pulses of a data block, flag >= 128
+)
+
+// Limits for the timings to load reliably on real hardware.
+const (
+	minReliablePulse = 400 // shortest bit pulse, in T-states
+	minBitRatio      = 1.5 // shortest ratio of the one-bit and zero-bit pulses
+	minPilotTone     = 256 // fewest pilot pulses for the ROM loader to lock on
+)
+
+// Direct recording T-states per sample for the 44100 and 22050 Hz sample rates,
+// recommended by the TZX specification.
+var directRecordingRates = map[uint16]bool{79: true, 158: true}
+
+// Warning is a problem found with a block, which may stop the tape loading.
+type Warning struct {
+	Block   int    // Block number, starting from 1
+	Name    string // Block type name
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("block #%02d %s: %s", w.Block, w.Name, w.Message)
+}
+
+// LintTimings inspects the pulse timings of the turbo and pure data blocks,
+// warning when they differ from the standard ROM timings, or when they would
+// be unreliable on real hardware, such as bit pulses that are too short, or
+// too similar to each other. Direct recording blocks not sampled at 22050 Hz
+// or 44100 Hz, as recommended by the specification, are also reported.
+func (t TZX) LintTimings() []Warning {
+	var warnings []Warning
+
+	for i, block := range t.blocks {
+		warn := func(format string, args ...interface{}) {
+			warnings = append(warnings, Warning{
+				Block:   t.BlockNumber(i),
+				Name:    block.Name(),
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		switch b := block.(type) {
+		case *blocks.TurboSpeedData:
+			lintStandard(warn, "pilot pulse", b.PilotPulse, romPilotPulse)
+			lintStandard(warn, "first sync pulse", b.SyncFirstPulse, romSyncFirstPulse)
+			lintStandard(warn, "second sync pulse", b.SyncSecondPulse, romSyncSecondPulse)
+			lintStandard(warn, "zero-bit pulse", b.ZeroBitPulse, romZeroBitPulse)
+			lintStandard(warn, "one-bit pulse", b.OneBitPulse, romOneBitPulse)
+			if b.PilotTone < minPilotTone {
+				warn("pilot tone of %d pulses is too short, expected at least %d", b.PilotTone, minPilotTone)
+			}
+			lintBits(warn, b.ZeroBitPulse, b.OneBitPulse)
+		case *blocks.PureData:
+			lintStandard(warn, "zero-bit pulse", b.ZeroBitPulse, romZeroBitPulse)
+			lintStandard(warn, "one-bit pulse", b.OneBitPulse, romOneBitPulse)
+			lintBits(warn, b.ZeroBitPulse, b.OneBitPulse)
+		case *blocks.DirectRecording:
+			if !directRecordingRates[b.TStatesPerSample] {
+				warn("sampled at %d T-states per sample (%d Hz), expected 79 (44100 Hz) or 158 (22050 Hz)",
+					b.TStatesPerSample, sampleRate(b.TStatesPerSample))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// lintStandard warns when the pulse differs from the ROM timing.
+func lintStandard(warn func(string, ...interface{}), name string, pulse, rom uint16) {
+	if pulse != rom {
+		warn("non-standard %s of %d T-states, the ROM uses %d", name, pulse, rom)
+	}
+}
+
+// lintBits warns when the bit pulses are too short to be read reliably, or
+// when the zero and one bits can not be told apart.
+func lintBits(warn func(string, ...interface{}), zero, one uint16) {
+	if zero < minReliablePulse {
+		warn("zero-bit pulse of %d T-states is too short to load reliably, expected at least %d", zero, minReliablePulse)
+	}
+	if one < minReliablePulse {
+		warn("one-bit pulse of %d T-states is too short to load reliably, expected at least %d", one, minReliablePulse)
+	}
+	if float64(one) < float64(zero)*minBitRatio {
+		warn("one-bit pulse of %d T-states is too close to the zero-bit pulse of %d T-states", one, zero)
+	}
+}
+
+// sampleRate returns the sample rate in Hz for the T-states per sample.
+func sampleRate(tstates uint16) int {
+	if tstates == 0 {
+		return 0
+	}
+	return 3500000 / int(tstates)
+}
@@ -0,0 +1,300 @@
+package tzx
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
+)
+
+// Reasons for a block failing to load in the loader simulation.
+const (
+	LoadLostSync = "lost sync"
+	LoadChecksum = "checksum mismatch"
+	LoadTimeout  = "timeout"
+)
+
+// BlockLoad is the result of loading a data block in the loader simulation.
+type BlockLoad struct {
+	Block  int    // Block number, starting from 1
+	Name   string // Block type name
+	Length int    // Bytes expected by the loader
+	Loaded int    // Bytes loaded before the block finished or failed
+	Reason string // Reason the block failed to load, empty when loaded cleanly
+	Detail string // Where and why the block failed
+}
+
+// OK reports whether the block loaded cleanly.
+func (b BlockLoad) OK() bool {
+	return b.Reason == ""
+}
+
+func (b BlockLoad) String() string {
+	if b.OK() {
+		return fmt.Sprintf("block #%02d %s: loaded %d bytes", b.Block, b.Name, b.Loaded)
+	}
+	return fmt.Sprintf("block #%02d %s: %s, %s", b.Block, b.Name, b.Reason, b.Detail)
+}
+
+// LoadResult is the outcome of the loader simulation, with an entry for each
+// data block played, in playback order. A block inside a loop has an entry
+// for each time it is played.
+type LoadResult struct {
+	Blocks []BlockLoad
+}
+
+// Failed returns the blocks that failed to load.
+func (r LoadResult) Failed() []BlockLoad {
+	var failed []BlockLoad
+	for _, b := range r.Blocks {
+		if !b.OK() {
+			failed = append(failed, b)
+		}
+	}
+	return failed
+}
+
+// loaderTimings are the pulse lengths the loader expects for a data block.
+type loaderTimings struct {
+	pilot    uint16 // zero when the block has no pilot tone
+	zero     uint16
+	one      uint16
+	length   int  // data bytes
+	checksum bool // the last byte is an XOR checksum of the block
+}
+
+// Loader tolerances, as a simplified model of the edge timing of the ROM.
+const (
+	loadPilotTolerance = 4 // pilot pulses may differ by a quarter of their length
+	loadTimeoutFactor  = 3 // pulses longer than this many one-bit pulses time out
+)
+
+// loader states
+const (
+	loadSeekPilot = iota
+	loadPilot
+	loadSync
+	loadData
+	loadComplete // all bytes loaded, with a valid checksum
+	loadDone     // the result has been recorded
+)
+
+// SimulateLoad plays the pulse stream of the tape through a simplified model
+// of the ROM loader: it locks on to a pilot tone of at least 256 pulses, waits
+// for the two short sync pulses, then frames the pairs of bit pulses into
+// bytes, checking the XOR checksum once all bytes are loaded. This reports,
+// for each data block played, whether it would load cleanly, or where and why
+// it would fail.
+//
+// The loader is set up with the timings of each block, so turbo blocks are
+// loaded as a turbo loader using the ROM routine with modified timings would.
+// The checksum is only checked for Standard and Turbo Speed Data blocks, as
+// Pure Data blocks are usually read by custom loaders. Generalized Data and
+// Direct Recording blocks are not decoded, although their pulses are played.
+//
+// An error is returned when the tape loops forever.
+func (t TZX) SimulateLoad() (LoadResult, error) {
+	var result LoadResult
+
+	if index := t.infiniteLoop(); index >= 0 {
+		return result, errors.Errorf("infinite loop detected at block #%02d", t.BlockNumber(index))
+	}
+
+	timings := make([]loaderTimings, len(t.blocks))
+	next := make([]int, len(t.blocks)+1) // index of the next data block
+	next[len(t.blocks)] = -1
+	for i := len(t.blocks) - 1; i >= 0; i-- {
+		next[i] = next[i+1]
+		if tm, ok := t.loaderTimings(i); ok {
+			timings[i] = tm
+			next[i] = i
+		}
+	}
+
+	var (
+		state   = loadSeekPilot
+		target  = -1 // index of the data block being loaded
+		pilots  int
+		longest int // longest pilot tone found, when too short to lock on
+		bits    int
+		current byte
+		data    []byte
+		first   uint32 // first pulse of the bit
+		reason  string
+		detail  string
+		prev    = -1
+	)
+
+	finish := func() {
+		if target < 0 || state == loadDone {
+			return
+		}
+		tm := timings[target]
+		load := BlockLoad{
+			Block:  t.BlockNumber(target),
+			Name:   t.blocks[target].Name(),
+			Length: tm.length,
+			Loaded: len(data),
+			Reason: reason,
+			Detail: detail,
+		}
+		if load.Reason == "" {
+			switch state {
+			case loadSeekPilot:
+				load.Reason = LoadTimeout
+				if longest > 0 {
+					load.Detail = fmt.Sprintf("pilot tone of %d pulses is too short to lock on", longest)
+				} else {
+					load.Detail = "no pilot tone found"
+				}
+			case loadPilot, loadSync:
+				load.Reason = LoadLostSync
+				load.Detail = "sync pulses not found after the pilot tone"
+			case loadData:
+				load.Reason = LoadTimeout
+				load.Detail = fmt.Sprintf("signal ended after %d of %d bytes", len(data), tm.length)
+			}
+		}
+		result.Blocks = append(result.Blocks, load)
+		state = loadDone
+	}
+
+	fail := func(r, format string, args ...interface{}) {
+		reason, detail = r, fmt.Sprintf(format, args...)
+		finish()
+	}
+
+	stream := t.PulseStream(0)
+	for {
+		pulse, ok := stream.Next()
+		if !ok {
+			break
+		}
+		block := stream.Block()
+		if block != prev {
+			// a new pass over the same block, from a loop, is a new load
+			if next[block] != target || block < prev {
+				finish()
+				target = next[block]
+				state, pilots, longest, bits, current, data, reason, detail = loadSeekPilot, 0, 0, 0, 0, nil, "", ""
+			}
+			prev = block
+		}
+		if target < 0 || state == loadDone {
+			continue
+		}
+
+		tm := timings[target]
+		length := pulse.Duration
+		timeout := uint32(tm.one) * loadTimeoutFactor
+
+		switch state {
+		case loadSeekPilot:
+			if tm.pilot > 0 && withinPilot(length, tm.pilot) {
+				pilots++
+				if pilots >= minPilotTone {
+					state = loadPilot
+				}
+			} else {
+				if pilots > longest {
+					longest = pilots
+				}
+				pilots = 0
+			}
+		case loadPilot:
+			switch {
+			case withinPilot(length, tm.pilot):
+			case length < uint32(tm.pilot)*3/4:
+				state = loadSync
+			default:
+				fail(LoadLostSync, "pulse of %d T-states breaks the pilot tone", length)
+			}
+		case loadSync:
+			if length >= uint32(tm.pilot)*3/4 {
+				fail(LoadLostSync, "second sync pulse of %d T-states is too long", length)
+				continue
+			}
+			state = loadData
+		case loadData:
+			if length > timeout {
+				fail(LoadTimeout, "no edge within %d T-states after %d of %d bytes", timeout, len(data), tm.length)
+				continue
+			}
+			if length < uint32(tm.zero)/2 || length > uint32(tm.one)*3/2 {
+				fail(LoadLostSync, "pulse of %d T-states at byte %d is not a bit pulse", length, len(data))
+				continue
+			}
+			if bits%2 == 0 {
+				first = length
+				bits++
+				continue
+			}
+			threshold := (uint32(tm.zero) + uint32(tm.one)) / 2
+			bit := length > threshold
+			if (first > threshold) != bit {
+				fail(LoadLostSync, "bit pulses of %d and %d T-states differ at byte %d", first, length, len(data))
+				continue
+			}
+			current <<= 1
+			if bit {
+				current |= 1
+			}
+			if bits++; bits == 16 {
+				data = append(data, current)
+				bits, current = 0, 0
+			}
+			if len(data) == tm.length {
+				if tm.checksum && !checksumValid(data) {
+					fail(LoadChecksum, "XOR of the %d bytes is not zero", len(data))
+					continue
+				}
+				state = loadComplete
+				finish()
+			}
+		}
+	}
+	finish()
+
+	return result, nil
+}
+
+// loaderTimings returns the timings the loader expects for the block at the
+// index, or false when it is not a data block.
+func (t TZX) loaderTimings(index int) (loaderTimings, bool) {
+	switch b := t.blocks[index].(type) {
+	case *blocks.StandardSpeedData:
+		data := tap.BlockBytes(b.DataBlock)
+		return loaderTimings{pilot: romPilotPulse, zero: romZeroBitPulse, one: romOneBitPulse, length: len(data), checksum: true}, true
+	case *blocks.TurboSpeedData:
+		return loaderTimings{pilot: b.PilotPulse, zero: b.ZeroBitPulse, one: b.OneBitPulse, length: len(b.DataBlock), checksum: true}, true
+	case *blocks.PureData:
+		tm := loaderTimings{zero: b.ZeroBitPulse, one: b.OneBitPulse, length: len(b.DataBlock)}
+		// the pilot tone is the Pure Tone before any sync Pulse Sequence
+		for i := index - 1; i >= 0; i-- {
+			if tone, ok := t.blocks[i].(*blocks.PureTone); ok {
+				tm.pilot = tone.Length
+				break
+			}
+			if _, ok := t.blocks[i].(*blocks.SequenceOfPulses); !ok {
+				break
+			}
+		}
+		return tm, true
+	}
+	return loaderTimings{}, false
+}
+
+func withinPilot(length uint32, pilot uint16) bool {
+	tolerance := uint32(pilot) / loadPilotTolerance
+	return length+tolerance >= uint32(pilot) && length <= uint32(pilot)+tolerance
+}
+
+func checksumValid(data []byte) bool {
+	var sum byte
+	for _, b := range data {
+		sum ^= b
+	}
+	return sum == 0
+}
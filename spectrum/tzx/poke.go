@@ -0,0 +1,136 @@
+package tzx
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+	"retroio/spectrum/tzx/blocks"
+)
+
+// ApplyPoke changes the byte at the memory address to the value, in the code
+// block loaded by the CODE header directly before it, and fixes the checksum
+// of the block. The block index is the block number, starting from 1, as shown
+// in the geometry. The address must be within the memory the block loads to.
+//
+// Only the blocks read into memory are changed; use WritePatched to save the
+// tape with the changes.
+func (t TZX) ApplyPoke(blockIndex int, addr uint16, value uint8) error {
+	block, err := t.blockByNumber(blockIndex)
+	if err != nil {
+		return err
+	}
+
+	header, err := t.codeHeader(blockIndex)
+	if err != nil {
+		return err
+	}
+
+	// the data loaded into memory, without the flag and checksum bytes
+	var data []byte
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		if b.DataBlock != nil && !tap.IsHeader(b.DataBlock) {
+			data = b.DataBlock.BlockData()
+		}
+	case *blocks.TurboSpeedData:
+		if len(b.DataBlock) >= 2 {
+			data = b.DataBlock[1 : len(b.DataBlock)-1]
+		}
+	}
+	if len(data) == 0 {
+		return errors.Errorf("block #%d (%s) is not a code block", blockIndex, block.Name())
+	}
+
+	start := int(header.StartAddress)
+	if int(addr) < start || int(addr) >= start+len(data) {
+		return errors.Errorf("address %d is outside block #%d, which loads from %d to %d", addr, blockIndex, start, start+len(data)-1)
+	}
+	data[int(addr)-start] = value
+
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		tap.RepairChecksum(b.DataBlock)
+	case *blocks.TurboSpeedData:
+		var sum uint8
+		for _, c := range b.DataBlock[:len(b.DataBlock)-1] {
+			sum ^= c
+		}
+		b.DataBlock[len(b.DataBlock)-1] = sum
+	}
+
+	return nil
+}
+
+// codeHeader returns the CODE header for the block, which must be stored in
+// the standard speed data block directly before it.
+func (t TZX) codeHeader(blockIndex int) (*headers.ByteData, error) {
+	if blockIndex > 1 {
+		if previous, err := t.blockByNumber(blockIndex - 1); err == nil {
+			if header, ok := previous.BlockData().(*headers.ByteData); ok {
+				return header, nil
+			}
+		}
+	}
+	return nil, errors.Errorf("block #%d has no CODE header, so the load address is not known", blockIndex)
+}
+
+// WritePatched writes the original bytes of the tape file, with the TAP data of
+// each standard and turbo speed data block replaced by the data in memory, such
+// as after applying pokes. The original must be the file the tape was read from.
+func (t TZX) WritePatched(original []byte, w io.Writer) error {
+	patched := make([]byte, len(original))
+	copy(patched, original)
+
+	for i, span := range t.spans {
+		// the TAP data starts with the flag byte, after the fields of the block
+		var data []byte
+		var offset int64
+		switch b := span.block.(type) {
+		case *blocks.StandardSpeedData:
+			data, offset = tap.BlockBytes(b.DataBlock), 5
+		case *blocks.TurboSpeedData:
+			data, offset = b.DataBlock, 0x13
+		}
+		if data == nil {
+			continue
+		}
+
+		start := span.offset + offset
+		if end := start + int64(len(data)); end > span.end || end > int64(len(patched)) {
+			return errors.Errorf("block #%d is not in the original tape data", i+1)
+		}
+		copy(patched[start:], data)
+	}
+
+	_, err := w.Write(patched)
+	return errors.Wrap(err, "unable to write the patched tape")
+}
+
+// PokeDefinition is a trainer, or cheat, from a POKEs custom info block, as
+// used by the Spectrum pokes databases.
+type PokeDefinition struct {
+	Block       int    // Block number of the custom info block
+	Description string // General description of the pokes of the block
+	blocks.Trainer
+}
+
+// Pokes returns the trainers of each POKEs custom info block on the tape.
+// Blocks that can not be fully decoded are reported by Warnings, with the
+// trainers decoded before the error returned here.
+func (t TZX) Pokes() []PokeDefinition {
+	var definitions []PokeDefinition
+	for i, block := range t.blocks {
+		info, ok := block.(*blocks.CustomInfo)
+		if !ok || info.Identifier() != blocks.PokesIdentifier {
+			continue
+		}
+		description, trainers, _ := info.Pokes()
+		for _, trainer := range trainers {
+			definitions = append(definitions, PokeDefinition{Block: t.BlockNumber(i), Description: description, Trainer: trainer})
+		}
+	}
+	return definitions
+}
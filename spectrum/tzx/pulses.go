@@ -0,0 +1,331 @@
+package tzx
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+// Pulse is a single pulse of the tape signal, as played on the EAR port.
+type Pulse struct {
+	High     bool   // Level of the pulse, true for high, false for low
+	Duration uint32 // Length of the pulse in T-states
+}
+
+// PulseStream plays the blocks of a tape, lazily producing the pulses of each
+// block as they are needed, so the signal of the whole tape is never held in
+// memory.
+//
+// The loop, jump and call sequence blocks are followed, and the pauses are
+// played following the "current pulse level" rules of the TZX specification:
+// the level is low at the start, each pulse of a data block is played at the
+// current level, after which the level changes, and a pause finishes the last
+// edge with 1 ms at the current level before going low.
+//
+// Consecutive pulses may have the same level, e.g. the two parts of a pause.
+// Blocks that only stop the tape, and CSW recordings, are ignored. The stream
+// ends when the control blocks loop forever without playing any pulses, e.g.
+// a jump to itself.
+type PulseStream struct {
+	blocks []Block
+	index  int // index of the next block to be played
+	block  int // index of the block being played
+	high   bool
+	queue  []Pulse
+
+	loopStart int
+	loopCount int
+	calls     []pulseCall
+
+	seen map[pulseState]bool // states played since the last pulse was queued
+}
+
+// pulseState is the playback position of the stream, including the loop and
+// call being played, which repeats when the tape loops forever.
+type pulseState struct {
+	index     int
+	loopStart int
+	loopCount int
+	calls     string
+}
+
+// pulseCall is a call sequence being played.
+type pulseCall struct {
+	index int // index of the call sequence block
+	next  int // current call of the sequence
+}
+
+// PulseStream returns a stream of the pulses of the tape, starting from the
+// block index of the Blocks slice.
+func (t TZX) PulseStream(start int) *PulseStream {
+	return &PulseStream{blocks: t.blocks, index: start, block: start, loopStart: -1}
+}
+
+// Next returns the next pulse of the tape, or false at the end of the tape.
+func (s *PulseStream) Next() (Pulse, bool) {
+	for len(s.queue) == 0 {
+		if s.index < 0 || s.index >= len(s.blocks) {
+			return Pulse{}, false
+		}
+
+		// a state played twice without any pulses is an infinite loop
+		state := pulseState{index: s.index, loopStart: s.loopStart, loopCount: s.loopCount, calls: fmt.Sprint(s.calls)}
+		if s.seen[state] {
+			s.index = -1
+			return Pulse{}, false
+		}
+		if s.seen == nil {
+			s.seen = make(map[pulseState]bool)
+		}
+		s.seen[state] = true
+
+		s.block = s.index
+		s.playBlock()
+	}
+	s.seen = nil
+
+	p := s.queue[0]
+	s.queue = s.queue[1:]
+	return p, true
+}
+
+// Block returns the index of the block currently being played.
+func (s *PulseStream) Block() int {
+	return s.block
+}
+
+// playBlock queues the pulses of the next block, and moves to the block
+// following it, taking into account any loops, jumps and calls.
+func (s *PulseStream) playBlock() {
+	next := s.index + 1
+
+	switch b := s.blocks[s.index].(type) {
+	case *blocks.StandardSpeedData:
+		data := tap.BlockBytes(b.DataBlock)
+		pilot := romDataPilotTone
+		if len(data) > 0 && data[0] < 128 {
+			pilot = romHeaderPilotTone
+		}
+		s.pulses(romPilotPulse, pilot)
+		s.pulse(romSyncFirstPulse)
+		s.pulse(romSyncSecondPulse)
+		s.data(romZeroBitPulse, romOneBitPulse, data, 8)
+		s.pause(b.Pause)
+	case *blocks.TurboSpeedData:
+		s.pulses(b.PilotPulse, int(b.PilotTone))
+		s.pulse(b.SyncFirstPulse)
+		s.pulse(b.SyncSecondPulse)
+		s.data(b.ZeroBitPulse, b.OneBitPulse, b.DataBlock, b.UsedBits)
+		s.pause(b.Pause)
+	case *blocks.PureTone:
+		s.pulses(b.Length, int(b.PulseCount))
+	case *blocks.SequenceOfPulses:
+		for _, length := range b.Lengths {
+			s.pulse(length)
+		}
+	case *blocks.PureData:
+		s.data(b.ZeroBitPulse, b.OneBitPulse, b.DataBlock, b.UsedBits)
+		s.pause(b.Pause)
+	case *blocks.DirectRecording:
+		s.directRecording(b)
+		s.pause(b.Pause)
+	case *blocks.GeneralizedData:
+		s.generalizedData(b)
+		s.pause(b.Pause)
+	case *blocks.KansasCityStandard:
+		s.pulses(b.PilotPulse, int(b.PilotTone))
+		s.kansasCityStandard(b)
+		s.pause(b.Pause)
+	case *blocks.PauseTapeCommand:
+		s.pause(b.Pause)
+	case *blocks.SetSignalLevel:
+		s.high = b.SignalLevel == 1
+	case *blocks.JumpTo:
+		next = s.index + int(b.Value)
+	case *blocks.LoopStart:
+		s.loopStart, s.loopCount = s.index+1, int(b.RepetitionCount)
+	case *blocks.LoopEnd:
+		if s.loopStart >= 0 {
+			s.loopCount--
+			if s.loopCount > 0 {
+				next = s.loopStart
+			} else {
+				s.loopStart = -1
+			}
+		}
+	case *blocks.CallSequence:
+		// call sequences cannot be nested, so a call within a call is ignored
+		if len(b.Blocks) > 0 && len(s.calls) == 0 {
+			s.calls = append(s.calls, pulseCall{index: s.index})
+			next = s.index + int(int16(b.Blocks[0]))
+		}
+	case *blocks.ReturnFromSequence:
+		if len(s.calls) > 0 {
+			c := &s.calls[len(s.calls)-1]
+			c.next++
+			sequence := s.blocks[c.index].(*blocks.CallSequence)
+			if c.next < len(sequence.Blocks) {
+				next = c.index + int(int16(sequence.Blocks[c.next]))
+			} else {
+				next = c.index + 1
+				s.calls = s.calls[:len(s.calls)-1]
+			}
+		}
+	}
+
+	s.index = next
+}
+
+// pulse queues a pulse at the current level, then changes the level.
+func (s *PulseStream) pulse(length uint16) {
+	s.queue = append(s.queue, Pulse{High: s.high, Duration: uint32(length)})
+	s.high = !s.high
+}
+
+func (s *PulseStream) pulses(length uint16, count int) {
+	for i := 0; i < count; i++ {
+		s.pulse(length)
+	}
+}
+
+// data queues two pulses for each bit, MSb first, with only the used bits
+// of the last byte being played.
+func (s *PulseStream) data(zero, one uint16, data []byte, usedBits uint8) {
+	bits := storage.NewBitReader(data)
+	bits.SetUsedBits(usedBits)
+	for {
+		bit, err := bits.ReadBit()
+		if err != nil {
+			break
+		}
+		length := zero
+		if bit {
+			length = one
+		}
+		s.pulse(length)
+		s.pulse(length)
+	}
+}
+
+// kansasCityStandard queues the pulses of each byte of a TSX Kansas City
+// Standard block: the leading bits, the 8 data bits in the order given by the
+// block, then the trailing bits, with each bit played as a number of pulses.
+func (s *PulseStream) kansasCityStandard(b *blocks.KansasCityStandard) {
+	bit := func(one bool) {
+		if one {
+			s.pulses(b.OneBitPulse, b.OnePulses())
+		} else {
+			s.pulses(b.ZeroBitPulse, b.ZeroPulses())
+		}
+	}
+
+	leading, leadingValue := b.LeadingBits()
+	trailing, trailingValue := b.TrailingBits()
+	for _, value := range b.DataBlock {
+		for i := 0; i < leading; i++ {
+			bit(leadingValue)
+		}
+		for i := uint(0); i < 8; i++ {
+			if b.MSbFirst() {
+				bit(value&(0x80>>i) != 0)
+			} else {
+				bit(value&(1<<i) != 0)
+			}
+		}
+		for i := 0; i < trailing; i++ {
+			bit(trailingValue)
+		}
+	}
+}
+
+// pause queues the pause, where the last edge is finished with 1 ms at the
+// current level, followed by a low level. Zero length pauses are ignored.
+func (s *PulseStream) pause(ms uint16) {
+	if ms == 0 {
+		return
+	}
+
+	const msLength = 3500 // T-states per ms
+	s.queue = append(s.queue, Pulse{High: s.high, Duration: msLength})
+	if ms > 1 {
+		s.queue = append(s.queue, Pulse{High: false, Duration: uint32(ms-1) * msLength})
+	}
+	s.high = false
+}
+
+// directRecording queues each run of samples at the same level as a pulse.
+// After the block, the current level is the last level played.
+func (s *PulseStream) directRecording(b *blocks.DirectRecording) {
+	var run uint32
+	level := s.high
+
+	samples := storage.NewBitReader(b.Data)
+	samples.SetUsedBits(b.UsedBits)
+	for {
+		high, err := samples.ReadBit()
+		if err != nil {
+			break
+		}
+		if high != level && run > 0 {
+			s.queue = append(s.queue, Pulse{High: level, Duration: run})
+			run = 0
+		}
+		level = high
+		run += uint32(b.TStatesPerSample)
+	}
+
+	if run > 0 {
+		s.queue = append(s.queue, Pulse{High: level, Duration: run})
+	}
+	s.high = level
+}
+
+// generalizedData queues the pilot/sync symbols, followed by the data symbols.
+func (s *PulseStream) generalizedData(b *blocks.GeneralizedData) {
+	for _, p := range b.PilotStreams {
+		if int(p.Symbol) >= len(b.PilotSymbols) {
+			continue
+		}
+		for i := 0; i < int(p.RepetitionCount); i++ {
+			s.symbol(b.PilotSymbols[p.Symbol])
+		}
+	}
+
+	nb := 0
+	for 1<<uint(nb) < len(b.DataSymbols) {
+		nb++
+	}
+
+	bits := storage.NewBitReader(b.DataStreams)
+	for i := 0; i < int(b.TOTD); i++ {
+		symbol, err := bits.ReadBits(nb)
+		if err != nil {
+			break
+		}
+		if int(symbol) < len(b.DataSymbols) {
+			s.symbol(b.DataSymbols[symbol])
+		}
+	}
+}
+
+// symbol queues the pulses of a symbol, up to the first zero-length pulse,
+// with the level of the first pulse set by the symbol flags.
+func (s *PulseStream) symbol(sym blocks.Symbol) {
+	switch sym.Flags & 0x03 {
+	case 0x01:
+		s.high = !s.high // no edge, prolongs the previous pulse
+	case 0x02:
+		s.high = false
+	case 0x03:
+		s.high = true
+	}
+
+	for _, length := range sym.PulseLengths {
+		if length == 0 {
+			break
+		}
+		s.pulse(length)
+	}
+}
@@ -0,0 +1,53 @@
+package tzx
+
+import (
+	"image"
+
+	"retroio/spectrum/screen"
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tap/headers"
+)
+
+// ExtractLoadingScreens decodes the SCREEN$ blocks of the tape, in tape order,
+// where most games have the loading screen as the first. A screen is a data
+// block of 6912 bytes following a CODE header loaded at 16384, the display
+// file, or a headerless data block of 6912 bytes, as loaded by many custom
+// loaders. Any FLASH attributes are shown in their first state.
+//
+// Only uncompressed screens are found: a compressed screen is unpacked by the
+// game's loader, and can not be decoded without running it.
+func (t TZX) ExtractLoadingScreens() ([]image.Image, error) {
+	var images []image.Image
+
+	var previous tap.Block
+	for _, block := range t.blocks {
+		data := block.BlockData()
+		if data == nil {
+			continue
+		}
+
+		if !tap.IsHeader(data) && len(data.BlockData()) == screen.Size && screenHeader(previous) {
+			img, err := screen.Decode(data.BlockData(), false)
+			if err != nil {
+				return images, err
+			}
+			images = append(images, img)
+		}
+		previous = data
+	}
+
+	return images, nil
+}
+
+// screenHeader returns true when a SCREEN$ data block may follow the block:
+// a CODE header for the display file, or any data block, for a headerless
+// screen.
+func screenHeader(block tap.Block) bool {
+	switch h := block.(type) {
+	case *headers.ByteData:
+		return h.StartAddress == 16384 && h.DataLength == screen.Size
+	case *headers.ProgramData, *headers.NumericData, *headers.AlphanumericData:
+		return false
+	}
+	return true
+}
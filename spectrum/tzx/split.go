@@ -0,0 +1,74 @@
+package tzx
+
+import (
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tzx/blocks"
+)
+
+// headerLength is the size of the TZX header, from the signature to the
+// minor version number.
+const headerLength = 10
+
+// SplitAtStops divides the tape at the points where the tape is stopped, a
+// Pause block of zero duration, or a Stop the Tape if in 48K Mode block,
+// returning a TZX file for each segment, as found on multiload tapes with a
+// segment for each level.
+//
+// The data must be the file the tape was read from, as the bytes of the blocks
+// are copied as is, including any skipped blocks. Each segment is given a TZX
+// header, and the archive info block of the tape, when it has one. The stop
+// blocks are not included, and segments without any blocks are dropped.
+func (t TZX) SplitAtStops(data []byte) ([][]byte, error) {
+	if len(t.spans) > 0 && t.spans[len(t.spans)-1].end > int64(len(data)) {
+		return nil, errors.New("the data is shorter than the tape")
+	}
+
+	header := []byte("ZXTape!\x1a")
+	if t.header.valid(t.maxMinorVersion()) == nil {
+		header = append(header, t.MajorVersion, t.MinorVersion)
+	} else {
+		header = append(header, supportedMajorVersion, supportedMinorVersion)
+	}
+
+	start := int64(headerLength)
+	var archive []byte
+	for _, span := range t.spans {
+		if span.block == t.archive {
+			archive = data[span.offset:span.end]
+			start = span.end
+			break
+		}
+	}
+
+	var segments [][]byte
+	segment := func(from, to int64) {
+		if to <= from {
+			return
+		}
+		file := append(append([]byte{}, header...), archive...)
+		segments = append(segments, append(file, data[from:to]...))
+	}
+
+	for _, span := range t.spans {
+		if !stopsTape(span.block) {
+			continue
+		}
+		segment(start, span.offset)
+		start = span.end
+	}
+	segment(start, int64(len(data)))
+
+	return segments, nil
+}
+
+// stopsTape returns true for the blocks that stop the tape.
+func stopsTape(block Block) bool {
+	switch b := block.(type) {
+	case *blocks.PauseTapeCommand:
+		return b.Pause == 0
+	case *blocks.StopTapeWhen48kMode:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package tzx
+
+import (
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
+)
+
+// BlockTiming is the timing profile of a tape block, as needed by the tape
+// engine of an emulator to replay it. All lengths are in T-states, and fields
+// not used by the block are zero.
+type BlockTiming struct {
+	Block        int    // Block number, starting from 1
+	Name         string // Block type name
+	PilotPulse   uint16
+	PilotPulses  uint16   // Number of pulses of the pilot tone
+	SyncPulses   []uint16 // Sync pulses, or the pulses of a Pulse Sequence block
+	ZeroBitPulse uint16
+	OneBitPulse  uint16
+	Pause        uint32 // Pause after the block, 3500 T-states per ms
+}
+
+// TimingProfile returns the timing profile of each block of the tape, giving
+// a block level view of the pulses played by PulseStream. The Standard Speed,
+// Turbo Speed, Pure Tone, Pulse Sequence and Pure Data blocks are populated,
+// as are the TSX Kansas City Standard blocks, along with the pause of the Pause blocks; all other blocks have an entry
+// with zeroed timings.
+func (t TZX) TimingProfile() []BlockTiming {
+	const msLength = 3500 // T-states per ms
+
+	var profile []BlockTiming
+
+	for i, block := range t.blocks {
+		timing := BlockTiming{Block: t.BlockNumber(i), Name: block.Name()}
+
+		switch b := block.(type) {
+		case *blocks.StandardSpeedData:
+			timing.PilotPulse = romPilotPulse
+			timing.PilotPulses = romDataPilotTone
+			if data := tap.BlockBytes(b.DataBlock); len(data) > 0 && data[0] < 128 {
+				timing.PilotPulses = romHeaderPilotTone
+			}
+			timing.SyncPulses = []uint16{romSyncFirstPulse, romSyncSecondPulse}
+			timing.ZeroBitPulse = romZeroBitPulse
+			timing.OneBitPulse = romOneBitPulse
+			timing.Pause = uint32(b.Pause) * msLength
+		case *blocks.TurboSpeedData:
+			timing.PilotPulse = b.PilotPulse
+			timing.PilotPulses = b.PilotTone
+			timing.SyncPulses = []uint16{b.SyncFirstPulse, b.SyncSecondPulse}
+			timing.ZeroBitPulse = b.ZeroBitPulse
+			timing.OneBitPulse = b.OneBitPulse
+			timing.Pause = uint32(b.Pause) * msLength
+		case *blocks.PureTone:
+			timing.PilotPulse = b.Length
+			timing.PilotPulses = b.PulseCount
+		case *blocks.SequenceOfPulses:
+			timing.SyncPulses = append([]uint16(nil), b.Lengths...)
+		case *blocks.PureData:
+			timing.ZeroBitPulse = b.ZeroBitPulse
+			timing.OneBitPulse = b.OneBitPulse
+			timing.Pause = uint32(b.Pause) * msLength
+		case *blocks.KansasCityStandard:
+			timing.PilotPulse = b.PilotPulse
+			timing.PilotPulses = b.PilotTone
+			timing.ZeroBitPulse = b.ZeroBitPulse
+			timing.OneBitPulse = b.OneBitPulse
+			timing.Pause = uint32(b.Pause) * msLength
+		case *blocks.PauseTapeCommand:
+			timing.Pause = uint32(b.Pause) * msLength
+		}
+
+		profile = append(profile, timing)
+	}
+
+	return profile
+}
+
+// Duration returns the playing time of the tape in T-states, by playing the
+// pulses of every block, see PulseStream. Tapes that loop forever are stopped
+// after MaxEdges edges, in which case complete is false.
+func (t TZX) Duration() (tstates uint64, complete bool) {
+	stream := t.EdgeStream(0)
+	for i := 0; i < MaxEdges; i++ {
+		if _, ok := stream.Next(); !ok {
+			return stream.Duration(), true
+		}
+	}
+	return stream.Duration(), false
+}
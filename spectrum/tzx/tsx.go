@@ -0,0 +1,50 @@
+package tzx
+
+import (
+	"retroio/spectrum/tzx/blocks"
+	"retroio/spectrum/tzx/blocks/types"
+	"retroio/storage"
+)
+
+// NewTSX returns a reader for a TSX tape, the TZX variant used for the MSX,
+// which adds its own blocks to those of the TZX specification, such as the
+// Kansas City Standard data block. These blocks are only read from a TSX tape,
+// as TZX tapes may use the same IDs for other blocks in future revisions, and
+// are otherwise skipped as unknown blocks.
+func NewTSX(reader *storage.Reader) *TZX {
+	return &TZX{reader: reader, tsx: true}
+}
+
+// tsxMinorVersion is the TZX revision of TSX tapes, v1.21, which follows the
+// latest TZX revision supported.
+const tsxMinorVersion = 21
+
+// maxMinorVersion returns the latest TZX revision that can be read, which is
+// later for TSX tapes.
+func (t TZX) maxMinorVersion() uint8 {
+	if t.tsx {
+		return tsxMinorVersion
+	}
+	return supportedMinorVersion
+}
+
+// newTSXBlock returns a TSX block based on the type ID byte, or nil when the
+// ID is not a TSX block.
+func newTSXBlock(id byte) Block {
+	switch types.BlockType(id) {
+	case types.KansasCityStandard:
+		return &blocks.KansasCityStandard{}
+	}
+	return nil
+}
+
+// newBlock returns the block for the type ID byte, including the TSX blocks
+// when reading a TSX tape.
+func (t TZX) newBlock(id byte, offset int64) (Block, error) {
+	if t.tsx {
+		if block := newTSXBlock(id); block != nil {
+			return block, nil
+		}
+	}
+	return newFromBlockID(id, offset)
+}
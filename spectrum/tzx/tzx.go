@@ -51,6 +51,7 @@
 package tzx
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -60,6 +61,7 @@ import (
 
 	"retroio/spectrum/basic"
 	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
 	"retroio/spectrum/tzx/blocks/types"
 	"retroio/storage"
 )
@@ -69,6 +71,12 @@ const (
 	supportedMinorVersion = 20
 )
 
+// extensionBlockID is the first block ID following the General Extension
+// Rule, where the block length is stored in the 4 bytes after the ID. Unknown
+// blocks from this ID are skipped, allowing tapes from future revisions of the
+// specification to be read, while unknown blocks below it are errors.
+const extensionBlockID = 0x30
+
 // TZX files store the header information at the start of the file, followed
 // by zero or more data blocks. Some TZX files include an ArchiveInfo block,
 // which is always stored as the first block, directly after the header.
@@ -78,6 +86,9 @@ type TZX struct {
 	header
 	archive Block
 	blocks  []Block
+	spans   []blockSpan       // position of each block read, in tape order
+	skipped []ErrUnknownBlock // unknown blocks skipped by their length
+	tsx     bool              // read the TSX (MSX) blocks, see NewTSX
 }
 
 // Block is an interface for Tape data blocks
@@ -88,6 +99,12 @@ type Block interface {
 	BlockData() tap.Block
 }
 
+// contextReader is implemented by the blocks holding large recordings, which
+// are read in chunks so the read can be cancelled part way through the block.
+type contextReader interface {
+	ReadContext(ctx context.Context, reader *storage.Reader, progress func(done, total int64)) error
+}
+
 // Header is the first block of data found in all TZX files.
 // The file is identified with the first 7 bytes being `ZXTape!`, followed by the
 // _end of file_ byte `26` (`1A` hex). This is followed by two bytes containing
@@ -105,11 +122,23 @@ func New(reader *storage.Reader) *TZX {
 
 // Read processes the header, and then each block on the tape.
 func (t *TZX) Read() error {
+	return t.ReadContext(context.Background(), nil)
+}
+
+// ReadContext processes the header, and then each block on the tape. Before
+// each block, and at the end of the tape, progress is called (when not nil)
+// with the number of bytes read, and the total file size, which is -1 when
+// the size is not known.
+//
+// The read is stopped between blocks, or part way through the data of CSW and
+// Direct Recording blocks, when the context is cancelled, returning ctx.Err().
+// The blocks read so far are kept.
+func (t *TZX) ReadContext(ctx context.Context, progress func(done, total int64)) error {
 	if err := t.readHeader(); err != nil {
 		return err
 	}
 
-	if err := t.readBlocks(); err != nil {
+	if err := t.readBlocks(ctx, progress); err != nil {
 		return err
 	}
 
@@ -124,7 +153,7 @@ func (t *TZX) readHeader() error {
 		return fmt.Errorf("binary.Read failed: %v", err)
 	}
 
-	if err := t.header.valid(); err != nil {
+	if err := t.reader.Tolerate(t.header.valid(t.maxMinorVersion())); err != nil {
 		return err
 	}
 
@@ -132,8 +161,17 @@ func (t *TZX) readHeader() error {
 }
 
 // readBlocks processes each TZX block on the tape.
-func (t *TZX) readBlocks() error {
+func (t *TZX) readBlocks(ctx context.Context, progress func(done, total int64)) error {
+	total := t.reader.Size()
+
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(t.reader.Offset(), total)
+		}
+
 		blockID, err := t.reader.PeekByte()
 		if err != nil {
 			if err == io.EOF {
@@ -142,16 +180,41 @@ func (t *TZX) readBlocks() error {
 			return err
 		}
 
-		block, err := newFromBlockID(blockID)
-		if err != nil {
-			return err
+		block, err := t.newBlock(blockID, t.reader.Offset())
+		var unknown ErrUnknownBlock
+		if errors.As(err, &unknown) && unknown.Extension() {
+			// kept in place of the block, so the block numbers still count it
+			block = &blocks.Skipped{}
+			t.skipped = append(t.skipped, unknown)
+		} else if err != nil {
+			// the length of the blocks below the extension IDs is not known,
+			// so they can not be skipped, even in lenient mode
+			return errors.Wrapf(err, "block #%d", t.blockCount()+1)
 		}
 
-		if err := block.Read(t.reader); err != nil {
+		offset := t.reader.Offset()
+		if r, ok := block.(contextReader); ok {
+			err = r.ReadContext(ctx, t.reader, progress)
+		} else {
+			err = block.Read(t.reader)
+		}
+		if err != nil && err == ctx.Err() {
+			return err
+		}
+		if err == nil {
+			err = t.reader.Err()
+		}
+		if storage.IsEOF(err) {
+			return storage.TruncatedError{Blocks: t.blockCount(), Offset: offset}
+		} else if err != nil {
 			return errors.Wrap(err, "error reading TZX block")
 		}
 
-		if block.Id() == types.ArchiveInfo {
+		t.spans = append(t.spans, blockSpan{block: block, offset: offset, end: t.reader.Offset()})
+
+		// only the archive info of the first tape is stored separately, as tapes
+		// concatenated with glue blocks may each have their own archive info
+		if block.Id() == types.ArchiveInfo && t.archive == nil && len(t.blocks) == 0 {
 			t.archive = block
 		} else {
 			t.blocks = append(t.blocks, block)
@@ -160,73 +223,288 @@ func (t *TZX) readBlocks() error {
 	return nil
 }
 
-// DisplayGeometry prints the metadata, archive info, data blocks, etc.
+// SkippedBlocks returns the unknown and deprecated blocks, with an ID from
+// 0x30, that were skipped using the block length following the ID.
+func (t TZX) SkippedBlocks() []ErrUnknownBlock {
+	return t.skipped
+}
+
+// blockCount returns the number of blocks read, including any archive info block.
+func (t TZX) blockCount() int {
+	if t.archive != nil {
+		return len(t.blocks) + 1
+	}
+	return len(t.blocks)
+}
+
+// Blocks returns the blocks of the tape, excluding any archive info block.
+// The slice is a copy, so changing it does not change the tape, but the blocks
+// are shared with the tape, and must not be modified; use ApplyPoke to patch
+// the data of a block.
+func (t TZX) Blocks() []Block {
+	blocks := make([]Block, len(t.blocks))
+	copy(blocks, t.blocks)
+	return blocks
+}
+
+// ArchiveInfo returns the archive info block, which is always block #1, or
+// nil when the tape has none. The block is shared with the tape, and must not
+// be modified.
+func (t TZX) ArchiveInfo() Block {
+	return t.archive
+}
+
+// BlockSummary returns the number of blocks of each block ID on the tape,
+// including the archive info block, as a quick structural fingerprint.
+func (t TZX) BlockSummary() map[uint8]int {
+	summary := make(map[uint8]int)
+	_ = t.Walk(func(index int, b Block) error {
+		summary[uint8(b.Id())]++
+		return nil
+	})
+	return summary
+}
+
+// DataBytes returns the length of the data payload of the block, the bytes
+// saved by the program, as opposed to the timings and metadata of the tape, or
+// zero for blocks without a payload. The data of the Standard Speed, Turbo
+// Speed, Pure Data, Direct Recording, CSW Recording, Generalized Data and TSX
+// Kansas City Standard blocks is counted.
+func DataBytes(block Block) int {
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		return len(tap.BlockBytes(b.DataBlock))
+	case *blocks.TurboSpeedData:
+		return len(b.DataBlock)
+	case *blocks.PureData:
+		return len(b.DataBlock)
+	case *blocks.DirectRecording:
+		return len(b.Data)
+	case *blocks.CswRecording:
+		return len(b.Data)
+	case *blocks.GeneralizedData:
+		return len(b.DataStreams)
+	case *blocks.KansasCityStandard:
+		return len(b.DataBlock)
+	}
+	return 0
+}
+
+// TotalDataBytes returns the total length of the data payload of all blocks on
+// the tape, see DataBytes.
+func (t TZX) TotalDataBytes() int {
+	total := 0
+	for _, block := range t.blocks {
+		total += DataBytes(block)
+	}
+	return total
+}
+
+// DataDensity returns the fraction of the file that is data payload, from 0 to
+// 1, with the rest being the header, block IDs, timings and descriptions. It
+// is zero when nothing has been read.
+func (t TZX) DataDensity() float64 {
+	size := t.reader.Offset()
+	if size <= 0 {
+		return 0
+	}
+	return float64(t.TotalDataBytes()) / float64(size)
+}
+
+// Walk calls fn for each block of the tape, in order, including the archive
+// info block, with the block number, starting from 1, as shown by the
+// geometry. When fn returns an error the walk is stopped, and the error
+// returned.
+func (t TZX) Walk(fn func(index int, b Block) error) error {
+	if t.archive != nil {
+		if err := fn(1, t.archive); err != nil {
+			return err
+		}
+	}
+	for i, block := range t.blocks {
+		if err := fn(t.BlockNumber(i), block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tapes returns the blocks of each tape, when several tapes have been joined
+// together. The 'ZXTape!' header of each joined tape is read as a glue block,
+// which is not included in the blocks. A tape without glue blocks is returned
+// as a single tape, with the archive info block of the first tape excluded.
+// As with Blocks, the blocks are shared with the tape.
+func (t TZX) Tapes() [][]Block {
+	tapes := [][]Block{{}}
+	for _, block := range t.blocks {
+		if _, ok := block.(*blocks.GlueBlock); ok {
+			tapes = append(tapes, []Block{})
+			continue
+		}
+		tapes[len(tapes)-1] = append(tapes[len(tapes)-1], block)
+	}
+	return tapes
+}
+
+// Title returns the tape title from the archive info block, if present.
+func (t TZX) Title() string {
+	if archive, ok := t.archive.(*blocks.ArchiveInfo); ok {
+		return archive.Title()
+	}
+	return ""
+}
+
+// BlockHashes returns the data hash of each block storing tape data. Blocks
+// without data, such as pauses and text descriptions, are not included, so
+// tapes differing only by these cosmetic blocks have the same hashes.
+func (t TZX) BlockHashes() []tap.BlockHash {
+	var hashes []tap.BlockHash
+	for i, block := range t.blocks {
+		b, ok := block.(interface{ DataHash() string })
+		if !ok || b.DataHash() == "" {
+			continue
+		}
+		hashes = append(hashes, tap.BlockHash{Block: t.BlockNumber(i), Name: block.Name(), Hash: b.DataHash()})
+	}
+	return hashes
+}
+
+// BlockSummaries returns the metadata of each block on the tape, including
+// the archive info block, which is always block #1 when present, along with
+// the offset of each block in the file.
+func (t TZX) BlockSummaries() []tap.BlockSummary {
+	var summaries []tap.BlockSummary
+	if t.archive != nil {
+		summary := tap.NewBlockSummary(1, t.archive.Name(), t.archive)
+		summary.ID = int(t.archive.Id())
+		summary.Offset = t.blockOffset(t.archive)
+		summaries = append(summaries, summary)
+	}
+	for i, block := range t.blocks {
+		summary := tap.NewBlockSummary(t.BlockNumber(i), block.Name(), block)
+		summary.ID = int(block.Id())
+		summary.Offset = t.blockOffset(block)
+		summary.Data, _, _ = blockPayload(block)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// blockOffset returns the offset of the block ID in the file, or -1 when the
+// block was not read from the file.
+func (t TZX) blockOffset(block Block) int64 {
+	for _, span := range t.spans {
+		if span.block == block {
+			return span.offset
+		}
+	}
+	return -1
+}
+
+// DisplayGeometry prints the metadata, archive info, data blocks, etc. Each
+// block is shown with the offset of its block ID in the file.
 func (t TZX) DisplayGeometry() {
 	// TODO: update `block`'s to store their index number
 	blockCountOffset := 1 // Block #'s start from 1
 
+	// tapes joined with glue blocks are displayed separately
+	if len(t.Tapes()) > 1 {
+		fmt.Println("TAPE #1:")
+	}
+
 	if t.archive != nil {
 		// Archive counts as a normal block, but it is not stored in blocks slice
 		blockCountOffset += 1
 
-		fmt.Println("ARCHIVE INFORMATION (BLOCK #1):")
+		fmt.Printf("ARCHIVE INFORMATION (BLOCK #1 @0x%04X):\n", t.blockOffset(t.archive))
 		fmt.Println(t.archive)
 	}
 
 	fmt.Println("DATA BLOCKS:")
+	tape := 1
 	for i, block := range t.blocks {
-		fmt.Printf("#%02d %s\n", i+blockCountOffset, block)
+		offset := t.blockOffset(block)
+		switch b := block.(type) {
+		case *blocks.GlueBlock:
+			tape++
+			fmt.Println()
+			fmt.Printf("TAPE #%d (BLOCK #%02d @0x%04X, %s):\n", tape, i+blockCountOffset, offset, b)
+		case *blocks.ArchiveInfo:
+			fmt.Printf("#%02d @0x%04X ARCHIVE INFORMATION:\n%s", i+blockCountOffset, offset, b)
+		default:
+			fmt.Printf("#%02d @0x%04X %s\n", i+blockCountOffset, offset, block)
+		}
 	}
 
 	fmt.Println()
-	fmt.Printf("TZX revision: v%d.%d", t.MajorVersion, t.MinorVersion)
+	fmt.Printf("TZX revision: v%d.%d\n", t.MajorVersion, t.MinorVersion)
+}
+
+// Warnings returns the problems found with the tape, which did not stop it
+// from being read, such as a TZX revision older than the supported one, for
+// the tape and any tapes joined to it with glue blocks, and the unknown blocks
+// skipped following the General Extension Rule.
+func (t TZX) Warnings() []string {
+	var warnings []string
+
+	for _, unknown := range t.skipped {
+		warnings = append(warnings, unknown.Error()+", the block was skipped")
+	}
+
 	if t.MinorVersion < supportedMinorVersion {
-		fmt.Printf(
-			" - WARNING! expected v%d.%d, this may lead to unexpected data or errors.",
-			supportedMajorVersion,
-			supportedMinorVersion,
-		)
+		warnings = append(warnings, fmt.Sprintf(
+			"TZX revision v%d.%d, expected v%d.%d, this may lead to unexpected data or errors",
+			t.MajorVersion, t.MinorVersion, supportedMajorVersion, supportedMinorVersion,
+		))
 	}
-	fmt.Println()
+
+	for i, block := range t.blocks {
+		if info, ok := block.(*blocks.CustomInfo); ok && info.Identifier() == blocks.PokesIdentifier {
+			if _, _, err := info.Pokes(); err != nil {
+				warnings = append(warnings, fmt.Sprintf("block #%02d %s: %s", t.BlockNumber(i), block.Name(), err))
+			}
+		}
+
+		glue, ok := block.(*blocks.GlueBlock)
+		if !ok {
+			continue
+		}
+		if major, minor := glue.Version(); major == supportedMajorVersion && minor < supportedMinorVersion {
+			warnings = append(warnings, fmt.Sprintf(
+				"block #%02d %s: joined tape revision v%d.%d, expected v%d.%d, this may lead to unexpected data or errors",
+				t.BlockNumber(i), block.Name(), major, minor, supportedMajorVersion, supportedMinorVersion,
+			))
+		}
+	}
+
+	return warnings
 }
 
 // DisplayBASIC outputs all BASIC programs
 func (t TZX) DisplayBASIC() {
-	isProgram := false
-	filename := ""
-
-	// TODO: update `block`'s to store their index number
-	// Archive counts as a normal block, but it is not stored in blocks slice
-	blockCountOffset := 1 // Block #'s start from 1
-	if t.archive != nil {
-		blockCountOffset += 1
+	var data []tap.Block
+	for _, block := range t.blocks {
+		data = append(data, block.BlockData())
 	}
 
 	listing := ""
-	for i, block := range t.blocks {
-		if block.BlockData() == nil {
+	for _, p := range tap.BasicPrograms(data) {
+		header := p.Header
+		listing += fmt.Sprintf("BLK#%02d: %s\n", t.BlockNumber(p.Index), storage.DisplayText(strings.Trim(header.Filename(), " ")))
+		listing += fmt.Sprintf("    %s\n", header.AutoStartText())
+		program, variables := header.SplitVariables(p.Data.BlockData())
+
+		lines, err := basic.Decode(program)
+		if err != nil {
+			listing += fmt.Sprintf("    %s\n", err)
 			continue
 		}
-		blk := block.BlockData()
-
-		if isProgram == true {
-			listing += fmt.Sprintf("BLK#%02d: %s\n", i+blockCountOffset, filename)
 
-			program, err := basic.Decode(blk.BlockData())
-			if err != nil {
-				listing += fmt.Sprintf("    %s\n", err)
-				continue
-			}
-
-			for _, line := range program {
-				listing += line
-			}
-			listing += "\n"
-			isProgram = false
-		} else if blk.Id() == 0 && blk.Filename() != "" {
-			filename = strings.Trim(block.BlockData().Filename(), " ")
-			isProgram = true
+		for _, line := range lines {
+			listing += line
 		}
+		listing += storage.DisplayText(basic.ListVariables(variables))
+		listing += "\n"
 	}
 	if len(listing) > 0 {
 		fmt.Println("BASIC PROGRAMS:")
@@ -238,22 +516,32 @@ func (t TZX) DisplayBASIC() {
 }
 
 // Validates the TZX header data.
-func (h header) valid() error {
-	var validationError error
+// All problems are reported together, so they can be tolerated in lenient mode.
+func (h header) valid(maxMinor uint8) error {
+	var problems []string
 
 	sig := [7]byte{}
 	copy(sig[:], "ZXTape!")
 	if h.Signature != sig {
-		validationError = errors.Wrapf(validationError, "Incorrect signature, got '%s'", h.Signature)
+		problems = append(problems, fmt.Sprintf("incorrect signature, got '%s'", h.Signature))
 	}
 
 	if h.Terminator != 0x1a {
-		validationError = errors.Wrapf(validationError, "Incorrect terminator, got '%b'", h.Terminator)
+		problems = append(problems, fmt.Sprintf("incorrect terminator, got 0x%02X", h.Terminator))
 	}
 
 	if h.MajorVersion != supportedMajorVersion {
-		validationError = errors.Wrapf(validationError, "Invalid version, got v%d.%d", h.MajorVersion, h.MinorVersion)
+		problems = append(problems, fmt.Sprintf("invalid version, got v%d.%d", h.MajorVersion, h.MinorVersion))
+	} else if h.MinorVersion > maxMinor {
+		problems = append(problems, fmt.Sprintf(
+			"unsupported version, got v%d.%d, expected v%d.%d or earlier",
+			h.MajorVersion, h.MinorVersion, supportedMajorVersion, maxMinor,
+		))
 	}
 
-	return validationError
+	if len(problems) > 0 {
+		return errors.Errorf("TZX header: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
 }
@@ -0,0 +1,412 @@
+package tzx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/spectrum/tzx/blocks/types"
+	"retroio/storage"
+)
+
+// fixture returns the contents of a tape in the testdata directory.
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readTape reads the tape data, failing the test on any error.
+func readTape(t *testing.T, data []byte) *TZX {
+	t.Helper()
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); err != nil {
+		t.Fatalf("unexpected error reading tape: %v", err)
+	}
+	return tape
+}
+
+func TestGeneralizedData(t *testing.T) {
+	tape := readTape(t, fixture(t, "gdb2.tzx"))
+	if len(tape.Blocks()) != 1 {
+		t.Fatalf("got %d blocks, want 1", len(tape.Blocks()))
+	}
+
+	block, ok := tape.Blocks()[0].(*blocks.GeneralizedData)
+	if !ok {
+		t.Fatalf("got block %T, want *blocks.GeneralizedData", tape.Blocks()[0])
+	}
+	data, err := block.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0xA5, 0x0F}) {
+		t.Errorf("got symbols % X, want A5 0F", data)
+	}
+
+	// 8 pilot pulses, 2 pulses for each of the 16 data symbols, and the pause
+	var pulses []Pulse
+	stream := tape.PulseStream(0)
+	for {
+		p, ok := stream.Next()
+		if !ok {
+			break
+		}
+		pulses = append(pulses, p)
+	}
+	if len(pulses) != 8+32+2 {
+		t.Fatalf("got %d pulses, want %d", len(pulses), 8+32+2)
+	}
+	if pulses[0].Duration != 2168 || pulses[8].Duration != 1710 || pulses[10].Duration != 855 {
+		t.Errorf("got pulses %d, %d, %d, want 2168, 1710, 855", pulses[0].Duration, pulses[8].Duration, pulses[10].Duration)
+	}
+}
+
+func TestGeneralizedDataOverrun(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int    // offset of the field in the fixture
+		value  uint32 // value written to the field
+	}{
+		{"block length", 11, 31},
+		{"pilot symbols", 17, 0xFFFFFFFF},
+		{"data symbols", 23, 0xFFFFFFFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fixture(t, "gdb2.tzx")
+			binary.LittleEndian.PutUint32(data[tt.offset:], tt.value)
+
+			err := New(storage.NewReader(bytes.NewReader(data))).Read()
+			if err == nil || !strings.Contains(err.Error(), "overrun the block length") {
+				t.Errorf("got error %v, want the tables to overrun the block", err)
+			}
+		})
+	}
+}
+
+func TestReadContextCancel(t *testing.T) {
+	// a direct recording block of 1 MB of samples
+	length := 1 << 20
+	data := append([]byte("ZXTape!\x1a\x01\x14"), 0x15, 79, 0, 0, 0, 8)
+	data = append(data, byte(length), byte(length>>8), byte(length>>16))
+	data = append(data, make([]byte, length)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	err := tape.ReadContext(ctx, func(done, total int64) {
+		if done > 64*1024 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if len(tape.Blocks()) != 0 {
+		t.Errorf("got %d blocks, want the cancelled block to be dropped", len(tape.Blocks()))
+	}
+}
+
+func TestPulseStreamLoops(t *testing.T) {
+	tone := []byte{0x12, 0x78, 0x08, 0x03, 0x00} // 3 pulses of 2168 T-states
+
+	tests := []struct {
+		name   string
+		blocks [][]byte
+		pulses int
+	}{
+		{"jump to itself", [][]byte{tone, {0x23, 0x00, 0x00}, tone}, 3},
+		{"jump back to a jump", [][]byte{tone, {0x23, 0x02, 0x00}, tone, {0x23, 0xFE, 0xFF}}, 3},
+		{"empty loop", [][]byte{{0x24, 0x03, 0x00}, {0x25}, tone}, 3},
+		{"loop of tones", [][]byte{{0x24, 0x03, 0x00}, tone, {0x25}}, 9},
+		{"call returning", [][]byte{{0x26, 0x02, 0x00, 0x02, 0x00, 0x02, 0x00}, tone, {0x27}}, 3},
+		{"call to itself", [][]byte{tone, {0x26, 0x01, 0x00, 0x00, 0x00}}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("ZXTape!\x1a\x01\x14")
+			for _, block := range tt.blocks {
+				data = append(data, block...)
+			}
+			tape := readTape(t, data)
+
+			pulses := 0
+			stream := tape.PulseStream(0)
+			for pulses <= 100 {
+				if _, ok := stream.Next(); !ok {
+					break
+				}
+				pulses++
+			}
+			if pulses != tt.pulses {
+				t.Errorf("got %d pulses, want %d", pulses, tt.pulses)
+			}
+		})
+	}
+}
+
+func TestBlocksCopy(t *testing.T) {
+	tape := readTape(t, fixture(t, "gdb2.tzx"))
+
+	blocks := tape.Blocks()
+	blocks[0] = nil
+	if tape.Blocks()[0] == nil {
+		t.Error("changing the returned slice changed the tape blocks")
+	}
+}
+
+func TestPulseStreamSignalLevel(t *testing.T) {
+	tone := []byte{0x12, 0x78, 0x08, 0x02, 0x00} // 2 pulses of 2168 T-states
+	high := []byte{0x2B, 0x01, 0x00, 0x00, 0x00, 0x01}
+	low := []byte{0x2B, 0x01, 0x00, 0x00, 0x00, 0x00}
+	single := []byte{0x12, 0x78, 0x08, 0x01, 0x00}
+
+	tests := []struct {
+		name   string
+		blocks [][]byte
+		want   []bool // level of each pulse
+	}{
+		{"low at the start", [][]byte{tone}, []bool{false, true}},
+		{"set high", [][]byte{high, tone}, []bool{true, false}},
+		{"set low", [][]byte{low, tone}, []bool{false, true}},
+		{"set low after a pulse", [][]byte{single, low, single}, []bool{false, false}},
+		{"set high after a pulse", [][]byte{high, single, high, single}, []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("ZXTape!\x1a\x01\x14")
+			for _, block := range tt.blocks {
+				data = append(data, block...)
+			}
+			tape := readTape(t, data)
+
+			var levels []bool
+			stream := tape.PulseStream(0)
+			for len(levels) <= len(tt.want) {
+				pulse, ok := stream.Next()
+				if !ok {
+					break
+				}
+				levels = append(levels, pulse.High)
+			}
+			if len(levels) != len(tt.want) {
+				t.Fatalf("got %d pulses, want %d", len(levels), len(tt.want))
+			}
+			for i, high := range levels {
+				if high != tt.want[i] {
+					t.Errorf("pulse %d: got high %v, want %v", i+1, high, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadUnknownBlocks(t *testing.T) {
+	tone := []byte{0x12, 0x78, 0x08, 0x03, 0x00} // 3 pulses of 2168 T-states
+
+	tests := []struct {
+		name    string
+		blocks  [][]byte
+		skipped []uint8 // IDs of the skipped blocks, or nil for an error
+	}{
+		// a block from a future revision of the specification, with its length
+		{"future block", [][]byte{tone, {0x4C, 0x05, 0x00, 0x00, 0x00, 'h', 'e', 'l', 'l', 'o'}, tone}, []uint8{0x4C}},
+		{"empty future block", [][]byte{{0xF0, 0x00, 0x00, 0x00, 0x00}, tone}, []uint8{0xF0}},
+		{"deprecated emulation info", [][]byte{tone, {0x34, 0, 0, 0, 0, 0, 0, 0, 0}, tone}, []uint8{0x34}},
+		{"unknown block", [][]byte{{0x29}}, nil},
+		{"unknown block with a length", [][]byte{tone, {0x1A, 0x01, 0x00, 0x00, 0x00, 0xFF}, tone}, nil},
+		{"deprecated C64 block", [][]byte{tone, {0x16, 0x01, 0x00, 0x00, 0x00, 0xFF}, tone}, nil},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []storage.Mode{storage.Strict, storage.Lenient} {
+			name := tt.name + "/strict"
+			if mode == storage.Lenient {
+				name = tt.name + "/lenient"
+			}
+			t.Run(name, func(t *testing.T) {
+				data := []byte("ZXTape!\x1a\x01\x14")
+				for _, block := range tt.blocks {
+					data = append(data, block...)
+				}
+				reader := storage.NewReader(bytes.NewReader(data))
+				reader.SetMode(mode)
+				tape := New(reader)
+				err := tape.Read()
+
+				if tt.skipped == nil {
+					var unknown ErrUnknownBlock
+					if !errors.As(err, &unknown) {
+						t.Fatalf("got error %v, want an unknown block error", err)
+					}
+					if unknown.Extension() {
+						t.Errorf("got block ID 0x%02X, want one below the extension IDs", unknown.ID)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error reading tape: %v", err)
+				}
+				if len(tape.SkippedBlocks()) != len(tt.skipped) {
+					t.Fatalf("got %d skipped blocks, want %d", len(tape.SkippedBlocks()), len(tt.skipped))
+				}
+				for i, block := range tape.SkippedBlocks() {
+					if block.ID != tt.skipped[i] {
+						t.Errorf("got skipped block ID 0x%02X, want 0x%02X", block.ID, tt.skipped[i])
+					}
+				}
+				// the skipped blocks are kept in place, so the blocks are numbered as on the tape
+				if len(tape.Blocks()) != len(tt.blocks) {
+					t.Fatalf("got %d blocks, want %d", len(tape.Blocks()), len(tt.blocks))
+				}
+				for i, block := range tape.Blocks() {
+					if block.Id() != types.BlockType(tt.blocks[i][0]) {
+						t.Errorf("got block #%d ID 0x%02X, want 0x%02X", i+1, block.Id(), tt.blocks[i][0])
+					}
+					if _, skipped := block.(*blocks.Skipped); skipped != (block.Id() != 0x12) {
+						t.Errorf("got block #%d %s, want the tones and the skipped blocks", i+1, block.Name())
+					}
+				}
+				if len(reader.Warnings()) != 0 {
+					t.Errorf("got warnings %q, want none", reader.Warnings())
+				}
+			})
+		}
+	}
+}
+
+func TestReadSkippedBlockFlow(t *testing.T) {
+	data := []byte("ZXTape!\x1a\x01\x14")
+	data = append(data, 0x23, 0x02, 0x00)                   // jump to the pause
+	data = append(data, 0x40, 0x00, 0x02, 0x00, 0x00, 1, 2) // deprecated snapshot, skipped
+	data = append(data, 0x20, 0xE8, 0x03)                   // pause
+
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); err != nil {
+		t.Fatalf("unexpected error reading tape: %v", err)
+	}
+	if len(tape.SkippedBlocks()) != 1 {
+		t.Errorf("got %d skipped blocks, want 1", len(tape.SkippedBlocks()))
+	}
+
+	all := tape.Blocks()
+	if len(all) != 3 {
+		t.Fatalf("got %d blocks, want the skipped block kept in place", len(all))
+	}
+	if skipped, ok := all[1].(*blocks.Skipped); !ok || skipped.Id() != types.Snapshot || skipped.Length != 2 {
+		t.Errorf("got block #2 %v, want the skipped snapshot", all[1])
+	}
+	if errs := tape.ValidateControlFlow(); len(errs) != 0 {
+		t.Errorf("got control flow errors %v, want none", errs)
+	}
+	if graph := tape.ControlFlowGraph(); !strings.Contains(graph, "#03") {
+		t.Errorf("got control flow\n%s\nwant the jump to block #03", graph)
+	}
+}
+
+func TestReadTruncatedFutureBlock(t *testing.T) {
+	data := []byte("ZXTape!\x1a\x01\x14\x4C\x10\x00\x00\x00hello")
+	tape := New(storage.NewReader(bytes.NewReader(data)))
+	if err := tape.Read(); !storage.IsTruncated(err) {
+		t.Errorf("got error %v, want a truncated error", err)
+	}
+}
+
+func TestReadTextBlocks(t *testing.T) {
+	data := []byte("ZXTape!\x1a\x01\x14")
+	data = append(data, 0x32, 0x06, 0x00, 0x01, 0x00, 0x03, 'T', 0xef, 't')  // archive info
+	data = append(data, 0x30, 0x05, 'C', 'a', 'f', 0xe9, '!')                // text description
+	data = append(data, 0x21, 0x03, 'G', 0xe0, 'p')                          // group start
+	data = append(data, 0x22)                                                // group end
+	data = append(data, 0x31, 0x05, 0x02, 0xa9, 'X')                         // message
+	data = append(data, 0x28, 0x06, 0x00, 0x01, 0x01, 0x00, 0x02, 'S', 0xfc) // select
+	data = append(data, 0x35)                                                // custom info, then the ID, length and info
+	data = append(data, "Instructions    "...)
+	data = append(data, 0x03, 0x00, 0x00, 0x00, 0xe9, 0x00, 0xff)
+	data = append(data, 0x30, 0x00) // empty text description
+	tape := readTape(t, data)
+
+	archive, ok := tape.ArchiveInfo().(*blocks.ArchiveInfo)
+	if !ok || len(archive.Strings) != 1 {
+		t.Fatalf("got archive info %v, want 1 string", tape.ArchiveInfo())
+	}
+	if text := archive.Strings[0]; text.Characters != "Tït" || text.Length != 3 {
+		t.Errorf("got archive text %q of length %d, want %q of length 3", text.Characters, text.Length, "Tït")
+	}
+
+	all := tape.Blocks()
+	if len(all) != 7 {
+		t.Fatalf("got %d blocks, want 7", len(all))
+	}
+	if b := all[0].(*blocks.TextDescription); b.Description != "Café!" || b.Length != 5 {
+		t.Errorf("got description %q of length %d, want %q of length 5", b.Description, b.Length, "Café!")
+	}
+	if b := all[1].(*blocks.GroupStart); b.GroupName != "Gàp" || b.Length != 3 {
+		t.Errorf("got group name %q of length %d, want %q of length 3", b.GroupName, b.Length, "Gàp")
+	}
+	if b := all[3].(*blocks.Message); b.Message != "©X" || b.Length != 2 || b.DisplayTime != 5 {
+		t.Errorf("got message %q of length %d for %ds, want %q of length 2 for 5s", b.Message, b.Length, b.DisplayTime, "©X")
+	}
+	if b := all[4].(*blocks.Select); len(b.Selections) != 1 || b.Selections[0].Description != "Sü" || b.Selections[0].Length != 2 {
+		t.Errorf("got selections %+v, want %q of length 2", b.Selections, "Sü")
+	}
+	// the custom info is not decoded, as it may be binary data
+	if b := all[5].(*blocks.CustomInfo); !bytes.Equal(b.Info, []byte{0xe9, 0x00, 0xff}) || b.Length != 3 {
+		t.Errorf("got custom info % X of length %d, want E9 00 FF of length 3", b.Info, b.Length)
+	}
+	if b := all[6].(*blocks.TextDescription); b.Description != "" || b.Length != 0 {
+		t.Errorf("got description %q of length %d, want an empty one", b.Description, b.Length)
+	}
+}
+
+func TestBasicProgramsInterleaved(t *testing.T) {
+	// #1 program header "first", #2 CODE header, #3 the code, #4 the program
+	// of "first", #5 program header "lost" without its data, #6 program header
+	// "second", #7 its program, #8 headerless data
+	tape := readTape(t, fixture(t, "interleaved.tzx"))
+
+	var data []tap.Block
+	for _, block := range tape.Blocks() {
+		data = append(data, block.BlockData())
+	}
+	programs := tap.BasicPrograms(data)
+
+	want := []struct {
+		name  string
+		block int
+		line  uint16
+	}{
+		{"first     ", 4, 10},
+		{"second    ", 7, 20},
+	}
+	if len(programs) != len(want) {
+		t.Fatalf("got %d programs, want %d", len(programs), len(want))
+	}
+	for i, p := range programs {
+		if p.Header.Filename() != want[i].name || tape.BlockNumber(p.Index) != want[i].block {
+			t.Errorf("got program %q in block #%d, want %q in block #%d",
+				p.Header.Filename(), tape.BlockNumber(p.Index), want[i].name, want[i].block)
+		}
+		if p.Header.AutoStartLine != want[i].line {
+			t.Errorf("got autostart line %d for %q, want %d", p.Header.AutoStartLine, p.Header.Filename(), want[i].line)
+		}
+		if len(p.Data.BlockData()) != int(p.Header.DataLength) {
+			t.Errorf("got %d bytes of data for %q, want %d", len(p.Data.BlockData()), p.Header.Filename(), p.Header.DataLength)
+		}
+	}
+}
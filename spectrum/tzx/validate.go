@@ -0,0 +1,119 @@
+package tzx
+
+import (
+	"fmt"
+
+	"retroio/spectrum/tzx/blocks"
+)
+
+// blockSpan is the position of a block in the file, from the block ID up to
+// the byte following the last byte read for the block.
+type blockSpan struct {
+	block  Block
+	offset int64
+	end    int64
+}
+
+// BlockLengthError is a block where the length given by its length fields
+// does not match the bytes read for the block, or overruns the end of the file.
+type BlockLengthError struct {
+	Block    int    // Block number, starting from 1
+	Name     string // Block type name
+	Offset   int64  // Offset of the block in the file
+	Declared int64  // Block length given by the length fields, including the block ID
+	Consumed int64  // Bytes read for the block, including the block ID
+	FileSize int64  // Total file size, when the block overruns the end of the file
+}
+
+func (e BlockLengthError) Error() string {
+	if e.FileSize > 0 {
+		return fmt.Sprintf(
+			"block #%d %s at offset %d: declared length %d overruns the file size of %d bytes",
+			e.Block, e.Name, e.Offset, e.Declared, e.FileSize,
+		)
+	}
+
+	problem := "overruns"
+	if e.Consumed < e.Declared {
+		problem = "underruns"
+	}
+	return fmt.Sprintf(
+		"block #%d %s at offset %d: %s its declared length, declared %d bytes, consumed %d bytes",
+		e.Block, e.Name, e.Offset, problem, e.Declared, e.Consumed,
+	)
+}
+
+// Validate cross-checks the length fields of each block read against the
+// bytes actually read for the block, and against the total file size, so
+// corrupted tapes that still parse can be detected. A BlockLengthError is
+// returned for each block whose length fields overrun or underrun the data.
+//
+// Blocks without length fields, e.g. pure tones, have a fixed size and are
+// not checked. The file size is only checked when it is known.
+func (t TZX) Validate() []error {
+	var problems []error
+	size := t.reader.Size()
+
+	for i, span := range t.spans {
+		declared, ok := declaredLength(span.block)
+		if !ok {
+			continue
+		}
+
+		e := BlockLengthError{
+			Block:    i + 1,
+			Name:     span.block.Name(),
+			Offset:   span.offset,
+			Declared: declared,
+			Consumed: span.end - span.offset,
+		}
+
+		if size >= 0 && span.offset+declared > size {
+			e.FileSize = size
+			problems = append(problems, e)
+		} else if e.Declared != e.Consumed {
+			problems = append(problems, e)
+		}
+	}
+
+	return problems
+}
+
+// declaredLength returns the total length of a block, including the block ID,
+// as given by the length fields of the block, or false for fixed size blocks.
+func declaredLength(block Block) (int64, bool) {
+	switch b := block.(type) {
+	case *blocks.StandardSpeedData:
+		return 5 + int64(b.DataLength()), true
+	case *blocks.TurboSpeedData:
+		return 0x13 + int64(b.Length), true
+	case *blocks.PureData:
+		return 0x0B + int64(b.Length), true
+	case *blocks.DirectRecording:
+		return 0x09 + int64(b.Length), true
+	case *blocks.CswRecording:
+		return 5 + int64(b.Length), true
+	case *blocks.GeneralizedData:
+		return 5 + int64(b.Length), true
+	case *blocks.GroupStart:
+		return 2 + int64(b.Length), true
+	case *blocks.TextDescription:
+		return 2 + int64(b.Length), true
+	case *blocks.Message:
+		return 3 + int64(b.Length), true
+	case *blocks.ArchiveInfo:
+		return 3 + int64(b.Length), true
+	case *blocks.Select:
+		return 3 + int64(b.Length), true
+	case *blocks.CustomInfo:
+		return 21 + int64(b.Length), true
+	case *blocks.StopTapeWhen48kMode:
+		return 5 + int64(b.Length), true
+	case *blocks.SetSignalLevel:
+		return 5 + int64(b.Length), true
+	case *blocks.KansasCityStandard:
+		return 5 + int64(b.Length), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,227 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/storage"
+)
+
+// ErrNoBlocks is returned when no tape blocks are found in the recording.
+var ErrNoBlocks = errors.New("wav: no tape blocks found")
+
+// ChecksumError is returned along with the decoded tape when the checksum of
+// one or more blocks is incorrect, which usually indicates a poor recording.
+type ChecksumError struct {
+	Blocks []int // Block numbers, starting from 1
+}
+
+func (e ChecksumError) Error() string {
+	var blocks []string
+	for _, b := range e.Blocks {
+		blocks = append(blocks, fmt.Sprintf("#%02d", b))
+	}
+	return fmt.Sprintf("checksum error in block %s", strings.Join(blocks, ", "))
+}
+
+// Decoder reconstructs the standard speed blocks from a cassette recording.
+// The edges of the signal are found at the zero-crossings, and the length of
+// the pulses between the edges is matched against the expected timings.
+type Decoder struct {
+	Threshold       int16   // Minimum sample amplitude for crossing zero, ignoring any noise around the zero level
+	Tolerance       float64 // Allowed deviation from the expected pulse lengths, e.g. 0.25 is 25%
+	PilotPulse      uint16  // Length of PILOT pulse {2168}
+	SyncFirstPulse  uint16  // Length of SYNC first pulse {667}
+	SyncSecondPulse uint16  // Length of SYNC second pulse {735}
+	ZeroBitPulse    uint16  // Length of ZERO bit pulse {855}
+	OneBitPulse     uint16  // Length of ONE bit pulse {1710}
+	PilotTone       int     // Minimum length of the PILOT tone (number of pulses) for detecting a block
+}
+
+// NewDecoder returns a decoder for the timings used by the ZX Spectrum ROM.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		Threshold:       1024,
+		Tolerance:       0.25,
+		PilotPulse:      2168,
+		SyncFirstPulse:  667,
+		SyncSecondPulse: 735,
+		ZeroBitPulse:    855,
+		OneBitPulse:     1710,
+		PilotTone:       256,
+	}
+}
+
+// DecodeWAV reads a mono PCM WAV recording of a cassette, returning the
+// standard speed blocks as a TAP, using the ZX Spectrum ROM timings.
+func DecodeWAV(r io.Reader) (*tap.TAP, error) {
+	return NewDecoder().Decode(r)
+}
+
+// Decode reads a mono PCM WAV recording of a cassette, returning the standard
+// speed blocks as a TAP. When any block checksums fail, a ChecksumError is
+// returned along with the tape.
+func (d *Decoder) Decode(r io.Reader) (*tap.TAP, error) {
+	data, decodeErr := d.DecodeBytes(r)
+	if data == nil {
+		return nil, decodeErr
+	}
+
+	// blocks with a bad checksum may not be valid headers, so are read as data
+	reader := storage.NewReader(bytes.NewReader(data))
+	reader.SetMode(storage.Lenient)
+
+	t := tap.New(reader)
+	if err := t.Read(); err != nil {
+		return nil, errors.Wrap(err, "unable to read decoded tape")
+	}
+
+	return t, decodeErr
+}
+
+// DecodeBytes reads a mono PCM WAV recording of a cassette, returning the
+// standard speed blocks as the bytes of a TAP file. When any block checksums
+// fail, a ChecksumError is returned along with the data.
+func (d *Decoder) DecodeBytes(r io.Reader) ([]byte, error) {
+	rate, samples, err := readSamples(r)
+	if err != nil {
+		return nil, err
+	}
+
+	pulses := d.pulses(rate, samples)
+
+	var data []byte
+	var failed []int
+	blocks := 0
+
+	for i := 0; i < len(pulses); {
+		block, next := d.readBlock(pulses, i)
+		i = next
+		if len(block) == 0 {
+			continue
+		}
+
+		blocks++
+		if checksum(block) != 0 {
+			failed = append(failed, blocks)
+		}
+
+		length := make([]byte, 2)
+		binary.LittleEndian.PutUint16(length, uint16(len(block)))
+		data = append(data, length...)
+		data = append(data, block...)
+	}
+
+	if blocks == 0 {
+		return nil, ErrNoBlocks
+	}
+	if len(failed) > 0 {
+		return data, ChecksumError{Blocks: failed}
+	}
+	return data, nil
+}
+
+// pulses returns the length of each pulse between the edges of the signal,
+// converted to T-states.
+func (d *Decoder) pulses(rate int, samples []int16) []float64 {
+	var pulses []float64
+
+	high := false
+	edge := -1
+
+	for i, s := range samples {
+		var level bool
+		switch {
+		case s > d.Threshold:
+			level = true
+		case s < -d.Threshold:
+			level = false
+		default:
+			continue // not crossed zero yet
+		}
+
+		if edge < 0 {
+			high, edge = level, i
+		} else if level != high {
+			pulses = append(pulses, float64(i-edge)*ClockRate/float64(rate))
+			high, edge = level, i
+		}
+	}
+
+	return pulses
+}
+
+// readBlock reads a single block, starting at the pulse i, returning the block
+// bytes and the pulse following the block. No bytes are returned when there
+// is no pilot tone and sync pulses at the start.
+func (d *Decoder) readBlock(pulses []float64, i int) ([]byte, int) {
+	pilot := 0
+	for i < len(pulses) && d.matches(pulses[i], d.PilotPulse) {
+		pilot++
+		i++
+	}
+	if pilot < d.PilotTone {
+		if pilot == 0 {
+			i++
+		}
+		return nil, i
+	}
+
+	// The sync pulses are too short to be measured accurately at the usual
+	// sample rates, so only their combined length is matched.
+	if i+1 >= len(pulses) || !d.matches(pulses[i]+pulses[i+1], d.SyncFirstPulse+d.SyncSecondPulse) {
+		return nil, i
+	}
+	i += 2
+
+	var block []byte
+	var b byte
+	bits := 0
+
+	for ; i < len(pulses); i += 2 {
+		// the last pulse of the block only ends with the next block, or not
+		// at all at the end of the recording, so the bit is given by its first pulse.
+		bit := 2 * pulses[i]
+		if i+1 < len(pulses) && pulses[i+1] <= float64(d.OneBitPulse)*(1+d.Tolerance) {
+			bit = pulses[i] + pulses[i+1]
+		}
+
+		switch {
+		case d.matches(bit, 2*d.ZeroBitPulse):
+			b <<= 1
+		case d.matches(bit, 2*d.OneBitPulse):
+			b = b<<1 | 1
+		default:
+			return block, i // end of the block
+		}
+
+		if bits++; bits == 8 {
+			block = append(block, b)
+			b, bits = 0, 0
+		}
+	}
+
+	return block, i
+}
+
+// matches returns true when the pulse length is within the tolerance of the expected length.
+func (d *Decoder) matches(pulse float64, expected uint16) bool {
+	delta := float64(expected) * d.Tolerance
+	return pulse >= float64(expected)-delta && pulse <= float64(expected)+delta
+}
+
+// checksum returns the XOR of all the block bytes, which is zero for a valid
+// block, as the last byte is the checksum of the flag and data bytes.
+func checksum(block []byte) uint8 {
+	var sum uint8
+	for _, b := range block {
+		sum ^= b
+	}
+	return sum
+}
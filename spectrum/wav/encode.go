@@ -0,0 +1,254 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"retroio/spectrum/tap"
+	"retroio/spectrum/tzx"
+	"retroio/spectrum/tzx/blocks"
+	"retroio/storage"
+)
+
+// Sample values for the low and high signal levels, as 8-bit unsigned samples.
+const (
+	lowLevel  = 0x20
+	highLevel = 0xe0
+)
+
+// Encoder plays the tape blocks, writing them as a mono 8-bit PCM WAV file
+// that can be loaded by a real ZX Spectrum.
+//
+// The pauses between blocks can be changed for loaders that fail on some
+// machines, with all pauses replaced with PauseMs, or scaled by PauseScale.
+// Pauses of zero length are never changed, as they mean "no pause".
+type Encoder struct {
+	SampleRate int     // Samples per second {44100}
+	PauseMs    int     // Length of all pauses (ms), or -1 to use the pauses from the tape
+	PauseScale float64 // Multiplier for the length of all pauses {1}
+	LeadIn     int     // Silence before the first block (ms)
+
+	samples []byte
+	level   bool    // current pulse level, true is high
+	tstates float64 // total length of the played pulses
+}
+
+// NewEncoder returns an encoder using the pauses as given on the tape.
+func NewEncoder() *Encoder {
+	return &Encoder{
+		SampleRate: 44100,
+		PauseMs:    -1,
+		PauseScale: 1,
+	}
+}
+
+// EncodeTAP writes the TAP blocks as a WAV file, using the ZX Spectrum ROM
+// timings, with the usual 1 second pause after each block.
+func (e *Encoder) EncodeTAP(w io.Writer, t *tap.TAP) error {
+	e.reset()
+
+	for _, block := range t.Blocks {
+		data := tap.BlockBytes(block.TapeData)
+		e.playData(romTimings(data), data, 8)
+		e.playPause(1000)
+	}
+
+	return e.write(w)
+}
+
+// EncodeTZX writes the TZX blocks as a WAV file. Only the blocks using pulse
+// timings are supported, and an error is returned for sampled blocks, such as
+// direct recordings. Set Signal Level blocks set the level of the next pulse.
+// Blocks without tape data, and control flow blocks, such as loops, are
+// ignored.
+func (e *Encoder) EncodeTZX(w io.Writer, t *tzx.TZX) error {
+	e.reset()
+
+	for _, block := range t.Blocks() {
+		switch b := block.(type) {
+		case *blocks.StandardSpeedData:
+			data := tap.BlockBytes(b.DataBlock)
+			e.playData(romTimings(data), data, 8)
+			e.playPause(b.Pause)
+		case *blocks.TurboSpeedData:
+			timings := timings{
+				pilotPulse: b.PilotPulse,
+				pilotTone:  b.PilotTone,
+				syncPulses: []uint16{b.SyncFirstPulse, b.SyncSecondPulse},
+				zeroPulse:  b.ZeroBitPulse,
+				onePulse:   b.OneBitPulse,
+			}
+			e.playData(timings, b.DataBlock, b.UsedBits)
+			e.playPause(b.Pause)
+		case *blocks.PureTone:
+			for i := 0; i < int(b.PulseCount); i++ {
+				e.playPulse(b.Length)
+			}
+		case *blocks.SequenceOfPulses:
+			for _, pulse := range b.Lengths {
+				e.playPulse(pulse)
+			}
+		case *blocks.PureData:
+			timings := timings{zeroPulse: b.ZeroBitPulse, onePulse: b.OneBitPulse}
+			e.playData(timings, b.DataBlock, b.UsedBits)
+			e.playPause(b.Pause)
+		case *blocks.PauseTapeCommand:
+			e.playPause(b.Pause)
+		case *blocks.SetSignalLevel:
+			e.level = b.SignalLevel == 1
+		case *blocks.GeneralizedData, *blocks.DirectRecording, *blocks.CswRecording:
+			return errors.Errorf("unable to play %s blocks to WAV", block.Name())
+		}
+	}
+
+	return e.write(w)
+}
+
+// timings of the pulses for a data block.
+type timings struct {
+	pilotPulse uint16
+	pilotTone  uint16 // number of pilot pulses
+	syncPulses []uint16
+	zeroPulse  uint16
+	onePulse   uint16
+}
+
+// romTimings returns the ZX Spectrum ROM timings, where the pilot tone is
+// longer for headers (flag < 128) than for data blocks.
+func romTimings(data []byte) timings {
+	t := timings{
+		pilotPulse: 2168,
+		pilotTone:  3223,
+		syncPulses: []uint16{667, 735},
+		zeroPulse:  855,
+		onePulse:   1710,
+	}
+	if len(data) > 0 && data[0] < 128 {
+		t.pilotTone = 8063
+	}
+	return t
+}
+
+// playData plays the pilot tone, sync pulses, and the data bits, MSb first,
+// with only the used bits of the last byte being played.
+func (e *Encoder) playData(t timings, data []byte, usedBits uint8) {
+	for i := 0; i < int(t.pilotTone); i++ {
+		e.playPulse(t.pilotPulse)
+	}
+	for _, pulse := range t.syncPulses {
+		e.playPulse(pulse)
+	}
+
+	bits := storage.NewBitReader(data)
+	bits.SetUsedBits(usedBits)
+	for {
+		bit, err := bits.ReadBit()
+		if err != nil {
+			break
+		}
+		pulse := t.zeroPulse
+		if bit {
+			pulse = t.onePulse
+		}
+		e.playPulse(pulse)
+		e.playPulse(pulse)
+	}
+}
+
+// playPulse plays a pulse at the current level, then changes the level so
+// the next pulse produces an edge.
+func (e *Encoder) playPulse(tstates uint16) {
+	e.play(float64(tstates), e.level)
+	e.level = !e.level
+}
+
+// playPause plays the pause after a block, with any override or scaling
+// applied. As given in the TZX specification, the last edge is finished with
+// 1 ms at the opposite level, after which the level is low. Zero length pauses
+// are ignored, and the level is not changed.
+func (e *Encoder) playPause(ms uint16) {
+	length := e.pauseLength(ms)
+	if length <= 0 {
+		return
+	}
+
+	edge := ClockRate / 1000.0
+	if length < 1 {
+		edge *= length
+	}
+	e.play(edge, e.level)
+	e.play((length-1)*ClockRate/1000, false)
+	e.level = false
+}
+
+// pauseLength returns the pause length in ms, after any override or scaling.
+func (e *Encoder) pauseLength(ms uint16) float64 {
+	if ms == 0 {
+		return 0
+	}
+
+	length := float64(ms)
+	if e.PauseMs >= 0 {
+		length = float64(e.PauseMs)
+	}
+	return length * e.PauseScale
+}
+
+// play adds the samples for the given length at the level. The total length
+// is tracked so rounding errors do not accumulate over the tape.
+func (e *Encoder) play(tstates float64, high bool) {
+	if tstates <= 0 {
+		return
+	}
+	e.tstates += tstates
+
+	sample := byte(lowLevel)
+	if high {
+		sample = highLevel
+	}
+
+	end := int(e.tstates * float64(e.SampleRate) / ClockRate)
+	for len(e.samples) < end {
+		e.samples = append(e.samples, sample)
+	}
+}
+
+// reset clears the samples, adding any lead-in silence.
+func (e *Encoder) reset() {
+	e.samples = nil
+	e.level = false
+	e.tstates = 0
+
+	e.play(float64(e.LeadIn)*ClockRate/1000, false)
+}
+
+// write writes the WAV header and samples.
+func (e *Encoder) write(w io.Writer) error {
+	buf := &bytes.Buffer{}
+
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+len(e.samples)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(buf, binary.LittleEndian, format{
+		AudioFormat:   1,
+		Channels:      1,
+		SampleRate:    uint32(e.SampleRate),
+		ByteRate:      uint32(e.SampleRate),
+		BlockAlign:    1,
+		BitsPerSample: 8,
+	})
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(e.samples)))
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "unable to write WAV header")
+	}
+	if _, err := w.Write(e.samples); err != nil {
+		return errors.Wrap(err, "unable to write WAV data")
+	}
+	return nil
+}
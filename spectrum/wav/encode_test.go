@@ -0,0 +1,61 @@
+package wav
+
+import (
+	"bytes"
+	"testing"
+
+	"retroio/spectrum/tzx"
+	"retroio/storage"
+)
+
+// headerLength is the length of the WAV header written by the encoder.
+const headerLength = 44
+
+func TestEncodeSetSignalLevel(t *testing.T) {
+	tone := []byte{0x12, 0x78, 0x08, 0x01, 0x00} // 1 pulse of 2168 T-states
+	high := []byte{0x2B, 0x01, 0x00, 0x00, 0x00, 0x01}
+	low := []byte{0x2B, 0x01, 0x00, 0x00, 0x00, 0x00}
+
+	// samples at the start of the second pulse
+	second := int(2168 * 44100 / ClockRate)
+
+	tests := []struct {
+		name   string
+		blocks [][]byte
+		sample int
+		want   byte
+	}{
+		{"low at the start", [][]byte{tone}, 0, lowLevel},
+		{"set high", [][]byte{high, tone}, 0, highLevel},
+		{"set low", [][]byte{low, tone}, 0, lowLevel},
+		{"toggled by the previous pulse", [][]byte{tone, tone}, second, highLevel},
+		{"set low after a pulse", [][]byte{tone, low, tone}, second, lowLevel},
+		{"set high after a pulse", [][]byte{high, tone, high, tone}, second, highLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("ZXTape!\x1a\x01\x14")
+			for _, block := range tt.blocks {
+				data = append(data, block...)
+			}
+			tape := tzx.New(storage.NewReader(bytes.NewReader(data)))
+			if err := tape.Read(); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := NewEncoder().EncodeTZX(&buf, tape); err != nil {
+				t.Fatal(err)
+			}
+
+			samples := buf.Bytes()[headerLength:]
+			if len(samples) <= tt.sample {
+				t.Fatalf("got %d samples, want more than %d", len(samples), tt.sample)
+			}
+			if samples[tt.sample] != tt.want {
+				t.Errorf("got sample %d of %02X, want %02X", tt.sample, samples[tt.sample], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package wav implements conversion between ZX Spectrum tapes and WAV audio
+// files, as recorded from, or played to, a real cassette deck.
+//
+// Only uncompressed PCM WAV files are supported, with 8 or 16 bit samples.
+// Tape timings are given in Z80 T-states, at the 3.5 MHz clock of the ZX
+// Spectrum, and are converted to samples using the sample rate of the file.
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// ClockRate is the Z80 clock speed of the ZX Spectrum, in T-states per second.
+const ClockRate = 3500000
+
+// format is the `fmt ` chunk of a WAV file.
+type format struct {
+	AudioFormat   uint16 // 1 = PCM
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// readSamples reads a mono PCM WAV file, returning the sample rate and the
+// samples, converted to signed 16-bit values.
+func readSamples(r io.Reader) (int, []int16, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return 0, nil, errors.Wrap(err, "unable to read WAV header")
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, nil, errors.New("not a RIFF/WAVE file")
+	}
+
+	var f *format
+
+	for {
+		var id [4]byte
+		var size uint32
+		if _, err := io.ReadFull(r, id[:]); err != nil {
+			return 0, nil, errors.Wrap(err, "WAV data chunk not found")
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return 0, nil, errors.Wrapf(err, "unable to read WAV '%s' chunk", id)
+		}
+
+		switch string(id[:]) {
+		case "fmt ":
+			f = &format{}
+			if err := binary.Read(r, binary.LittleEndian, f); err != nil {
+				return 0, nil, errors.Wrap(err, "unable to read WAV format")
+			}
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)+int64(size&1)-16); err != nil {
+				return 0, nil, errors.Wrap(err, "unable to read WAV format")
+			}
+		case "data":
+			if f == nil {
+				return 0, nil, errors.New("WAV data chunk found before the format chunk")
+			}
+			if f.AudioFormat != 1 {
+				return 0, nil, errors.Errorf("unsupported WAV audio format %d, only PCM is supported", f.AudioFormat)
+			}
+			if f.Channels != 1 {
+				return 0, nil, errors.Errorf("unsupported WAV with %d channels, only mono is supported", f.Channels)
+			}
+
+			// the data is read up to the size, so a corrupt size, or the
+			// 0xFFFFFFFF size of a streamed recording, is not allocated in full
+			data, err := ioutil.ReadAll(io.LimitReader(r, int64(size)))
+			if err != nil {
+				return 0, nil, errors.Wrap(err, "unable to read WAV data")
+			}
+			samples, err := convertSamples(data, f.BitsPerSample)
+			return int(f.SampleRate), samples, err
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size)+int64(size&1)); err != nil {
+				return 0, nil, errors.Wrapf(err, "unable to skip WAV '%s' chunk", id)
+			}
+		}
+	}
+}
+
+// convertSamples converts unsigned 8-bit, or signed 16-bit, samples to signed 16-bit values.
+func convertSamples(data []byte, bits uint16) ([]int16, error) {
+	switch bits {
+	case 8:
+		samples := make([]int16, len(data))
+		for i, b := range data {
+			samples[i] = (int16(b) - 128) << 8
+		}
+		return samples, nil
+	case 16:
+		samples := make([]int16, len(data)/2)
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+		}
+		return samples, nil
+	default:
+		return nil, errors.Errorf("unsupported WAV sample size of %d bits", bits)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// BitReader reads the individual bits of a byte slice, as used for the data
+// of the tape pulse and sample blocks. Bits are read MSb first, unless set to
+// LSb first with SetLSbFirst.
+type BitReader struct {
+	data     []byte
+	size     int // number of bits available
+	position int // number of bits read
+	lsbFirst bool
+}
+
+// NewBitReader returns a reader for all the bits of the data.
+func NewBitReader(data []byte) *BitReader {
+	return &BitReader{data: data, size: len(data) * 8}
+}
+
+// SetUsedBits limits the bits read from the last byte, as given by the "used
+// bits in last byte" field of the data blocks. With LSb first order, the used
+// bits are the lowest bits. A value of 0 or 8 uses the whole byte.
+func (r *BitReader) SetUsedBits(used uint8) {
+	r.size = len(r.data) * 8
+	if len(r.data) > 0 && used > 0 && used < 8 {
+		r.size -= 8 - int(used)
+	}
+}
+
+// SetLSbFirst sets the order the bits of each byte are read in.
+func (r *BitReader) SetLSbFirst(lsbFirst bool) {
+	r.lsbFirst = lsbFirst
+}
+
+// Remaining returns the number of bits left to read.
+func (r BitReader) Remaining() int {
+	return r.size - r.position
+}
+
+// ReadBit reads the next bit, returning io.EOF when no bits remain.
+func (r *BitReader) ReadBit() (bool, error) {
+	if r.position >= r.size {
+		return false, io.EOF
+	}
+
+	b := r.data[r.position/8]
+	shift := uint(7 - r.position%8)
+	if r.lsbFirst {
+		shift = uint(r.position % 8)
+	}
+	r.position++
+
+	return b>>shift&1 == 1, nil
+}
+
+// ReadBits reads the next n bits, up to 32, as a number with the first bit
+// read as the most significant. When fewer than n bits remain, the remaining
+// bits are read, and io.ErrUnexpectedEOF returned.
+func (r *BitReader) ReadBits(n int) (uint32, error) {
+	if n < 0 || n > 32 {
+		return 0, errors.Errorf("can not read %d bits, expected 0 to 32", n)
+	}
+	if n > 0 && r.Remaining() == 0 {
+		return 0, io.EOF
+	}
+
+	var value uint32
+	for i := 0; i < n; i++ {
+		bit, err := r.ReadBit()
+		if err != nil {
+			return value, io.ErrUnexpectedEOF
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+
+	return value, nil
+}
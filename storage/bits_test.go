@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"io"
+	"testing"
+)
+
+func TestReadBits(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		lsbFirst bool
+		used     uint8
+		reads    []int
+		want     []uint32
+	}{
+		{"whole bytes", []byte{0xA5, 0x0F}, false, 0, []int{8, 8}, []uint32{0xA5, 0x0F}},
+		{"across byte boundaries", []byte{0xA5, 0x0F, 0xF0}, false, 0, []int{4, 8, 12}, []uint32{0xA, 0x50, 0xFF0}},
+		{"single bits", []byte{0xA0}, false, 4, []int{1, 1, 1, 1}, []uint32{1, 0, 1, 0}},
+		{"32 bits across 5 bytes", []byte{0xFF, 0x00, 0xFF, 0x00, 0xFF}, false, 0, []int{3, 32, 5}, []uint32{7, 0xF807F807, 0x1F}},
+		{"zero bits", []byte{0x80}, false, 1, []int{0, 1}, []uint32{0, 1}},
+		{"LSb first", []byte{0xA5}, true, 0, []int{4, 4}, []uint32{0xA, 0x5}},
+		{"LSb first across byte boundaries", []byte{0x0F, 0xF0}, true, 0, []int{4, 8, 4}, []uint32{0xF, 0x00, 0xF}},
+		{"used bits", []byte{0xFF, 0xC0}, false, 2, []int{8, 2}, []uint32{0xFF, 3}},
+		{"used bits across byte boundary", []byte{0x0F, 0xA0}, false, 3, []int{4, 7}, []uint32{0, 0x7D}},
+		{"used bits LSb first", []byte{0x05}, true, 3, []int{3}, []uint32{5}},
+		{"used bits of 8", []byte{0x12, 0x34}, false, 8, []int{16}, []uint32{0x1234}},
+		{"used bits of 0", []byte{0x12, 0x34}, false, 0, []int{16}, []uint32{0x1234}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewBitReader(tt.data)
+			r.SetLSbFirst(tt.lsbFirst)
+			r.SetUsedBits(tt.used)
+
+			for i, n := range tt.reads {
+				got, err := r.ReadBits(n)
+				if err != nil {
+					t.Fatalf("read #%d of %d bits: %v", i+1, n, err)
+				}
+				if got != tt.want[i] {
+					t.Errorf("read #%d of %d bits: got %#x, want %#x", i+1, n, got, tt.want[i])
+				}
+			}
+
+			if r.Remaining() != 0 {
+				t.Errorf("got %d bits remaining, want 0", r.Remaining())
+			}
+			if _, err := r.ReadBit(); err != io.EOF {
+				t.Errorf("got error %v reading past the end, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReadBitsPartial(t *testing.T) {
+	// only the top 3 bits of the last byte are used
+	r := NewBitReader([]byte{0xFF, 0xA0})
+	r.SetUsedBits(3)
+
+	if r.Remaining() != 11 {
+		t.Fatalf("got %d bits, want 11", r.Remaining())
+	}
+	if _, err := r.ReadBits(8); err != nil {
+		t.Fatal(err)
+	}
+
+	// the 3 remaining bits are read before the error
+	got, err := r.ReadBits(4)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, want io.ErrUnexpectedEOF", err)
+	}
+	if got != 5 {
+		t.Errorf("got %#x from the partial read, want 0x5", got)
+	}
+
+	if _, err := r.ReadBits(1); err != io.EOF {
+		t.Errorf("got error %v once all the bits are read, want io.EOF", err)
+	}
+}
+
+func TestReadBitsInvalid(t *testing.T) {
+	r := NewBitReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	for _, n := range []int{-1, 33} {
+		if _, err := r.ReadBits(n); err == nil {
+			t.Errorf("expected an error reading %d bits", n)
+		}
+	}
+	if r.Remaining() != 40 {
+		t.Errorf("got %d bits remaining, want all 40 unread", r.Remaining())
+	}
+}
+
+func TestSetUsedBitsEmpty(t *testing.T) {
+	r := NewBitReader(nil)
+	r.SetUsedBits(3)
+	if r.Remaining() != 0 {
+		t.Errorf("got %d bits, want 0", r.Remaining())
+	}
+	if _, err := r.ReadBits(1); err != io.EOF {
+		t.Errorf("got error %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+// ChecksumReader wraps a Reader, calculating checksums over all the bytes read
+// through it. This allows a block to be verified as it is read, by comparing
+// the accumulated value with the checksum stored after the block data.
+//
+// Both checksum types used by the supported formats are maintained:
+//   - XOR: all bytes XORed together (ZX Spectrum tape blocks).
+//   - Sum: a 16-bit sum of all bytes (AMSDOS file headers).
+type ChecksumReader struct {
+	reader *Reader
+	xor    uint8
+	sum    uint16
+}
+
+// NewChecksumReader returns a ChecksumReader reading from the given Reader.
+func NewChecksumReader(r *Reader) *ChecksumReader {
+	return &ChecksumReader{reader: r}
+}
+
+// Read from the underlying reader, and follows the reader interface.
+// All bytes read are added to the checksums.
+func (c *ChecksumReader) Read(b []byte) (int, error) {
+	n, err := c.reader.Read(b)
+	c.update(b[:n])
+	return n, err
+}
+
+// ReadByte reads a single byte, adding it to the checksums.
+func (c *ChecksumReader) ReadByte() (byte, error) {
+	b := make([]byte, 1)
+	if _, err := c.Read(b); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// Reset clears the checksums, ready for reading the next block.
+func (c *ChecksumReader) Reset() {
+	c.xor = 0
+	c.sum = 0
+}
+
+// Sum returns the 16-bit sum of all bytes read since the last Reset.
+func (c *ChecksumReader) Sum() uint16 {
+	return c.sum
+}
+
+// XOR returns all bytes read since the last Reset, XORed together.
+func (c *ChecksumReader) XOR() uint8 {
+	return c.xor
+}
+
+func (c *ChecksumReader) update(b []byte) {
+	for _, v := range b {
+		c.xor ^= v
+		c.sum += uint16(v)
+	}
+}
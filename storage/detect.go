@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownFormat is returned by DetectFormat when the media format can not
+// be identified from the data.
+var ErrUnknownFormat = errors.New("storage: unknown media format")
+
+// detectLength is the number of bytes needed to match all the signatures.
+const detectLength = 34
+
+// signatures of the media formats, which are found at the start of the file.
+// The C64 TAP and CRT signatures must be checked before the shorter T64 one.
+var signatures = []struct {
+	format    string
+	signature []byte
+}{
+	{"tzx", []byte("ZXTape!\x1a")},
+	{"pzx", []byte("PZXT")},
+	{"dsk", []byte("MV - CPC")},
+	{"dsk", []byte("EXTENDED")},
+	{"c64tap", []byte("C64-TAPE-RAW")},
+	{"crt", []byte("C64 CARTRIDGE")},
+	{"uef", []byte("UEF File!\x00")},
+	{"t64", []byte("C64")},
+}
+
+// DetectFormat identifies the media format from the first bytes of the data,
+// returning the format name, which matches the usual file extension:
+// `tzx`, `pzx`, `dsk`, `t64`, `tap` (ZX Spectrum), `c64tap` (C64 raw tape),
+// `crt` (C64 cartridge), and `uef` (Acorn tape).
+//
+// When r is a *Reader the bytes are only peeked, so it can still be used for
+// reading the media, otherwise those bytes are consumed.
+//
+// As the Amstrad CDT format is identical to TZX, these are reported as `tzx`.
+func DetectFormat(r io.Reader) (string, error) {
+	data, err := detectHeader(r)
+	if err != nil {
+		return "", err
+	}
+	return detectSignature(data)
+}
+
+// detectHeader returns the first bytes of the data, needed to match all the
+// signatures, which are only peeked when r is a *Reader.
+func detectHeader(r io.Reader) ([]byte, error) {
+	if reader, ok := r.(*Reader); ok {
+		b, err := reader.Peek(detectLength)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	b := make([]byte, detectLength)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return b[:n], nil
+}
+
+// detectSignature returns the format whose signature the data starts with.
+func detectSignature(data []byte) (string, error) {
+	for _, s := range signatures {
+		if bytes.HasPrefix(data, s.signature) {
+			return s.format, nil
+		}
+	}
+
+	// ZX Spectrum TAP files have no signature, but usually start with a
+	// 19 byte header block: a length of 19, a zero flag and a header type.
+	if len(data) >= 4 && data[0] == 19 && data[1] == 0 && data[2] == 0 && data[3] <= 3 {
+		return "tap", nil
+	}
+
+	return "", ErrUnknownFormat
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// DataHash returns the CRC32 checksum of the data, as 8 hexadecimal digits.
+// CRC32 is used by preservation databases, such as TOSEC, to identify dumps.
+func DataHash(data ...[]byte) string {
+	hash := crc32.NewIEEE()
+	for _, d := range data {
+		_, _ = hash.Write(d)
+	}
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Machines identified by Identify.
+const (
+	MachineSpectrum  = "ZX Spectrum"
+	MachineAmstrad   = "Amstrad"
+	MachineCommodore = "Commodore 64"
+	MachineAcorn     = "Acorn"
+)
+
+// Info is the media format identified from the header bytes of a file.
+type Info struct {
+	Machine string // Machine the media is for, e.g. MachineSpectrum
+	Format  string // Format name, as returned by DetectFormat
+	Version string // Format version, or empty when the format has none
+}
+
+func (i Info) String() string {
+	if i.Version == "" {
+		return fmt.Sprintf("%s (%s)", i.Format, i.Machine)
+	}
+	return fmt.Sprintf("%s %s (%s)", i.Format, i.Version, i.Machine)
+}
+
+// machines maps the format names to the machine they are used by. DSK discs
+// are used by the Amstrad CPC and PCW, and the Spectrum +3, which was made by
+// Amstrad, so these are all reported as Amstrad. UEF tapes are used by both the
+// Acorn BBC Micro and Electron.
+var machines = map[string]string{
+	"tzx":    MachineSpectrum,
+	"pzx":    MachineSpectrum,
+	"tap":    MachineSpectrum,
+	"dsk":    MachineAmstrad,
+	"t64":    MachineCommodore,
+	"c64tap": MachineCommodore,
+	"crt":    MachineCommodore,
+	"uef":    MachineAcorn,
+}
+
+// Identify returns the machine, format and version of the media, using only
+// the header bytes, without parsing the rest of the data. This allows files to
+// be routed to the correct format reader.
+//
+// When r is a *Reader the header bytes are only peeked, so the same reader can
+// then be used to read the media, otherwise those bytes are consumed.
+//
+// As the Amstrad CDT format is identical to TZX, these are identified as ZX
+// Spectrum TZX tapes; only the file extension can tell them apart.
+func Identify(r io.Reader) (Info, error) {
+	data, err := detectHeader(r)
+	if err != nil {
+		return Info{}, err
+	}
+
+	format, err := detectSignature(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{
+		Machine: machines[format],
+		Format:  format,
+		Version: formatVersion(format, data),
+	}, nil
+}
+
+// formatVersion returns the version stored in the header bytes of the format.
+func formatVersion(format string, data []byte) string {
+	switch format {
+	case "tzx":
+		// signature, then major and minor revision
+		if len(data) >= 10 {
+			return fmt.Sprintf("%d.%d", data[8], data[9])
+		}
+	case "pzx":
+		// PZXT tag, block size, then major and minor revision
+		if len(data) >= 10 {
+			return fmt.Sprintf("%d.%d", data[8], data[9])
+		}
+	case "dsk":
+		if data[0] == 'E' {
+			return "extended"
+		}
+		return "standard"
+	case "t64":
+		// following the 32 byte tape description
+		if len(data) >= 34 {
+			return fmt.Sprintf("$%04x", binary.LittleEndian.Uint16(data[32:34]))
+		}
+	case "c64tap":
+		// following the 12 byte signature
+		if len(data) >= 13 {
+			return fmt.Sprintf("%d", data[12])
+		}
+	case "crt":
+		// big endian, following the signature and header length
+		if len(data) >= 22 {
+			return fmt.Sprintf("%d.%02d", data[20], data[21])
+		}
+	case "uef":
+		// following the signature, minor then major version
+		if len(data) >= 12 {
+			return fmt.Sprintf("%d.%d", data[11], data[10])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+// header returns the signature followed by the bytes, padded to the detect length.
+func header(signature string, b ...byte) []byte {
+	data := append([]byte(signature), b...)
+	return append(data, make([]byte, detectLength)...)
+}
+
+func TestIdentify(t *testing.T) {
+	t64 := header("C64S tape image file")
+	t64[32], t64[33] = 0x01, 0x01
+
+	crt := header("C64 CARTRIDGE   \x00\x00\x00\x40\x01\x00")
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{"tzx", header("ZXTape!\x1a", 1, 20), Info{MachineSpectrum, "tzx", "1.20"}},
+		{"pzx", header("PZXT", 2, 0, 0, 0, 1, 0), Info{MachineSpectrum, "pzx", "1.0"}},
+		{"tap", header("\x13\x00\x00\x03"), Info{MachineSpectrum, "tap", ""}},
+		{"dsk", header("MV - CPCEMU Disk-File\r\n"), Info{MachineAmstrad, "dsk", "standard"}},
+		{"extended dsk", header("EXTENDED CPC DSK File\r\n"), Info{MachineAmstrad, "dsk", "extended"}},
+		{"t64", t64, Info{MachineCommodore, "t64", "$0101"}},
+		{"c64 tap", header("C64-TAPE-RAW", 1), Info{MachineCommodore, "c64tap", "1"}},
+		{"crt", crt, Info{MachineCommodore, "crt", "1.00"}},
+		{"uef", header("UEF File!\x00", 10, 0), Info{MachineAcorn, "uef", "0.10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Identify(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifyUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"text", []byte("10 PRINT \"HELLO\"")},
+		{"tap data block", header("\x13\x00\xff\x00")},
+		{"short signature", []byte("ZXTape")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Identify(bytes.NewReader(tt.data)); err != ErrUnknownFormat {
+				t.Errorf("got error %v, want %v", err, ErrUnknownFormat)
+			}
+		})
+	}
+}
+
+func TestIdentifyPeeks(t *testing.T) {
+	data := header("ZXTape!\x1a", 1, 20)
+	r := NewReader(bytes.NewReader(data))
+
+	if _, err := Identify(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Offset() != 0 {
+		t.Errorf("got offset %d after Identify, want 0", r.Offset())
+	}
+	if b := r.ReadBytes(8); string(b) != "ZXTape!\x1a" {
+		t.Errorf("got %q reading the header, want the signature", b)
+	}
+}
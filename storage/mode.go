@@ -0,0 +1,40 @@
+package storage
+
+// Mode sets how a parser handles data that does not follow the format
+// specification, such as a wrong version number, or an unknown block type.
+type Mode int
+
+const (
+	// Strict mode returns an error for any violation of the specification.
+	Strict Mode = iota
+
+	// Lenient mode records a warning for each violation, and parses the
+	// remaining data as far as possible.
+	Lenient
+)
+
+// SetMode changes how violations of the specification are handled.
+// By default the reader is in Strict mode.
+func (r *Reader) SetMode(mode Mode) {
+	r.mode = mode
+}
+
+// Lenient reports whether the reader is in Lenient mode.
+func (r *Reader) Lenient() bool {
+	return r.mode == Lenient
+}
+
+// Tolerate returns the error unchanged in Strict mode. In Lenient mode the
+// error is recorded as a warning, and nil is returned so parsing can continue.
+func (r *Reader) Tolerate(err error) error {
+	if err == nil || r.mode == Strict {
+		return err
+	}
+	r.warnings = append(r.warnings, err.Error())
+	return nil
+}
+
+// Warnings returns all the errors tolerated in Lenient mode.
+func (r *Reader) Warnings() []string {
+	return r.warnings
+}
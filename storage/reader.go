@@ -1,79 +1,183 @@
-// Package image provides an io.Reader interface, along with various byte reading
+// Package storage provides an io.Reader interface, along with various byte reading
 // helper functions for processing image data files.
 //
 // To make this reader easier to use, most of these functions omit the handling
 // of errors during the read operation. As we are working with known header/data
 // structures, this has been done solely for ease of use.
 // Should an EOF ever be reached...well...then there is something seriously wrong
-// with the image file, so a `fatal` is triggered.
+// with the image file (most likely it is truncated), so the first error is
+// recorded and can be checked with `Err()` once a block has been read.
 package storage
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
+	"os"
 )
 
+// ErrSeekUnsupported is returned by Seek when the source reader is not an io.Seeker.
+var ErrSeekUnsupported = errors.New("storage: reader does not support seeking")
+
 // Image reader, using the bufio.Reader to allow for Peeking.
 type Reader struct {
+	source io.Reader
 	reader *bufio.Reader
+	offset int64 // number of bytes consumed from the underlying reader
+	err    error // first error encountered by the helper functions
+
+	mode     Mode     // how violations of the format specification are handled
+	warnings []string // violations tolerated in lenient mode
 }
 
 // NewReader first converts the regular reader to a buffered reader.
 func NewReader(r io.Reader) *Reader {
-	return &Reader{reader: bufio.NewReader(r)}
+	return &Reader{source: r, reader: bufio.NewReader(r)}
 }
 
 // Read exactly the requested bytes from the reader, and follows the reader interface.
 // It will read either the currently buffered bytes, or perform a io.ReadFull.
-func (r Reader) Read(b []byte) (int, error) {
+func (r *Reader) Read(b []byte) (int, error) {
+	var n int
+	var err error
+
 	// if the buffer contains enough bytes, use them.
 	if len(b) <= r.reader.Buffered() {
-		return r.reader.Read(b)
+		n, err = r.reader.Read(b)
+	} else {
+		n, err = io.ReadFull(r.reader, b)
 	}
+	r.offset += int64(n)
 
-	return io.ReadFull(r.reader, b)
+	return n, err
 }
 
 // ReadByte delegates to the underlying Reader function, and reads a single byte.
-// Errors are discarded so this should only be used when a byte is known to be present.
-func (r Reader) ReadByte() byte {
-	b, _ := r.reader.ReadByte()
+// This follows the io.ByteReader interface.
+func (r *Reader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+	return b, err
+}
+
+// ReadUint8 reads a single byte from the reader.
+// Errors are recorded (see `Err()`) so this should only be used when a byte is known to be present.
+func (r *Reader) ReadUint8() uint8 {
+	b, err := r.ReadByte()
+	r.setError(err)
 	return b
 }
 
 // ReadBytes reads a variable length of bytes from the reader.
-// Errors are discarded so this should only be used when a byte is known to be present.
-func (r Reader) ReadBytes(number int) []byte {
+// Errors are recorded (see `Err()`) so this should only be used when the bytes are known to be present.
+func (r *Reader) ReadBytes(number int) []byte {
 	b := make([]byte, number)
-	_, _ = r.Read(b)
+	_, err := r.Read(b)
+	r.setError(err)
 	return b
 }
 
+// payloadChunkSize is the number of bytes read at a time by ReadBytesContext.
+const payloadChunkSize = 64 * 1024
+
+// ReadBytesContext reads a variable length of bytes from the reader, like
+// ReadBytes, in chunks so a large payload can be cancelled part way through.
+// Before each chunk the context is checked, and progress is called (when not
+// nil) with the reader offset and Size. As the bytes are allocated as they are
+// read, a corrupt length does not allocate more than the data available.
+// Errors are recorded (see `Err()`) as well as being returned.
+func (r *Reader) ReadBytesContext(ctx context.Context, number int, progress func(done, total int64)) ([]byte, error) {
+	var data []byte
+	for len(data) < number {
+		if err := ctx.Err(); err != nil {
+			return data, err
+		}
+		if progress != nil {
+			progress(r.Offset(), r.Size())
+		}
+
+		size := number - len(data)
+		if size > payloadChunkSize {
+			size = payloadChunkSize
+		}
+		chunk := make([]byte, size)
+		n, err := r.Read(chunk)
+		data = append(data, chunk[:n]...)
+		if err != nil {
+			r.setError(err)
+			return data, r.Err()
+		}
+	}
+	return data, nil
+}
+
+// Err returns the first error encountered by the error-less helper functions,
+// such as ReadUint8, ReadShort, ReadLong. As these are only used within a known
+// data structure, an EOF is reported as io.ErrUnexpectedEOF.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) setError(err error) {
+	if err == nil || r.err != nil {
+		return
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	r.err = err
+}
+
 // ReadShort reads a value from the reader, converting the little endian ordered bytes to a uint16.
-func (r Reader) ReadShort() uint16 {
+func (r *Reader) ReadShort() uint16 {
 	b := r.ReadBytes(2)
 	return binary.LittleEndian.Uint16(b[:])
 }
 
+// ReadUint24 reads a 3-byte value from the reader, converting the little endian
+// ordered bytes to a uint32.
+func (r *Reader) ReadUint24() (uint32, error) {
+	b := make([]byte, 3)
+	if _, err := r.Read(b); err != nil {
+		r.setError(err)
+		return 0, err
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16, nil
+}
+
+// ReadUint24BigEndian reads a 3-byte value from the reader, converting the
+// big endian ordered bytes to a uint32.
+func (r *Reader) ReadUint24BigEndian() (uint32, error) {
+	b := make([]byte, 3)
+	if _, err := r.Read(b); err != nil {
+		r.setError(err)
+		return 0, err
+	}
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]), nil
+}
+
 // ReadLong reads a value from the reader, converting the little endian ordered bytes to a uint32.
-func (r Reader) ReadLong() uint32 {
+func (r *Reader) ReadLong() uint32 {
 	b := r.ReadBytes(4)
 	return binary.LittleEndian.Uint32(b[:])
 }
 
 // Buffered delegates to the underlying Reader function, returning the number of bytes left in the buffer.
-func (r Reader) Buffered() int {
+func (r *Reader) Buffered() int {
 	return r.reader.Buffered()
 }
 
 // Peek returns the next n bytes without advancing the reader.
-func (r Reader) Peek(n int) ([]byte, error) {
+func (r *Reader) Peek(n int) ([]byte, error) {
 	return r.reader.Peek(n)
 }
 
 // PeekByte reads a byte without advancing the reader.
-func (r Reader) PeekByte() (uint8, error) {
+func (r *Reader) PeekByte() (uint8, error) {
 	b, err := r.reader.Peek(1)
 	if err != nil {
 		return 0, err
@@ -83,7 +187,7 @@ func (r Reader) PeekByte() (uint8, error) {
 
 // PeekShort reads two bytes without advancing the reader, converting
 // the little endian ordered bytes to a uint16.
-func (r Reader) PeekShort() (uint16, error) {
+func (r *Reader) PeekShort() (uint16, error) {
 	b, err := r.reader.Peek(2)
 	if err != nil {
 		return 0, err
@@ -92,17 +196,66 @@ func (r Reader) PeekShort() (uint16, error) {
 }
 
 // Discard delegates to the underlying Reader function.
-func (r Reader) Discard(n int) (int, error) {
-	return r.reader.Discard(n)
+func (r *Reader) Discard(n int) (int, error) {
+	discarded, err := r.reader.Discard(n)
+	r.offset += int64(discarded)
+	return discarded, err
 }
 
-// BytesToLong converts a slice of 4 little endian ordered bytes to uint32.
-func (r Reader) BytesToLong(b []byte) uint32 {
-	return binary.LittleEndian.Uint32(b[:])
+// Seek sets the offset for the next Read, and follows the io.Seeker interface.
+// This is only possible when the source reader is an io.Seeker, otherwise
+// ErrSeekUnsupported is returned. Any buffered (peeked) data is discarded.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := r.source.(io.Seeker)
+	if !ok {
+		return r.offset, ErrSeekUnsupported
+	}
+
+	// The source position is ahead of the reader offset by the buffered
+	// bytes, so relative seeks are converted to an absolute offset.
+	if whence == io.SeekCurrent {
+		offset += r.offset
+		whence = io.SeekStart
+	}
+
+	position, err := seeker.Seek(offset, whence)
+	if err != nil {
+		return r.offset, err
+	}
+
+	r.reader.Reset(r.source)
+	r.offset = position
+
+	return position, nil
 }
 
-// Bytes3ToLong converts a slice of 3 little endian ordered bytes to uint32.
-func (r Reader) Bytes3ToLong(b [3]byte) uint32 {
-	l := append(b[:], 0) // add 4th byte
-	return binary.LittleEndian.Uint32(l[:])
+// Seekable reports whether Seek is supported by the source reader.
+func (r *Reader) Seekable() bool {
+	_, ok := r.source.(io.Seeker)
+	return ok
+}
+
+// Offset returns the current byte offset into the underlying reader.
+// Peeking does not advance the offset.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Size returns the size of the underlying file in bytes, or -1 when the
+// source is not a file, e.g. a decompressed stream.
+func (r *Reader) Size() int64 {
+	file, ok := r.source.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return -1
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// BytesToLong converts a slice of 4 little endian ordered bytes to uint32.
+func (r *Reader) BytesToLong(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b[:])
 }
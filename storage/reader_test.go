@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestOffset(t *testing.T) {
+	data := make([]byte, 64)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	tests := []struct {
+		name   string
+		read   func(r *Reader)
+		offset int64
+	}{
+		{"start", func(r *Reader) {}, 0},
+		{"ReadUint8", func(r *Reader) { r.ReadUint8() }, 1},
+		{"ReadByte", func(r *Reader) { _, _ = r.ReadByte() }, 1},
+		{"ReadShort", func(r *Reader) { r.ReadShort() }, 2},
+		{"ReadLong", func(r *Reader) { r.ReadLong() }, 4},
+		{"ReadBytes", func(r *Reader) { r.ReadBytes(10) }, 10},
+		{"Read", func(r *Reader) { _, _ = r.Read(make([]byte, 7)) }, 7},
+		{"Discard", func(r *Reader) { _, _ = r.Discard(5) }, 5},
+		{"Peek", func(r *Reader) { _, _ = r.Peek(8) }, 0},
+		{"PeekByte", func(r *Reader) { _, _ = r.PeekByte() }, 0},
+		{"PeekShort", func(r *Reader) { _, _ = r.PeekShort() }, 0},
+		{"mixed reads and peeks", func(r *Reader) {
+			r.ReadUint8()
+			_, _ = r.Peek(16)
+			r.ReadShort()
+			_, _ = r.PeekByte()
+			r.ReadLong()
+			_, _ = r.PeekShort()
+			_, _ = r.Discard(3)
+			r.ReadBytes(4)
+		}, 14},
+		{"seek", func(r *Reader) {
+			r.ReadBytes(20)
+			_, _ = r.Seek(-5, io.SeekCurrent)
+		}, 15},
+		{"read past the end", func(r *Reader) { _, _ = r.Read(make([]byte, 100)) }, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data))
+			tt.read(r)
+			if got := r.Offset(); got != tt.offset {
+				t.Errorf("got offset %d, want %d", got, tt.offset)
+			}
+		})
+	}
+}
+
+func TestOffsetAfterPeek(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{1, 2, 3, 4}))
+
+	if _, err := r.Peek(4); err != nil {
+		t.Fatal(err)
+	}
+	if b := r.ReadUint8(); b != 1 {
+		t.Errorf("got byte %d after peeking, want 1", b)
+	}
+	if got := r.Offset(); got != 1 {
+		t.Errorf("got offset %d, want 1", got)
+	}
+}
+
+func TestReadUint24(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		little    uint32
+		big       uint32
+		shortRead bool
+	}{
+		{"zero", []byte{0x00, 0x00, 0x00}, 0, 0, false},
+		{"one", []byte{0x01, 0x00, 0x00}, 0x000001, 0x010000, false},
+		{"mixed", []byte{0x12, 0x34, 0x56}, 0x563412, 0x123456, false},
+		{"max value", []byte{0xFF, 0xFF, 0xFF}, 0xFFFFFF, 0xFFFFFF, false},
+		{"short", []byte{0x12, 0x34}, 0, 0, true},
+		{"empty", []byte{}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(tt.data))
+			got, err := r.ReadUint24()
+			if tt.shortRead {
+				if err == nil {
+					t.Error("little endian: expected an error")
+				}
+				if r.Err() == nil {
+					t.Error("little endian: expected the error to be recorded")
+				}
+			} else if err != nil || got != tt.little {
+				t.Errorf("little endian: got 0x%06X, %v, want 0x%06X", got, err, tt.little)
+			}
+
+			r = NewReader(bytes.NewReader(tt.data))
+			got, err = r.ReadUint24BigEndian()
+			if tt.shortRead {
+				if err == nil {
+					t.Error("big endian: expected an error")
+				}
+			} else if err != nil || got != tt.big {
+				t.Errorf("big endian: got 0x%06X, %v, want 0x%06X", got, err, tt.big)
+			}
+		})
+	}
+}
+
+func TestReadBytesContext(t *testing.T) {
+	data := make([]byte, 3*payloadChunkSize+10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	r := NewReader(bytes.NewReader(data))
+	calls := 0
+	got, err := r.ReadBytesContext(context.Background(), len(data), func(done, total int64) {
+		calls++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("bytes read do not match the data")
+	}
+	if calls != 4 {
+		t.Errorf("got %d progress calls, want 4", calls)
+	}
+
+	// a length beyond the data is truncated
+	r = NewReader(bytes.NewReader(data[:10]))
+	got, err = r.ReadBytesContext(context.Background(), 1<<30, nil)
+	if err != io.ErrUnexpectedEOF || len(got) != 10 {
+		t.Errorf("got %d bytes and error %v, want 10 bytes and %v", len(got), err, io.ErrUnexpectedEOF)
+	}
+
+	// the context is checked before each chunk, so a cancel from progress
+	// stops the read once the chunk that follows it has been read
+	ctx, cancel := context.WithCancel(context.Background())
+	r = NewReader(bytes.NewReader(data))
+	got, err = r.ReadBytesContext(ctx, len(data), func(done, total int64) {
+		if done > 0 {
+			cancel()
+		}
+	})
+	if err != context.Canceled || len(got) != 2*payloadChunkSize {
+		t.Errorf("got %d bytes and error %v, want %d bytes and %v", len(got), err, 2*payloadChunkSize, context.Canceled)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ASCIIOnly replaces any non-ASCII characters of the text given to DisplayText
+// with '?', for terminals that can not show UTF-8.
+var ASCIIOnly bool
+
+// DecodeLatin1 returns the ISO 8859-1 (Latin-1) text, as used for the strings
+// of the tape and disk formats, as a UTF-8 string. Each byte is the Unicode
+// code point of the same value, so the bytes 0xA0 to 0xFF become accented
+// letters and symbols, such as 0xE9 for 'é'.
+func DecodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// ReadLatin1String reads a string stored as its length, of 1 or 2 bytes given
+// by lengthBytes, with the 2 byte length in little endian order, followed by
+// that many Latin-1 characters, returning the text as a UTF-8 string. A zero
+// length gives an empty string.
+//
+// Unlike the error-less helpers, the error is returned, as well as recorded.
+func (r *Reader) ReadLatin1String(lengthBytes int) (string, error) {
+	if lengthBytes != 1 && lengthBytes != 2 {
+		return "", fmt.Errorf("storage: invalid string length size %d, expected 1 or 2", lengthBytes)
+	}
+
+	text, err := r.ReadPrefixedBytes(lengthBytes)
+	if err != nil {
+		return "", err
+	}
+	return DecodeLatin1(text), nil
+}
+
+// ReadPrefixedBytes reads data stored as its length, of 1, 2 or 4 bytes given
+// by lengthBytes, in little endian order, followed by that many bytes. As the
+// bytes are allocated as they are read, a corrupt length does not allocate
+// more than the data available.
+//
+// Unlike the error-less helpers, the error is returned, as well as recorded.
+func (r *Reader) ReadPrefixedBytes(lengthBytes int) ([]byte, error) {
+	if lengthBytes != 1 && lengthBytes != 2 && lengthBytes != 4 {
+		return nil, fmt.Errorf("storage: invalid length size %d, expected 1, 2 or 4", lengthBytes)
+	}
+
+	prefix := make([]byte, 4)
+	if _, err := r.Read(prefix[:lengthBytes]); err != nil {
+		return nil, r.prefixError(err)
+	}
+	length := binary.LittleEndian.Uint32(prefix)
+
+	data, err := r.ReadBytesContext(context.Background(), int(length), nil)
+	if err != nil {
+		return nil, r.prefixError(err)
+	}
+	return data, nil
+}
+
+// prefixError records the error, returning it with an EOF reported as an
+// io.ErrUnexpectedEOF, as the data is incomplete.
+func (r *Reader) prefixError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	r.setError(err)
+	return err
+}
+
+// DisplayText returns the text ready for display, with any non-ASCII
+// characters replaced when ASCIIOnly is set. The non-breaking space is
+// replaced with a normal space.
+func DisplayText(s string) string {
+	if !ASCIIOnly {
+		return s
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x80:
+			return r
+		case r == 0xa0:
+			return ' '
+		default:
+			return '?'
+		}
+	}, s)
+}
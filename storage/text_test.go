@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLatin1(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", []byte{}, ""},
+		{"ASCII", []byte("HELLO 48K"), "HELLO 48K"},
+		{"non-breaking space", []byte{0xa0}, "\u00a0"},
+		{"copyright", []byte{0xa9, ' ', '1', '9', '8', '4'}, "© 1984"},
+		{"pound sign", []byte{0xa3, '5', '.', '9', '9'}, "£5.99"},
+		{"accented letters", []byte{'C', 'a', 'f', 0xe9, ' ', 'M', 0xfc, 'l', 'l', 'e', 'r'}, "Café Müller"},
+		{"upper case", []byte{0xc0, 0xc9, 0xd1, 0xd6}, "ÀÉÑÖ"},
+		{"last character", []byte{0xff}, "ÿ"},
+		{"full range", []byte{0xa0, 0xbf, 0xc0, 0xdf, 0xe0, 0xff}, "\u00a0¿Àßàÿ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeLatin1(tt.data); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeLatin1Range(t *testing.T) {
+	for c := 0xa0; c <= 0xff; c++ {
+		got := []rune(DecodeLatin1([]byte{byte(c)}))
+		if len(got) != 1 || got[0] != rune(c) {
+			t.Errorf("byte %02X: got %q, want U+%04X", c, string(got), c)
+		}
+	}
+}
+
+func TestDisplayText(t *testing.T) {
+	defer func(asciiOnly bool) { ASCIIOnly = asciiOnly }(ASCIIOnly)
+
+	tests := []struct {
+		name  string
+		text  string
+		utf8  string
+		ascii string
+	}{
+		{"ASCII", "HELLO 48K", "HELLO 48K", "HELLO 48K"},
+		{"non-breaking space", "A\u00a0B", "A\u00a0B", "A B"},
+		{"copyright", "© 1984", "© 1984", "? 1984"},
+		{"accented letters", "Café Müller", "Café Müller", "Caf? M?ller"},
+		{"last character", "ÿ", "ÿ", "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ASCIIOnly = false
+			if got := DisplayText(tt.text); got != tt.utf8 {
+				t.Errorf("got %q, want %q", got, tt.utf8)
+			}
+			ASCIIOnly = true
+			if got := DisplayText(tt.text); got != tt.ascii {
+				t.Errorf("got %q with ASCII only, want %q", got, tt.ascii)
+			}
+		})
+	}
+}
+
+func TestReadLatin1String(t *testing.T) {
+	max := bytes.Repeat([]byte{'A'}, 255)
+	max16 := bytes.Repeat([]byte{0xe9}, 65535)
+
+	tests := []struct {
+		name        string
+		lengthBytes int
+		data        []byte
+		want        string
+	}{
+		{"empty", 1, []byte{0}, ""},
+		{"empty, 2 byte length", 2, []byte{0, 0}, ""},
+		{"ASCII", 1, []byte{5, 'H', 'E', 'L', 'L', 'O'}, "HELLO"},
+		{"2 byte length", 2, []byte{5, 0, 'H', 'E', 'L', 'L', 'O'}, "HELLO"},
+		{"high-bit characters", 1, []byte{6, 'C', 'a', 'f', 0xe9, ' ', 0xa9}, "Café ©"},
+		{"all high-bit characters", 2, []byte{4, 0, 0xa0, 0xc0, 0xe0, 0xff}, "\u00a0Ààÿ"},
+		{"maximum length", 1, append([]byte{255}, max...), strings.Repeat("A", 255)},
+		{"maximum 2 byte length", 2, append([]byte{0xff, 0xff}, max16...), strings.Repeat("é", 65535)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// followed by the next byte of the data, which must not be read
+			r := NewReader(bytes.NewReader(append(tt.data, 0x42)))
+			got, err := r.ReadLatin1String(tt.lengthBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if r.Offset() != int64(len(tt.data)) {
+				t.Errorf("got offset %d, want %d", r.Offset(), len(tt.data))
+			}
+			if r.Err() != nil {
+				t.Errorf("got recorded error %v, want none", r.Err())
+			}
+		})
+	}
+}
+
+func TestReadLatin1StringErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		lengthBytes int
+		data        []byte
+		want        error
+	}{
+		{"no length", 1, []byte{}, io.ErrUnexpectedEOF},
+		{"half a length", 2, []byte{5}, io.ErrUnexpectedEOF},
+		{"no text", 1, []byte{5}, io.ErrUnexpectedEOF},
+		{"truncated text", 1, []byte{5, 'H', 'E'}, io.ErrUnexpectedEOF},
+		{"truncated maximum length", 1, append([]byte{255}, make([]byte, 254)...), io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(tt.data))
+			if _, err := r.ReadLatin1String(tt.lengthBytes); err != tt.want {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+			if r.Err() != tt.want {
+				t.Errorf("got recorded error %v, want %v", r.Err(), tt.want)
+			}
+		})
+	}
+
+	for _, lengthBytes := range []int{0, 3, 4} {
+		r := NewReader(bytes.NewReader([]byte{1, 0, 0, 0, 'A'}))
+		if _, err := r.ReadLatin1String(lengthBytes); err == nil {
+			t.Errorf("expected an error for a %d byte length", lengthBytes)
+		}
+		if r.Offset() != 0 {
+			t.Errorf("got offset %d for a %d byte length, want nothing read", r.Offset(), lengthBytes)
+		}
+	}
+}
+
+func TestReadPrefixedBytes(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{3, 0, 0, 0, 0x00, 0xe9, 0xff, 0x42}))
+	got, err := r.ReadPrefixedBytes(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x00, 0xe9, 0xff}; !bytes.Equal(got, want) {
+		t.Errorf("got % X, want % X", got, want)
+	}
+
+	// a corrupt length is not allocated before the data is found to be missing
+	r = NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0x7f, 'A'}))
+	if _, err := r.ReadPrefixedBytes(4); err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnexpectedEOF is wrapped by all errors for data that ends part way
+// through a block, and can be checked for using errors.Is.
+var ErrUnexpectedEOF = io.ErrUnexpectedEOF
+
+// TruncatedError is returned when the data ends part way through a block.
+// Parsers return this alongside any blocks read before the truncation, so
+// whatever is recoverable from a partial image can still be displayed.
+// Use errors.As to retrieve it from a wrapped error.
+type TruncatedError struct {
+	Blocks int   // Number of complete blocks read
+	Offset int64 // Offset of the start of the incomplete block
+}
+
+func (e TruncatedError) Error() string {
+	return fmt.Sprintf("unexpected EOF after block %d at offset %d", e.Blocks, e.Offset)
+}
+
+// Unwrap returns ErrUnexpectedEOF, so errors.Is can be used to check for truncation.
+func (e TruncatedError) Unwrap() error {
+	return ErrUnexpectedEOF
+}
+
+// IsEOF reports whether the error was caused by reaching the end of the data.
+func IsEOF(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsTruncated reports whether the error is, or wraps, a TruncatedError.
+func IsTruncated(err error) bool {
+	var truncated TruncatedError
+	return errors.As(err, &truncated)
+}
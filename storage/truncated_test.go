@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestTruncatedErrorWrapped(t *testing.T) {
+	err := errors.Wrap(TruncatedError{Blocks: 3, Offset: 42}, "block #4")
+
+	var truncated TruncatedError
+	if !errors.As(err, &truncated) {
+		t.Fatalf("errors.As did not find the TruncatedError in %q", err)
+	}
+	if truncated.Blocks != 3 || truncated.Offset != 42 {
+		t.Errorf("got blocks %d at offset %d, want 3 at 42", truncated.Blocks, truncated.Offset)
+	}
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("errors.Is(%q, ErrUnexpectedEOF) = false, want true", err)
+	}
+}
+
+func TestIsEOF(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		eof       bool
+		truncated bool
+	}{
+		{"nil", nil, false, false},
+		{"EOF", io.EOF, true, false},
+		{"wrapped EOF", errors.Wrap(io.EOF, "reading"), true, false},
+		{"unexpected EOF", errors.WithMessage(io.ErrUnexpectedEOF, "reading"), true, false},
+		{"truncated", TruncatedError{Blocks: 1}, true, true},
+		{"wrapped truncated", errors.Wrap(errors.Wrap(TruncatedError{}, "block"), "tape"), true, true},
+		{"other", errors.New("bad block"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEOF(tt.err); got != tt.eof {
+				t.Errorf("IsEOF() = %v, want %v", got, tt.eof)
+			}
+			if got := IsTruncated(tt.err); got != tt.truncated {
+				t.Errorf("IsTruncated() = %v, want %v", got, tt.truncated)
+			}
+		})
+	}
+}